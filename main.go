@@ -1,17 +1,32 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"crypto/ed25519"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/lmittmann/tint"
+	"github.com/package-url/packageurl-go"
 	"github.com/samber/oops"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	"github.com/aquasecurity/vexhub-crawler/pkg/logging"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/report"
+	"github.com/aquasecurity/vexhub-crawler/pkg/trace"
 	"github.com/aquasecurity/vexhub-crawler/pkg/vexhub"
 )
 
@@ -20,44 +35,661 @@ func init() {
 	slog.SetDefault(slog.New(tint.NewHandler(os.Stderr, nil)))
 }
 
+// exitCodes are the exit codes by the category of the error, so that CI pipelines can branch on them.
+// The other errors, including invalid config, exit with 1.
+var exitCodes = map[report.Category]int{
+	report.CategoryNoVEX:      2,
+	report.CategoryValidation: 3,
+	report.CategoryDownload:   4,
+}
+
 func main() {
 	if err := run(); err != nil {
 		slog.Error("Fatal error")
 		fmt.Printf("%+v", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// setupLogging sets the global logger with the levels, see logging.ParseLevels.
+func setupLogging(level, components string) error {
+	levels, err := logging.ParseLevels(level, components)
+	if err != nil {
+		return err
 	}
+	base := tint.NewHandler(os.Stderr, &tint.Options{Level: levels.Min()})
+	slog.SetDefault(slog.New(logging.NewHandler(base, levels)))
+	return nil
+}
+
+func exitCode(err error) int {
+	if code, ok := exitCodes[crawl.Categorize(err)]; ok {
+		return code
+	}
+	return 1
 }
 
 func run() error {
 	ctx := context.Background()
 
-	configPath := flag.String("config", "crawler.yaml", "Crawler config")
-	vexHubDir := flag.String("vexhub-dir", "", "Vex Hub directory")
-	strict := flag.Bool("strict", false, "Strict mode")
-	debug := flag.Bool("debug", false, "Enable debug logging")
-	flag.Parse()
+	// Crawling is the default command
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "crawl":
+			return runCrawl(ctx, args[1:])
+		case "sources":
+			return runSources(args[1:])
+		case "list":
+			return runList(args[1:])
+		case "prune":
+			return runPrune(args[1:])
+		case "export":
+			return runExport(args[1:])
+		case "diff":
+			return runDiff(args[1:])
+		case "verify":
+			return runVerify(args[1:])
+		case "validate":
+			return runValidate(ctx, args[1:])
+		case "serve":
+			return runServe(args[1:])
+		case "grpc":
+			return runGRPC(args[1:])
+		}
+	}
+	return runCrawl(ctx, args)
+}
 
-	if *vexHubDir == "" {
-		return fmt.Errorf("--vexhub-dir is required")
+func runCrawl(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("crawl", flag.ExitOnError)
+	configPath := flags.String("config", "crawler.yaml", "Crawler config")
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	strict := flags.Bool("strict", false, "Strict mode")
+	strictPURL := flags.Bool("strict-purl", false, "Fail a package if any VEX document doesn't match its PURL")
+	timeout := flags.Duration("timeout", 0, "Deadline of the whole crawl (e.g. 30m)")
+	maxBytes := flags.Int64("max-bytes", 0, "Budget of downloaded bytes across the whole crawl")
+	verifyWrites := flags.Bool("verify-writes", false, "Re-validate VEX documents after writing them into VEX Hub")
+	maxTags := flags.Int("max-tags", 0, "Also crawl up to the given number of the latest semver tags")
+	reportPath := flags.String("report", "", "Write the crawl report to the file")
+	statePath := flags.String("state", "", "Record the packages crawled successfully to the file until the crawl completes")
+	resume := flags.Bool("resume", false, "Resume the interrupted crawl recorded in the state file")
+	since := flags.Duration("since", 0, "Skip the packages already in VEX Hub whose source has had no commit within the duration (e.g. 168h)")
+	deterministicTempDir := flags.Bool("deterministic-tmpdir", false, "Download sources into temporary directories named after the PURL and ref")
+	clone := flags.Bool("clone", false, "Clone source repositories instead of downloading archives from GitHub and GitLab")
+	parseWorkers := flags.Int("parse-workers", 1, "Number of files validated in parallel within a package")
+	incremental := flags.Bool("incremental", false, "Validate only the files changed since the commit of the previous crawl")
+	semanticChanges := flags.Bool("semantic-changes", false, "Ignore formatting-only changes of the VEX documents in a git VEX Hub")
+	stdin := flags.Bool("stdin", false, "Read newline-delimited PURLs, optionally followed by URLs, to crawl from stdin instead of the config")
+	force := flags.Bool("force", false, "Crawl even if some packages fail the validation before the crawl")
+	debug := flags.Bool("debug", false, "Enable debug logging, same as --log-level debug")
+	logLevel := flags.String("log-level", "info", "Minimum log level (debug, info, warn or error)")
+	logLevels := flags.String("log-levels", "", "Comma-separated log levels of the components overriding --log-level (e.g. crawl/vex=warn,download=debug)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *resume && *statePath == "" {
+		return fmt.Errorf("--state is required for --resume")
 	}
 	if *debug {
-		slog.SetDefault(slog.New(tint.NewHandler(os.Stderr, &tint.Options{
-			Level: slog.LevelDebug,
-		})))
+		*logLevel = "debug"
+	}
+	if err := setupLogging(*logLevel, *logLevels); err != nil {
+		return err
 	}
 
 	c, err := config.Load(*configPath)
 	if err != nil {
 		return oops.Wrapf(err, "failed to load")
 	}
+	if len(c.Hubs) > 0 && (*vexHubDir != "" || *stdin) {
+		return fmt.Errorf("--vexhub-dir and --stdin can't be used when hubs are configured")
+	} else if len(c.Hubs) == 0 && *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	}
+	if err = download.ConfigureTLS(c.TLS); err != nil {
+		return oops.Wrapf(err, "failed to configure TLS")
+	}
+	if err = download.ConfigureGitHubApp(c.GitHubApp); err != nil {
+		return oops.Wrapf(err, "failed to configure the GitHub App")
+	}
+	if err = download.ConfigureBasicAuth(c.BasicAuth); err != nil {
+		return oops.Wrapf(err, "failed to configure basic auth")
+	}
+	if err = download.ConfigureCredentialHelper(c.CredentialHelper); err != nil {
+		return oops.Wrapf(err, "failed to configure the credential helper")
+	}
+	if err = download.ConfigureUserAgent(c.UserAgent); err != nil {
+		return oops.Wrapf(err, "failed to configure the User-Agent")
+	}
+	var signingKey ed25519.PrivateKey
+	if c.ManifestSigningKey != "" {
+		if signingKey, err = manifest.LoadSigningKey(c.ManifestSigningKey); err != nil {
+			return oops.Wrapf(err, "failed to load the manifest signing key")
+		}
+	}
+	tp, shutdown, err := trace.NewTracerProvider(ctx)
+	if err != nil {
+		return oops.Wrapf(err, "failed to configure tracing")
+	}
+	defer func() {
+		if serr := shutdown(ctx); serr != nil {
+			slog.Warn("Failed to flush the spans", slog.Any("err", serr))
+		}
+	}()
+	if *stdin {
+		pkgs, invalid, err := config.ReadPackages(os.Stdin)
+		if err != nil {
+			return oops.Wrapf(err, "failed to read packages from stdin")
+		}
+		for _, e := range invalid {
+			slog.Warn("Invalid package", slog.Int("line", e.Line), slog.Any("err", e.Err))
+		}
+		if *strict && len(invalid) > 0 {
+			return oops.With("invalid_lines", len(invalid)).Errorf("invalid packages in stdin")
+		}
+		c.Packages = pkgs
+	}
 
-	if err = crawl.Packages(ctx, crawl.Options{
-		VEXHubDir: *vexHubDir,
-		Packages:  c.Packages,
-		Strict:    *strict,
-	}); err != nil {
+	// Wildcard packages are expanded first so that the packages they expand into are validated.
+	// All the packages are validated before any download so that the config can be fixed in one pass.
+	prepare := func(dir string, pkgs []config.Package) ([]config.Package, int, error) {
+		pkgs, err := crawl.Expand(ctx, pkgs, c.MaxExpansion)
+		if err != nil {
+			if *strict {
+				return nil, 0, oops.Wrapf(err, "failed to expand wildcard packages")
+			}
+			slog.Warn("Failed to expand wildcard packages", slog.String("vexhub_dir", dir), slog.Any("err", err))
+		}
+		if c.Parents {
+			config.DeriveParents(pkgs)
+		}
+		errs := crawl.Validate(dir, pkgs)
+		for _, e := range errs {
+			slog.Warn("Invalid package", slog.String("vexhub_dir", dir), slog.String("purl", e.PURL), slog.Any("err", e.Err))
+		}
+		return pkgs, len(errs), nil
+	}
+	var invalid int
+	if len(c.Hubs) == 0 {
+		if c.Packages, invalid, err = prepare(*vexHubDir, c.Packages); err != nil {
+			return err
+		}
+	}
+	for i, h := range c.Hubs {
+		pkgs, n, err := prepare(h.Dir, h.Packages)
+		if err != nil {
+			return oops.With("hub", h.Name).Wrap(err)
+		}
+		c.Hubs[i].Packages, invalid = pkgs, invalid+n
+	}
+	if invalid > 0 && !*force {
+		return oops.With("invalid_packages", invalid).Errorf("invalid packages, fix them or use --force")
+	}
+	if err = checkGit(download.DetectGit(ctx), c, *clone); err != nil {
+		return err
+	}
+
+	// Flags aren't part of the config, but change the behavior as much
+	c.LogEffective(slog.With(
+		slog.Bool("strict", *strict),
+		slog.Bool("strict_purl", *strictPURL),
+		slog.Duration("timeout", *timeout),
+		slog.Int64("max_bytes", *maxBytes),
+		slog.Int("max_tags", *maxTags),
+		slog.Int("parse_workers", *parseWorkers),
+		slog.Bool("verify_writes", *verifyWrites),
+		slog.Bool("semantic_changes", *semanticChanges),
+		slog.Bool("clone", *clone),
+		slog.Bool("resume", *resume),
+		slog.Duration("since", *since),
+	))
+
+	r, err := crawl.Packages(ctx, crawl.Options{
+		VEXHubDir:            *vexHubDir,
+		Packages:             c.Packages,
+		Hubs:                 c.Hubs,
+		Strict:               *strict,
+		StrictPURL:           *strictPURL,
+		Timeout:              *timeout,
+		MaxBytes:             *maxBytes,
+		MaxTags:              *maxTags,
+		VerifyWrites:         *verifyWrites,
+		SemanticChanges:      *semanticChanges,
+		Embargoes:            c.Embargoes,
+		ManifestEncoding:     &c.ManifestEncoding,
+		ManifestName:         c.ManifestName,
+		ManifestSigningKey:   signingKey,
+		Authors:              c.Authors,
+		Defaults:             c.Defaults,
+		Types:                c.Types,
+		Compress:             c.Compress,
+		DigestAlgorithm:      c.DigestAlgorithm,
+		Groups:               c.Groups,
+		MergeManifests:       c.MergeManifests,
+		Latest:               c.Latest,
+		History:              c.History,
+		Quarantine:           c.Quarantine,
+		Statuses:             c.Statuses,
+		Concurrency:          c.Concurrency,
+		ParseWorkers:         *parseWorkers,
+		DeterministicTempDir: *deterministicTempDir,
+		Clone:                *clone,
+		Incremental:          *incremental,
+		StatePath:            *statePath,
+		Resume:               *resume,
+		Since:                *since,
+		Tracer:               tp.Tracer(trace.TracerName),
+	})
+	if *reportPath != "" {
+		if werr := report.Write(*reportPath, r); werr != nil {
+			return oops.Wrapf(werr, "failed to write the report")
+		}
+	}
+	if err != nil {
 		return oops.Wrapf(err, "failed to crawl packages")
 	}
 
-	return oops.Wrap(vexhub.GenerateIndex(*vexHubDir))
+	if len(c.Hubs) == 0 {
+		if err = vexhub.GenerateIndex(*vexHubDir, vexhub.WithManifestName(c.ManifestName)); err != nil {
+			return oops.Wrap(err)
+		}
+	}
+	for _, h := range c.Hubs {
+		if err = vexhub.GenerateIndex(h.Dir, vexhub.WithManifestName(c.ManifestName)); err != nil {
+			return oops.With("hub", h.Name).Wrap(err)
+		}
+	}
+	// Fail the run in which nothing was crawled, even without --strict, with the exit code of the failures
+	return crawl.Failure(r)
+}
+
+func runSources(args []string) error {
+	flags := flag.NewFlagSet("sources", flag.ExitOnError)
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	manifestName := flags.String("manifest-name", manifest.FileName, "File name of the manifests")
+	format := flags.String("format", "json", "Output format (json, cyclonedx)")
+	output := flags.String("output", "", "Output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	}
+
+	sources, err := vexhub.ListSources(*vexHubDir, vexhub.WithManifestName(*manifestName))
+	if err != nil {
+		return oops.Wrapf(err, "failed to list sources")
+	}
+
+	switch *format {
+	case "json":
+		return writeJSON(*output, sources)
+	case "cyclonedx":
+		return writeJSON(*output, sources.CycloneDX())
+	default:
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+}
+
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	manifestName := flags.String("manifest-name", manifest.FileName, "File name of the manifests")
+	format := flags.String("format", "text", "Output format (text, json)")
+	long := flags.Bool("long", false, "Also print the number of sources and the last update in the text format")
+	configPath := flags.String("config", "", "List only the packages no longer in the config file")
+	output := flags.String("output", "", "Output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	}
+
+	pkgs, err := vexhub.ListPackages(*vexHubDir, vexhub.WithManifestName(*manifestName))
+	if err != nil {
+		return oops.Wrapf(err, "failed to list packages")
+	}
+	if *configPath != "" {
+		configured, err := configuredPackages(*configPath, *vexHubDir)
+		if err != nil {
+			return err
+		}
+		pkgs = vexhub.Orphans(pkgs, configured)
+	}
+
+	if *format == "json" {
+		return writeJSON(*output, pkgs)
+	} else if *format != "text" {
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return oops.With("filePath", *output).Wrapf(err, "failed to create the output file")
+		}
+		defer f.Close()
+		w = f
+	}
+	for _, p := range pkgs {
+		line := p.ID
+		if *long {
+			line = fmt.Sprintf("%s\t%d\t%s", p.ID, p.Sources, p.Updated.Format(time.RFC3339))
+		}
+		if _, err = fmt.Fprintln(w, line); err != nil {
+			return oops.Wrapf(err, "failed to write")
+		}
+	}
+	return nil
+}
+
+func runPrune(args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	configPath := flags.String("config", "", "Config file")
+	manifestName := flags.String("manifest-name", manifest.FileName, "File name of the manifests")
+	del := flags.Bool("delete", false, "Remove the orphaned packages instead of only reporting them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	} else if *configPath == "" {
+		return fmt.Errorf("--config is required")
+	}
+
+	configured, err := configuredPackages(*configPath, *vexHubDir)
+	if err != nil {
+		return err
+	}
+	pkgs, err := vexhub.ListPackages(*vexHubDir, vexhub.WithManifestName(*manifestName))
+	if err != nil {
+		return oops.Wrapf(err, "failed to list packages")
+	}
+	orphans := vexhub.Orphans(pkgs, configured)
+	if !*del {
+		for _, p := range orphans {
+			fmt.Printf("%s\t%s\n", p.ID, p.Dir)
+		}
+		slog.Info("Found orphaned packages, pass --delete to remove them", slog.Int("packages", len(orphans)))
+		return nil
+	}
+
+	removed, err := vexhub.Prune(*vexHubDir, orphans, vexhub.WithManifestName(*manifestName))
+	if err != nil {
+		return oops.Wrapf(err, "failed to prune")
+	}
+	slog.Info("Removed orphaned packages", slog.Int("packages", len(removed)),
+		slog.Int("skipped", len(orphans)-len(removed)))
+	if len(removed) > 0 {
+		return vexhub.GenerateIndex(*vexHubDir, vexhub.WithManifestName(*manifestName))
+	}
+	return nil
+}
+
+// configuredPackages loads the packages of the config crawled into the VEX Hub directory,
+// which are the ones of the hub with the directory if any.
+func configuredPackages(configPath, vexHubDir string) ([]config.Package, error) {
+	c, err := config.Load(configPath)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to load")
+	}
+	for _, h := range c.Hubs {
+		if h.Dir == filepath.Clean(vexHubDir) {
+			return h.Packages, nil
+		}
+	}
+	return c.Packages, nil
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	manifestName := flags.String("manifest-name", manifest.FileName, "File name of the manifests")
+	output := flags.String("output", "", "Output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return oops.With("filePath", *output).Wrapf(err, "failed to create the output file")
+		}
+		defer f.Close()
+		w = f
+	}
+	return oops.Wrapf(vexhub.Export(*vexHubDir, w, vexhub.WithManifestName(*manifestName)), "failed to export")
+}
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	manifestName := flags.String("manifest-name", manifest.FileName, "File name of the manifests")
+	from := flags.String("from", "", "Git revision to compare from (default: HEAD)")
+	to := flags.String("to", "", "Git revision to compare to (default: the working tree)")
+	output := flags.String("output", "", "Output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	}
+
+	diff, err := vexhub.DiffStates(*vexHubDir, *from, *to, vexhub.WithManifestName(*manifestName))
+	if err != nil {
+		return oops.Wrapf(err, "failed to diff")
+	}
+	return writeJSON(*output, diff)
+}
+
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	manifestName := flags.String("manifest-name", manifest.FileName, "File name of the manifests")
+	publicKey := flags.String("manifest-public-key", "", "PEM-encoded Ed25519 public key checking the manifest signatures")
+	checkLinks := flags.Bool("check-links", false, "Check that the source URLs of the manifests are reachable")
+	linkHosts := flags.String("link-hosts", strings.Join(vexhub.DefaultLinkHosts, ","), "Comma-separated hosts whose source URLs are checked")
+	linkInterval := flags.Duration("link-interval", 200*time.Millisecond, "Minimum interval between the requests checking the source URLs")
+	output := flags.String("output", "", "Output file of the problems (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	}
+
+	opts := []vexhub.Option{vexhub.WithManifestName(*manifestName)}
+	if *publicKey != "" {
+		key, err := manifest.LoadVerifyingKey(*publicKey)
+		if err != nil {
+			return oops.Wrapf(err, "failed to load the manifest public key")
+		}
+		opts = append(opts, vexhub.WithManifestPublicKey(key))
+	}
+	if *checkLinks {
+		opts = append(opts, vexhub.WithLinkCheck(vexhub.LinkCheck{
+			Client:   download.Client(),
+			Hosts:    strings.Split(*linkHosts, ","),
+			Interval: *linkInterval,
+		}))
+	}
+	problems, err := vexhub.Verify(*vexHubDir, opts...)
+	if err != nil {
+		return oops.Wrapf(err, "failed to verify")
+	}
+	if err = writeJSON(*output, problems); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return oops.With("problems", len(problems)).Errorf("VEX Hub has integrity problems")
+	}
+	return nil
+}
+
+func runValidate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	configPath := flags.String("config", "", "Crawler config whose settings apply, including the ones of the package if configured")
+	purl := flags.String("purl", "", "PURL of the package the VEX documents must match")
+	cpe := flags.String("cpe", "", "CPE of the package the VEX documents must match, instead of --purl")
+	strictPURL := flags.Bool("strict-purl", false, "Fail if any VEX document doesn't match the PURL")
+	output := flags.String("output", "", "Output file of the result (default: stdout)")
+	// The path may come before the flags, e.g. "validate ./repo --purl <purl>"
+	var dir string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dir, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if dir == "" {
+		dir = flags.Arg(0)
+	}
+
+	if dir == "" {
+		return fmt.Errorf("the path of the directory is required")
+	} else if (*purl == "") == (*cpe == "") {
+		return fmt.Errorf("either --purl or --cpe is required")
+	}
+
+	pkg := config.Package{CPE: *cpe}
+	if *purl != "" {
+		p, err := packageurl.FromString(*purl)
+		if err != nil {
+			return oops.Code("load_config_error").With("purl", *purl).Wrapf(err, "invalid PURL")
+		}
+		pkg.PURL = p
+	}
+	opts := crawl.Options{StrictPURL: *strictPURL}
+	if *configPath != "" {
+		c, err := config.Load(*configPath)
+		if err != nil {
+			return oops.Wrapf(err, "failed to load")
+		}
+		pkg = configuredPackage(c, pkg)
+		opts.Authors, opts.Defaults, opts.Embargoes = c.Authors, c.Defaults, c.Embargoes
+		opts.Statuses, opts.Compress = c.Statuses, c.Compress
+	}
+
+	result, err := crawl.ValidateDir(ctx, dir, pkg, opts)
+	if result != nil {
+		if werr := writeJSON(*output, result); werr != nil {
+			return werr
+		}
+	}
+	return oops.With("dir", dir).Wrapf(err, "the package would fail")
+}
+
+// checkGit fails the crawl before any download if the config requires a feature of the git CLI missing on the host,
+// rather than failing every package it affects. Without git, the sources offering archives are still crawled.
+func checkGit(git download.GitCapabilities, c *config.Config, clone bool) error {
+	if git.Git() {
+		slog.Debug("Detected the git CLI", slog.String("version", git.Version), slog.Bool("lfs", git.LFS))
+	} else if clone {
+		return oops.Wrapf(download.ErrGitUnavailable, "--clone requires git")
+	} else {
+		slog.Warn("The git CLI is not installed, only the sources offering archive downloads can be crawled")
+	}
+
+	pkgs := slices.Clone(c.Packages)
+	for _, h := range c.Hubs {
+		pkgs = append(pkgs, h.Packages...)
+	}
+	for _, pkg := range pkgs {
+		lfs := cmp.Or(pkg.Overrides.LFS, c.Defaults.LFS)
+		if lfs != nil && *lfs && !git.LFS {
+			return oops.With("purl", pkg.ID()).Wrapf(download.ErrLFSUnavailable, "lfs is enabled")
+		}
+	}
+	return nil
+}
+
+// configuredPackage returns the package of the config with the same ID, in any hub, so that its settings apply.
+// The given package is returned if it isn't configured.
+func configuredPackage(c *config.Config, pkg config.Package) config.Package {
+	pkgs := c.Packages
+	for _, h := range c.Hubs {
+		pkgs = append(pkgs, h.Packages...)
+	}
+	for _, p := range pkgs {
+		if p.ID() == pkg.ID() {
+			return p
+		}
+	}
+	return pkg
+}
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	manifestName := flags.String("manifest-name", manifest.FileName, "File name of the manifests")
+	addr := flags.String("addr", "localhost:8080", "Address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           vexhub.NewHandler(*vexHubDir, vexhub.WithManifestName(*manifestName)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	slog.Info("Serving VEX Hub", slog.String("addr", *addr), slog.String("dir", *vexHubDir))
+	return oops.With("addr", *addr).Wrapf(server.ListenAndServe(), "failed to serve")
+}
+
+func runGRPC(args []string) error {
+	flags := flag.NewFlagSet("grpc", flag.ExitOnError)
+	vexHubDir := flags.String("vexhub-dir", "", "Vex Hub directory")
+	manifestName := flags.String("manifest-name", manifest.FileName, "File name of the manifests")
+	addr := flags.String("addr", "localhost:9090", "Address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *vexHubDir == "" {
+		return fmt.Errorf("--vexhub-dir is required")
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return oops.With("addr", *addr).Wrapf(err, "failed to listen")
+	}
+	server := vexhub.NewGRPCServer(*vexHubDir, vexhub.WithManifestName(*manifestName))
+	slog.Info("Serving VEX Hub over gRPC", slog.String("addr", *addr), slog.String("dir", *vexHubDir))
+	return oops.With("addr", *addr).Wrapf(server.Serve(lis), "failed to serve")
+}
+
+// writeJSON writes the value as JSON to the file, or stdout if the file is empty.
+func writeJSON(output string, v any) error {
+	w := os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return oops.With("filePath", output).Wrapf(err, "failed to create the output file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+	return oops.Wrapf(e.Encode(v), "JSON encode error")
 }
@@ -1,35 +1,363 @@
 package config
 
 import (
+	"cmp"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
 
+	openvex "github.com/openvex/go-vex/pkg/vex"
 	"github.com/package-url/packageurl-go"
 	"github.com/samber/oops"
 	"gopkg.in/yaml.v3"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// TypeCPE is the type of the packages tracked by CPE rather than PURL, used to filter them.
+const TypeCPE = "cpe"
+
+// Wildcard is the name of the packages standing for all the packages in their namespace.
+const Wildcard = "*"
+
+// DefaultMaxExpansion bounds the packages a wildcard expands into, so that a large organization
+// doesn't turn a crawl into thousands of downloads by surprise.
+const DefaultMaxExpansion = 100
+
+// Default limits of the quarantine, so that a publisher rejecting everything doesn't fill the disk.
+const (
+	DefaultQuarantineMaxFiles    = 20
+	DefaultQuarantineMaxFileSize = 1 << 20 // 1 MiB
 )
 
 type Package struct {
+	// PURL identifies the package. If it has a version, the VEX documents are crawled as of the git tag of the version
+	// and stored in the versioned directory.
 	PURL packageurl.PackageURL
 	URL  string
+
+	// PublicURL is the URL advertised in the manifest instead of URL, e.g. when URL is an internal mirror.
+	// URL is required if it's set.
+	PublicURL string
+
+	// CPE identifies the package by CPE instead of the PURL, e.g. "cpe:2.3:a:example:product".
+	// The URL is required since the source repository can't be detected from a CPE.
+	CPE string
+
+	// Authors overrides the global allowlist of the VEX document authors for the package.
+	Authors []string
+
+	// Overrides overrides the global discovery and validation settings for the package.
+	Overrides Overrides
+
+	// Trusted marks a first-party source whose documents are only parsed and matched against the PURL,
+	// skipping the author allowlist.
+	Trusted bool
+
+	// Parent is the PURL of the package owning this one, recorded in the manifest and the index.
+	// It's set explicitly, or derived from the namespace nesting if Config.Parents is set.
+	Parent string
+}
+
+// ID returns the CPE if the package is tracked by CPE, otherwise the PURL.
+// It identifies the package in the report and the state.
+func (p Package) ID() string {
+	if p.CPE != "" {
+		return p.CPE
+	}
+	return p.PURL.String()
+}
+
+// Wildcard reports whether the package stands for all the packages in its namespace, such as
+// "pkg:golang/github.com/example/*", to be expanded before crawling.
+func (p Package) Wildcard() bool {
+	return p.CPE == "" && p.PURL.Name == Wildcard
+}
+
+// Type returns the PURL type, or TypeCPE if the package is tracked by CPE.
+func (p Package) Type() string {
+	if p.CPE != "" {
+		return TypeCPE
+	}
+	return p.PURL.Type
+}
+
+// Overrides customizes how VEX documents are discovered and validated.
+// Zero values fall back to the global settings, and then to the built-in defaults.
+type Overrides struct {
+	Patterns    []string `yaml:"patterns"`      // Glob patterns of the VEX document file names, e.g. "*.vex.json"
+	Subdir      string   `yaml:"subdir"`        // Directory searched for VEX documents in the source repository
+	RootDirs    []string `yaml:"root_dirs"`     // Directories used as the root if they exist, e.g. "security/vex"
+	RootDirMode string   `yaml:"root_dir_mode"` // Precedence of the root directories over the subdirectory, e.g. "subdir-only"
+	Format      string   `yaml:"format"`        // Format of the VEX documents, e.g. "openvex", instead of detecting it
+	StrictPURL  *bool    `yaml:"strict_purl"`   // Whether to fail the package on a document not matching the PURL
+	SkipInvalid *bool    `yaml:"skip_invalid"`  // Whether to skip invalid documents instead of failing the package
+	LFS         *bool    `yaml:"lfs"`           // Whether to fetch the documents stored in Git LFS
+	MatchCPE    *bool    `yaml:"match_cpe"`     // Whether to also match product IDs that are CPEs naming the package
+
+	// MatchSubcomponents is whether to also match the subcomponents of the products in OpenVEX statements
+	MatchSubcomponents *bool `yaml:"match_subcomponents"`
+
+	// StrictTimestamps is whether to fail OpenVEX documents with missing or malformed statement timestamps
+	StrictTimestamps *bool `yaml:"strict_timestamps"`
+
+	// StrictJustifications is whether to fail OpenVEX documents with not_affected statements lacking a justification
+	StrictJustifications *bool `yaml:"strict_justifications"`
+
+	// SBOM is whether to also extract the VEX statements embedded in SBOMs, e.g. "sbom.spdx.json"
+	SBOM *bool `yaml:"sbom"`
+
+	// ProductPatterns are the regular expressions of the product IDs also referring to the package
+	ProductPatterns []Regexp `yaml:"product_patterns"`
+
+	// SignificantQualifiers are the PURL qualifiers that must be equal for a product to match the package, e.g. "arch".
+	// The others are ignored. Omitted keeps the qualifier matching of go-vex, and empty ignores all the qualifiers.
+	SignificantQualifiers []string `yaml:"significant_qualifiers"`
+
+	// DigestPolicy is how the digests of the PURLs are matched, e.g. "ignore" for OCI images pinned by digest
+	DigestPolicy string `yaml:"digest_policy"`
+
+	// DuplicateNames is how documents with colliding file names are stored, e.g. "hash-suffix" to keep both
+	DuplicateNames string `yaml:"duplicate_names"`
+}
+
+// Regexp is a regular expression compiled when the config is decoded, so that invalid ones fail loading.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func (r *Regexp) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return oops.With("line", node.Line).Wrapf(err, "invalid regular expression")
+	}
+	r.Regexp = re
+	return nil
+}
+
+// TypeFilter selects the PURL types to crawl so that a config can be shared by hubs of different scopes.
+// Packages tracked by CPE are filtered as TypeCPE.
+// A type listed in Include is always crawled. If Include is empty, all types but those in Exclude are crawled.
+type TypeFilter struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Allows reports whether packages of the PURL type are crawled.
+func (f TypeFilter) Allows(typ string) bool {
+	if slices.Contains(f.Include, typ) {
+		return true
+	} else if len(f.Include) > 0 {
+		return false
+	}
+	return !slices.Contains(f.Exclude, typ)
+}
+
+// Embargo withholds statements about the vulnerability from VEX Hub until the date.
+type Embargo struct {
+	Vulnerability string    `yaml:"vulnerability"`
+	Until         time.Time `yaml:"until"` // Zero means indefinitely
+}
+
+// Active returns true if the embargo is still in effect at the given time.
+func (e Embargo) Active(now time.Time) bool {
+	return e.Until.IsZero() || now.Before(e.Until)
+}
+
+// History keeps the previous versions of the VEX documents in VEX Hub when they change upstream.
+// Zero values mean no limit.
+type History struct {
+	Keep   int           `yaml:"keep"`    // Versions kept per package
+	MaxAge time.Duration `yaml:"max_age"` // Age after which versions are pruned, e.g. "2160h"
+}
+
+// Quarantine keeps the files rejected by the validation outside the published trees of VEX Hub for inspection.
+// Zero values mean the defaults.
+type Quarantine struct {
+	MaxFiles    int   `yaml:"max_files"`     // Files kept per package
+	MaxFileSize int64 `yaml:"max_file_size"` // Size in bytes above which only the reason is kept
+}
+
+// Concurrency limits the packages crawled at the same time. Downloading is network-bound while parsing is CPU-bound,
+// so they're limited separately, and connected by a queue of the packages downloaded and waiting to be parsed.
+type Concurrency struct {
+	Downloads int `yaml:"downloads"` // Sources downloaded at the same time
+	Parsers   int `yaml:"parsers"`   // Downloaded sources whose VEX documents are validated and copied at the same time
+	Queue     int `yaml:"queue"`     // Downloaded sources waiting to be parsed. Zero means as many as Downloads
+
+	// PerHost limits the sources downloaded at the same time from any one host, within Downloads,
+	// e.g. for internal hosts that can't handle parallelism. Zero means no limit other than Downloads.
+	PerHost int `yaml:"per_host"`
+
+	// Hosts overrides PerHost for the hosts, e.g. {"git.example.com": 1}.
+	Hosts map[string]int `yaml:"hosts"`
+}
+
+// DefaultConcurrency downloads several sources in parallel and parses them one at a time.
+var DefaultConcurrency = Concurrency{
+	Downloads: 4,
+	Parsers:   1,
 }
 
 type configFile struct {
-	Packages packages `yaml:"pkg"`
+	Packages     packages                  `yaml:"pkg"`
+	CPEs         []cpePackage              `yaml:"cpe"`
+	Embargoes    []Embargo                 `yaml:"embargo"`
+	Manifest     manifest.Encoding         `yaml:"manifest"`
+	ManifestName string                    `yaml:"manifest_name"`
+	SigningKey   string                    `yaml:"manifest_signing_key"`
+	Authors      []string                  `yaml:"authors"`
+	TLS          download.TLSConfig        `yaml:"tls"`
+	GitHubApp    download.GitHubApp        `yaml:"github_app"`
+	BasicAuth    []download.BasicAuth      `yaml:"basic_auth"`
+	Credentials  download.CredentialHelper `yaml:"credential_helper"`
+	Defaults     Overrides                 `yaml:"defaults"`
+	Types        TypeFilter                `yaml:"types"`
+	Compress     bool                      `yaml:"compress"`
+	Groups       bool                      `yaml:"groups"`
+	Merge        bool                      `yaml:"merge_manifests"`
+	Latest       bool                      `yaml:"latest"`
+	Concurrency  Concurrency               `yaml:"concurrency"`
+	UserAgent    string                    `yaml:"user_agent"`
+	Statuses     []string                  `yaml:"statuses"`
+	Parents      bool                      `yaml:"parents"`
+	History      *History                  `yaml:"history"`
+	Quarantine   *Quarantine               `yaml:"quarantine"`
+	MaxExpansion int                       `yaml:"max_expansion"`
+	Digest       string                    `yaml:"digest_algorithm"`
+	Hubs         []hubFile                 `yaml:"hubs"`
+}
+
+// hubFile is a VEX Hub crawled along with the others in one run, with its own packages.
+type hubFile struct {
+	Name     string       `yaml:"name"`
+	Dir      string       `yaml:"dir"`
+	Packages packages     `yaml:"pkg"`
+	CPEs     []cpePackage `yaml:"cpe"`
 }
 
 type packages map[string][]struct {
 	Namespace  string `yaml:"namespace"`
 	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
 	Qualifiers []struct {
 		Key   string `yaml:"key"`
 		Value string `yaml:"value"`
 	} `yaml:"qualifiers"`
 	Subpath string `yaml:"subpath"`
 
-	URL string `yaml:"url"`
+	URL       string    `yaml:"url"`
+	PublicURL string    `yaml:"public_url"`
+	Authors   []string  `yaml:"authors"`
+	Trusted   bool      `yaml:"trusted"`
+	Parent    string    `yaml:"parent"`
+	Overrides Overrides `yaml:",inline"`
+}
+
+// cpePackage is a package tracked by CPE, listed apart from the PURL ones since it has no type.
+type cpePackage struct {
+	ID        string    `yaml:"id"`
+	URL       string    `yaml:"url"`
+	PublicURL string    `yaml:"public_url"`
+	Authors   []string  `yaml:"authors"`
+	Trusted   bool      `yaml:"trusted"`
+	Overrides Overrides `yaml:",inline"`
 }
 
 type Config struct {
+	Packages         []Package
+	Embargoes        []Embargo
+	ManifestEncoding manifest.Encoding
+
+	// ManifestName is the file name of the manifests, e.g. "vex-manifest.json" for tooling expecting another name.
+	// It's set in the shared config since reading the VEX Hub depends on it.
+	ManifestName string
+
+	// ManifestSigningKey is the path to the PEM-encoded Ed25519 private key signing the manifests, see
+	// manifest.LoadSigningKey. Empty doesn't sign them. Like the compression, it's set in the shared config
+	// so that all the manifests are signed consistently.
+	ManifestSigningKey string
+
+	// Authors is the allowlist of the VEX document authors. Empty means no restriction.
+	Authors []string
+
+	// TLS configures TLS of the connections made to download sources.
+	TLS download.TLSConfig
+
+	// GitHubApp authenticates to GitHub as an installation of the app, e.g. to crawl private repositories.
+	GitHubApp download.GitHubApp
+
+	// BasicAuth authenticates the requests to the listed hosts over HTTPS, e.g. private file servers.
+	BasicAuth []download.BasicAuth
+
+	// CredentialHelper obtains the credentials of the listed hosts from an external command at download time.
+	CredentialHelper download.CredentialHelper
+
+	// Defaults are the global discovery and validation settings, overridden per package.
+	Defaults Overrides
+
+	// Types selects the PURL types to crawl.
+	Types TypeFilter
+
+	// Compress stores the VEX documents gzip-compressed.
+	// Like the manifest encoding, it's set in the shared config so that runs store documents consistently.
+	Compress bool
+
+	// Groups records the sources sharing a vulnerability ID in the manifests.
+	Groups bool
+
+	// MergeManifests preserves the fields of the manifests curated out-of-band, instead of regenerating them.
+	MergeManifests bool
+
+	// Latest merges the OpenVEX documents of each package into a stable file.
+	// Like the compression, it's set in the shared config since it changes the layout of VEX Hub.
+	Latest bool
+
+	// DigestAlgorithm is the algorithm of the digests of the stored files recorded in the manifests,
+	// e.g. "sha512" where SHA-256 isn't allowed. It's set in the shared config like the compression.
+	DigestAlgorithm string
+
+	// Concurrency limits the packages downloaded and parsed at the same time.
+	Concurrency Concurrency
+
+	// UserAgent is sent in the requests over HTTP and git. Empty means download.DefaultUserAgent.
+	UserAgent string
+
+	// Statuses are the OpenVEX statuses of the statements kept in VEX Hub, e.g. "affected". Empty keeps all.
+	// Like the compression, it's set in the shared config since it changes the content of VEX Hub.
+	Statuses []string
+
+	// Parents derives the parent of each package without an explicit one from the namespace nesting.
+	Parents bool
+
+	// History keeps the previous versions of the VEX documents. Nil disables it.
+	// Like the compression, it's set in the shared config since it changes the layout of VEX Hub.
+	History *History
+
+	// Quarantine keeps the files rejected by the validation in the quarantine directory of VEX Hub. Nil disables it.
+	Quarantine *Quarantine
+
+	// MaxExpansion limits the packages each wildcard package expands into.
+	MaxExpansion int
+
+	// Hubs are the VEX Hub directories crawled in one run, each with its own packages, instead of Packages.
+	// The other settings are shared by all the hubs.
+	Hubs []Hub
+}
+
+// Hub is a VEX Hub directory with its own packages, e.g. of a business unit.
+type Hub struct {
+	Name     string // Identifies the hub in logs and in the report
+	Dir      string
 	Packages []Package
 }
 
@@ -41,27 +369,178 @@ func Load(configPath string) (*Config, error) {
 	}
 	defer f.Close()
 
-	var config configFile
+	// Fields not specified in the file keep the defaults
+	config := configFile{
+		Manifest:     manifest.DefaultEncoding,
+		ManifestName: manifest.FileName,
+		Concurrency:  DefaultConcurrency,
+		MaxExpansion: DefaultMaxExpansion,
+		Digest:       manifest.DefaultDigestAlgorithm,
+	}
 	if err = yaml.NewDecoder(f).Decode(&config); err != nil {
 		return nil, errBuilder.Wrapf(err, "failed to decode the file")
 	}
+	if err = interpolate(&config); err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
 
-	pkgs, err := parsePackages(config.Packages)
+	pkgs, err := parseSources(config.Packages, config.CPEs, config.Parents)
 	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to parse packages")
+		return nil, errBuilder.Wrap(err)
+	}
+	hubs, err := parseHubs(config.Hubs, config.Parents)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	} else if len(hubs) > 0 && len(pkgs) > 0 {
+		return nil, errBuilder.Errorf("pkg and cpe must be listed under hubs when hubs are configured")
+	}
+
+	if config.ManifestName == "" || filepath.Base(config.ManifestName) != config.ManifestName {
+		return nil, errBuilder.With("manifest_name", config.ManifestName).Errorf("manifest name must be a file name")
+	}
+
+	if c := config.Concurrency; c.Downloads < 1 || c.Parsers < 1 || c.Queue < 0 {
+		return nil, errBuilder.With("concurrency", c).Errorf("downloads and parsers must be positive and queue must not be negative")
+	}
+	if c := config.Concurrency; c.PerHost < 0 {
+		return nil, errBuilder.With("per_host", c.PerHost).Errorf("per-host concurrency must not be negative")
+	}
+	for host, n := range config.Concurrency.Hosts {
+		if n < 1 {
+			return nil, errBuilder.With("host", host).With("concurrency", n).Errorf("concurrency of the host must be positive")
+		}
+	}
+
+	for _, status := range config.Statuses {
+		if !openvex.Status(status).Valid() {
+			return nil, errBuilder.With("status", status).With("valid_statuses", openvex.Statuses()).Errorf("invalid status")
+		}
+	}
+
+	if !slices.Contains(manifest.DigestAlgorithms(), config.Digest) {
+		return nil, errBuilder.With("digest_algorithm", config.Digest).
+			With("supported", manifest.DigestAlgorithms()).Errorf("unsupported digest algorithm")
+	}
+
+	if config.MaxExpansion < 1 {
+		return nil, errBuilder.With("max_expansion", config.MaxExpansion).Errorf("max_expansion must be positive")
+	}
+
+	if h := config.History; h != nil && (h.Keep < 0 || h.MaxAge < 0) {
+		return nil, errBuilder.With("history", *h).Errorf("history retention must not be negative")
+	}
+	if q := config.Quarantine; q != nil {
+		if q.MaxFiles < 0 || q.MaxFileSize < 0 {
+			return nil, errBuilder.With("quarantine", *q).Errorf("quarantine limits must not be negative")
+		}
+		q.MaxFiles = cmp.Or(q.MaxFiles, DefaultQuarantineMaxFiles)
+		q.MaxFileSize = cmp.Or(q.MaxFileSize, DefaultQuarantineMaxFileSize)
+	}
+
+	for _, e := range config.Embargoes {
+		if e.Vulnerability == "" {
+			return nil, errBuilder.Errorf("vulnerability is required for embargo")
+		}
 	}
 
 	return &Config{
-		Packages: pkgs,
+		Packages:           pkgs,
+		Embargoes:          config.Embargoes,
+		ManifestEncoding:   config.Manifest,
+		ManifestName:       config.ManifestName,
+		ManifestSigningKey: config.SigningKey,
+		Authors:            config.Authors,
+		TLS:                config.TLS,
+		GitHubApp:          config.GitHubApp,
+		BasicAuth:          config.BasicAuth,
+		CredentialHelper:   config.Credentials,
+		Defaults:           config.Defaults,
+		Types:              config.Types,
+		Compress:           config.Compress,
+		Groups:             config.Groups,
+		MergeManifests:     config.Merge,
+		Latest:             config.Latest,
+		Concurrency:        config.Concurrency,
+		UserAgent:          cmp.Or(config.UserAgent, download.DefaultUserAgent()),
+		Statuses:           config.Statuses,
+		Parents:            config.Parents,
+		History:            config.History,
+		Quarantine:         config.Quarantine,
+		MaxExpansion:       config.MaxExpansion,
+		DigestAlgorithm:    config.Digest,
+		Hubs:               hubs,
 	}, nil
 }
 
+// parseSources parses the packages tracked by PURL and by CPE.
+func parseSources(packages packages, cpes []cpePackage, parents bool) ([]Package, error) {
+	pkgs, err := parsePackages(packages)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to parse packages")
+	}
+	if parents {
+		DeriveParents(pkgs)
+	}
+	for _, c := range cpes {
+		if c.ID == "" {
+			return nil, oops.Errorf("id is required for cpe")
+		} else if c.URL == "" {
+			return nil, oops.With("cpe", c.ID).Errorf("url is required for cpe")
+		} else if err = validateURLs(c.URL, c.PublicURL); err != nil {
+			return nil, oops.With("cpe", c.ID).Wrap(err)
+		}
+		pkgs = append(pkgs, Package{
+			CPE:       c.ID,
+			URL:       c.URL,
+			PublicURL: c.PublicURL,
+			Authors:   c.Authors,
+			Overrides: c.Overrides,
+			Trusted:   c.Trusted,
+		})
+	}
+	return pkgs, nil
+}
+
+// parseHubs parses the hubs, whose names and directories must be unique.
+func parseHubs(hubFiles []hubFile, parents bool) ([]Hub, error) {
+	var hubs []Hub
+	names, dirs := make(map[string]bool), make(map[string]bool)
+	for _, h := range hubFiles {
+		errBuilder := oops.With("hub", h.Name)
+		if h.Name == "" || h.Dir == "" {
+			return nil, errBuilder.Errorf("name and dir are required for hubs")
+		}
+		dir := filepath.Clean(h.Dir)
+		if names[h.Name] {
+			return nil, errBuilder.Errorf("duplicate hub name")
+		} else if dirs[dir] {
+			return nil, errBuilder.With("dir", h.Dir).Errorf("duplicate hub dir")
+		}
+		names[h.Name], dirs[dir] = true, true
+
+		pkgs, err := parseSources(h.Packages, h.CPEs, parents)
+		if err != nil {
+			return nil, errBuilder.Wrap(err)
+		}
+		hubs = append(hubs, Hub{Name: h.Name, Dir: dir, Packages: pkgs})
+	}
+	return hubs, nil
+}
+
 func parsePackages(packages packages) ([]Package, error) {
 	var pkgs []Package
 	for pkgType, pkgList := range packages {
 		for _, pkg := range pkgList {
 			if pkg.Name == "" {
 				return nil, oops.Errorf("name is required")
+			} else if err := validateURLs(pkg.URL, pkg.PublicURL); err != nil {
+				return nil, oops.With("name", pkg.Name).Wrap(err)
+			} else if pkg.Name == Wildcard && (pkg.URL != "" || pkg.PublicURL != "" || pkg.Version != "") {
+				return nil, oops.With("namespace", pkg.Namespace).Errorf("url, public_url and version must be omitted for wildcard")
+			}
+			parent, err := parseParent(pkg.Parent)
+			if err != nil {
+				return nil, oops.With("name", pkg.Name).Wrap(err)
 			}
 
 			var qs packageurl.Qualifiers
@@ -76,14 +555,55 @@ func parsePackages(packages packages) ([]Package, error) {
 				Type:       pkgType,
 				Namespace:  pkg.Namespace,
 				Name:       pkg.Name,
+				Version:    pkg.Version,
 				Qualifiers: qs,
 				Subpath:    pkg.Subpath,
 			}
 			pkgs = append(pkgs, Package{
-				PURL: purl,
-				URL:  pkg.URL,
+				PURL:      purl,
+				URL:       pkg.URL,
+				PublicURL: pkg.PublicURL,
+				Authors:   pkg.Authors,
+				Overrides: pkg.Overrides,
+				Trusted:   pkg.Trusted,
+				Parent:    parent,
 			})
 		}
 	}
 	return pkgs, nil
 }
+
+// parseParent validates the PURL of the parent package and returns it in the canonical form, without a version.
+func parseParent(parent string) (string, error) {
+	if parent == "" {
+		return "", nil
+	}
+	purl, err := packageurl.FromString(parent)
+	if err != nil {
+		return "", oops.With("parent", parent).Wrapf(err, "invalid parent")
+	}
+	purl.Version = ""
+	return purl.String(), nil
+}
+
+// validateURLs checks the download URL and the public URL advertised instead, if any.
+// The public URL must be an absolute HTTP(S) URL since it's shown to consumers, and it requires the download URL.
+func validateURLs(rawURL, publicURL string) error {
+	if rawURL != "" {
+		if _, err := xurl.Parse(rawURL); err != nil {
+			return oops.With("url", xurl.Redact(rawURL)).Wrapf(err, "invalid url")
+		}
+	}
+	if publicURL == "" {
+		return nil
+	} else if rawURL == "" {
+		return oops.Errorf("url is required for public_url")
+	}
+	u, err := url.Parse(publicURL)
+	if err != nil {
+		return oops.With("public_url", publicURL).Wrapf(err, "invalid public_url")
+	} else if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return oops.With("public_url", publicURL).Errorf("public_url must be an absolute HTTP(S) URL")
+	}
+	return nil
+}
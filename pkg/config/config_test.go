@@ -0,0 +1,386 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+)
+
+func TestLoad_PublicURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "mirror",
+			content: `
+pkg:
+  npm:
+    - name: foo
+      url: https://mirror.example.internal/foo.git
+      public_url: https://github.com/example/foo
+`,
+			want: "https://github.com/example/foo",
+		},
+		{
+			name: "cpe",
+			content: `
+cpe:
+  - id: cpe:2.3:a:example:foo
+    url: https://mirror.example.internal/foo.git
+    public_url: https://github.com/example/foo
+`,
+			want: "https://github.com/example/foo",
+		},
+		{
+			name: "no download URL",
+			content: `
+pkg:
+  npm:
+    - name: foo
+      public_url: https://github.com/example/foo
+`,
+			wantErr: "url is required for public_url",
+		},
+		{
+			name: "relative",
+			content: `
+pkg:
+  npm:
+    - name: foo
+      url: https://mirror.example.internal/foo.git
+      public_url: github.com/example/foo
+`,
+			wantErr: "public_url must be an absolute HTTP(S) URL",
+		},
+		{
+			name: "invalid download URL",
+			content: `
+pkg:
+  npm:
+    - name: foo
+      url: "https://mirror.example.internal/%zz"
+      public_url: https://github.com/example/foo
+`,
+			wantErr: "invalid url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "crawler.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(tt.content), 0644))
+
+			c, err := config.Load(configPath)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, c.Packages, 1)
+			assert.Equal(t, tt.want, c.Packages[0].PublicURL)
+		})
+	}
+}
+
+func TestLoad_Statuses(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "actionable",
+			content: "statuses: [affected, under_investigation]\n",
+			want:    []string{"affected", "under_investigation"},
+		},
+		{
+			name:    "all by default",
+			content: "compress: true\n",
+		},
+		{
+			name:    "invalid",
+			content: "statuses: [exploitable]\n",
+			wantErr: "invalid status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "crawler.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(tt.content), 0644))
+
+			c, err := config.Load(configPath)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c.Statuses)
+		})
+	}
+}
+
+func TestLoad_Parents(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "namespace nesting",
+			content: `
+parents: true
+pkg:
+  golang:
+    - namespace: github.com/example
+      name: foo
+    - namespace: github.com/example/foo
+      name: bar
+    - namespace: github.com/example/foo/bar
+      name: baz
+    - namespace: github.com/example
+      name: foobar
+  npm:
+    - name: foo
+`,
+			want: map[string]string{
+				"pkg:golang/github.com/example/foo":         "",
+				"pkg:golang/github.com/example/foo/bar":     "pkg:golang/github.com/example/foo",
+				"pkg:golang/github.com/example/foo/bar/baz": "pkg:golang/github.com/example/foo/bar",
+				"pkg:golang/github.com/example/foobar":      "",
+				"pkg:npm/foo":                               "",
+			},
+		},
+		{
+			name: "explicit",
+			content: `
+parents: true
+pkg:
+  golang:
+    - namespace: github.com/example
+      name: foo
+    - namespace: github.com/example/foo
+      name: bar
+      parent: pkg:golang/github.com/example/umbrella@v1.0.0
+`,
+			want: map[string]string{
+				"pkg:golang/github.com/example/foo":     "",
+				"pkg:golang/github.com/example/foo/bar": "pkg:golang/github.com/example/umbrella",
+			},
+		},
+		{
+			name: "not derived by default",
+			content: `
+pkg:
+  golang:
+    - namespace: github.com/example
+      name: foo
+    - namespace: github.com/example/foo
+      name: bar
+`,
+			want: map[string]string{
+				"pkg:golang/github.com/example/foo":     "",
+				"pkg:golang/github.com/example/foo/bar": "",
+			},
+		},
+		{
+			name: "invalid",
+			content: `
+pkg:
+  npm:
+    - name: foo
+      parent: foo
+`,
+			wantErr: "invalid parent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "crawler.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(tt.content), 0644))
+
+			c, err := config.Load(configPath)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			got := make(map[string]string)
+			for _, pkg := range c.Packages {
+				got[pkg.ID()] = pkg.Parent
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoad_Wildcard(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		wantWildcard     bool
+		wantMaxExpansion int
+		wantErr          string
+	}{
+		{
+			name: "wildcard",
+			content: `
+pkg:
+  golang:
+    - namespace: github.com/example
+      name: "*"
+`,
+			wantWildcard:     true,
+			wantMaxExpansion: config.DefaultMaxExpansion,
+		},
+		{
+			name: "max expansion",
+			content: `
+max_expansion: 10
+pkg:
+  golang:
+    - namespace: github.com/example
+      name: foo
+`,
+			wantMaxExpansion: 10,
+		},
+		{
+			name: "wildcard with url",
+			content: `
+pkg:
+  golang:
+    - namespace: github.com/example
+      name: "*"
+      url: https://github.com/example/foo
+`,
+			wantErr: "url, public_url and version must be omitted for wildcard",
+		},
+		{
+			name:    "invalid max expansion",
+			content: "max_expansion: -1\n",
+			wantErr: "max_expansion must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "crawler.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(tt.content), 0644))
+
+			c, err := config.Load(configPath)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMaxExpansion, c.MaxExpansion)
+			require.Len(t, c.Packages, 1)
+			assert.Equal(t, tt.wantWildcard, c.Packages[0].Wildcard())
+		})
+	}
+}
+
+func TestLoad_Hubs(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantHubs map[string]int // Number of packages by hub name
+		wantErr  string
+	}{
+		{
+			name: "hubs",
+			content: `
+hubs:
+  - name: public
+    dir: ./public/
+    pkg:
+      golang:
+        - namespace: github.com/example
+          name: foo
+  - name: internal
+    dir: internal
+    pkg:
+      golang:
+        - namespace: github.com/example
+          name: foo
+        - namespace: github.com/example
+          name: bar
+`,
+			wantHubs: map[string]int{"public": 1, "internal": 2},
+		},
+		{
+			name: "duplicate name",
+			content: `
+hubs:
+  - name: public
+    dir: a
+  - name: public
+    dir: b
+`,
+			wantErr: "duplicate hub name",
+		},
+		{
+			name: "duplicate dir",
+			content: `
+hubs:
+  - name: a
+    dir: public
+  - name: b
+    dir: ./public
+`,
+			wantErr: "duplicate hub dir",
+		},
+		{
+			name: "missing dir",
+			content: `
+hubs:
+  - name: public
+`,
+			wantErr: "name and dir are required for hubs",
+		},
+		{
+			name: "packages outside hubs",
+			content: `
+pkg:
+  golang:
+    - namespace: github.com/example
+      name: foo
+hubs:
+  - name: public
+    dir: public
+`,
+			wantErr: "pkg and cpe must be listed under hubs when hubs are configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "crawler.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(tt.content), 0644))
+
+			c, err := config.Load(configPath)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Empty(t, c.Packages)
+			got := make(map[string]int)
+			for _, h := range c.Hubs {
+				got[h.Name] = len(h.Packages)
+			}
+			assert.Equal(t, tt.wantHubs, got)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"log/slog"
+	"slices"
+)
+
+// LogEffective logs the resolved configuration at info level in a single line,
+// which helps to find out why a crawl behaved unexpectedly.
+// Secrets are never logged: credentials are reported only by the hosts they're configured for,
+// and embargoed vulnerability IDs only by their count.
+func (c *Config) LogEffective(logger *slog.Logger) {
+	logger.Info("Effective configuration", c.effectiveAttrs()...)
+}
+
+func (c *Config) effectiveAttrs() []any {
+	var clientCertHosts, caCertHosts, basicAuthHosts []string
+	for _, cert := range c.TLS.ClientCerts {
+		clientCertHosts = append(clientCertHosts, cert.Hosts...)
+	}
+	for _, cert := range c.TLS.CACerts {
+		caCertHosts = append(caCertHosts, cert.Hosts...)
+	}
+	for _, a := range c.BasicAuth {
+		basicAuthHosts = append(basicAuthHosts, a.Hosts...)
+	}
+
+	var trusted, cpes, wildcards int
+	hubs := make(map[string]int) // Packages by hub
+	all := c.Packages
+	for _, h := range c.Hubs {
+		hubs[h.Name] = len(h.Packages)
+		all = append(slices.Clip(all), h.Packages...)
+	}
+	for _, pkg := range all {
+		if pkg.Trusted {
+			trusted++
+		}
+		if pkg.CPE != "" {
+			cpes++
+		}
+		if pkg.Wildcard() {
+			wildcards++
+		}
+	}
+
+	return []any{
+		slog.Int("packages", len(c.Packages)),
+		slog.Any("hubs", hubs),
+		slog.Int("trusted_packages", trusted),
+		slog.Int("cpe_packages", cpes),
+		slog.Int("wildcard_packages", wildcards),
+		slog.Int("max_expansion", c.MaxExpansion),
+		slog.Group("types",
+			slog.Any("include", c.Types.Include),
+			slog.Any("exclude", c.Types.Exclude),
+		),
+		slog.Group("defaults", overridesAttrs(c.Defaults)...),
+		slog.Group("concurrency", concurrencyAttrs(c.Concurrency)...),
+		slog.Group("layout",
+			slog.String("manifest_name", c.ManifestName),
+			slog.Bool("manifest_signed", c.ManifestSigningKey != ""),
+			slog.String("manifest_indent", c.ManifestEncoding.Indent),
+			slog.Bool("manifest_trailing_newline", c.ManifestEncoding.TrailingNewline),
+			slog.Bool("compress", c.Compress),
+			slog.String("digest_algorithm", c.DigestAlgorithm),
+			slog.Bool("groups", c.Groups),
+			slog.Bool("merge_manifests", c.MergeManifests),
+			slog.Bool("latest", c.Latest),
+			slog.Any("statuses", c.Statuses),
+			slog.Bool("parents", c.Parents),
+			slog.Any("history", c.History),
+			slog.Any("quarantine", c.Quarantine),
+		),
+		slog.Any("authors", c.Authors),
+		slog.Int("embargoes", len(c.Embargoes)),
+		slog.Group("tls",
+			slog.Any("client_cert_hosts", compactHosts(clientCertHosts)),
+			slog.Any("ca_cert_hosts", compactHosts(caCertHosts)),
+			slog.Any("insecure_hosts", c.TLS.InsecureHosts),
+		),
+		slog.Any("basic_auth_hosts", compactHosts(basicAuthHosts)),
+		slog.Any("credential_helper_hosts", c.CredentialHelper.Hosts),
+		slog.String("user_agent", c.UserAgent),
+		slog.Group("github_app",
+			slog.Int64("app_id", c.GitHubApp.AppID),
+			slog.Int64("installation_id", c.GitHubApp.InstallationID),
+			slog.Any("hosts", c.GitHubApp.Hosts),
+		),
+	}
+}
+
+func concurrencyAttrs(c Concurrency) []any {
+	attrs := []any{
+		slog.Int("downloads", c.Downloads),
+		slog.Int("parsers", c.Parsers),
+		slog.Int("queue", c.Queue),
+	}
+	if c.PerHost > 0 {
+		attrs = append(attrs, slog.Int("per_host", c.PerHost))
+	}
+	if len(c.Hosts) > 0 {
+		attrs = append(attrs, slog.Any("hosts", c.Hosts))
+	}
+	return attrs
+}
+
+func overridesAttrs(o Overrides) []any {
+	attrs := []any{
+		slog.Any("patterns", o.Patterns),
+		slog.String("subdir", o.Subdir),
+		slog.Any("root_dirs", o.RootDirs),
+		slog.String("root_dir_mode", o.RootDirMode),
+		slog.String("format", o.Format),
+	}
+	if o.StrictPURL != nil {
+		attrs = append(attrs, slog.Bool("strict_purl", *o.StrictPURL))
+	}
+	if o.SkipInvalid != nil {
+		attrs = append(attrs, slog.Bool("skip_invalid", *o.SkipInvalid))
+	}
+	if o.LFS != nil {
+		attrs = append(attrs, slog.Bool("lfs", *o.LFS))
+	}
+	if o.MatchCPE != nil {
+		attrs = append(attrs, slog.Bool("match_cpe", *o.MatchCPE))
+	}
+	if o.MatchSubcomponents != nil {
+		attrs = append(attrs, slog.Bool("match_subcomponents", *o.MatchSubcomponents))
+	}
+	if o.StrictTimestamps != nil {
+		attrs = append(attrs, slog.Bool("strict_timestamps", *o.StrictTimestamps))
+	}
+	if o.StrictJustifications != nil {
+		attrs = append(attrs, slog.Bool("strict_justifications", *o.StrictJustifications))
+	}
+	if o.SBOM != nil {
+		attrs = append(attrs, slog.Bool("sbom", *o.SBOM))
+	}
+	if len(o.ProductPatterns) > 0 {
+		var patterns []string
+		for _, re := range o.ProductPatterns {
+			patterns = append(patterns, re.String())
+		}
+		attrs = append(attrs, slog.Any("product_patterns", patterns))
+	}
+	if o.SignificantQualifiers != nil {
+		attrs = append(attrs, slog.Any("significant_qualifiers", o.SignificantQualifiers))
+	}
+	if o.DigestPolicy != "" {
+		attrs = append(attrs, slog.String("digest_policy", o.DigestPolicy))
+	}
+	if o.DuplicateNames != "" {
+		attrs = append(attrs, slog.String("duplicate_names", o.DuplicateNames))
+	}
+	return attrs
+}
+
+// compactHosts sorts the hosts and removes duplicates.
+func compactHosts(hosts []string) []string {
+	slices.Sort(hosts)
+	return slices.Compact(hosts)
+}
@@ -0,0 +1,69 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+func TestConfig_LogEffective(t *testing.T) {
+	strictPURL := true
+	c := &config.Config{
+		Embargoes:        []config.Embargo{{Vulnerability: "CVE-2024-0001", Until: time.Now().Add(time.Hour)}},
+		ManifestEncoding: manifest.DefaultEncoding,
+		ManifestName:     manifest.FileName,
+		TLS: download.TLSConfig{
+			ClientCerts: []download.ClientCert{
+				{Hosts: []string{"git.example.com"}, Cert: "/secrets/client.pem", Key: "/secrets/client-key.pem"},
+			},
+			InsecureHosts: []string{"test.example.com"},
+		},
+		GitHubApp: download.GitHubApp{AppID: 1234, InstallationID: 5678, PrivateKey: "/secrets/app.pem"},
+		BasicAuth: []download.BasicAuth{{Hosts: []string{"files.example.com"}, Username: "user", Password: "/secrets/password"}},
+		CredentialHelper: download.CredentialHelper{
+			Command: []string{"/usr/local/bin/vault-credential", "get"},
+			Hosts:   []string{"git.example.com"},
+		},
+		Defaults:    config.Overrides{Patterns: []string{"*.vex.json"}, StrictPURL: &strictPURL},
+		Concurrency: config.DefaultConcurrency,
+	}
+
+	var buf bytes.Buffer
+	c.LogEffective(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "INFO", got["level"])
+	assert.Equal(t, "Effective configuration", got["msg"])
+	assert.Equal(t, map[string]any{
+		"patterns":      []any{"*.vex.json"},
+		"subdir":        "",
+		"root_dirs":     nil,
+		"root_dir_mode": "",
+		"format":        "",
+		"strict_purl":   true,
+	}, got["defaults"])
+	assert.Equal(t, map[string]any{"downloads": 4.0, "parsers": 1.0, "queue": 0.0}, got["concurrency"])
+	assert.Equal(t, map[string]any{
+		"client_cert_hosts": []any{"git.example.com"},
+		"ca_cert_hosts":     nil,
+		"insecure_hosts":    []any{"test.example.com"},
+	}, got["tls"])
+	assert.Equal(t, []any{"files.example.com"}, got["basic_auth_hosts"])
+	assert.Equal(t, []any{"git.example.com"}, got["credential_helper_hosts"])
+	assert.Equal(t, map[string]any{"app_id": 1234.0, "installation_id": 5678.0, "hosts": nil}, got["github_app"])
+	assert.Equal(t, 1.0, got["embargoes"])
+
+	// Secrets and embargoed vulnerabilities are redacted
+	assert.NotContains(t, buf.String(), "/secrets/")
+	assert.NotContains(t, buf.String(), "CVE-2024-0001")
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/samber/oops"
+)
+
+// envPattern matches the references to environment variables, e.g. "${GITLAB_TOKEN}".
+// The bare "$VAR" form is not supported so that a literal "$" doesn't need escaping.
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces the references to environment variables in the value.
+// A variable set to an empty string is allowed, while an unset one is an error.
+func expandEnv(s string) (string, error) {
+	var missing []string
+	expanded := envPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envPattern.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", oops.With("variables", missing).Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// interpolate expands the environment variables in the URLs and the credentials,
+// so that the config referencing private hosts can be committed without secrets.
+// Other fields are taken literally to avoid surprises.
+func interpolate(c *configFile) error {
+	expand := func(field string, value *string) error {
+		expanded, err := expandEnv(*value)
+		if err != nil {
+			return oops.With("field", field).Wrapf(err, "failed to interpolate %s", field)
+		}
+		*value = expanded
+		return nil
+	}
+
+	sources := func(prefix string, pkgs packages, cpes []cpePackage) error {
+		for typ, list := range pkgs {
+			for i := range list {
+				if err := expand(fmt.Sprintf("%spkg.%s[%s].url", prefix, typ, list[i].Name), &list[i].URL); err != nil {
+					return err
+				}
+			}
+		}
+		for i := range cpes {
+			if err := expand(fmt.Sprintf("%scpe[%s].url", prefix, cpes[i].ID), &cpes[i].URL); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := sources("", c.Packages, c.CPEs); err != nil {
+		return err
+	}
+	for _, h := range c.Hubs {
+		if err := sources(fmt.Sprintf("hubs[%s].", h.Name), h.Packages, h.CPEs); err != nil {
+			return err
+		}
+	}
+	for i := range c.TLS.ClientCerts {
+		cert := &c.TLS.ClientCerts[i]
+		for name, value := range map[string]*string{"cert": &cert.Cert, "key": &cert.Key, "ca_cert": &cert.CACert} {
+			if err := expand(fmt.Sprintf("tls.client_certs[%d].%s", i, name), value); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range c.TLS.CACerts {
+		if err := expand(fmt.Sprintf("tls.ca_certs[%d].path", i), &c.TLS.CACerts[i].Path); err != nil {
+			return err
+		}
+	}
+	for i := range c.BasicAuth {
+		a := &c.BasicAuth[i]
+		for name, value := range map[string]*string{"username": &a.Username, "password": &a.Password} {
+			if err := expand(fmt.Sprintf("basic_auth[%d].%s", i, name), value); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range c.Credentials.Command {
+		if err := expand(fmt.Sprintf("credential_helper.command[%d]", i), &c.Credentials.Command[i]); err != nil {
+			return err
+		}
+	}
+	if err := expand("manifest_signing_key", &c.SigningKey); err != nil {
+		return err
+	}
+	if err := expand("github_app.private_key", &c.GitHubApp.PrivateKey); err != nil {
+		return err
+	}
+	if err := expand("github_app.api_url", &c.GitHubApp.APIURL); err != nil {
+		return err
+	}
+	return nil
+}
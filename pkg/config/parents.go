@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+)
+
+// DeriveParents sets the parent of each package without an explicit one to the nearest other package
+// of the same type whose namespace and name nest it, e.g. "pkg:golang/github.com/example/foo" is the parent of
+// "pkg:golang/github.com/example/foo/bar". Packages tracked by CPE have no namespace and are left as is.
+func DeriveParents(pkgs []Package) {
+	for i, pkg := range pkgs {
+		if pkg.CPE != "" || pkg.Parent != "" {
+			continue
+		}
+		p := purlPath(pkg)
+		var parent *Package
+		for j := range pkgs {
+			candidate := &pkgs[j]
+			if candidate.CPE != "" || candidate.PURL.Type != pkg.PURL.Type {
+				continue
+			}
+			cp := purlPath(*candidate)
+			if !strings.HasPrefix(p, cp+"/") {
+				continue
+			}
+			if parent == nil || len(cp) > len(purlPath(*parent)) {
+				parent = candidate
+			}
+		}
+		if parent != nil {
+			purl := parent.PURL
+			purl.Version = ""
+			pkgs[i].Parent = purl.String()
+		}
+	}
+}
+
+// purlPath returns the namespace and the name of the package joined by slashes.
+func purlPath(pkg Package) string {
+	return strings.Trim(pkg.PURL.Namespace+"/"+pkg.PURL.Name, "/")
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+)
+
+// LineError is an invalid line in a list of packages.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e LineError) Unwrap() error {
+	return e.Err
+}
+
+// ReadPackages reads a newline-delimited list of packages, e.g. piped from other tooling.
+// Each line is a PURL optionally followed by the URL of the source repository, separated by whitespace,
+// such as "pkg:npm/foo https://github.com/example/foo". A CPE may be given instead of the PURL, followed by the URL,
+// which is required. Blank lines and lines starting with "#" are ignored.
+// Versions are dropped since VEX documents are crawled per package.
+// Invalid lines don't stop reading and are returned along with the valid packages.
+func ReadPackages(r io.Reader) ([]Package, []LineError, error) {
+	var pkgs []Package
+	var invalid []LineError
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pkg, err := parsePackageLine(line)
+		if err != nil {
+			invalid = append(invalid, LineError{Line: n, Err: err})
+			continue
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, oops.Code("read_packages_error").In("config").Wrapf(err, "failed to read packages")
+	}
+	return pkgs, invalid, nil
+}
+
+func parsePackageLine(line string) (Package, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 2 {
+		return Package{}, oops.Errorf("too many fields")
+	}
+	if strings.HasPrefix(fields[0], "cpe:") {
+		if len(fields) < 2 {
+			return Package{}, oops.Errorf("url is required for cpe")
+		}
+		return Package{CPE: fields[0], URL: fields[1]}, nil
+	}
+	purl, err := packageurl.FromString(fields[0])
+	if err != nil {
+		return Package{}, oops.Wrapf(err, "invalid PURL")
+	} else if purl.Name == "" {
+		return Package{}, oops.Errorf("name is required")
+	}
+	purl.Version = ""
+
+	pkg := Package{PURL: purl}
+	if len(fields) == 2 {
+		pkg.URL = fields[1]
+	}
+	return pkg, nil
+}
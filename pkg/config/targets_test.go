@@ -0,0 +1,43 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+)
+
+func TestReadPackages(t *testing.T) {
+	input := `# Packages emitted by other tooling
+pkg:npm/foo
+pkg:golang/github.com/example/package@v1.2.3 https://github.com/example/package
+
+not-a-purl
+pkg:npm/bar https://example.com/bar extra
+  pkg:pypi/requests  
+cpe:2.3:a:example:product https://github.com/example/product
+cpe:2.3:a:example:other
+`
+	pkgs, invalid, err := config.ReadPackages(strings.NewReader(input))
+	require.NoError(t, err)
+
+	var got [][2]string
+	for _, pkg := range pkgs {
+		got = append(got, [2]string{pkg.ID(), pkg.URL})
+	}
+	assert.Equal(t, [][2]string{
+		{"pkg:npm/foo", ""},
+		{"pkg:golang/github.com/example/package", "https://github.com/example/package"},
+		{"pkg:pypi/requests", ""},
+		{"cpe:2.3:a:example:product", "https://github.com/example/product"},
+	}, got)
+
+	require.Len(t, invalid, 3)
+	assert.Equal(t, 5, invalid[0].Line)
+	assert.Equal(t, 6, invalid[1].Line)
+	assert.ErrorContains(t, invalid[1], "line 6: too many fields")
+	assert.ErrorContains(t, invalid[2], "line 9: url is required for cpe")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/samber/oops"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
 	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
@@ -57,12 +58,9 @@ func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL,
 		return nil, errBuilder.Wrapf(err, "failed to create request")
 	}
 
-	// Need to set a user-agent header
+	// The client sets the User-Agent header, which is required
 	// cf. https://crates.io/data-access
-	req.Header.Set("User-Agent", "aquasecurity/vex-crawler")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := download.Client().Do(req)
 	if err != nil {
 		return nil, errBuilder.Wrapf(err, "failed to get package info")
 	}
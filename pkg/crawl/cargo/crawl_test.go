@@ -13,6 +13,7 @@ import (
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/cargo"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
 )
 
 func TestCrawler_DetectSrc(t *testing.T) {
@@ -68,7 +69,7 @@ func TestCrawler_DetectSrc(t *testing.T) {
 		t.Run(
 			tt.name, func(t *testing.T) {
 				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					require.Equal(t, "aquasecurity/vex-crawler", r.Header.Get("User-Agent"))
+					require.Equal(t, download.UserAgent(), r.Header.Get("User-Agent"))
 
 					f, err := os.ReadFile(filepath.Join("testdata", r.RequestURI))
 					if err != nil {
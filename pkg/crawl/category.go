@@ -0,0 +1,127 @@
+package crawl
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	"github.com/aquasecurity/vexhub-crawler/pkg/report"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+var (
+	// validationErrors are returned by the validators, and by Collect for the documents failing the package.
+	validationErrors = []error{
+		vex.ErrPURLMismatch,
+		vex.ErrNoStatement,
+		vex.ErrInvalidTimestamp,
+		vex.ErrMissingJustification,
+		vex.ErrLFSPointer,
+		vex.ErrNameCollision,
+	}
+
+	// Codes of the errors by category, compared with the deepest code of the oops errors
+	categoryCodes = map[report.Category][]string{
+		report.CategoryConfig: {
+			"load_config_error",
+			"read_packages_error",
+			"url_parse_error",
+			"tls_config_error",
+			"github_app_config_error",
+			"basic_auth_config_error",
+			"credential_helper_config_error",
+			"signing_key_error",
+			"log_level_error",
+		},
+		report.CategoryValidation: {
+			"validation_error",
+		},
+		report.CategoryDownload: {
+			"download_error",
+			"crawl_error", // Detection of the source repository from the registries
+			"expand_error",
+			"list_tags_error",
+			"remote_head_error",
+			"last_commit_error",
+			"fetch_latest_version_error",
+			"fetch_pom_error",
+			"github_app_token_error",
+			"basic_auth_error",
+			"credential_helper_error",
+		},
+	}
+)
+
+// Categorize classifies the error by its sentinel errors, and then by the code of the oops error.
+// Errors of unknown kinds are report.CategoryOther.
+func Categorize(err error) report.Category {
+	var failure *FailureError
+	if errors.As(err, &failure) {
+		return failure.Category
+	}
+	if errors.Is(err, url.ErrSubdirsTraversal) {
+		return report.CategoryConfig
+	} else if errors.Is(err, download.ErrGitUnavailable) || errors.Is(err, download.ErrLFSUnavailable) {
+		// The host lacks a feature the config requires
+		return report.CategoryConfig
+	} else if errors.Is(err, vex.ErrNoVEXFile) {
+		return report.CategoryNoVEX
+	}
+	for _, target := range validationErrors {
+		if errors.Is(err, target) {
+			return report.CategoryValidation
+		}
+	}
+	if errors.Is(err, vex.ErrPartialDownload) {
+		return report.CategoryDownload
+	}
+	if oopsErr, ok := oops.AsOops(err); ok {
+		for category, codes := range categoryCodes {
+			if slices.Contains(codes, oopsErr.Code()) {
+				return category
+			}
+		}
+	}
+	return report.CategoryOther
+}
+
+// FailureError is returned by Failure for the runs in which every package failed.
+type FailureError struct {
+	Category report.Category // Category of the run, see Failure
+	Failed   int
+}
+
+func (e *FailureError) Error() string {
+	return fmt.Sprintf("all the %d packages failed", e.Failed)
+}
+
+// Failure returns a *FailureError if no package succeeded in the run, so that the run fails even without
+// Options.Strict, or nil if any package succeeded or was skipped. The categories of the failures take precedence
+// in the order of report.CategoryDownload, report.CategoryValidation and report.CategoryNoVEX,
+// since network problems likely cause the others.
+func Failure(r report.Report) error {
+	pkgs := r.Packages
+	for _, h := range r.Hubs {
+		pkgs = append(slices.Clip(pkgs), h.Packages...)
+	}
+	failures := make(map[report.Category]bool)
+	for _, p := range pkgs {
+		if p.Status != report.StatusFailed {
+			return nil
+		}
+		failures[p.Category] = true
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	for _, category := range []report.Category{report.CategoryDownload, report.CategoryValidation, report.CategoryNoVEX} {
+		if failures[category] {
+			return &FailureError{Category: category, Failed: len(pkgs)}
+		}
+	}
+	return &FailureError{Category: report.CategoryOther, Failed: len(pkgs)}
+}
@@ -0,0 +1,158 @@
+package crawl_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/samber/oops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	"github.com/aquasecurity/vexhub-crawler/pkg/report"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want report.Category
+	}{
+		{
+			name: "no VEX file",
+			err:  oops.Code("crawl_error").Wrap(oops.Code("collect_error").Wrap(vex.ErrNoVEXFile)),
+			want: report.CategoryNoVEX,
+		},
+		{
+			name: "subdirs traversal",
+			err:  oops.Code("crawl_error").Wrap(url.ErrSubdirsTraversal),
+			want: report.CategoryConfig,
+		},
+		{
+			name: "git unavailable",
+			err:  oops.Code("crawl_error").Wrap(oops.Code("download_error").Wrap(download.ErrGitUnavailable)),
+			want: report.CategoryConfig,
+		},
+		{
+			name: "validation sentinel",
+			err:  oops.Code("download_error").Wrap(fmt.Errorf("foo: %w", vex.ErrNoStatement)),
+			want: report.CategoryValidation,
+		},
+		{
+			name: "partial download",
+			err:  oops.Wrap(vex.ErrPartialDownload),
+			want: report.CategoryDownload,
+		},
+		{
+			name: "deepest code",
+			err:  oops.Code("crawl_error").Wrap(oops.Code("validation_error").Errorf("invalid")),
+			want: report.CategoryValidation,
+		},
+		{
+			name: "download code",
+			err:  oops.Code("download_error").Errorf("unexpected status"),
+			want: report.CategoryDownload,
+		},
+		{
+			name: "config code",
+			err:  oops.Code("load_config_error").Errorf("invalid config"),
+			want: report.CategoryConfig,
+		},
+		{
+			name: "failure",
+			err:  &crawl.FailureError{Category: report.CategoryNoVEX, Failed: 2},
+			want: report.CategoryNoVEX,
+		},
+		{
+			name: "unknown code",
+			err:  oops.Code("foo_error").Errorf("foo"),
+			want: report.CategoryOther,
+		},
+		{
+			name: "plain error",
+			err:  fmt.Errorf("foo"),
+			want: report.CategoryOther,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, crawl.Categorize(tt.err))
+		})
+	}
+}
+
+func TestFailure(t *testing.T) {
+	failed := func(category report.Category) report.Package {
+		return report.Package{Status: report.StatusFailed, Category: category}
+	}
+	tests := []struct {
+		name string
+		r    report.Report
+		want report.Category // Empty if the run doesn't fail
+	}{
+		{
+			name: "partial failure",
+			r: report.Report{Packages: []report.Package{
+				failed(report.CategoryDownload),
+				{Status: report.StatusSucceeded},
+			}},
+		},
+		{
+			name: "skipped",
+			r: report.Report{Packages: []report.Package{
+				failed(report.CategoryDownload),
+				{Status: report.StatusSkipped},
+			}},
+		},
+		{
+			name: "no packages",
+		},
+		{
+			name: "download takes precedence",
+			r: report.Report{Packages: []report.Package{
+				failed(report.CategoryNoVEX),
+				failed(report.CategoryDownload),
+				failed(report.CategoryValidation),
+			}},
+			want: report.CategoryDownload,
+		},
+		{
+			name: "validation over no VEX",
+			r: report.Report{Packages: []report.Package{
+				failed(report.CategoryNoVEX),
+				failed(report.CategoryValidation),
+			}},
+			want: report.CategoryValidation,
+		},
+		{
+			name: "other",
+			r: report.Report{Packages: []report.Package{
+				failed(report.CategoryOther),
+			}},
+			want: report.CategoryOther,
+		},
+		{
+			name: "hubs",
+			r: report.Report{Hubs: []report.Hub{
+				{Packages: []report.Package{failed(report.CategoryNoVEX)}},
+				{Packages: []report.Package{failed(report.CategoryNoVEX)}},
+			}},
+			want: report.CategoryNoVEX,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := crawl.Failure(tt.r)
+			if tt.want == "" {
+				require.NoError(t, err)
+				return
+			}
+			var failure *crawl.FailureError
+			require.ErrorAs(t, err, &failure)
+			assert.Equal(t, tt.want, failure.Category)
+		})
+	}
+}
@@ -1,11 +1,20 @@
 package crawl
 
 import (
+	"cmp"
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"log/slog"
+	"regexp"
+	"slices"
+	"time"
 
+	openvex "github.com/openvex/go-vex/pkg/vex"
 	"github.com/package-url/packageurl-go"
 	"github.com/samber/oops"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/cargo"
@@ -15,6 +24,9 @@ import (
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/oci"
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/pypi"
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/report"
+	"github.com/aquasecurity/vexhub-crawler/pkg/trace"
 	"github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
@@ -22,61 +34,536 @@ type Options struct {
 	VEXHubDir string
 	Packages  []config.Package
 	Strict    bool
+
+	// Hubs are crawled in the run instead of Packages into VEXHubDir, each into its own directory.
+	// The hubs share the concurrency, the download budget and the timeout, and the report groups the results by hub.
+	Hubs []config.Hub
+
+	// StrictPURL fails the package crawl on any VEX document whose PURL doesn't match.
+	StrictPURL bool
+
+	// Timeout is the wall-clock deadline of the whole crawl. Zero means no deadline.
+	Timeout time.Duration
+
+	// MaxBytes is the budget of downloaded bytes across the whole crawl. Zero means no limit.
+	MaxBytes int64
+
+	// VerifyWrites re-validates the VEX documents after they're written into VEX Hub.
+	VerifyWrites bool
+
+	// SemanticChanges ignores formatting-only changes of the VEX documents, see vex.Options.SemanticChanges.
+	SemanticChanges bool
+
+	// Embargoes withhold statements about the vulnerabilities from VEX Hub.
+	Embargoes []config.Embargo
+
+	// ManifestEncoding is the JSON encoding of the manifests. Nil means manifest.DefaultEncoding.
+	ManifestEncoding *manifest.Encoding
+
+	// ManifestName is the file name of the manifests. Empty means manifest.FileName.
+	ManifestName string
+
+	// ManifestSigningKey signs the manifests into detached signatures. Nil doesn't sign them.
+	ManifestSigningKey ed25519.PrivateKey
+
+	// Authors is the allowlist of the VEX document authors, unless overridden by the package.
+	Authors []string
+
+	// DeterministicTempDir downloads the sources into predictable temporary directories.
+	DeterministicTempDir bool
+
+	// Compress stores the VEX documents gzip-compressed.
+	Compress bool
+
+	// DigestAlgorithm is the algorithm of the digests in the manifests. Empty means manifest.DefaultDigestAlgorithm.
+	DigestAlgorithm string
+
+	// Groups records the sources sharing a vulnerability ID in the manifests.
+	Groups bool
+
+	// MergeManifests carries the fields unknown to the crawler over from the previous manifests.
+	MergeManifests bool
+
+	// Latest merges the OpenVEX documents of each package into vex.LatestFileName.
+	Latest bool
+
+	// History keeps the previous versions of the VEX documents, see vex.Options.History. Nil disables it.
+	History *config.History
+
+	// Quarantine keeps the files rejected by the validation, see vex.Options.Quarantine. Nil disables it.
+	Quarantine *config.Quarantine
+
+	// Statuses are the statuses of the statements kept in VEX Hub, see vex.Options.Statuses. Empty keeps all.
+	Statuses []string
+
+	// ParseWorkers is the number of files validated in parallel within a package.
+	ParseWorkers int
+
+	// Clone always clones the source repositories instead of downloading archives from GitHub and GitLab.
+	Clone bool
+
+	// Incremental validates only the files changed since the commit of the previous crawl of each package.
+	Incremental bool
+
+	// Types selects the PURL types to crawl. Packages of other types are skipped.
+	Types config.TypeFilter
+
+	// Defaults are the global discovery and validation settings, unless overridden by the package.
+	Defaults config.Overrides
+
+	// StatePath is the file recording the packages crawled successfully in the run.
+	// It is removed when the run completes. Empty disables recording.
+	StatePath string
+
+	// Resume skips the packages recorded in the state file if their remote HEAD is unchanged.
+	Resume bool
+
+	// Since skips the packages already in VEX Hub whose source has had no commit within the duration,
+	// which reduces the load on both the crawler and the hosts. Packages whose last commit can't be determined,
+	// e.g. archives, are crawled. Zero crawls all the packages.
+	Since time.Duration
+
+	// Concurrency limits the packages downloaded and collected at the same time.
+	Concurrency config.Concurrency
+
+	// MaxTags additionally crawls up to the given number of the latest semver tags
+	// into versioned directories. Zero disables crawling tags.
+	MaxTags int
+
+	// FailOnHookError fails the package if any post-crawl hook fails, see RegisterPostCrawlHook.
+	// By default, the errors of the hooks are logged and the package succeeds.
+	FailOnHookError bool
+
+	// Tracer records a span of the run, whose children are the spans of the phases of each package, see vex.Options.
+	// Nil disables tracing.
+	Tracer oteltrace.Tracer
 }
 
 type Crawler interface {
 	DetectSrc(context.Context, config.Package) (*url.URL, error)
 }
 
-func Packages(ctx context.Context, opts Options) error {
-	for _, pkg := range opts.Packages {
-		logger := slog.With(slog.String("type", pkg.PURL.Type), slog.String("purl", pkg.PURL.String()))
-		logger.Info("Crawling package...")
-		if err := crawlPackage(ctx, opts.VEXHubDir, pkg); err != nil {
-			if opts.Strict {
-				return oops.Wrapf(err, "strict")
+// Packages crawls the packages and returns the report of the run.
+// The report covers the packages processed so far even if an error is returned.
+func Packages(ctx context.Context, opts Options) (r report.Report, err error) {
+	var pkgs []hubPackage
+	if len(opts.Hubs) == 0 {
+		for _, pkg := range opts.Packages {
+			pkgs = append(pkgs, hubPackage{pkg: pkg})
+		}
+	}
+	for i := range opts.Hubs {
+		for _, pkg := range opts.Hubs[i].Packages {
+			pkgs = append(pkgs, hubPackage{pkg: pkg, hub: &opts.Hubs[i]})
+		}
+	}
+
+	ctx, span := trace.OrNoop(opts.Tracer).Start(ctx, "crawl",
+		oteltrace.WithAttributes(attribute.Int("packages", len(pkgs)), attribute.Int("hubs", len(opts.Hubs))))
+	defer func() { trace.End(span, err) }()
+
+	if len(opts.Statuses) > 0 {
+		slog.Warn("Dropping the statements of other statuses from VEX Hub", slog.Any("statuses", opts.Statuses))
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	st := &state{Packages: make(map[string]string)}
+	if opts.StatePath != "" && opts.Resume {
+		if st, err = loadState(opts.StatePath); err != nil {
+			return report.Report{}, oops.Wrapf(err, "failed to load the state")
+		}
+	}
+
+	p := &pipeline{opts: opts, pkgs: pkgs, st: st, hosts: newHostLimiter(opts.Concurrency)}
+	done, stopped, err := p.run(ctx)
+	r = newReport(opts.Hubs, done, stopped)
+	if err != nil {
+		return r, err
+	}
+
+	// Nothing is left to resume after a complete run, unless packages failed and should be retried
+	if opts.StatePath != "" && r.Stopped == "" && !failed(r) {
+		if err := removeState(opts.StatePath); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// failed reports whether a package of the report failed, including the packages of the hubs.
+func failed(r report.Report) bool {
+	isFailed := func(p report.Package) bool { return p.Status == report.StatusFailed }
+	if slices.ContainsFunc(r.Packages, isFailed) {
+		return true
+	}
+	return slices.ContainsFunc(r.Hubs, func(h report.Hub) bool { return slices.ContainsFunc(h.Packages, isFailed) })
+}
+
+// newReport returns the report of the outcomes, grouped by hub if hubs are crawled.
+func newReport(hubs []config.Hub, done []outcome, stopped string) report.Report {
+	r := report.Report{Stopped: stopped}
+	if len(hubs) == 0 {
+		for _, o := range done {
+			r.Packages = append(r.Packages, o.entries...)
+		}
+		return r
+	}
+
+	r.Hubs = make([]report.Hub, len(hubs))
+	for i, h := range hubs {
+		r.Hubs[i] = report.Hub{Name: h.Name, Dir: h.Dir, Packages: make([]report.Package, 0)}
+	}
+	for _, o := range done {
+		i := slices.IndexFunc(hubs, func(h config.Hub) bool { return h.Name == o.hub.Name })
+		r.Hubs[i].Packages = append(r.Hubs[i].Packages, o.entries...)
+	}
+	return r
+}
+
+// exhausted returns the reason if the crawl has run out of time or download budget.
+func exhausted(ctx context.Context, opts Options, downloaded int64) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "timeout exceeded"
+	} else if ctx.Err() != nil {
+		return ctx.Err().Error()
+	} else if opts.MaxBytes > 0 && downloaded >= opts.MaxBytes {
+		return "download budget exceeded"
+	}
+	return ""
+}
+
+// vexOptions returns the options of the package.
+// The package settings take precedence over the global ones, which take precedence over the built-in defaults.
+func vexOptions(opts Options, pkg config.Package) vex.Options {
+	authors := opts.Authors
+	if len(pkg.Authors) > 0 {
+		authors = pkg.Authors
+	}
+
+	global, overrides := opts.Defaults, pkg.Overrides
+	strictPURL := opts.StrictPURL || (global.StrictPURL != nil && *global.StrictPURL)
+	if overrides.StrictPURL != nil {
+		strictPURL = *overrides.StrictPURL
+	}
+	skipInvalid := global.SkipInvalid != nil && *global.SkipInvalid
+	if overrides.SkipInvalid != nil {
+		skipInvalid = *overrides.SkipInvalid
+	}
+	lfs := global.LFS != nil && *global.LFS
+	if overrides.LFS != nil {
+		lfs = *overrides.LFS
+	}
+	matchCPE := global.MatchCPE != nil && *global.MatchCPE
+	if overrides.MatchCPE != nil {
+		matchCPE = *overrides.MatchCPE
+	}
+	matchSubcomponents := global.MatchSubcomponents != nil && *global.MatchSubcomponents
+	if overrides.MatchSubcomponents != nil {
+		matchSubcomponents = *overrides.MatchSubcomponents
+	}
+	strictTimestamps := global.StrictTimestamps != nil && *global.StrictTimestamps
+	if overrides.StrictTimestamps != nil {
+		strictTimestamps = *overrides.StrictTimestamps
+	}
+	strictJustifications := global.StrictJustifications != nil && *global.StrictJustifications
+	if overrides.StrictJustifications != nil {
+		strictJustifications = *overrides.StrictJustifications
+	}
+	sbom := global.SBOM != nil && *global.SBOM
+	if overrides.SBOM != nil {
+		sbom = *overrides.SBOM
+	}
+	productRegexps := global.ProductPatterns
+	if len(overrides.ProductPatterns) > 0 {
+		productRegexps = overrides.ProductPatterns
+	}
+	var productPatterns []*regexp.Regexp
+	for _, re := range productRegexps {
+		productPatterns = append(productPatterns, re.Regexp)
+	}
+	// Unlike the other lists, an empty list of qualifiers is meaningful, ignoring all of them
+	significantQualifiers := global.SignificantQualifiers
+	if overrides.SignificantQualifiers != nil {
+		significantQualifiers = overrides.SignificantQualifiers
+	}
+	patterns := global.Patterns
+	if len(overrides.Patterns) > 0 {
+		patterns = overrides.Patterns
+	}
+	var statuses []openvex.Status
+	for _, s := range opts.Statuses {
+		statuses = append(statuses, openvex.Status(s))
+	}
+	rootDirs := global.RootDirs
+	if len(overrides.RootDirs) > 0 {
+		rootDirs = overrides.RootDirs
+	}
+
+	return vex.Options{
+		StrictPURL:            strictPURL,
+		SkipInvalid:           skipInvalid,
+		LFS:                   lfs,
+		MatchCPE:              matchCPE,
+		MatchSubcomponents:    matchSubcomponents,
+		StrictTimestamps:      strictTimestamps,
+		StrictJustifications:  strictJustifications,
+		SBOM:                  sbom,
+		PublicURL:             pkg.PublicURL,
+		ProductPatterns:       productPatterns,
+		SignificantQualifiers: significantQualifiers,
+		VerifyWrites:          opts.VerifyWrites,
+		SemanticChanges:       opts.SemanticChanges,
+		DeterministicTempDir:  opts.DeterministicTempDir,
+		Clone:                 opts.Clone,
+		Incremental:           opts.Incremental,
+		Compress:              opts.Compress,
+		DigestAlgorithm:       opts.DigestAlgorithm,
+		Groups:                opts.Groups,
+		MergeManifest:         opts.MergeManifests,
+		Latest:                opts.Latest,
+		Statuses:              statuses,
+		History:               history(opts.History),
+		Quarantine:            quarantine(opts.Quarantine),
+		ParseWorkers:          opts.ParseWorkers,
+		Tracer:                opts.Tracer,
+		Embargoed:             embargoed(opts.Embargoes),
+		ManifestEncoding:      opts.ManifestEncoding,
+		ManifestName:          opts.ManifestName,
+		ManifestSigningKey:    opts.ManifestSigningKey,
+		Authors:               authors,
+		Trusted:               pkg.Trusted,
+		Patterns:              patterns,
+		RootDirs:              rootDirs,
+		RootDirMode:           vex.RootDirMode(cmp.Or(overrides.RootDirMode, global.RootDirMode)),
+		Subdir:                cmp.Or(overrides.Subdir, global.Subdir),
+		Format:                vex.Format(cmp.Or(overrides.Format, global.Format)),
+		DigestPolicy:          vex.DigestPolicy(cmp.Or(overrides.DigestPolicy, global.DigestPolicy)),
+		DuplicateNames:        vex.DuplicateNames(cmp.Or(overrides.DuplicateNames, global.DuplicateNames)),
+		Version:               pkg.PURL.Version,
+		Parent:                pkg.Parent,
+	}
+}
+
+// history returns the retention of the previous versions of the VEX documents, or nil if disabled.
+func history(h *config.History) *vex.History {
+	if h == nil {
+		return nil
+	}
+	return &vex.History{Keep: h.Keep, MaxAge: h.MaxAge}
+}
+
+// quarantine returns the limits of the quarantine, or nil if disabled.
+func quarantine(q *config.Quarantine) *vex.Quarantine {
+	if q == nil {
+		return nil
+	}
+	return &vex.Quarantine{MaxFiles: q.MaxFiles, MaxFileSize: q.MaxFileSize}
+}
+
+// embargoed returns a predicate reporting whether the vulnerability is under an active embargo.
+func embargoed(embargoes []config.Embargo) func(string) bool {
+	if len(embargoes) == 0 {
+		return nil
+	}
+	now := time.Now()
+	return func(vulnID string) bool {
+		for _, e := range embargoes {
+			if e.Vulnerability == vulnID && e.Active(now) {
+				return true
 			}
-			logger.Warn(err.Error(), slog.Any("error", err))
 		}
+		return false
 	}
-	return nil
 }
 
-func crawlPackage(ctx context.Context, vexHubDir string, pkg config.Package) error {
-	errBuilder := oops.Code("crawl_package").With("type", pkg.PURL.Type).With("purl", pkg.PURL.String())
+func packageReport(id string, result *vex.Result, err error) report.Package {
+	p := report.Package{
+		ID:     id,
+		Status: report.StatusSucceeded,
+		Result: result,
+	}
+	if err != nil {
+		p.Status = report.StatusFailed
+		p.Error = err.Error()
+		p.Category = Categorize(err)
+	}
+	return p
+}
+
+// crawlTags crawls the latest tags of the source repository into versioned directories.
+// Failures are reported but don't fail the package.
+func crawlTags(ctx context.Context, opts Options, pkg config.Package, src *url.URL, hosts *hostLimiter) []report.Package {
+	logger := trace.Logger(ctx).With(slog.String("purl", pkg.ID()))
+	tags, err := vex.LatestTags(ctx, src, opts.MaxTags)
+	if err != nil {
+		logger.Warn("Failed to list tags", slog.Any("error", err))
+		return nil
+	}
+
+	var entries []report.Package
+	for _, tag := range tags {
+		if ctx.Err() != nil {
+			break
+		}
+		logger.Info("Crawling tag...", slog.String("tag", tag))
+		u := *src
+		u.SetRef(tag)
+
+		vopts := vexOptions(opts, pkg)
+		vopts.Version = tag
+		release, err := hosts.acquire(ctx, u.Hostname())
+		if err != nil {
+			break
+		}
+		result, err := crawlSource(ctx, opts.VEXHubDir, &u, pkg, vopts)
+		release()
+		if err != nil {
+			logger.Warn(err.Error(), slog.String("tag", tag), slog.Any("error", err))
+		}
+		entries = append(entries, packageReport(versionedID(pkg, tag), result, err))
+	}
+	return entries
+}
+
+// crawlSource crawls the package tracked by either PURL or CPE.
+func crawlSource(ctx context.Context, vexHubDir string, src *url.URL, pkg config.Package, opts vex.Options) (*vex.Result, error) {
+	if pkg.CPE == "" {
+		return vex.CrawlPackage(ctx, vexHubDir, src, targetPURL(pkg), opts)
+	}
+	cpe, err := vex.ParseCPE(pkg.CPE)
+	if err != nil {
+		return nil, err
+	}
+	return vex.CrawlCPE(ctx, vexHubDir, src, cpe, opts)
+}
+
+// fetchSource downloads the source repository of the package tracked by either PURL or CPE.
+func fetchSource(ctx context.Context, src *url.URL, pkg config.Package, opts vex.Options) (*vex.Source, error) {
+	if pkg.CPE == "" {
+		return vex.Fetch(ctx, src, targetPURL(pkg), opts)
+	}
+	cpe, err := vex.ParseCPE(pkg.CPE)
+	if err != nil {
+		return &vex.Source{}, err
+	}
+	return vex.FetchCPE(ctx, src, cpe, opts)
+}
+
+// collectSource copies the VEX documents of the package tracked by either PURL or CPE into VEX Hub.
+func collectSource(ctx context.Context, source *vex.Source, vexHubDir string, src *url.URL, pkg config.Package, opts vex.Options) (*vex.Result, error) {
+	if pkg.CPE == "" {
+		return source.Collect(ctx, vexHubDir, src, targetPURL(pkg), opts)
+	}
+	cpe, err := vex.ParseCPE(pkg.CPE)
+	if err != nil {
+		return &vex.Result{}, err
+	}
+	return source.CollectCPE(ctx, vexHubDir, src, cpe, opts)
+}
+
+// targetPURL returns the PURL matched against the product IDs of the VEX documents, without the version,
+// since the documents of a release may refer to the package regardless of the version. The version is crawled as
+// vex.Options.Version instead.
+func targetPURL(pkg config.Package) packageurl.PackageURL {
+	purl := pkg.PURL
+	purl.Version = ""
+	return purl
+}
+
+// pinVersion pins the source to the tag releasing the version in the PURL, so that the VEX documents are
+// crawled as of the release. The default branch is crawled with a warning if no tag is found,
+// and a ref already pinned in the URL takes precedence. It reports whether the source is pinned, by the tag or the URL,
+// so that only then the documents are stored as the version.
+func pinVersion(ctx context.Context, pkg config.Package, src *url.URL) bool {
+	version := pkg.PURL.Version
+	if pkg.CPE != "" || version == "" || src.IsFile() || src.IsBucket() {
+		return false
+	} else if src.Ref() != "" {
+		return true
+	}
+	logger := trace.Logger(ctx).With(slog.String("purl", pkg.ID()), slog.String("version", version))
+	tag, err := vex.VersionTag(ctx, src, pkg.PURL.Name, version)
+	if err != nil {
+		logger.Warn("Failed to resolve the tag of the version, crawling the default branch", slog.Any("error", err))
+		return false
+	} else if tag == "" {
+		logger.Warn("No tag found for the version, crawling the default branch")
+		return false
+	}
+	logger.Info("Crawling the tag of the version", slog.String("tag", tag))
+	src.SetRef(tag)
+	return true
+}
+
+// versionedID returns the ID of the package at the version, as recorded in the manifest.
+func versionedID(pkg config.Package, version string) string {
+	if pkg.CPE == "" {
+		purl := pkg.PURL
+		purl.Version = version
+		return purl.String()
+	}
+	cpe, err := vex.ParseCPE(pkg.CPE)
+	if err != nil {
+		return pkg.CPE
+	}
+	cpe.Version = version
+	return cpe.String()
+}
 
-	var crawler Crawler
-	switch pkg.PURL.Type {
+// newCrawler returns the crawler of the PURL type, or nil if the type is not supported.
+func newCrawler(typ string) Crawler {
+	switch typ {
 	case packageurl.TypeCargo:
-		crawler = cargo.NewCrawler()
+		return cargo.NewCrawler()
 	case packageurl.TypeGolang:
-		crawler = golang.NewCrawler()
+		return golang.NewCrawler()
 	case packageurl.TypeMaven:
-		crawler = maven.NewCrawler()
+		return maven.NewCrawler()
 	case packageurl.TypeNPM:
-		crawler = npm.NewCrawler()
+		return npm.NewCrawler()
 	case packageurl.TypePyPi:
-		crawler = pypi.NewCrawler()
+		return pypi.NewCrawler()
 	case packageurl.TypeOCI:
-		crawler = oci.NewCrawler()
-	default:
-		return oops.Errorf("unsupported package type: %s", pkg.PURL.Type)
+		return oci.NewCrawler()
+	}
+	return nil
+}
+
+func detectSrc(ctx context.Context, pkg config.Package) (*url.URL, error) {
+	errBuilder := oops.Code("crawl_package").With("type", pkg.Type()).With("purl", pkg.ID())
+	if pkg.CPE != "" {
+		// The source can't be detected from a CPE
+		if pkg.URL == "" {
+			return nil, errBuilder.Errorf("url is required for cpe")
+		} else if _, err := vex.ParseCPE(pkg.CPE); err != nil {
+			return nil, errBuilder.Wrapf(err, "invalid CPE")
+		}
+		src, err := url.Parse(pkg.URL)
+		if err != nil {
+			return nil, errBuilder.With("url", url.Redact(pkg.URL)).Wrapf(err, "failed to normalize URL")
+		}
+		return src, nil
+	}
+
+	crawler := newCrawler(pkg.PURL.Type)
+	if crawler == nil {
+		return nil, oops.Errorf("unsupported package type: %s", pkg.PURL.Type)
 	}
 
 	var src *url.URL
 	var err error
 	if pkg.URL != "" {
 		if src, err = url.Parse(pkg.URL); err != nil {
-			return errBuilder.With("url", pkg.URL).Wrapf(err, "failed to normalize URL")
+			return nil, errBuilder.With("url", url.Redact(pkg.URL)).Wrapf(err, "failed to normalize URL")
 		}
 	} else {
 		if src, err = crawler.DetectSrc(ctx, pkg); err != nil {
-			return errBuilder.Wrapf(err, "failed to detect source repository")
+			return nil, errBuilder.Wrapf(err, "failed to detect source repository")
 		}
 	}
-
-	if err = vex.CrawlPackage(ctx, vexHubDir, src, pkg.PURL); err != nil {
-		return errBuilder.Wrapf(err, "failed to crawl package")
-	}
-	return nil
+	return src, nil
 }
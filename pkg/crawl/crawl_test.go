@@ -0,0 +1,708 @@
+package crawl_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	openvex "github.com/openvex/go-vex/pkg/vex"
+	"github.com/package-url/packageurl-go"
+	"github.com/sosedoff/gitkit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/report"
+)
+
+// newServer serves a git repository with a VEX document covering the products.
+func newServer(t *testing.T, productIDs ...string) *httptest.Server {
+	return newServerAt(t, time.Now(), productIDs...)
+}
+
+// newServerAt serves a git repository like newServer, committed at the time.
+func newServerAt(t *testing.T, when time.Time, productIDs ...string) *httptest.Server {
+	wtDir := t.TempDir()
+	r, err := git.PlainInit(wtDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	doc := openvex.New()
+	for _, id := range productIDs {
+		doc.Statements = append(doc.Statements, openvex.Statement{
+			Vulnerability: openvex.Vulnerability{Name: "CVE-2024-0001"},
+			Products:      []openvex.Product{{Component: openvex.Component{ID: id}}},
+			Status:        openvex.StatusNotAffected,
+			Justification: openvex.VulnerableCodeNotPresent,
+		})
+	}
+	content, err := json.Marshal(doc)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(wtDir, ".vex"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(wtDir, ".vex", "openvex.json"), content, 0644))
+
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	_, err = wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: when},
+	})
+	require.NoError(t, err)
+
+	bareDir := t.TempDir()
+	_, err = git.PlainClone(filepath.Join(bareDir, "testrepo.git"), true, &git.CloneOptions{URL: wtDir})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(gitkit.New(gitkit.Config{Dir: bareDir}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func statuses(r report.Report) map[string]report.Status {
+	m := make(map[string]report.Status)
+	for _, p := range r.Packages {
+		m[p.ID] = p.Status
+	}
+	return m
+}
+
+func TestPackages_Resume(t *testing.T) {
+	server := newServer(t, "pkg:golang/github.com/example/foo", "pkg:golang/github.com/example/bar")
+	var pkgs []config.Package
+	for _, name := range []string{"foo", "bar"} {
+		pkgs = append(pkgs, config.Package{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: name},
+			URL:  server.URL + "/testrepo.git",
+		})
+	}
+
+	vexHubDir := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	opts := crawl.Options{
+		VEXHubDir: vexHubDir,
+		Packages:  pkgs[:1],
+		StatePath: statePath,
+	}
+
+	// Interrupted run, after the first package
+	r, err := crawl.Packages(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, r.Packages, 1)
+	require.NotNil(t, r.Packages[0].Result)
+	require.NoError(t, os.WriteFile(statePath,
+		[]byte(`{"packages": {"pkg:golang/github.com/example/foo": "`+r.Packages[0].Result.Commit+`"}}`), 0644))
+
+	// Resumed run
+	opts.Packages = pkgs
+	opts.Resume = true
+	r, err = crawl.Packages(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Empty(t, r.Stopped)
+	assert.Equal(t, map[string]report.Status{
+		"pkg:golang/github.com/example/foo": report.StatusSkipped,
+		"pkg:golang/github.com/example/bar": report.StatusSucceeded,
+	}, statuses(r))
+	assert.NoFileExists(t, statePath, "the state should be cleared after a complete run")
+
+	// Packages whose remote HEAD has changed are crawled again
+	require.NoError(t, os.WriteFile(statePath,
+		[]byte(`{"packages": {"pkg:golang/github.com/example/foo": "0000000000000000000000000000000000000000"}}`), 0644))
+	r, err = crawl.Packages(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]report.Status{
+		"pkg:golang/github.com/example/foo": report.StatusSucceeded,
+		"pkg:golang/github.com/example/bar": report.StatusSucceeded,
+	}, statuses(r))
+}
+
+func TestPackages_ResumeFailed(t *testing.T) {
+	server := newServer(t, "pkg:golang/github.com/example/foo")
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	opts := crawl.Options{
+		VEXHubDir: t.TempDir(),
+		Packages: []config.Package{
+			{
+				PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: "foo"},
+				URL:  server.URL + "/testrepo.git",
+			},
+			{
+				PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: "bar"},
+				URL:  server.URL + "/missing.git",
+			},
+		},
+		StatePath: statePath,
+	}
+
+	// The run completes, but the failed package is left to be retried
+	r, err := crawl.Packages(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Empty(t, r.Stopped)
+	assert.Equal(t, map[string]report.Status{
+		"pkg:golang/github.com/example/foo": report.StatusSucceeded,
+		"pkg:golang/github.com/example/bar": report.StatusFailed,
+	}, statuses(r))
+
+	b, err := os.ReadFile(statePath)
+	require.NoError(t, err, "the state should be kept for the next run")
+	var st struct {
+		Packages map[string]string `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal(b, &st))
+	assert.Contains(t, st.Packages, "pkg:golang/github.com/example/foo")
+	assert.NotContains(t, st.Packages, "pkg:golang/github.com/example/bar")
+}
+
+func TestPackages_MaxBytes(t *testing.T) {
+	server := newServer(t, "pkg:golang/github.com/example/foo")
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"packages": {}}`), 0644))
+	opts := crawl.Options{
+		VEXHubDir: t.TempDir(),
+		Packages: []config.Package{
+			{
+				PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: "foo"},
+				URL:  server.URL + "/testrepo.git",
+			},
+		},
+		StatePath: statePath,
+		MaxBytes:  1,
+	}
+
+	// The budget is exceeded while the last package is in flight, which is canceled
+	r, err := crawl.Packages(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, "download budget exceeded", r.Stopped)
+	assert.Equal(t, map[string]report.Status{
+		"pkg:golang/github.com/example/foo": report.StatusFailed,
+	}, statuses(r))
+	assert.FileExists(t, statePath, "the state should be kept for the next run")
+}
+
+func TestPackages_Types(t *testing.T) {
+	server := newServer(t, "pkg:golang/github.com/example/foo", "pkg:npm/foo", "pkg:cargo/foo")
+	pkgs := []config.Package{
+		{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: "foo"},
+			URL:  server.URL + "/testrepo.git",
+		},
+		{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeNPM, Name: "foo"},
+			URL:  server.URL + "/testrepo.git",
+		},
+		{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeCargo, Name: "foo"},
+			URL:  server.URL + "/testrepo.git",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		types config.TypeFilter
+		want  []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"pkg:golang/github.com/example/foo", "pkg:npm/foo", "pkg:cargo/foo"},
+		},
+		{
+			name:  "include",
+			types: config.TypeFilter{Include: []string{"npm"}},
+			want:  []string{"pkg:npm/foo"},
+		},
+		{
+			name:  "exclude",
+			types: config.TypeFilter{Exclude: []string{"npm"}},
+			want:  []string{"pkg:golang/github.com/example/foo", "pkg:cargo/foo"},
+		},
+		{
+			name:  "include takes precedence",
+			types: config.TypeFilter{Include: []string{"npm", "cargo"}, Exclude: []string{"npm"}},
+			want:  []string{"pkg:npm/foo", "pkg:cargo/foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := crawl.Packages(context.Background(), crawl.Options{
+				VEXHubDir: t.TempDir(),
+				Packages:  pkgs,
+				Types:     tt.types,
+			})
+			require.NoError(t, err)
+
+			var got []string
+			for _, p := range r.Packages {
+				assert.Equal(t, report.StatusSucceeded, p.Status)
+				got = append(got, p.ID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPackages_Concurrency(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f"}
+	var productIDs []string
+	for _, name := range names {
+		productIDs = append(productIDs, "pkg:golang/github.com/example/"+name)
+	}
+	server := newServer(t, productIDs...)
+
+	var pkgs []config.Package
+	for _, name := range names {
+		pkgs = append(pkgs, config.Package{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: name},
+			URL:  server.URL + "/testrepo.git",
+		})
+	}
+	// Fails at the download stage
+	pkgs = append(pkgs, config.Package{
+		PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: "missing"},
+		URL:  server.URL + "/missing.git",
+	})
+
+	tests := []struct {
+		name        string
+		concurrency config.Concurrency
+	}{
+		{
+			name: "sequential",
+		},
+		{
+			name:        "more downloads than parsers",
+			concurrency: config.Concurrency{Downloads: 4, Parsers: 1, Queue: 1},
+		},
+		{
+			name:        "more parsers than downloads",
+			concurrency: config.Concurrency{Downloads: 1, Parsers: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vexHubDir := t.TempDir()
+			r, err := crawl.Packages(context.Background(), crawl.Options{
+				VEXHubDir:   vexHubDir,
+				Packages:    pkgs,
+				Concurrency: tt.concurrency,
+			})
+			require.NoError(t, err)
+
+			// The report follows the order of the packages
+			var got []string
+			for _, p := range r.Packages {
+				got = append(got, p.ID)
+			}
+			assert.Equal(t, append(productIDs, "pkg:golang/github.com/example/missing"), got)
+
+			for _, p := range r.Packages[:len(names)] {
+				assert.Equal(t, report.StatusSucceeded, p.Status, p.ID)
+			}
+			assert.Equal(t, report.StatusFailed, r.Packages[len(names)].Status)
+
+			for _, name := range names {
+				assert.FileExists(t, filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", name, "openvex.json"))
+			}
+		})
+	}
+}
+
+func TestPackages_PerHostConcurrency(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+	var productIDs []string
+	for _, name := range names {
+		productIDs = append(productIDs, "pkg:golang/github.com/example/"+name)
+	}
+	server := newServer(t, productIDs...)
+
+	// Records the maximum number of requests in flight, which is the number of concurrent clones
+	var (
+		mu                    sync.Mutex
+		inFlight, maxInFlight int
+	)
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+	})
+
+	var pkgs []config.Package
+	for _, name := range names {
+		pkgs = append(pkgs, config.Package{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: name},
+			URL:  server.URL + "/testrepo.git",
+		})
+	}
+
+	tests := []struct {
+		name        string
+		concurrency config.Concurrency
+	}{
+		{
+			name:        "per host",
+			concurrency: config.Concurrency{Downloads: 4, Parsers: 2, PerHost: 1},
+		},
+		{
+			name:        "host override",
+			concurrency: config.Concurrency{Downloads: 4, Parsers: 2, PerHost: 4, Hosts: map[string]int{"127.0.0.1": 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			maxInFlight = 0
+			mu.Unlock()
+			vexHubDir := t.TempDir()
+			r, err := crawl.Packages(context.Background(), crawl.Options{
+				VEXHubDir:   vexHubDir,
+				Packages:    pkgs,
+				Concurrency: tt.concurrency,
+			})
+			require.NoError(t, err)
+			for _, p := range r.Packages {
+				assert.Equal(t, report.StatusSucceeded, p.Status, p.ID)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, 1, maxInFlight)
+		})
+	}
+}
+
+func TestPackages_SaturatedHost(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+	var productIDs []string
+	for _, name := range names {
+		productIDs = append(productIDs, "pkg:golang/github.com/example/"+name)
+	}
+	server := newServer(t, productIDs...)
+
+	// The requests to the throttled host are slow, and the number of them completed is recorded
+	// when the other host is first requested
+	var (
+		mu               sync.Mutex
+		completed        int
+		completedAtOther = -1
+	)
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host != "127.0.0.1" {
+			mu.Lock()
+			if completedAtOther < 0 {
+				completedAtOther = completed
+			}
+			mu.Unlock()
+			handler.ServeHTTP(w, r)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+		mu.Lock()
+		completed++
+		mu.Unlock()
+	})
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	var pkgs []config.Package
+	for _, name := range names {
+		pkg := config.Package{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: name},
+			URL:  server.URL + "/testrepo.git",
+		}
+		if name == "d" {
+			pkg.URL = "http://localhost:" + port + "/testrepo.git"
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	r, err := crawl.Packages(context.Background(), crawl.Options{
+		VEXHubDir:   t.TempDir(),
+		Packages:    pkgs,
+		Concurrency: config.Concurrency{Downloads: 2, Parsers: 2, Hosts: map[string]int{"127.0.0.1": 1}},
+	})
+	require.NoError(t, err)
+	for _, p := range r.Packages {
+		assert.Equal(t, report.StatusSucceeded, p.Status, p.ID)
+	}
+
+	// The package of the other host doesn't wait behind the ones of the saturated host
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 0, completedAtOther)
+}
+
+func TestPackages_Version(t *testing.T) {
+	wtDir := t.TempDir()
+	r, err := git.PlainInit(wtDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(wtDir, ".vex"), 0755))
+
+	// The release is tagged, and the default branch has moved on
+	commits := make(map[string]string)
+	for _, vulnID := range []string{"CVE-2024-0001", "CVE-2024-0002"} {
+		doc := openvex.New()
+		doc.Statements = []openvex.Statement{{
+			Vulnerability: openvex.Vulnerability{Name: openvex.VulnerabilityID(vulnID)},
+			Products:      []openvex.Product{{Component: openvex.Component{ID: "pkg:golang/github.com/example/foo"}}},
+			Status:        openvex.StatusNotAffected,
+			Justification: openvex.VulnerableCodeNotPresent,
+		}}
+		content, err := json.Marshal(doc)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(wtDir, ".vex", "openvex.json"), content, 0644))
+		_, err = wt.Add(".")
+		require.NoError(t, err)
+		hash, err := wt.Commit(vulnID, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+		commits[vulnID] = hash.String()
+		if vulnID == "CVE-2024-0001" {
+			_, err = r.CreateTag("v1.0.0", hash, nil)
+			require.NoError(t, err)
+		}
+	}
+
+	bareDir := t.TempDir()
+	_, err = git.PlainClone(filepath.Join(bareDir, "testrepo.git"), true, &git.CloneOptions{URL: wtDir})
+	require.NoError(t, err)
+	server := httptest.NewServer(gitkit.New(gitkit.Config{Dir: bareDir}))
+	t.Cleanup(server.Close)
+
+	var pkgs []config.Package
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		pkgs = append(pkgs, config.Package{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: "foo", Version: version},
+			URL:  server.URL + "/testrepo.git",
+		})
+	}
+	vexHubDir := t.TempDir()
+	rep, err := crawl.Packages(context.Background(), crawl.Options{VEXHubDir: vexHubDir, Packages: pkgs})
+	require.NoError(t, err)
+	require.Len(t, rep.Packages, 2)
+
+	pkgDir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "foo")
+	tests := []struct {
+		version    string
+		wantCommit string
+		wantVuln   string
+		wantDir    string // Directory of the documents in the package directory
+		wantID     string // ID in the manifest
+	}{
+		{
+			version:    "1.0.0",
+			wantCommit: commits["CVE-2024-0001"],
+			wantVuln:   "CVE-2024-0001",
+			wantDir:    "1.0.0",
+			wantID:     "pkg:golang/github.com/example/foo@1.0.0",
+		},
+		{
+			// No tag, so the default branch is stored as the package regardless of the version
+			version:    "2.0.0",
+			wantCommit: commits["CVE-2024-0002"],
+			wantVuln:   "CVE-2024-0002",
+			wantID:     "pkg:golang/github.com/example/foo",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			entry := rep.Packages[i]
+			assert.Equal(t, "pkg:golang/github.com/example/foo@"+tt.version, entry.ID)
+			require.Equal(t, report.StatusSucceeded, entry.Status)
+			assert.Equal(t, tt.wantCommit, entry.Commit)
+
+			dir := filepath.Join(pkgDir, tt.wantDir)
+			doc, err := openvex.Open(filepath.Join(dir, "openvex.json"))
+			require.NoError(t, err)
+			require.Len(t, doc.Statements, 1)
+			assert.Equal(t, openvex.VulnerabilityID(tt.wantVuln), doc.Statements[0].Vulnerability.Name)
+
+			m, err := manifest.Read(filepath.Join(dir, manifest.FileName))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, m.ID)
+		})
+	}
+	assert.NoDirExists(t, filepath.Join(pkgDir, "2.0.0"))
+}
+
+func TestPackages_Since(t *testing.T) {
+	server := newServerAt(t, time.Now().Add(-30*24*time.Hour),
+		"pkg:golang/github.com/example/foo", "pkg:golang/github.com/example/bar")
+	var pkgs []config.Package
+	for _, name := range []string{"foo", "bar"} {
+		pkgs = append(pkgs, config.Package{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: name},
+			URL:  server.URL + "/testrepo.git",
+		})
+	}
+	vexHubDir := t.TempDir()
+	_, err := crawl.Packages(context.Background(), crawl.Options{VEXHubDir: vexHubDir, Packages: pkgs[:1]})
+	require.NoError(t, err)
+
+	// The package never crawled is crawled regardless of the activity
+	r, err := crawl.Packages(context.Background(), crawl.Options{
+		VEXHubDir: vexHubDir,
+		Packages:  pkgs,
+		Since:     7 * 24 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]report.Status{
+		"pkg:golang/github.com/example/foo": report.StatusSkipped,
+		"pkg:golang/github.com/example/bar": report.StatusSucceeded,
+	}, statuses(r))
+	assert.NotEmpty(t, r.Packages[0].Commit)
+
+	r, err = crawl.Packages(context.Background(), crawl.Options{
+		VEXHubDir: vexHubDir,
+		Packages:  pkgs,
+		Since:     90 * 24 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]report.Status{
+		"pkg:golang/github.com/example/foo": report.StatusSucceeded,
+		"pkg:golang/github.com/example/bar": report.StatusSucceeded,
+	}, statuses(r))
+}
+
+// recordingHook records the packages of the "hook" namespace it's called for, failing for the ones named "fail".
+type recordingHook struct {
+	name  string
+	mu    *sync.Mutex
+	calls *[]string
+}
+
+func (h recordingHook) Name() string { return h.name }
+
+func (h recordingHook) PostCrawl(_ context.Context, event crawl.PostCrawlEvent) error {
+	if event.Package.PURL.Namespace != "github.com/hook" {
+		return nil // Registered for the whole test binary
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if event.Manifest == nil || event.Manifest.ID != event.Package.ID() || event.Result.AcceptedFiles != 1 {
+		return fmt.Errorf("unexpected event: %+v", event)
+	}
+	*h.calls = append(*h.calls, h.name+" "+event.Package.PURL.Name)
+	if event.Package.PURL.Name == "fail" {
+		return fmt.Errorf("hook error")
+	}
+	return nil
+}
+
+func TestPackages_PostCrawlHook(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	crawl.RegisterPostCrawlHook(recordingHook{name: "first", mu: &mu, calls: &calls})
+	crawl.RegisterPostCrawlHook(recordingHook{name: "second", mu: &mu, calls: &calls})
+
+	server := newServer(t, "pkg:golang/github.com/hook/ok", "pkg:golang/github.com/hook/fail")
+	var pkgs []config.Package
+	for _, name := range []string{"ok", "fail"} {
+		pkgs = append(pkgs, config.Package{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/hook", Name: name},
+			URL:  server.URL + "/testrepo.git",
+		})
+	}
+
+	tests := []struct {
+		name        string
+		failOnError bool
+		want        map[string]report.Status
+	}{
+		{
+			name: "logged",
+			want: map[string]report.Status{
+				"pkg:golang/github.com/hook/ok":   report.StatusSucceeded,
+				"pkg:golang/github.com/hook/fail": report.StatusSucceeded,
+			},
+		},
+		{
+			name:        "fail on error",
+			failOnError: true,
+			want: map[string]report.Status{
+				"pkg:golang/github.com/hook/ok":   report.StatusSucceeded,
+				"pkg:golang/github.com/hook/fail": report.StatusFailed,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			r, err := crawl.Packages(context.Background(), crawl.Options{
+				VEXHubDir:       t.TempDir(),
+				Packages:        pkgs,
+				Concurrency:     config.Concurrency{Downloads: 1, Parsers: 1},
+				FailOnHookError: tt.failOnError,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, statuses(r))
+
+			// The hooks run in registration order, and the first error stops the others if it fails the package
+			want := []string{"first ok", "second ok", "first fail"}
+			if !tt.failOnError {
+				want = append(want, "second fail")
+			}
+			assert.Equal(t, want, calls)
+		})
+	}
+}
+
+func TestPackages_Hubs(t *testing.T) {
+	server := newServer(t, "pkg:golang/github.com/example/shared", "pkg:golang/github.com/example/other")
+	pkg := func(name string) config.Package {
+		return config.Package{
+			PURL: packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: name},
+			URL:  server.URL + "/testrepo.git",
+		}
+	}
+	hubs := []config.Hub{
+		{Name: "public", Dir: t.TempDir(), Packages: []config.Package{pkg("shared")}},
+		{Name: "internal", Dir: t.TempDir(), Packages: []config.Package{pkg("shared"), pkg("other")}},
+	}
+
+	r, err := crawl.Packages(context.Background(), crawl.Options{
+		Hubs:        hubs,
+		Concurrency: config.Concurrency{Downloads: 2, Parsers: 2},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, r.Packages)
+	require.Len(t, r.Hubs, 2)
+
+	// The results are grouped by hub in the config order, and a package shared by the hubs is written into each of them
+	for i, h := range hubs {
+		assert.Equal(t, h.Name, r.Hubs[i].Name)
+		assert.Equal(t, h.Dir, r.Hubs[i].Dir)
+		got := statuses(report.Report{Packages: r.Hubs[i].Packages})
+		require.Len(t, got, len(h.Packages))
+		for _, p := range h.Packages {
+			assert.Equal(t, report.StatusSucceeded, got[p.ID()])
+			assert.FileExists(t, filepath.Join(h.Dir, "pkg", "golang", "github.com", "example", p.PURL.Name, "openvex.json"))
+		}
+	}
+	assert.NoFileExists(t, filepath.Join(hubs[0].Dir, "pkg", "golang", "github.com", "example", "other", "openvex.json"))
+}
@@ -0,0 +1,71 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/github"
+)
+
+// Expander expands a wildcard package into concrete packages, e.g. the repositories of an organization.
+type Expander interface {
+	Expand(ctx context.Context, pkg config.Package, limit int) ([]config.Package, error)
+}
+
+// newExpander returns the expander of the wildcard package, or nil if its type or host isn't supported.
+func newExpander(pkg config.Package) Expander {
+	if pkg.PURL.Type == packageurl.TypeGolang && strings.HasPrefix(pkg.PURL.Namespace, "github.com/") {
+		return github.NewExpander()
+	}
+	return nil
+}
+
+// Expand replaces the wildcard packages, such as "pkg:golang/github.com/example/*", with up to limit packages each,
+// listed from the host. The packages listed explicitly as well keep their own settings.
+// The wildcard packages failing to expand are dropped, and the errors are returned along with the other packages.
+func Expand(ctx context.Context, pkgs []config.Package, limit int) ([]config.Package, error) {
+	explicit := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if !pkg.Wildcard() {
+			explicit[pkg.ID()] = true
+		}
+	}
+
+	var expanded []config.Package
+	var errs []error
+	for _, pkg := range pkgs {
+		if !pkg.Wildcard() {
+			expanded = append(expanded, pkg)
+			continue
+		}
+		errBuilder := oops.Code("expand_package").With("purl", pkg.ID())
+		expander := newExpander(pkg)
+		if expander == nil {
+			errs = append(errs, errBuilder.Errorf("wildcard is not supported for the type or the host"))
+			continue
+		}
+		concrete, err := expander.Expand(ctx, pkg, limit)
+		if err != nil {
+			errs = append(errs, errBuilder.Wrapf(err, "failed to expand"))
+			continue
+		}
+		slog.Info("Expanded wildcard package", slog.String("purl", pkg.ID()), slog.Int("packages", len(concrete)))
+		if len(concrete) >= limit {
+			slog.Warn("Wildcard package reached the expansion limit", slog.String("purl", pkg.ID()),
+				slog.Int("limit", limit))
+		}
+		for _, p := range concrete {
+			if !explicit[p.ID()] {
+				explicit[p.ID()] = true // Listed once even if multiple wildcards cover it
+				expanded = append(expanded, p)
+			}
+		}
+	}
+	return expanded, errors.Join(errs...)
+}
@@ -0,0 +1,188 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+const githubAPI = "https://api.github.com"
+
+// maxRateLimitWait is how long the expansion waits for the rate limit to reset before giving up.
+const maxRateLimitWait = time.Minute
+
+// Repository is a repository in the listing of an organization or a user.
+type Repository struct {
+	Name     string `json:"name"`
+	HTMLURL  string `json:"html_url"`
+	Archived bool   `json:"archived"`
+	Fork     bool   `json:"fork"`
+}
+
+// Expander expands the wildcard Go packages of a GitHub organization, such as "pkg:golang/github.com/example/*",
+// into the packages of its repositories.
+type Expander struct {
+	url string
+}
+
+type Option func(*Expander)
+
+func WithURL(url string) Option {
+	return func(e *Expander) {
+		e.url = url
+	}
+}
+
+func NewExpander(opts ...Option) *Expander {
+	expander := &Expander{
+		url: githubAPI,
+	}
+	for _, opt := range opts {
+		opt(expander)
+	}
+	return expander
+}
+
+// Expand lists up to limit repositories of the organization, or the user, in the namespace of the wildcard package,
+// and returns a package per repository, inheriting the settings of the wildcard package.
+// Archived repositories and forks are skipped since they don't publish VEX documents of their own.
+func (e *Expander) Expand(ctx context.Context, pkg config.Package, limit int) ([]config.Package, error) {
+	errBuilder := oops.Code("expand_error").In("github").With("purl", pkg.PURL.String())
+	host, owner, ok := strings.Cut(pkg.PURL.Namespace, "/")
+	if !ok || host != "github.com" || owner == "" || strings.Contains(owner, "/") {
+		return nil, errBuilder.Errorf("namespace must be a GitHub organization, e.g. github.com/example")
+	}
+
+	repos, err := e.list(ctx, "orgs", owner, limit)
+	if errors.Is(err, errNotFound) {
+		repos, err = e.list(ctx, "users", owner, limit) // Not an organization
+	}
+	if err != nil {
+		return nil, errBuilder.With("owner", owner).Wrap(err)
+	}
+
+	var pkgs []config.Package
+	for _, repo := range repos {
+		p := pkg
+		p.PURL = packageurl.PackageURL{
+			Type:      pkg.PURL.Type,
+			Namespace: pkg.PURL.Namespace,
+			Name:      repo.Name,
+		}
+		p.URL = repo.HTMLURL
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// errNotFound is returned when the owner doesn't exist as the kind of account listed.
+var errNotFound = errors.New("not found")
+
+// list lists up to limit active repositories of the owner, following the pagination.
+func (e *Expander) list(ctx context.Context, kind, owner string, limit int) ([]Repository, error) {
+	next, err := url.JoinPath(e.url, kind, owner, "repos")
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to build the URL")
+	}
+	next += "?per_page=100"
+
+	var repos []Repository
+	for next != "" && len(repos) < limit {
+		var page []Repository
+		if next, err = e.get(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		for _, repo := range page {
+			if repo.Archived || repo.Fork || len(repos) >= limit {
+				continue
+			}
+			repos = append(repos, repo)
+		}
+	}
+	return repos, nil
+}
+
+// get decodes the page into v and returns the URL of the next page, if any.
+// It waits for the rate limit to reset if it's soon enough.
+func (e *Expander) get(ctx context.Context, pageURL string, v any) (string, error) {
+	errBuilder := oops.With("url", pageURL)
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return "", errBuilder.Wrapf(err, "failed to create the request")
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		resp, err := download.Client().Do(req)
+		if err != nil {
+			return "", errBuilder.Wrapf(err, "request error")
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			defer resp.Body.Close()
+			if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+				return "", errBuilder.Wrapf(err, "failed to decode response")
+			}
+			return nextPage(resp.Header.Get("Link")), nil
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return "", errBuilder.Wrap(errNotFound)
+		}
+
+		resp.Body.Close()
+		wait, limited := rateLimitWait(resp, time.Now())
+		if !limited {
+			return "", errBuilder.Errorf("failed to list repositories: %s", resp.Status)
+		} else if wait > maxRateLimitWait {
+			return "", errBuilder.With("wait", wait).Errorf("rate limited")
+		}
+		select {
+		case <-ctx.Done():
+			return "", errBuilder.Wrap(ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// rateLimitWait returns how long to wait before retrying, if the response is rate-limited.
+// cf. https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api#exceeding-the-rate-limit
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+	return 0, resp.StatusCode == http.StatusTooManyRequests
+}
+
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPage returns the URL of the next page in the Link header, or an empty string if it's the last page.
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		if m := linkNext.FindStringSubmatch(part); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/github"
+)
+
+func TestExpander_Expand(t *testing.T) {
+	var rateLimited bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/example/repos", func(w http.ResponseWriter, r *http.Request) {
+		if !rateLimited {
+			rateLimited = true
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		repos := []github.Repository{
+			{Name: "foo", HTMLURL: "https://github.com/example/foo"},
+			{Name: "archived", HTMLURL: "https://github.com/example/archived", Archived: true},
+			{Name: "fork", HTMLURL: "https://github.com/example/fork", Fork: true},
+		}
+		if r.URL.Query().Get("page") == "2" {
+			repos = []github.Repository{
+				{Name: "bar", HTMLURL: "https://github.com/example/bar"},
+				{Name: "baz", HTMLURL: "https://github.com/example/baz"},
+			}
+		} else {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/orgs/example/repos?per_page=100&page=2>; rel="next"`, r.Host))
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(repos))
+	})
+	mux.HandleFunc("/users/someone/repos", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewEncoder(w).Encode([]github.Repository{
+			{Name: "tool", HTMLURL: "https://github.com/someone/tool"},
+		}))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		namespace string
+		limit     int
+		want      map[string]string
+		wantErr   string
+	}{
+		{
+			name:      "organization",
+			namespace: "github.com/example",
+			limit:     10,
+			want: map[string]string{
+				"pkg:golang/github.com/example/foo": "https://github.com/example/foo",
+				"pkg:golang/github.com/example/bar": "https://github.com/example/bar",
+				"pkg:golang/github.com/example/baz": "https://github.com/example/baz",
+			},
+		},
+		{
+			name:      "limit",
+			namespace: "github.com/example",
+			limit:     2,
+			want: map[string]string{
+				"pkg:golang/github.com/example/foo": "https://github.com/example/foo",
+				"pkg:golang/github.com/example/bar": "https://github.com/example/bar",
+			},
+		},
+		{
+			name:      "user",
+			namespace: "github.com/someone",
+			limit:     10,
+			want: map[string]string{
+				"pkg:golang/github.com/someone/tool": "https://github.com/someone/tool",
+			},
+		},
+		{
+			name:      "unknown owner",
+			namespace: "github.com/unknown",
+			limit:     10,
+			wantErr:   "not found",
+		},
+		{
+			name:      "not GitHub",
+			namespace: "gitlab.com/example",
+			limit:     10,
+			wantErr:   "namespace must be a GitHub organization",
+		},
+	}
+
+	expander := github.NewExpander(github.WithURL(server.URL))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := config.Package{
+				PURL:    packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: tt.namespace, Name: config.Wildcard},
+				Trusted: true,
+			}
+			pkgs, err := expander.Expand(context.Background(), pkg, tt.limit)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			got := make(map[string]string)
+			for _, p := range pkgs {
+				got[p.ID()] = p.URL
+				assert.True(t, p.Trusted, "the settings should be inherited")
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
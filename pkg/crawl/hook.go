@@ -0,0 +1,85 @@
+package crawl
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"sync"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/trace"
+)
+
+// PostCrawlHook is called after each package is crawled into VEX Hub, so that custom pipelines can
+// trigger notifications, push the documents to external stores or run custom validation without forking.
+type PostCrawlHook interface {
+	// Name identifies the hook in logs and errors.
+	Name() string
+
+	// PostCrawl is called once the VEX documents of the default branch are written into VEX Hub.
+	// It's not called for packages failing to crawl or skipped, and it may be called concurrently
+	// for different packages.
+	PostCrawl(ctx context.Context, event PostCrawlEvent) error
+}
+
+// PostCrawlEvent describes the package crawled.
+type PostCrawlEvent struct {
+	Package  config.Package
+	Dir      string             // Directory of the package in VEX Hub
+	Manifest *manifest.Manifest // Manifest of the package, or nil if it couldn't be read
+	Result   *vex.Result
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []PostCrawlHook
+)
+
+// RegisterPostCrawlHook registers the hook for Packages. Hooks run in registration order,
+// and their errors are logged unless Options.FailOnHookError is set.
+func RegisterPostCrawlHook(h PostCrawlHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, h)
+}
+
+func postCrawlHooks() []PostCrawlHook {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return hooks
+}
+
+// runHooks calls the registered hooks in order with the crawled package.
+// With failOnError, the first error stops the remaining hooks and is returned.
+func runHooks(ctx context.Context, opts Options, pkg config.Package, result *vex.Result) error {
+	hs := postCrawlHooks()
+	if len(hs) == 0 {
+		return nil
+	}
+
+	logger := trace.Logger(ctx).With(slog.String("purl", pkg.ID()))
+	event := PostCrawlEvent{Package: pkg, Result: result}
+	if dir, err := validateTarget(opts.VEXHubDir, pkg); err == nil {
+		event.Dir = dir
+		m, err := manifest.Read(vex.LongPath(filepath.Join(dir, cmp.Or(opts.ManifestName, manifest.FileName))))
+		if err != nil {
+			logger.Warn("Failed to read the manifest for the post-crawl hooks", slog.Any("error", err))
+		} else {
+			event.Manifest = &m
+		}
+	}
+
+	for _, h := range hs {
+		err := h.PostCrawl(ctx, event)
+		if err == nil {
+			continue
+		} else if opts.FailOnHookError {
+			return trace.Errors(ctx).With("hook", h.Name()).Wrapf(err, "post-crawl hook failed")
+		}
+		logger.Warn("Post-crawl hook failed", slog.String("hook", h.Name()), slog.Any("error", err))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package crawl
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+)
+
+// hostLimiter limits the sources downloaded at the same time from each host, within the downloaders of the pipeline,
+// see config.Concurrency.PerHost. The downloaders don't wait for a saturated host, they requeue its packages
+// and take others meanwhile, see tryAcquire.
+type hostLimiter struct {
+	perHost int
+	hosts   map[string]int // Limits by lowercase host name
+
+	mu       sync.Mutex
+	sems     map[string]chan struct{}
+	released chan struct{} // Closed and replaced whenever a slot is released, see changed
+}
+
+func newHostLimiter(c config.Concurrency) *hostLimiter {
+	hosts := make(map[string]int)
+	for host, n := range c.Hosts {
+		hosts[strings.ToLower(host)] = n
+	}
+	return &hostLimiter{
+		perHost:  c.PerHost,
+		hosts:    hosts,
+		sems:     make(map[string]chan struct{}),
+		released: make(chan struct{}),
+	}
+}
+
+// acquire waits until a source can be downloaded from the host, and returns the function releasing it.
+// The host is the name without the port, so that the services of a host share its limit.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	sem := l.sem(host)
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return l.release(sem), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// tryAcquire is like acquire, but reports false rather than waiting if the host is saturated.
+func (l *hostLimiter) tryAcquire(host string) (func(), bool) {
+	sem := l.sem(host)
+	if sem == nil {
+		return func() {}, true
+	}
+	select {
+	case sem <- struct{}{}:
+		return l.release(sem), true
+	default:
+		return nil, false
+	}
+}
+
+// changed returns the channel closed when a slot of any host is released, so that the requeued packages are retried.
+func (l *hostLimiter) changed() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.released
+}
+
+// sem returns the semaphore of the host, or nil if the host is not limited.
+func (l *hostLimiter) sem(host string) chan struct{} {
+	host = strings.ToLower(host)
+	limit, ok := l.hosts[host]
+	if !ok {
+		limit = l.perHost
+	}
+	if host == "" || limit <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem, ok := l.sems[host]
+	if !ok {
+		sem = make(chan struct{}, limit)
+		l.sems[host] = sem
+	}
+	return sem
+}
+
+// release returns the function releasing a slot of the semaphore, which wakes up the waiters of changed.
+func (l *hostLimiter) release(sem chan struct{}) func() {
+	return func() {
+		<-sem
+		l.mu.Lock()
+		close(l.released)
+		l.released = make(chan struct{})
+		l.mu.Unlock()
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/samber/oops"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
 	"github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
@@ -115,7 +116,7 @@ func (c *Crawler) fetchLatestVersion(baseURL *url.URL) (string, error) {
 
 	errBuilder := oops.Code("fetch_latest_version_error").With("metadata url", metaURL.String())
 
-	resp, err := http.Get(metaURL.String())
+	resp, err := download.Client().Get(metaURL.String())
 	if err != nil {
 		return "", errBuilder.Wrapf(err, "failed to get artifact metadata")
 	}
@@ -141,7 +142,7 @@ func (c *Crawler) fetchPOM(baseURL *url.URL, name, latest string) (*POM, error)
 	pomURL.Path = path.Join(pomURL.Path, latest, fmt.Sprintf("%s-%s.pom", name, latest))
 
 	errBuilder := oops.Code("fetch_pom_error").With("pom url", pomURL.String())
-	resp, err := http.Get(pomURL.String())
+	resp, err := download.Client().Get(pomURL.String())
 	if err != nil {
 		return nil, errBuilder.Wrapf(err, "failed to get package info")
 	}
@@ -9,6 +9,7 @@ import (
 	"github.com/samber/oops"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
 	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
@@ -51,7 +52,7 @@ func (c *Crawler) DetectSrc(_ context.Context, pkg config.Package) (*xurl.URL, e
 	}
 
 	errBuilder = errBuilder.With("url", npmURL)
-	resp, err := http.Get(npmURL)
+	resp, err := download.Client().Get(npmURL)
 	if err != nil {
 		return nil, errBuilder.Wrapf(err, "failed to get package info")
 	}
@@ -0,0 +1,401 @@
+package crawl
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/report"
+	"github.com/aquasecurity/vexhub-crawler/pkg/trace"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// hubPackage is a package to crawl into the hub, or into Options.VEXHubDir if the hub is nil.
+type hubPackage struct {
+	pkg config.Package
+	hub *config.Hub
+}
+
+// job is a package to crawl, numbered in the order of the config.
+type job struct {
+	hubPackage
+	index     int
+	requestID string // Shared by the log lines and errors of the package, see trace.WithID
+
+	// The source detected before the job was requeued because its host was saturated, and the function releasing
+	// the slot of the host acquired when it was taken again.
+	detected *url.URL
+	release  func()
+
+	pinned bool // Whether the source is pinned to the version of the PURL, see pinVersion
+}
+
+// context returns the context carrying the request ID of the job.
+func (j job) context(ctx context.Context) context.Context {
+	return trace.WithID(ctx, j.requestID)
+}
+
+// options returns the options crawling into the hub of the job.
+func (j job) options(opts Options) Options {
+	if j.hub != nil {
+		opts.VEXHubDir = j.hub.Dir
+	}
+	return opts
+}
+
+// vexOptions returns the options collecting the package, under the versioned directory only if the source is
+// pinned to the version. Otherwise, the default branch would be mislabeled as the release.
+func (j job) vexOptions(opts Options) vex.Options {
+	vopts := vexOptions(opts, j.pkg)
+	if !j.pinned {
+		vopts.Version = ""
+	}
+	return vopts
+}
+
+// stateKey returns the key of the package in the state, qualified by the hub since hubs may share packages.
+func (j job) stateKey() string {
+	if j.hub != nil {
+		return j.hub.Name + " " + j.pkg.ID()
+	}
+	return j.pkg.ID()
+}
+
+// fetched is a package whose source is downloaded and waits in the queue to be collected.
+// If the package is done at the download stage, e.g. it failed or is resumed, entries are set instead of the source.
+type fetched struct {
+	job
+	src     *url.URL
+	source  *vex.Source
+	entries []report.Package
+	err     error
+}
+
+// outcome is a package done with, along with its report entries.
+type outcome struct {
+	job
+	entries []report.Package
+	err     error
+}
+
+// pipeline crawls the packages in two stages connected by a bounded queue:
+// the sources are downloaded by up to Concurrency.Downloads workers, and collected into VEX Hub
+// by up to Concurrency.Parsers workers. The downloaders block while the queue is full,
+// so a slow parser doesn't let downloaded sources pile up on disk.
+type pipeline struct {
+	opts       Options
+	pkgs       []hubPackage
+	downloaded *download.Counter // Bytes downloaded so far, counted against MaxBytes as they arrive
+	hosts      *hostLimiter      // Downloads in flight by host
+
+	mu      sync.Mutex // Guards the fields below
+	cursor  int        // Index of the next package to download
+	blocked []job      // Packages requeued until their host has a free slot
+	stopped string     // Reason the crawl stopped early
+	st      *state
+}
+
+// run crawls the packages and returns the outcomes in the order of the config.
+// The reason is returned if the crawl stopped early, and the error is the first failure in strict mode.
+func (p *pipeline) run(ctx context.Context) ([]outcome, string, error) {
+	downloads, parsers := max(p.opts.Concurrency.Downloads, 1), max(p.opts.Concurrency.Parsers, 1)
+	queueSize := cmp.Or(max(p.opts.Concurrency.Queue, 0), downloads)
+
+	// Canceled on the first failure in strict mode, and once the downloads exceed MaxBytes
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p.downloaded = download.NewCounter(p.opts.MaxBytes, cancel)
+	workCtx = download.WithCounter(workCtx, p.downloaded)
+
+	queue := make(chan fetched, queueSize)
+	outcomes := make(chan outcome)
+
+	var downloaders sync.WaitGroup
+	for range downloads {
+		downloaders.Add(1)
+		go func() {
+			defer downloaders.Done()
+			for {
+				j, ok := p.next(ctx, workCtx)
+				if !ok {
+					return
+				}
+				if f, ok := p.fetch(workCtx, j); ok {
+					queue <- f
+				}
+			}
+		}()
+	}
+	go func() {
+		downloaders.Wait()
+		close(queue)
+	}()
+
+	var collectors sync.WaitGroup
+	for range parsers {
+		collectors.Add(1)
+		go func() {
+			defer collectors.Done()
+			for f := range queue {
+				outcomes <- p.collect(workCtx, f)
+			}
+		}()
+	}
+	go func() {
+		collectors.Wait()
+		close(outcomes)
+	}()
+
+	var done []outcome
+	var err error
+	for o := range outcomes {
+		if err != nil {
+			continue // Drain the pipeline after a failure in strict mode
+		}
+		done = append(done, o)
+		logger := trace.Logger(o.context(ctx)).With(slog.String("type", o.pkg.Type()), slog.String("purl", o.pkg.ID()))
+		if o.err != nil {
+			if p.opts.Strict {
+				err = oops.Wrapf(o.err, "strict")
+				cancel()
+				continue
+			}
+			logger.Warn(o.err.Error(), slog.Any("error", o.err))
+		} else if p.opts.StatePath != "" && o.entries[0].Status == report.StatusSucceeded {
+			p.mu.Lock()
+			p.st.Packages[o.stateKey()] = o.entries[0].Commit
+			serr := p.st.save(p.opts.StatePath)
+			p.mu.Unlock()
+			if serr != nil {
+				logger.Warn("Failed to save the state", slog.Any("error", serr))
+			}
+		}
+	}
+
+	// The deadline or the budget may be hit while the last packages are in flight, after the last call to next
+	if reason := exhausted(ctx, p.opts, p.downloaded.Load()); err == nil && p.stopped == "" && reason != "" {
+		slog.Warn("Stopped the crawl", slog.String("reason", reason))
+		p.stopped = reason
+	}
+
+	slices.SortFunc(done, func(a, b outcome) int {
+		return cmp.Compare(a.index, b.index)
+	})
+	return done, p.stopped, err
+}
+
+// next returns the next package to download, or false if none is left or the crawl has stopped.
+// The downloads in flight are canceled as soon as the budget is exceeded, see download.Counter.
+// If only the requeued packages are left, it waits until the host of any of them has a free slot.
+func (p *pipeline) next(ctx, workCtx context.Context) (job, bool) {
+	for {
+		changed := p.hosts.changed()
+		j, ok, wait := p.take(ctx, workCtx)
+		if !wait {
+			return j, ok
+		}
+		select {
+		case <-changed:
+		case <-workCtx.Done():
+		}
+	}
+}
+
+// take returns the next package to download, the requeued ones first. It reports whether the requeued
+// packages are left while their hosts are saturated, in which case the caller waits for a slot.
+func (p *pipeline) take(ctx, workCtx context.Context) (j job, ok, wait bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.stopped == "" {
+		if reason := exhausted(ctx, p.opts, p.downloaded.Load()); reason != "" {
+			slog.Warn("Stopping the crawl", slog.String("reason", reason))
+			p.stopped = reason
+			break
+		} else if workCtx.Err() != nil {
+			return job{}, false, false // Failed in strict mode
+		}
+
+		for i, b := range p.blocked {
+			if release, ok := p.hosts.tryAcquire(b.detected.Hostname()); ok {
+				p.blocked = slices.Delete(p.blocked, i, i+1)
+				b.release = release
+				return b, true, false
+			}
+		}
+		if p.cursor == len(p.pkgs) {
+			return job{}, false, len(p.blocked) > 0
+		}
+
+		j := job{hubPackage: p.pkgs[p.cursor], index: p.cursor, requestID: trace.NewID()}
+		p.cursor++
+		if !p.opts.Types.Allows(j.pkg.Type()) {
+			slog.Info("Skipping package of filtered type", slog.String("type", j.pkg.Type()),
+				slog.String("purl", j.pkg.ID()))
+			continue
+		}
+		return j, true, false
+	}
+	return job{}, false, false
+}
+
+// requeue puts the package back until its host has a free slot, so that the downloader takes another one meanwhile.
+func (p *pipeline) requeue(j job, src *url.URL) {
+	trace.Logger(j.context(context.Background())).Debug("Requeuing package of a saturated host",
+		slog.String("purl", j.pkg.ID()), slog.String("host", src.Hostname()))
+	j.detected = src
+	p.mu.Lock()
+	p.blocked = append(p.blocked, j)
+	p.mu.Unlock()
+}
+
+// fetch detects and downloads the source repository of the package. It reports false if the package is requeued
+// because its host is saturated. The remote HEAD and the last commit are looked up within the slot of the host
+// as well, since they hit the same host as the download.
+func (p *pipeline) fetch(ctx context.Context, j job) (fetched, bool) {
+	ctx = j.context(ctx)
+	pkg := j.pkg
+	errBuilder := trace.Errors(ctx).Code("crawl_package").With("type", pkg.Type()).With("purl", pkg.ID())
+
+	src := j.detected
+	if src == nil {
+		trace.Logger(ctx).Info("Crawling package...", slog.String("type", pkg.Type()), slog.String("purl", pkg.ID()))
+		var err error
+		if src, err = detectSrc(ctx, pkg); err != nil {
+			return fetched{job: j, entries: []report.Package{packageReport(pkg.ID(), nil, err)}, err: err}, true
+		}
+		j.pinned = pinVersion(ctx, pkg, src)
+	}
+	f := fetched{job: j, src: src}
+
+	release := j.release
+	if release == nil {
+		var ok bool
+		if release, ok = p.hosts.tryAcquire(src.Hostname()); !ok {
+			p.requeue(j, src)
+			return f, false
+		}
+	}
+	defer release()
+
+	opts := j.options(p.opts)
+	if entry, ok := p.resumed(ctx, j, src); ok {
+		f.entries = []report.Package{entry}
+		return f, true
+	} else if entry, ok = inactive(ctx, opts, pkg, src); ok {
+		f.entries = []report.Package{entry}
+		return f, true
+	}
+
+	source, err := fetchSource(ctx, src, pkg, j.vexOptions(opts))
+	if err != nil {
+		err = errBuilder.Wrapf(err, "failed to crawl package")
+		f.entries, f.err = []report.Package{packageReport(pkg.ID(), source.Result(), err)}, err
+		return f, true
+	}
+	f.source = source
+	return f, true
+}
+
+// resumed returns the skipped entry if the package was crawled in the interrupted run at the current remote HEAD.
+func (p *pipeline) resumed(ctx context.Context, j job, src *url.URL) (report.Package, bool) {
+	pkg := j.pkg
+	p.mu.Lock()
+	commit, ok := p.st.Packages[j.stateKey()]
+	p.mu.Unlock()
+	if !ok || commit == "" {
+		return report.Package{}, false
+	}
+
+	logger := trace.Logger(ctx)
+	if head, err := vex.RemoteHead(ctx, src); err != nil {
+		logger.Warn("Failed to get the remote HEAD, crawling again", slog.String("purl", pkg.ID()),
+			slog.Any("error", err))
+		return report.Package{}, false
+	} else if head != commit {
+		return report.Package{}, false
+	}
+	logger.Info("Already crawled in the interrupted run", slog.String("purl", pkg.ID()),
+		slog.String("commit", commit))
+	return report.Package{
+		ID:     pkg.ID(),
+		Status: report.StatusSkipped,
+		Result: &vex.Result{Commit: commit},
+	}, true
+}
+
+// inactive returns the skipped entry if the package is already in VEX Hub and its source has had no commit
+// within Since. The package is crawled if the last commit can't be determined.
+func inactive(ctx context.Context, opts Options, pkg config.Package, src *url.URL) (report.Package, bool) {
+	if opts.Since <= 0 {
+		return report.Package{}, false
+	}
+	dir, err := validateTarget(opts.VEXHubDir, pkg)
+	if err != nil {
+		return report.Package{}, false
+	} else if _, err = os.Stat(vex.LongPath(filepath.Join(dir, cmp.Or(opts.ManifestName, manifest.FileName)))); err != nil {
+		return report.Package{}, false // Never crawled
+	}
+
+	logger := trace.Logger(ctx).With(slog.String("purl", pkg.ID()))
+	commit, when, err := vex.LastCommit(ctx, src)
+	if err != nil {
+		logger.Debug("Failed to get the last commit, crawling", slog.Any("error", err))
+		return report.Package{}, false
+	} else if time.Since(when) <= opts.Since {
+		return report.Package{}, false
+	}
+	logger.Info("Skipping package without recent activity", slog.String("commit", commit),
+		slog.Time("committed_at", when))
+	return report.Package{
+		ID:     pkg.ID(),
+		Status: report.StatusSkipped,
+		Result: &vex.Result{Commit: commit},
+	}, true
+}
+
+// collect copies the VEX documents from the downloaded source into VEX Hub, and crawls the tags if enabled.
+// The error is returned only if the default branch fails.
+func (p *pipeline) collect(ctx context.Context, f fetched) outcome {
+	o := outcome{job: f.job, entries: f.entries, err: f.err}
+	if f.source == nil {
+		return o
+	}
+	defer f.source.Close()
+
+	ctx = f.context(ctx)
+	pkg := f.pkg
+	errBuilder := trace.Errors(ctx).Code("crawl_package").With("type", pkg.Type()).With("purl", pkg.ID())
+	if err := ctx.Err(); err != nil {
+		o.err = errBuilder.Wrapf(err, "failed to crawl package")
+		o.entries = []report.Package{packageReport(pkg.ID(), nil, o.err)}
+		return o
+	}
+
+	opts := f.options(p.opts)
+	result, err := collectSource(ctx, f.source, opts.VEXHubDir, f.src, pkg, f.vexOptions(opts))
+	if err == nil {
+		err = runHooks(ctx, opts, pkg, result)
+	}
+	if err != nil {
+		o.err = errBuilder.Wrapf(err, "failed to crawl package")
+		o.entries = []report.Package{packageReport(pkg.ID(), result, o.err)}
+		return o
+	}
+	o.entries = []report.Package{packageReport(pkg.ID(), result, nil)}
+
+	if opts.MaxTags > 0 {
+		o.entries = append(o.entries, crawlTags(ctx, opts, pkg, f.src, p.hosts)...)
+	}
+	return o
+}
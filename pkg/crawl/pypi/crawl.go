@@ -9,6 +9,7 @@ import (
 	"github.com/samber/oops"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
 	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
@@ -55,7 +56,7 @@ func (c *Crawler) DetectSrc(_ context.Context, pkg config.Package) (*xurl.URL, e
 	}
 
 	errBuilder = errBuilder.With("url", pypiURL)
-	resp, err := http.Get(pypiURL)
+	resp, err := download.Client().Get(pypiURL)
 	if err != nil {
 		return nil, errBuilder.Wrapf(err, "failed to get package info")
 	}
@@ -0,0 +1,59 @@
+package crawl
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/samber/oops"
+)
+
+// state records the packages crawled successfully in the current run
+// so that an interrupted run can be resumed without redoing them.
+type state struct {
+	Packages map[string]string `json:"packages"` // PURL to the commit hash crawled
+}
+
+// loadState loads the state file. An empty state is returned if the file doesn't exist.
+func loadState(filePath string) (*state, error) {
+	s := &state{Packages: make(map[string]string)}
+	b, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, oops.With("filePath", filePath).Wrapf(err, "failed to read the state file")
+	}
+	if err = json.Unmarshal(b, s); err != nil {
+		return nil, oops.With("filePath", filePath).Wrapf(err, "failed to decode the state file")
+	}
+	if s.Packages == nil {
+		s.Packages = make(map[string]string)
+	}
+	return s, nil
+}
+
+// save writes the state to the file. It's called after each package so that the state survives crashes.
+func (s *state) save(filePath string) error {
+	b, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return oops.Wrapf(err, "JSON encode error")
+	}
+
+	// Write to a temporary file and rename it so that the state file is never truncated
+	tmp := filePath + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return oops.With("filePath", tmp).Wrapf(err, "failed to write the state file")
+	}
+	if err = os.Rename(tmp, filePath); err != nil {
+		return oops.With("filePath", filePath).Wrapf(err, "failed to rename the state file")
+	}
+	return nil
+}
+
+// removeState removes the state file after a complete run.
+func removeState(filePath string) error {
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return oops.With("filePath", filePath).Wrapf(err, "failed to remove the state file")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package crawl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// TargetError is a problem with a configured package found before crawling.
+type TargetError struct {
+	PURL string // CPE if the package is tracked by CPE
+	Err  error
+}
+
+func (e TargetError) Error() string {
+	return fmt.Sprintf("%s: %s", e.PURL, e.Err)
+}
+
+func (e TargetError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the packages without any network access, so that no download happens with a broken config.
+// The PURLs are canonicalized and validated as well as the CPEs, the URLs must resolve to a supported source,
+// and no two packages may be stored in the same directory of VEX Hub.
+// All the problems are returned rather than the first one so that the config can be fixed in one pass.
+func Validate(vexHubDir string, pkgs []config.Package) []TargetError {
+	var errs []TargetError
+	dirs := make(map[string]string) // Directory in VEX Hub to the first package stored there
+	for _, pkg := range pkgs {
+		id := pkg.ID()
+		dir, err := validateTarget(vexHubDir, pkg)
+		if err != nil {
+			errs = append(errs, TargetError{PURL: id, Err: err})
+			continue
+		}
+		if pkg.URL != "" {
+			if err := validateURL(pkg.URL); err != nil {
+				errs = append(errs, TargetError{PURL: id, Err: err})
+			}
+		}
+
+		if other, ok := dirs[dir]; ok {
+			errs = append(errs, TargetError{
+				PURL: id,
+				Err:  oops.With("dir", dir).Errorf("stored in the same directory as %s", other),
+			})
+			continue
+		}
+		dirs[dir] = id
+	}
+	return errs
+}
+
+// validateTarget validates the PURL or the CPE of the package, and returns the directory in VEX Hub.
+func validateTarget(vexHubDir string, pkg config.Package) (string, error) {
+	if pkg.CPE == "" {
+		if err := validatePURL(pkg.PURL); err != nil {
+			return "", err
+		}
+		return vex.PackageDir(vexHubDir, pkg.PURL, pkg.PURL.Version), nil
+	}
+
+	cpe, err := vex.ParseCPE(pkg.CPE)
+	if err != nil {
+		return "", oops.Wrapf(err, "invalid CPE")
+	} else if cpe.Version != "" {
+		return "", oops.Errorf("version must be omitted")
+	} else if pkg.URL == "" {
+		return "", oops.Errorf("url is required for cpe")
+	}
+	return vex.CPEDir(vexHubDir, cpe, ""), nil
+}
+
+func validatePURL(purl packageurl.PackageURL) error {
+	if newCrawler(purl.Type) == nil {
+		return oops.Errorf("unsupported package type: %s", purl.Type)
+	} else if purl.Name == "" {
+		return oops.Errorf("name is required")
+	} else if purl.Type == packageurl.TypeOCI && purl.Qualifiers.Map()["repository_url"] == "" {
+		return oops.Errorf("repository_url qualifier is required for oci")
+	}
+
+	// The PURL must survive a round trip so that the manifest ID maps back to the package
+	purl.Subpath = vex.CanonicalSubpath(purl.Subpath)
+	if _, err := packageurl.FromString(purl.String()); err != nil {
+		return oops.Wrapf(err, "invalid PURL")
+	}
+	return nil
+}
+
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return oops.Wrapf(err, "invalid URL")
+	} else if u.IsFile() {
+		return nil // Downloaded without go-getter
+	}
+	if err = download.Supported(u.GetterString()); err != nil {
+		return oops.With("url", url.Redact(rawURL)).Wrapf(err, "unsupported URL")
+	}
+	return nil
+}
+
+// ValidateDir validates the local directory as if it were the downloaded source of the package, with the settings of
+// the package and the global ones like a crawl, and reports which files would be collected, see vex.ValidateDir.
+func ValidateDir(ctx context.Context, dir string, pkg config.Package, opts Options) (*vex.DirResult, error) {
+	vopts := vexOptions(opts, pkg)
+	if pkg.CPE == "" {
+		return vex.ValidateDir(ctx, dir, targetPURL(pkg), vopts)
+	}
+	cpe, err := vex.ParseCPE(pkg.CPE)
+	if err != nil {
+		return nil, oops.Wrapf(err, "invalid CPE")
+	}
+	return vex.ValidateDirCPE(ctx, dir, cpe, vopts)
+}
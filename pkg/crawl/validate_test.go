@@ -0,0 +1,88 @@
+package crawl_test
+
+import (
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl"
+)
+
+func TestValidate(t *testing.T) {
+	pkg := func(purl, url string) config.Package {
+		p, err := packageurl.FromString(purl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return config.Package{PURL: p, URL: url}
+	}
+	tests := []struct {
+		name string
+		pkgs []config.Package
+		want map[string]string // PURL or CPE to the error
+	}{
+		{
+			name: "valid",
+			pkgs: []config.Package{
+				pkg("pkg:npm/foo", ""),
+				pkg("pkg:golang/github.com/example/package", "https://github.com/example/package"),
+				pkg("pkg:golang/github.com/example/package#cmd/tool", ""),
+				pkg("pkg:golang/github.com/example/package@v1.2.3", ""),
+				pkg("pkg:pypi/bar", "https://example.com/vex/bar.openvex.json"),
+				pkg("pkg:cargo/baz", "/srv/mirror/baz.bundle"),
+				pkg("pkg:npm/qux", "s3://bucket/vex/qux?region=eu-west-1"),
+				pkg("pkg:npm/quux", "gs://bucket/vex/quux"),
+				pkg("pkg:oci/trivy?repository_url=ghcr.io/aquasecurity/trivy", ""),
+				pkg("pkg:oci/trivy?repository_url=index.docker.io/aquasec/trivy", ""),
+				{CPE: "cpe:2.3:a:example:product", URL: "https://github.com/example/product"},
+			},
+		},
+		{
+			name: "all problems are reported",
+			pkgs: []config.Package{
+				pkg("pkg:deb/debian/curl", ""),
+				pkg("pkg:oci/trivy", ""),
+				pkg("pkg:npm/bar", "ftp://example.com/bar"),
+				pkg("pkg:npm/baz", "https://example.com/%zz"),
+				pkg("pkg:npm/qux", "https://example.com/qux.tar.gz//../etc"),
+				pkg("pkg:golang/github.com/example/package#cmd", ""),
+				pkg("pkg:golang/github.com/example/package#./cmd/", ""),
+				{CPE: "cpe:2.3:a:example:product:1.0.0"},
+				{CPE: "cpe:2.3:a:example:other"},
+				{CPE: "cpe:/a:example:other", URL: "https://github.com/example/other"},
+				{CPE: "cpe:2.3:a:*:product", URL: "https://github.com/example/product"},
+			},
+			want: map[string]string{
+				"pkg:deb/debian/curl": "unsupported package type: deb",
+				"pkg:oci/trivy":       "repository_url qualifier is required for oci",
+				"pkg:npm/bar":         "unsupported URL",
+				"pkg:npm/baz":         "invalid URL",
+				"pkg:npm/qux":         "subdirectories escape the source",
+				"pkg:golang/github.com/example/package#./cmd": "stored in the same directory as pkg:golang/github.com/example/package#cmd",
+				"cpe:2.3:a:example:product:1.0.0":             "version must be omitted",
+				"cpe:2.3:a:example:other":                     "url is required for cpe",
+				"cpe:2.3:a:*:product":                         "invalid CPE",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := crawl.Validate("/vexhub", tt.pkgs)
+			got := make(map[string]string)
+			for _, e := range errs {
+				got[e.PURL] = e.Err.Error()
+			}
+			if tt.want == nil {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Len(t, got, len(tt.want))
+			for purl, want := range tt.want {
+				assert.Contains(t, got[purl], want, purl)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package vex
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// githubAPI is the base URL of the GitHub REST API.
+const githubAPI = "https://api.github.com"
+
+// LastCommit returns the hash and the time of the commit the remote repository would be crawled at,
+// so that sources without recent activity can be skipped before downloading them.
+// Repositories on GitHub are looked up through the API, which is cheaper than fetching,
+// and the others by fetching the commit alone into memory. Archives, bundles, single files and buckets have no known commit.
+func LastCommit(ctx context.Context, u *xurl.URL) (string, time.Time, error) {
+	errBuilder := oops.Code("last_commit_error").In("crawl").With("url", u.Redacted())
+	if u.IsArchive() || u.IsBundle() || u.IsFile() || u.IsBucket() {
+		return "", time.Time{}, errBuilder.Errorf("no git history")
+	}
+
+	if repo, ok := u.GitHubRepo(); ok {
+		hash, when, err := githubCommit(ctx, repo, u.Ref())
+		if err == nil {
+			return hash, when, nil
+		}
+		// e.g. rate-limited
+		slog.Debug("Failed to get the commit from the GitHub API, fetching it", slog.String("url", u.Redacted()),
+			slog.Any("err", err))
+	}
+
+	ref, hash, err := remoteRef(ctx, u)
+	if err != nil {
+		return "", time.Time{}, errBuilder.Wrap(err)
+	}
+	when, err := fetchCommitTime(ctx, u, ref, hash)
+	if err != nil {
+		return "", time.Time{}, errBuilder.With("commit", hash).Wrap(err)
+	}
+	return hash, when, nil
+}
+
+// githubCommit returns the hash and the committer time of the commit of the ref, or of the default branch if empty.
+func githubCommit(ctx context.Context, repo, ref string) (string, time.Time, error) {
+	apiURL := githubAPI + "/repos/" + repo + "/commits/" + cmp.Or(ref, "HEAD")
+	errBuilder := oops.With("api_url", apiURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return "", time.Time{}, errBuilder.Wrapf(err, "failed to create the request")
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := download.Client().Do(req)
+	if err != nil {
+		return "", time.Time{}, errBuilder.Wrapf(err, "request error")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, errBuilder.With("status", resp.Status).Errorf("unexpected status")
+	}
+
+	var commit struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&commit); err != nil {
+		return "", time.Time{}, errBuilder.Wrapf(err, "JSON decode error")
+	}
+	return commit.SHA, commit.Commit.Committer.Date, nil
+}
+
+// fetchCommitTime fetches the commit of the reference without its history into memory and returns the committer time.
+// The commit is fetched by hash if the reference is empty, which not all the servers allow.
+func fetchCommitTime(ctx context.Context, u *xurl.URL, ref plumbing.ReferenceName, hash string) (time.Time, error) {
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return time.Time{}, oops.Wrapf(err, "failed to initialize the repository")
+	}
+	remote, err := repo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{u.RepoString()},
+	})
+	if err != nil {
+		return time.Time{}, oops.Wrapf(err, "failed to create the remote")
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(cmp.Or(ref.String(), hash) + ":refs/heads/crawl")},
+		Depth:    1,
+		Tags:     git.NoTags,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return time.Time{}, oops.Wrapf(err, "failed to fetch the commit")
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return time.Time{}, oops.Wrapf(err, "failed to read the commit")
+	}
+	return commit.Committer.When, nil
+}
@@ -0,0 +1,82 @@
+package vex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// mediaTypeJSON is the media type of the original documents recorded for compressed files.
+// All the supported formats are JSON.
+const mediaTypeJSON = "application/json"
+
+// writeCompressed writes the content, or the file if the content is nil, gzip-compressed to the destination.
+// The gzip header has neither a name nor a timestamp so that the output is deterministic and doesn't cause churn.
+func writeCompressed(fsys FS, to, from string, content []byte) error {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	var err error
+	if content != nil {
+		_, err = gw.Write(content)
+	} else {
+		err = copyFile(fsys, gw, from)
+	}
+	if err != nil {
+		return oops.Wrapf(err, "failed to compress the file")
+	}
+	if err = gw.Close(); err != nil {
+		return oops.Wrapf(err, "gzip close error")
+	}
+	if err = fsys.WriteFile(to, buf.Bytes(), 0644); err != nil {
+		return oops.Wrapf(err, "failed to write the file")
+	}
+	return nil
+}
+
+func copyFile(fsys FS, w io.Writer, path string) error {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// decompressTemp decompresses the stored file of the source into a temporary file with the original name.
+// The returned function removes the temporary file.
+func decompressTemp(fsys FS, dir string, src manifest.Source) (string, func(), error) {
+	f, err := fsys.Open(filepath.Join(dir, src.Path))
+	if err != nil {
+		return "", nil, oops.Wrapf(err, "failed to open the file")
+	}
+	r, err := src.Decompress(f)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, oops.Wrapf(err, "failed to decompress the file")
+	}
+
+	tmpDir, err := fsys.MkdirTemp("", "vexhub-crawler-")
+	if err != nil {
+		return "", nil, oops.Wrapf(err, "failed to create a temporary directory")
+	}
+	cleanup := func() { fsys.RemoveAll(tmpDir) }
+
+	tmpPath := filepath.Join(tmpDir, strings.TrimSuffix(src.Path, ".gz"))
+	if err = fsys.WriteFile(tmpPath, data, 0644); err != nil {
+		cleanup()
+		return "", nil, oops.Wrapf(err, "failed to create a temporary file")
+	}
+	return tmpPath, cleanup, nil
+}
@@ -0,0 +1,68 @@
+package vex_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	openvex "github.com/openvex/go-vex/pkg/vex"
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+func TestCrawlPackage_Compress(t *testing.T) {
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	vexHubDir := t.TempDir()
+	opts := vex.Options{
+		Compress:     true,
+		VerifyWrites: true,
+	}
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, opts)
+	require.NoError(t, err)
+
+	pkgDir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package")
+	assert.NoFileExists(t, filepath.Join(pkgDir, "openvex.json"))
+	first, err := os.ReadFile(filepath.Join(pkgDir, "openvex.json.gz"))
+	require.NoError(t, err)
+
+	m, err := manifest.Read(filepath.Join(pkgDir, manifest.FileName))
+	require.NoError(t, err)
+	require.Len(t, m.Sources, 1)
+	src := m.Sources[0]
+	assert.Equal(t, "openvex.json.gz", src.Path)
+	assert.Equal(t, manifest.CompressionGzip, src.Compression)
+	assert.Equal(t, "application/json", src.MediaType)
+	assert.Equal(t, "openvex", src.Format)
+
+	r, err := src.Open(pkgDir)
+	require.NoError(t, err)
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	doc, err := openvex.Parse(content)
+	require.NoError(t, err)
+	assert.Equal(t, "CVE-2024-0001", doc.Statements[0].Vulnerability.ID)
+
+	// The compressed file is deterministic so that recrawling doesn't cause churn
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, opts)
+	require.NoError(t, err)
+	second, err := os.ReadFile(filepath.Join(pkgDir, "openvex.json.gz"))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
@@ -0,0 +1,101 @@
+package vex
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/samber/oops"
+)
+
+// CPE identifies a component tracked by CPE rather than PURL, such as "cpe:2.3:a:example:product".
+// Targets are version-less like the PURLs of packages, so that the VEX documents of all the versions are collected,
+// while the version is set in the manifest IDs of tags.
+type CPE struct {
+	Part    string // "a" for applications, "o" for operating systems and "h" for hardware
+	Vendor  string
+	Product string
+	Version string // Empty for any version
+}
+
+// ParseCPE parses the CPE in either the 2.3 formatted string or the 2.2 URI binding,
+// e.g. "cpe:2.3:a:example:product:*:*:*:*:*:*:*:*" or "cpe:/a:example:product".
+// The fields after the version are optional and must be wildcards if any.
+func ParseCPE(s string) (CPE, error) {
+	errBuilder := oops.With("cpe", s)
+	fields, ok := cpeFields(s)
+	if !ok {
+		return CPE{}, errBuilder.Errorf(`CPE must start with "cpe:2.3:" or "cpe:/"`)
+	} else if len(fields) < 3 {
+		return CPE{}, errBuilder.Errorf("part, vendor and product are required")
+	}
+	for _, f := range fields[:3] {
+		if f == "" || f == "*" || f == "-" {
+			return CPE{}, errBuilder.Errorf("part, vendor and product must not be wildcards")
+		}
+	}
+	if f := fields[0]; f != "a" && f != "o" && f != "h" {
+		return CPE{}, errBuilder.Errorf("unknown part: %s", f)
+	}
+	for _, f := range fields[1:3] {
+		if strings.ContainsAny(f, `/\`) || f == "." || f == ".." {
+			return CPE{}, errBuilder.Errorf("vendor and product must be valid directory names")
+		}
+	}
+	if len(fields) > 4 {
+		for _, f := range fields[4:] {
+			if f != "" && f != "*" {
+				return CPE{}, errBuilder.Errorf("fields other than the version must be omitted")
+			}
+		}
+	}
+
+	cpe := CPE{
+		Part:    fields[0],
+		Vendor:  strings.ToLower(fields[1]),
+		Product: strings.ToLower(fields[2]),
+	}
+	if len(fields) > 3 && fields[3] != "*" {
+		cpe.Version = fields[3]
+	}
+	return cpe, nil
+}
+
+// cpeFields returns the fields of the CPE after the prefix, or false if it's not a CPE.
+func cpeFields(s string) ([]string, bool) {
+	switch {
+	case strings.HasPrefix(s, "cpe:2.3:"):
+		return strings.Split(strings.TrimPrefix(s, "cpe:2.3:"), ":"), true
+	case strings.HasPrefix(s, "cpe:/"):
+		return strings.Split(strings.TrimPrefix(s, "cpe:/"), ":"), true
+	}
+	return nil, false
+}
+
+// String returns the CPE in the 2.3 formatted string, which is the ID of the manifest.
+func (c CPE) String() string {
+	version := c.Version
+	if version == "" {
+		version = "*"
+	}
+	return strings.Join([]string{"cpe", "2.3", c.Part, c.Vendor, c.Product, version, "*", "*", "*", "*", "*", "*", "*"}, ":")
+}
+
+// Matches reports whether the product ID is a CPE of the component, regardless of the version.
+func (c CPE) Matches(productID string) bool {
+	fields, ok := cpeFields(productID)
+	if !ok || len(fields) < 3 {
+		return false
+	}
+	return fields[0] == c.Part && normalizeCPE(fields[1]) == normalizeCPE(c.Vendor) &&
+		normalizeCPE(fields[2]) == normalizeCPE(c.Product)
+}
+
+// CPEDir returns the directory in VEX Hub storing the VEX documents of the component,
+// such as "cpe/<part>/<vendor>/<product>/<version>", next to the "pkg" tree of PackageDir.
+func CPEDir(vexHubDir string, cpe CPE, version string) string {
+	dir := filepath.Join(vexHubDir, "cpe", cpe.Part, cpe.Vendor, cpe.Product)
+	if version != "" {
+		dir = filepath.Join(dir, version)
+	}
+	return filepath.Clean(filepath.ToSlash(dir))
+}
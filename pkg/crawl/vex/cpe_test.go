@@ -0,0 +1,91 @@
+package vex_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+func TestParseCPE(t *testing.T) {
+	tests := []struct {
+		name    string
+		cpe     string
+		want    vex.CPE
+		wantErr string
+	}{
+		{
+			name: "2.3",
+			cpe:  "cpe:2.3:a:Example:Product:*:*:*:*:*:*:*:*",
+			want: vex.CPE{Part: "a", Vendor: "example", Product: "product"},
+		},
+		{
+			name: "2.3 without trailing fields",
+			cpe:  "cpe:2.3:o:example:os",
+			want: vex.CPE{Part: "o", Vendor: "example", Product: "os"},
+		},
+		{
+			name: "2.2",
+			cpe:  "cpe:/a:example:product:1.0.0",
+			want: vex.CPE{Part: "a", Vendor: "example", Product: "product", Version: "1.0.0"},
+		},
+		{
+			name:    "not a cpe",
+			cpe:     "pkg:npm/product",
+			wantErr: "CPE must start with",
+		},
+		{
+			name:    "wildcard vendor",
+			cpe:     "cpe:2.3:a:*:product",
+			wantErr: "must not be wildcards",
+		},
+		{
+			name:    "unknown part",
+			cpe:     "cpe:2.3:x:example:product",
+			wantErr: "unknown part",
+		},
+		{
+			name:    "path traversal",
+			cpe:     "cpe:2.3:a:..:product",
+			wantErr: "valid directory names",
+		},
+		{
+			name:    "update",
+			cpe:     "cpe:2.3:a:example:product:*:beta",
+			wantErr: "fields other than the version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vex.ParseCPE(tt.cpe)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCPE_String(t *testing.T) {
+	cpe := vex.CPE{Part: "a", Vendor: "example", Product: "product"}
+	assert.Equal(t, "cpe:2.3:a:example:product:*:*:*:*:*:*:*:*", cpe.String())
+
+	cpe.Version = "1.0.0"
+	assert.Equal(t, "cpe:2.3:a:example:product:1.0.0:*:*:*:*:*:*:*", cpe.String())
+
+	// The string round-trips
+	got, err := vex.ParseCPE(cpe.String())
+	require.NoError(t, err)
+	assert.Equal(t, cpe, got)
+}
+
+func TestCPEDir(t *testing.T) {
+	cpe := vex.CPE{Part: "a", Vendor: "example", Product: "product"}
+	assert.Equal(t, "hub/cpe/a/example/product", vex.CPEDir("hub", cpe, ""))
+	assert.Equal(t, "hub/cpe/a/example/product/v1.0.0", vex.CPEDir("hub", cpe, "v1.0.0"))
+}
@@ -1,136 +1,1123 @@
 package vex
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/ed25519"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io/fs"
 	"log/slog"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/openvex/go-vex/pkg/vex"
 	"github.com/package-url/packageurl-go"
 	"github.com/samber/oops"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/download"
 	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/trace"
 	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
+// Errors returned by validators to classify the documents.
+// A document is returned along with ErrPURLMismatch, ErrEmbargoed and ErrFilteredOut for diagnostics.
 var (
-	errPURLMismatch = fmt.Errorf("PURL does not match")
-	errNoStatement  = fmt.Errorf("no statements found")
+	ErrPURLMismatch = fmt.Errorf("PURL does not match")
+	ErrNoStatement  = fmt.Errorf("no statements found")
+	ErrEmbargoed    = fmt.Errorf("all statements are embargoed")
+
+	// ErrFilteredOut is returned for documents whose statements are all dropped by Options.Statuses.
+	ErrFilteredOut = fmt.Errorf("all statements are filtered out by status")
+
+	// ErrUntrustedAuthor is returned for documents whose author is not in Options.Authors.
+	ErrUntrustedAuthor = fmt.Errorf("author is not allowed")
+
+	// ErrNameCollision is returned for files whose names differ only in case from a file already copied or the manifest,
+	// since they would overwrite each other on case-insensitive filesystems such as macOS and Windows.
+	ErrNameCollision = fmt.Errorf("file name collides with another file regardless of case")
+
+	// ErrNoEmbeddedVEX is returned for SBOMs without any VEX statement, since most SBOMs only list the components,
+	// e.g. a CycloneDX SBOM without vulnerabilities next to the VEX documents.
+	ErrNoEmbeddedVEX = fmt.Errorf("no VEX statements embedded in the SBOM")
+
+	// ErrInvalidTimestamp is returned for documents with missing or malformed statement timestamps
+	// if Options.StrictTimestamps is set.
+	ErrInvalidTimestamp = fmt.Errorf("statement timestamps are missing or malformed")
+
+	// ErrMissingJustification is returned for documents with not_affected statements lacking a justification
+	// if Options.StrictJustifications is set.
+	ErrMissingJustification = fmt.Errorf("not_affected statements have no justification or impact statement")
+
+	// ErrNoVEXFile is returned when the source has no VEX document accepted for the package.
+	ErrNoVEXFile = fmt.Errorf("no VEX file found")
+
+	// ErrPartialDownload is returned when a download succeeded but left no checkout to walk,
+	// e.g. a clone without the .git directory or an empty archive.
+	ErrPartialDownload = fmt.Errorf("download is empty or partial")
+)
+
+// DefaultRootDirs are the directories used as the root if they exist, unless Options.RootDirs is set.
+var DefaultRootDirs = []string{".vex"}
+
+// RootDirMode is the precedence of the root directories, see Options.RootDirs, over the searched directory.
+type RootDirMode string
+
+const (
+	// RootDirModeSubdirThenVEX uses the first root directory existing in the searched directory,
+	// or the searched directory itself. It's the default.
+	RootDirModeSubdirThenVEX RootDirMode = "subdir-then-vex"
+
+	// RootDirModeSubdirOnly always uses the searched directory, ignoring the root directories.
+	RootDirModeSubdirOnly RootDirMode = "subdir-only"
+
+	// RootDirModeVEXAnywhere uses the first root directory existing in the searched directory or the nearest of its
+	// parents up to the repository root, e.g. a ".vex" directory at the root of a monorepo crawled with a subdirectory.
+	RootDirModeVEXAnywhere RootDirMode = "vex-anywhere"
 )
 
-func CrawlPackage(ctx context.Context, vexHubDir string, url *xurl.URL, purl packageurl.PackageURL) error {
-	errBuilder := oops.In("crawl").With("purl", purl.String()).With("url", url)
-	tmpDir, err := os.MkdirTemp("", "vexhub-crawler-*")
+// maxFoundProducts is the maximum number of product IDs included in errors and logs on PURL mismatch.
+const maxFoundProducts = 10
+
+// Options configures how VEX documents are collected for a package.
+type Options struct {
+	// StrictPURL makes the crawl fail if any VEX document doesn't match the PURL,
+	// rather than skipping the document.
+	StrictPURL bool
+
+	// Version stores the VEX documents under a versioned directory, such as "pkg/<type>/<namespace>/<name>/<version>/".
+	// It is used to keep VEX documents as of a release tag.
+	Version string
+
+	// Parent is the PURL of the package owning this one, recorded in the manifest.
+	Parent string
+
+	// VerifyWrites re-validates each VEX document after it's moved into VEX Hub
+	// and fails the crawl if any is no longer valid.
+	VerifyWrites bool
+
+	// SemanticChanges compares the JSON content of the files modified since the git index of VEX Hub,
+	// rather than their bytes, so that formatting-only changes upstream, e.g. whitespace or key order,
+	// don't update the manifest. The files only changed in formatting are restored to the indexed version.
+	// It's more expensive since both versions are parsed, and the bytes are compared if either doesn't parse.
+	SemanticChanges bool
+
+	// Embargoed reports whether statements about the vulnerability must be withheld.
+	// Such statements are removed from the documents, and documents left without statements are skipped.
+	Embargoed func(vulnID string) bool
+
+	// Statuses are the statuses of the statements kept in VEX Hub, e.g. only the actionable "affected" and
+	// "under_investigation". The other statements are dropped and the documents are rewritten without them,
+	// and the documents left without statements are skipped. Empty keeps all the statements.
+	// CycloneDX analysis states are mapped to the OpenVEX statuses, and vulnerabilities without a state are kept.
+	Statuses []vex.Status
+
+	// ManifestEncoding is the JSON encoding of the manifest. Nil means manifest.DefaultEncoding.
+	ManifestEncoding *manifest.Encoding
+
+	// DigestAlgorithm is the algorithm of the digests of the stored files recorded in the manifest,
+	// see manifest.NewDigest. Empty means manifest.DefaultDigestAlgorithm.
+	DigestAlgorithm string
+
+	// Patterns are the glob patterns of the VEX document file names, overriding the built-in conventions.
+	Patterns []string
+
+	// Subdir is the directory searched for VEX documents in the source repository,
+	// overriding the subdirectory in the URL.
+	Subdir string
+
+	// Format forces the format of the VEX documents instead of detecting it.
+	Format Format
+
+	// DeterministicTempDir downloads the source into the directory from TempDirPath rather than a random one,
+	// which makes runs easier to debug. The directory is still removed after the crawl.
+	DeterministicTempDir bool
+
+	// Authors restricts the accepted documents to those written by any of the authors.
+	// Documents from other authors are skipped. Empty means no restriction.
+	Authors []string
+
+	// Compress stores the VEX documents gzip-compressed as "<name>.gz" to save space in large hubs.
+	// The compression and the original media type are recorded in the manifest.
+	Compress bool
+
+	// Clone always clones the git repository, even if the host offers archive downloads.
+	// By default, the tarball of the commit is downloaded from GitHub and GitLab since no history is needed.
+	Clone bool
+
+	// ManifestName is the file name of the manifest. Empty means manifest.FileName.
+	ManifestName string
+
+	// ManifestSigningKey signs the manifest into a detached signature next to it, with manifest.SignatureSuffix,
+	// so that consumers can check the sources weren't tampered with. Nil doesn't sign the manifest.
+	ManifestSigningKey ed25519.PrivateKey
+
+	// Groups records the sources sharing a vulnerability ID in the manifest,
+	// so that consumers can find advisories split across multiple documents.
+	Groups bool
+
+	// MergeManifest carries the fields unknown to the crawler over from the previous manifest, see manifest.Merge,
+	// for the hubs augmenting the manifests out-of-band. By default, the manifest is regenerated entirely.
+	MergeManifest bool
+
+	// Latest merges the OpenVEX documents of each package into LatestFileName, recorded in the manifest,
+	// so that consumers can fetch the current VEX of the package without knowing the file names.
+	Latest bool
+
+	// ParseWorkers is the number of files validated in parallel while the directory is walked,
+	// which speeds up monorepos with thousands of VEX documents. Zero or one validates sequentially.
+	// Validators registered with RegisterValidator must be safe for concurrent use if it's more than one.
+	ParseWorkers int
+
+	// Trusted selects the relaxed validation profile for first-party sources,
+	// where documents are only parsed and matched against the PURL.
+	// The strict profile, the default, also enforces the author allowlist.
+	// Embargoes are honored regardless of the profile.
+	Trusted bool
+
+	// SkipInvalid skips the files failing to parse or validate, such as a corrupt document or one without statements,
+	// so that the package succeeds on the valid files. The skipped files are recorded in Result.Invalid.
+	// By default, any invalid file fails the whole package.
+	SkipInvalid bool
+
+	// RootDirs are the directories relative to the searched directory, such as "security/vex",
+	// that are used as the root if they exist. The first existing one wins. Empty means DefaultRootDirs.
+	RootDirs []string
+
+	// RootDirMode is the precedence of RootDirs over the searched directory. Empty means RootDirModeSubdirThenVEX.
+	RootDirMode RootDirMode
+
+	// LFS fetches the content of the files stored in Git LFS after cloning, which requires the git-lfs extension.
+	// Archive downloads are disabled since archives may contain the pointer files instead of the content.
+	// Otherwise, VEX documents stored in Git LFS fail with ErrLFSPointer.
+	LFS bool
+
+	// MatchCPE also matches the PURL against product IDs that are CPEs naming the package,
+	// for publishers not using PURLs. vex.PurlMatches is always tried first.
+	MatchCPE bool
+
+	// ProductPatterns are the regular expressions of the product IDs also referring to the package,
+	// e.g. "^https://example\.com/products/foo$", tried after vex.PurlMatches and the CPE comparison.
+	ProductPatterns []*regexp.Regexp
+
+	// SignificantQualifiers are the PURL qualifiers compared when matching product PURLs, e.g. "arch".
+	// They must be equal in the target and the product, a missing qualifier only matching a missing one,
+	// while the other qualifiers are ignored. Nil keeps vex.PurlMatches, where the qualifiers of the target
+	// must be in the product, and empty ignores all the qualifiers.
+	SignificantQualifiers []string
+
+	// DigestPolicy is how the digests of the package and the product PURLs are matched, e.g. for OCI images.
+	// Empty means DigestPolicyExact.
+	DigestPolicy DigestPolicy
+
+	// DuplicateNames is how a file whose name collides with a file already copied, regardless of case, is stored,
+	// since the documents are copied into a single directory. Empty skips the file if the names differ in case,
+	// and overwrites the other file if they're the same.
+	DuplicateNames DuplicateNames
+
+	// MatchSubcomponents also matches the subcomponents of the products in OpenVEX statements,
+	// so that a document about a product embedding the package is recognized, e.g. an image shipping the library.
+	MatchSubcomponents bool
+
+	// SBOM also extracts the VEX statements embedded in SBOMs, e.g. "sbom.spdx.json" and "bom.json", keeping only
+	// the statements about the package. SPDX 3.0 documents are converted into OpenVEX, and CycloneDX SBOMs are
+	// reduced to the vulnerabilities affecting the package. SBOMs without any statement are skipped.
+	SBOM bool
+
+	// Incremental validates only the files changed since the previous crawl, based on the commit in the manifest,
+	// and carries over the other sources from the manifest. The unchanged files that weren't accepted are ignored,
+	// so the settings affecting the validation must not change in between. The whole tree is walked
+	// if the changes are unknown, e.g. the previous commit isn't in a shallow clone, or with Embargoed or Groups.
+	Incremental bool
+
+	// PublicURL is the URL of the source advertised in the manifest instead of the download URL,
+	// e.g. the canonical repository of an internal mirror. The permalinks are built from it and the commit.
+	PublicURL string
+
+	// StrictTimestamps fails the OpenVEX documents having statements without a timestamp, either their own or
+	// the one of the document, or with a timestamp that is not RFC 3339. Such documents are invalid, see SkipInvalid.
+	// By default, the malformed timestamps are dropped so that the documents still parse,
+	// and the statements are counted in Result.Timestamps.
+	StrictTimestamps bool
+
+	// StrictJustifications fails the OpenVEX documents having not_affected statements with neither a justification
+	// nor an impact statement. Such documents are invalid, see SkipInvalid.
+	// By default, they are accepted and the statements are counted in Result.Justifications.
+	StrictJustifications bool
+
+	// Download downloads the source into the directory. Nil means download.Download.
+	// It can be replaced e.g. in tests to simulate flaky downloads.
+	Download func(ctx context.Context, src, dst string) (download.Stats, error)
+
+	// History keeps the previous versions of the VEX documents in HistoryDir when they change or disappear upstream,
+	// for audit trails without the git history of VEX Hub. The versions are named after the time they're archived at
+	// and the commit they were crawled from, and pruned according to the retention. Nil disables the history.
+	History *History
+
+	// Quarantine writes the files rejected by the validation, e.g. invalid documents or documents from untrusted authors,
+	// into QuarantineDir with the reason, so that maintainers can inspect them when onboarding a publisher.
+	// It only keeps the files rejected by the latest crawl of each package, within the limits. Nil disables it.
+	Quarantine *Quarantine
+
+	// Tracer records the spans of the download, the walk, the validation of each file and the manifest write,
+	// as children of the span in the context. Nil disables tracing.
+	Tracer oteltrace.Tracer
+
+	// fsys is the filesystem of the documents being validated, set by Collect.
+	fsys FS
+
+	// single is set for the sources downloaded from a single-file URL, whose document is validated
+	// whatever its name, see Source.Collect.
+	single bool
+
+	// accepted is called with the relative path of each file copied into VEX Hub and its source, see ValidateDir.
+	accepted func(relPath string, src manifest.Source)
+}
+
+// fs returns the filesystem of the documents being validated.
+func (o Options) fs() FS {
+	if o.fsys == nil {
+		return OSFS
+	}
+	return o.fsys
+}
+
+// download returns the function downloading the sources.
+func (o Options) download() func(ctx context.Context, src, dst string) (download.Stats, error) {
+	if o.Download == nil {
+		return download.Download
+	}
+	return o.Download
+}
+
+// tracer returns the tracer of the spans, which is a no-op if tracing is disabled.
+func (o Options) tracer() oteltrace.Tracer {
+	return trace.OrNoop(o.Tracer)
+}
+
+// rootDirs returns the directories used as the root if they exist, in order of precedence.
+func (o Options) rootDirs() []string {
+	if len(o.RootDirs) == 0 {
+		return DefaultRootDirs
+	}
+	return o.RootDirs
+}
+
+// manifestName returns the file name of the manifest, which is preserved on reset and excluded from changes.
+func (o Options) manifestName() string {
+	return cmp.Or(o.ManifestName, manifest.FileName)
+}
+
+// manifestFiles returns the manifest and its signature, if signed, which are preserved on reset and excluded from changes.
+func (o Options) manifestFiles() []string {
+	if o.ManifestSigningKey == nil {
+		return []string{o.manifestName()}
+	}
+	return []string{o.manifestName(), o.manifestName() + manifest.SignatureSuffix}
+}
+
+// reserved reports whether the file name in the package directory is written by the crawler rather than copied.
+func (o Options) reserved(name string) bool {
+	return slices.Contains(o.manifestFiles(), name) || (o.Latest && name == LatestFileName)
+}
+
+// Result describes the outcome of crawling a package.
+type Result struct {
+	Commit          string        `json:"commit,omitempty"` // Commit hash crawled, if the source is a git repository
+	DownloadedBytes int64         `json:"downloaded_bytes,omitempty"`
+	DownloadedFiles int           `json:"downloaded_files,omitempty"`
+	CandidateFiles  int           `json:"candidate_files,omitempty"` // Files handled by any validator
+	AcceptedFiles   int           `json:"accepted_files,omitempty"`  // Files copied into VEX Hub
+	Skipped         []SkippedFile `json:"skipped,omitempty"`
+	Invalid         []SkippedFile `json:"invalid,omitempty"`           // Files failing to parse or validate, skipped with Options.SkipInvalid
+	UnchangedFiles  int           `json:"unchanged_files,omitempty"`   // Accepted files carried over without validation, see Options.Incremental
+	MovedTo         string        `json:"moved_to,omitempty"`          // Location the repository has moved to, see Source.MovedTo
+	Quarantined     int           `json:"quarantined_files,omitempty"` // Rejected files written into QuarantineDir, see Options.Quarantine
+
+	// Host is the host the source is downloaded from, e.g. "github.com", or the bucket of object storage.
+	// It's empty for local sources.
+	Host string `json:"host,omitempty"`
+
+	// DownloadMS and WalkMS are the milliseconds spent downloading the source, and walking and validating its files,
+	// so that the slow hosts and publishers can be spotted in the report.
+	DownloadMS int64 `json:"download_ms,omitempty"`
+	WalkMS     int64 `json:"walk_ms,omitempty"`
+
+	// Timestamps lists the accepted files having statements with missing or malformed timestamps.
+	Timestamps []TimestampProblems `json:"timestamps,omitempty"`
+
+	// Justifications lists the accepted files having not_affected statements without a justification.
+	Justifications []JustificationProblems `json:"justifications,omitempty"`
+}
+
+// TimestampProblems is the number of statements with timestamp problems in a VEX document,
+// which break sorting and merging the statements downstream.
+type TimestampProblems struct {
+	Path    string `json:"path"`
+	Missing int    `json:"missing,omitempty"` // Statements without a timestamp, neither their own nor the document's
+	Invalid int    `json:"invalid,omitempty"` // Statements whose timestamp is not RFC 3339, dropped from the stored document
+}
+
+// JustificationProblems is the number of not_affected statements in a VEX document with neither a justification
+// nor an impact statement, which OpenVEX requires.
+type JustificationProblems struct {
+	Path    string `json:"path"`
+	Missing int    `json:"missing"`
+}
+
+// SkippedFile is a VEX document that was found in the source repository but not copied.
+type SkippedFile struct {
+	Path     string   `json:"path"`
+	Reason   string   `json:"reason"`
+	Products []string `json:"products,omitempty"` // Product IDs the document actually contains
+}
+
+// CrawlPackage downloads the source repository and copies the VEX documents matching the PURL into VEX Hub.
+// The returned result is populated even on error so that the caller can report partial progress.
+func CrawlPackage(ctx context.Context, vexHubDir string, url *xurl.URL, purl packageurl.PackageURL, opts Options) (*Result, error) {
+	return crawl(ctx, vexHubDir, url, purlTarget(purl), opts)
+}
+
+// CrawlCPE downloads the source repository and copies the VEX documents matching the CPE into VEX Hub.
+// It's the counterpart of CrawlPackage for components tracked by CPE, stored under CPEDir.
+func CrawlCPE(ctx context.Context, vexHubDir string, url *xurl.URL, cpe CPE, opts Options) (*Result, error) {
+	return crawl(ctx, vexHubDir, url, cpeTarget(cpe), opts)
+}
+
+func crawl(ctx context.Context, vexHubDir string, url *xurl.URL, t target, opts Options) (*Result, error) {
+	src, err := fetch(ctx, url, t, opts)
 	if err != nil {
-		return errBuilder.Wrapf(err, "failed to create a temporary directory")
+		return src.Result(), err
 	}
-	defer os.RemoveAll(tmpDir)
+	defer src.Close()
+	return src.collect(ctx, vexHubDir, url, t, opts)
+}
+
+// target is what the VEX documents are collected for: a package identified by PURL, or a component identified by CPE.
+// Both share the download and the walk, and differ in the matching and the layout of VEX Hub.
+type target struct {
+	id         string                                 // Matched against the product IDs
+	kind       string                                 // "purl" or "cpe", the attribute of the ID in logs and errors
+	name       string                                 // Name of the directory the source is downloaded into
+	matcher    string                                 // Matcher of the ID itself, so that other matchers are logged
+	dir        func(vexHubDir, version string) string // Directory in VEX Hub
+	manifestID func(version string) string            // ID in the manifest
+}
 
-	dst := filepath.Join(tmpDir, purl.Name)
-	if err = download.Download(ctx, url.GetterString(), dst); err != nil {
-		return errBuilder.Wrapf(err, "download error")
+func purlTarget(purl packageurl.PackageURL) target {
+	purl.Subpath = CanonicalSubpath(purl.Subpath) // So that the manifest ID maps back to the same directory
+	return target{
+		id:      purl.String(),
+		kind:    "purl",
+		name:    purl.Name,
+		matcher: MatcherPURL,
+		dir: func(vexHubDir, version string) string {
+			return PackageDir(vexHubDir, purl, version)
+		},
+		manifestID: func(version string) string {
+			id := purl
+			if version != "" {
+				id.Version = version
+			}
+			return id.String()
+		},
 	}
+}
 
-	permaLink := githubPermalink(dst)
-	if permaLink != nil {
-		errBuilder.With("permalink", permaLink.String())
+func cpeTarget(cpe CPE) target {
+	cpe.Version = ""
+	return target{
+		id:      cpe.String(),
+		kind:    "cpe",
+		name:    cpe.Product,
+		matcher: MatcherCPE,
+		dir: func(vexHubDir, version string) string {
+			return CPEDir(vexHubDir, cpe, version)
+		},
+		manifestID: func(version string) string {
+			id := cpe
+			id.Version = version
+			return id.String()
+		},
 	}
+}
 
-	vexDir := filepath.Join(vexHubDir, "pkg", purl.Type, purl.Namespace, purl.Name, purl.Subpath)
-	if purl.Type == packageurl.TypeOCI {
-		name := purl.Qualifiers.Map()["repository_url"]
-		vexDir = filepath.Join(vexHubDir, "pkg", purl.Type, name)
+// Source is a source repository downloaded into a temporary directory, waiting to be collected.
+type Source struct {
+	Dir    string // Directory the repository is downloaded into
+	Commit string // Commit hash the repository is downloaded at, if known
+	Stats  download.Stats
+
+	// MovedTo is the location the repository was downloaded from after following a rename or a transfer,
+	// or nil if it hasn't moved. The VEX documents are advertised with it rather than the configured URL.
+	MovedTo *xurl.URL
+
+	Host     string        // Host the source is downloaded from, see Result.Host
+	Duration time.Duration // Time spent downloading the source
+
+	tmpDir string
+	lock   *tempDirLock // Lock of the deterministic temporary directory, see makeTempDir
+	single bool         // Downloaded from a single-file URL, see fetchFile
+}
+
+// Close removes the downloaded source.
+func (s *Source) Close() error {
+	if s.tmpDir == "" {
+		return nil
 	}
-	vexDir = filepath.Clean(filepath.ToSlash(vexDir))
-	errBuilder = errBuilder.With("dir", vexDir)
+	// The directory is unlocked only once removed, so that another run doesn't find it stale
+	defer s.lock.release()
+	return os.RemoveAll(s.tmpDir)
+}
 
-	// Reset the directory
-	if err = resetDir(vexDir); err != nil {
-		return errBuilder.Wrapf(err, "failed to reset the directory")
+// Result returns the result of the package carrying the download stats, e.g. if the download has failed.
+func (s *Source) Result() *Result {
+	return &Result{
+		DownloadedBytes: s.Stats.Bytes,
+		DownloadedFiles: s.Stats.Files,
+		Host:            s.Host,
+		DownloadMS:      s.Duration.Milliseconds(),
 	}
+}
 
-	var found bool
-	var sources []manifest.Source
-	logger := slog.With(slog.String("purl", purl.String()), "url", url)
+// Collect copies the VEX documents matching the PURL from the downloaded source into VEX Hub.
+// The context only carries the request ID of the crawl, see trace.WithID.
+func (s *Source) Collect(ctx context.Context, vexHubDir string, url *xurl.URL, purl packageurl.PackageURL, opts Options) (*Result, error) {
+	return s.collect(ctx, vexHubDir, url, purlTarget(purl), opts)
+}
+
+// CollectCPE copies the VEX documents matching the CPE from the downloaded source into VEX Hub.
+func (s *Source) CollectCPE(ctx context.Context, vexHubDir string, url *xurl.URL, cpe CPE, opts Options) (*Result, error) {
+	return s.collect(ctx, vexHubDir, url, cpeTarget(cpe), opts)
+}
 
-	root := filepath.Join(dst, url.Subdirs())
-	if _, err := os.Stat(filepath.Join(root, ".vex")); err == nil {
-		root = filepath.Join(root, ".vex") // If the directory contains a .vex directory, use it as the root
+func (s *Source) collect(ctx context.Context, vexHubDir string, url *xurl.URL, t target, opts Options) (*Result, error) {
+	if s.MovedTo != nil {
+		url = s.MovedTo
+	}
+	opts.single = s.single
+	result, err := collect(ctx, OSFS, vexHubDir, s.Dir, url, t, s.Commit, opts)
+	result.DownloadedBytes, result.DownloadedFiles = s.Stats.Bytes, s.Stats.Files
+	result.Host, result.DownloadMS = s.Host, s.Duration.Milliseconds()
+	if s.MovedTo != nil {
+		result.MovedTo = s.MovedTo.Public()
 	}
-	err = filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+	return result, err
+}
+
+// Fetch downloads the source repository of the package into a temporary directory,
+// so that downloading and collecting can be scheduled separately. The caller must close the source.
+// On error, the source is already removed and only carries the download stats.
+func Fetch(ctx context.Context, url *xurl.URL, purl packageurl.PackageURL, opts Options) (*Source, error) {
+	return fetch(ctx, url, purlTarget(purl), opts)
+}
+
+// FetchCPE downloads the source repository of the component tracked by CPE, like Fetch.
+func FetchCPE(ctx context.Context, url *xurl.URL, cpe CPE, opts Options) (*Source, error) {
+	return fetch(ctx, url, cpeTarget(cpe), opts)
+}
+
+func fetch(ctx context.Context, url *xurl.URL, t target, opts Options) (s *Source, err error) {
+	ctx, span := opts.tracer().Start(ctx, "download", oteltrace.WithAttributes(
+		attribute.String(t.kind, t.id), attribute.String("url", url.Redacted())))
+	start := time.Now()
+	defer func() {
+		s.Duration = time.Since(start)
+		span.SetAttributes(attribute.String("commit", s.Commit), attribute.Int64("bytes", s.Stats.Bytes))
+		trace.End(span, err)
+	}()
+
+	s = &Source{Host: url.Host}
+	errBuilder := trace.Errors(ctx).In("crawl").With(t.kind, t.id).With("url", url.Redacted())
+	tmpDir, lock, err := makeTempDir(t.id, url.Ref(), opts.DeterministicTempDir)
+	if err != nil {
+		return s, errBuilder.Wrap(err)
+	}
+	s.tmpDir, s.lock, s.Dir = tmpDir, lock, filepath.Join(tmpDir, t.name)
+
+	if url.IsFile() {
+		s.single = true
+		s.Stats, err = fetchFile(ctx, s.Dir, url)
 		if err != nil {
-			return errBuilder.Wrapf(err, "failed to walk the directory")
-		} else if d.IsDir() {
-			return nil
-		} else if !matchPath(filePath) {
-			return nil
+			s.Close()
+			return s, errBuilder.Wrapf(err, "download error")
+		}
+		return s, nil
+	}
+
+	if moved, err := resolveMove(ctx, url); err != nil {
+		slog.Debug("Failed to check whether the repository has moved", slog.String("url", url.Redacted()),
+			slog.Any("err", err))
+	} else if moved != nil {
+		trace.Logger(ctx).Warn("Repository has moved, update its URL in the config", slog.String(t.kind, t.id),
+			slog.String("url", url.Redacted()), slog.String("moved_to", moved.Redacted()))
+		url, s.MovedTo, s.Host = moved, moved, moved.Host
+		errBuilder = errBuilder.With("moved_to", moved.Redacted())
+	}
+
+	src, commit := archiveSource(ctx, url, opts)
+	if src == "" {
+		src = url.GetterString()
+	}
+	s.Stats, err = opts.download()(ctx, src, s.Dir)
+	if err != nil {
+		s.Close()
+		return s, errBuilder.Wrapf(err, "download error")
+	} else if err = verifyDownload(s.Dir, src); err != nil {
+		s.Close()
+		return s, errBuilder.Wrap(err)
+	}
+
+	if opts.LFS {
+		if err = pullLFS(ctx, s.Dir); err != nil {
+			s.Close()
+			return s, errBuilder.Wrapf(err, "failed to fetch Git LFS files")
+		}
+		if s.Stats, err = download.DiskUsage(s.Dir); err != nil {
+			s.Close()
+			return s, errBuilder.Wrapf(err, "failed to calculate the size")
+		}
+	}
+
+	if commit == "" {
+		commit = HeadCommit(s.Dir)
+	}
+	s.Commit = commit
+	return s, nil
+}
+
+// fetchFile is the fast path for a single VEX document served over HTTP(S).
+// The document is downloaded into the directory with a single request rather than through go-getter,
+// and then collected as the only file of the directory, whatever its name and the patterns.
+func fetchFile(ctx context.Context, dst string, url *xurl.URL) (download.Stats, error) {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return download.Stats{}, oops.Wrapf(err, "failed to create the directory")
+	}
+	return download.File(ctx, url.String(), filepath.Join(dst, path.Base(url.Path)))
+}
+
+// verifyDownload checks that the download left a checkout to walk, so that a partial tree,
+// e.g. left by an interrupted clone, fails with ErrPartialDownload rather than confusing the walk.
+// Git sources must have the .git directory, and the others at least one file.
+func verifyDownload(dir, src string) error {
+	errBuilder := oops.With("dir", dir)
+	if strings.HasPrefix(src, "git::") || strings.HasPrefix(src, "bundle::") {
+		if _, err := os.Stat(filepath.Join(dir, git.GitDirName)); err != nil {
+			return errBuilder.Wrapf(ErrPartialDownload, "no .git directory")
 		}
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) == 0 {
+		return errBuilder.Wrapf(ErrPartialDownload, "no files")
+	}
+	return nil
+}
+
+// Collect copies the VEX documents matching the PURL from the downloaded source repository into VEX Hub
+// and writes the manifest. The commit is the one the repository was downloaded at, if known.
+// Everything is done on the filesystem so that the pipeline can be tested without disk or network access.
+// The returned result is populated even on error so that the caller can report partial progress.
+func Collect(fsys FS, vexHubDir, repoDir string, url *xurl.URL, purl packageurl.PackageURL, commit string, opts Options) (*Result, error) {
+	return collect(context.Background(), fsys, vexHubDir, repoDir, url, purlTarget(purl), commit, opts)
+}
+
+// CollectCPE copies the VEX documents matching the CPE from the downloaded source repository into VEX Hub,
+// like Collect.
+func CollectCPE(fsys FS, vexHubDir, repoDir string, url *xurl.URL, cpe CPE, commit string, opts Options) (*Result, error) {
+	return collect(context.Background(), fsys, vexHubDir, repoDir, url, cpeTarget(cpe), commit, opts)
+}
+
+func collect(ctx context.Context, fsys FS, vexHubDir, repoDir string, url *xurl.URL, t target, commit string, opts Options) (*Result, error) {
+	result := &Result{Commit: commit}
+	opts.fsys = fsys
+	spanAttrs := oteltrace.WithAttributes(attribute.String(t.kind, t.id), attribute.String("url", url.Redacted()),
+		attribute.String("commit", commit))
+	errBuilder := trace.Errors(ctx).In("crawl").With(t.kind, t.id).With("url", url.Redacted())
+
+	// The sources are advertised with the public URL rather than the mirror they're downloaded from
+	publicURL := url
+	if opts.PublicURL != "" {
+		var err error
+		if publicURL, err = xurl.Parse(opts.PublicURL); err != nil {
+			return result, errBuilder.With("public_url", opts.PublicURL).Wrapf(err, "invalid public URL")
+		}
+	}
+	switch opts.DigestPolicy {
+	case "", DigestPolicyExact, DigestPolicyIgnore, DigestPolicyMatch:
+	default:
+		return result, errBuilder.With("digest_policy", opts.DigestPolicy).Errorf("unknown digest policy")
+	}
+	switch opts.DuplicateNames {
+	case "", DuplicateNamesError, DuplicateNamesHashSuffix, DuplicateNamesPath:
+	default:
+		return result, errBuilder.With("duplicate_names", opts.DuplicateNames).Errorf("unknown strategy for duplicate names")
+	}
+	permaLink := GitPermalink(repoDir)
+	if permaLink == nil || opts.PublicURL != "" {
+		// There is no .git directory in archives, and the remote of a mirror isn't advertised
+		permaLink = publicURL.Permalink(commit)
+	}
+	if permaLink != nil {
+		errBuilder.With("permalink", permaLink.String())
+	}
+
+	vexDir := t.dir(vexHubDir, opts.Version)
+	errBuilder = errBuilder.With("dir", vexDir)
+
+	inc, err := newIncremental(fsys, repoDir, vexDir, commit, opts)
+	if err != nil {
+		return result, errBuilder.Wrapf(err, "failed to compare with the previous crawl")
+	}
+
+	snap, err := takeSnapshot(fsys, vexDir, opts)
+	if err != nil {
+		return result, errBuilder.Wrapf(err, "failed to read the previous crawl")
+	}
 
-		relPath, err := filepath.Rel(dst, filePath) // Relative path from the repository root, not from ".vex/"
+	// Reset the directory, keeping the files that may be carried over
+	if err = resetDir(fsys, vexDir, append(inc.keep(), opts.manifestFiles()...)...); err != nil {
+		return result, errBuilder.Wrapf(err, "failed to reset the directory")
+	}
+	q, err := newQuarantine(fsys, vexHubDir, vexDir, publicURL.Public(), opts)
+	if err != nil {
+		return result, errBuilder.Wrap(err)
+	}
+	reject := func(filePath, relPath string, reason error) error {
+		kept, err := q.add(filePath, relPath, reason)
 		if err != nil {
-			return errBuilder.With("file_path", filePath).Wrapf(err, "failed to get the relative path")
+			return errBuilder.Wrapf(err, "failed to quarantine")
+		} else if kept {
+			result.Quarantined++
 		}
+		return nil
+	}
 
+	var sources []manifest.Source
+	groups := make(map[string][]string) // Source paths by vulnerability ID
+	logger := trace.Logger(ctx).With(slog.String(t.kind, t.id), slog.String("url", url.Redacted()))
+	if inc != nil {
+		logger.Info("Validating the files changed since the previous crawl only", slog.Int("changed", len(inc.changed)))
+	}
+	carried := make(map[string]bool) // Stored files carried over from the previous crawl
+
+	// Copied file names by the case-folded name, so that the result doesn't depend on the case sensitivity of the filesystem.
+	// The manifest is reserved so that a VEX file can't overwrite it.
+	copied := make(map[string]string)
+	for _, name := range opts.manifestFiles() {
+		copied[strings.ToLower(name)] = name
+	}
+	if opts.Latest {
+		copied[strings.ToLower(LatestFileName)] = LatestFileName
+	}
+
+	root := repoDir // The document of a single-file source is at the root
+	if !opts.single {
+		if root, err = searchRoot(fsys, repoDir, url.Subdirs(), opts); err != nil {
+			return result, errBuilder.Wrap(err)
+		}
+	}
+	walkStart := time.Now()
+	walkCtx, walkSpan := opts.tracer().Start(ctx, "walk", spanAttrs, oteltrace.WithAttributes(attribute.String("root", root)))
+	outcomes, err := validateFiles(fsys, root, opts.ParseWorkers, logger, func(filePath string) fileOutcome {
+		relPath, _ := filepath.Rel(repoDir, filePath)
+		if _, ok, ignored := inc.unchanged(relPath, opts); ok {
+			return fileOutcome{carried: true}
+		} else if ignored {
+			return fileOutcome{}
+		}
+		matched, err := selectValidators(filePath, opts)
+		if err != nil || len(matched) == 0 {
+			return fileOutcome{err: err}
+		}
+		_, span := opts.tracer().Start(walkCtx, "validate", spanAttrs, oteltrace.WithAttributes(attribute.String("path", relPath)))
+		defer func() { trace.End(span, err) }()
+		if pointer, perr := isLFSPointer(fsys, filePath); perr != nil || pointer {
+			err = cmp.Or(perr, ErrLFSPointer)
+			return fileOutcome{matched: matched, err: err}
+		}
+		// The validator with the highest precedence is selected so that the result is deterministic
 		logger.Info("Parsing VEX file", slog.String("path", relPath))
-		if err = validateVEX(filePath, purl.String()); errors.Is(err, errNoStatement) {
-			return errBuilder.With("path", relPath).Wrapf(err, "no statement found")
-		} else if errors.Is(err, errPURLMismatch) {
-			logger.Info("PURL does not match", slog.String("path", relPath))
-			return nil
+		doc, err := matched[0].Validate(filePath, t.id, opts)
+		if err == nil && !opts.Trusted {
+			err = checkAuthors(doc, opts.Authors)
+		}
+		return fileOutcome{matched: matched, doc: doc, err: err}
+	})
+	trace.End(walkSpan, err)
+	result.WalkMS = time.Since(walkStart).Milliseconds()
+	if err != nil {
+		return result, errBuilder.Wrapf(err, "failed to walk the directory")
+	}
+
+	// The files are copied in the walk order so that the sources are deterministic
+	for _, outcome := range outcomes {
+		filePath, matched, doc, err := outcome.filePath, outcome.matched, outcome.doc, outcome.err
+		if outcome.carried {
+			// The stored file and its properties are kept, while the URL points to the new commit
+			relPath, _ := filepath.Rel(repoDir, filePath)
+			prev, _, _ := inc.unchanged(relPath, opts)
+			src := fileSource(relPath, publicURL, permaLink)
+			src.Path, src.Format, src.StatementCount = prev.Path, prev.Format, prev.StatementCount
+			src.Compression, src.MediaType = prev.Compression, prev.MediaType
+			src.SBOM = prev.SBOM
+			sources = append(sources, *src)
+			copied[strings.ToLower(prev.Path)] = prev.Path
+			carried[prev.Path] = true
+			result.CandidateFiles++
+			result.AcceptedFiles++
+			result.UnchangedFiles++
+			continue
+		}
+		if len(matched) == 0 && err != nil {
+			return result, errBuilder.With("file_path", filePath).Wrapf(err, "failed to select the validator")
+		} else if len(matched) == 0 {
+			continue
+		}
+		result.CandidateFiles++
+
+		relPath, rerr := filepath.Rel(repoDir, filePath) // Relative path from the repository root, not from ".vex/"
+		if rerr != nil {
+			return result, errBuilder.With("file_path", filePath).Wrapf(rerr, "failed to get the relative path")
+		}
+
+		if len(matched) > 1 {
+			logger.Warn("Multiple validators match the VEX file", slog.String("path", relPath),
+				slog.String("selected", validatorName(matched[0])), slog.Any("ignored", validatorNames(matched[1:])))
+		}
+		if doc != nil && err == nil && doc.Matcher != t.matcher {
+			logger.Info("Matched the target with another kind of product ID", slog.String("path", relPath),
+				slog.String("matcher", doc.Matcher))
+		}
+		if doc != nil && err == nil && doc.Subcomponent {
+			logger.Info("Matched the target with a subcomponent", slog.String("path", relPath))
+		}
+		if doc != nil && doc.Withheld > 0 {
+			// Don't log the vulnerability IDs as they are embargoed
+			logger.Info("Withheld embargoed statements", slog.String("path", relPath), slog.Int("count", doc.Withheld))
+		}
+		if doc != nil && doc.Filtered > 0 {
+			logger.Info("Dropped statements filtered out by status", slog.String("path", relPath),
+				slog.Int("count", doc.Filtered))
+		}
+		if errors.Is(err, ErrEmbargoed) || errors.Is(err, ErrFilteredOut) || errors.Is(err, ErrNoEmbeddedVEX) {
+			result.Skipped = append(result.Skipped, SkippedFile{
+				Path:   relPath,
+				Reason: err.Error(),
+			})
+			continue
+		} else if errors.Is(err, ErrUntrustedAuthor) {
+			logger.Warn("Rejected VEX file from an unknown author", slog.String("path", relPath),
+				slog.Any("authors", doc.Authors))
+			if qerr := reject(filePath, relPath, err); qerr != nil {
+				return result, qerr
+			}
+			result.Skipped = append(result.Skipped, SkippedFile{
+				Path:   relPath,
+				Reason: err.Error(),
+			})
+			continue
+		} else if errors.Is(err, ErrPURLMismatch) && opts.StrictPURL {
+			return result, errBuilder.With("path", relPath).With("found_products", foundProducts(doc.Products)).
+				Wrapf(err, "strict PURL")
+		} else if errors.Is(err, ErrPURLMismatch) {
+			logger.Info("PURL does not match", slog.String("path", relPath),
+				slog.Any("found_products", foundProducts(doc.Products)))
+			result.Skipped = append(result.Skipped, SkippedFile{
+				Path:     relPath,
+				Reason:   err.Error(),
+				Products: doc.Products,
+			})
+			continue
+		} else if err != nil && opts.SkipInvalid {
+			logger.Warn("Skipped invalid VEX file", slog.String("path", relPath), slog.Any("error", err))
+			if qerr := reject(filePath, relPath, err); qerr != nil {
+				return result, qerr
+			}
+			result.Invalid = append(result.Invalid, SkippedFile{
+				Path:   relPath,
+				Reason: err.Error(),
+			})
+			continue
 		} else if err != nil {
-			return errBuilder.Wrapf(err, "failed to validate VEX file")
+			// The package fails, but the file is still kept for inspection
+			if qerr := reject(filePath, relPath, err); qerr != nil {
+				return result, qerr
+			}
+			if errors.Is(err, ErrNoStatement) {
+				return result, errBuilder.With("path", relPath).Wrapf(err, "no statement found")
+			}
+			return result, errBuilder.Code("validation_error").Wrapf(err, "failed to validate VEX file")
+		}
+
+		// The first file in the walk order wins. Without a strategy for the duplicate names,
+		// files with the exact same name still overwrite each other as before.
+		base := filepath.Base(filePath)
+		other, collides := copied[strings.ToLower(storedName(base, opts))]
+		if collides && opts.DuplicateNames == DuplicateNamesError {
+			return result, errBuilder.With("path", relPath).With("other", other).Wrap(ErrNameCollision)
+		} else if collides && opts.DuplicateNames != "" {
+			if base, err = dedupName(fsys, root, filePath, doc.Content, opts.DuplicateNames); err != nil {
+				return result, errBuilder.With("path", relPath).Wrapf(err, "failed to rename the duplicate")
+			}
+			if other, collides = copied[strings.ToLower(storedName(base, opts))]; !collides {
+				logger.Info("Renamed VEX file colliding with another file", slog.String("path", relPath),
+					slog.String("stored", storedName(base, opts)))
+			}
+		}
+		to, err := SafeJoin(vexDir, base)
+		if err != nil {
+			return result, errBuilder.With("path", relPath).Wrap(err)
+		}
+		to = storedName(to, opts)
+		name := filepath.Base(to)
+		if collides && (opts.DuplicateNames != "" || other != name || opts.reserved(other)) {
+			logger.Warn("Skipped VEX file colliding with another file", slog.String("path", relPath),
+				slog.String("other", other))
+			result.Skipped = append(result.Skipped, SkippedFile{
+				Path:   relPath,
+				Reason: ErrNameCollision.Error(),
+			})
+			continue
 		}
+		copied[strings.ToLower(name)] = name
 
-		found = true
-		to := filepath.Join(vexDir, filepath.Base(filePath))
-		if err = os.Rename(filePath, to); err != nil {
-			return errBuilder.With("from", filePath).With("to", to).Wrapf(err, "failed to rename")
+		result.AcceptedFiles++
+		if doc.MissingTimestamps+doc.InvalidTimestamps > 0 {
+			logger.Warn("Statements with missing or malformed timestamps", slog.String("path", relPath),
+				slog.Int("missing", doc.MissingTimestamps), slog.Int("invalid", doc.InvalidTimestamps))
+			result.Timestamps = append(result.Timestamps, TimestampProblems{
+				Path:    relPath,
+				Missing: doc.MissingTimestamps,
+				Invalid: doc.InvalidTimestamps,
+			})
+		}
+		if doc.MissingJustifications > 0 {
+			logger.Warn("not_affected statements without a justification", slog.String("path", relPath),
+				slog.Int("missing", doc.MissingJustifications))
+			result.Justifications = append(result.Justifications, JustificationProblems{
+				Path:    relPath,
+				Missing: doc.MissingJustifications,
+			})
+		}
+		if opts.Compress {
+			if err = writeCompressed(fsys, to, filePath, doc.Content); err != nil {
+				return result, errBuilder.With("to", to).Wrapf(err, "failed to compress")
+			}
+		} else if doc.Content != nil {
+			// Write the remaining statements instead of the original document
+			if err = fsys.WriteFile(to, doc.Content, 0644); err != nil {
+				return result, errBuilder.With("to", to).Wrapf(err, "failed to write")
+			}
+		} else if err = fsys.Rename(filePath, to); err != nil {
+			return result, errBuilder.With("from", filePath).With("to", to).Wrapf(err, "failed to rename")
 		}
 
-		if src := fileSource(relPath, url, permaLink); src != nil {
+		if src := fileSource(relPath, publicURL, permaLink); src != nil {
+			src.Path = filepath.Base(to) // Renamed if compressed or duplicate
+			src.Format = string(doc.Format)
+			src.StatementCount = doc.Statements
+			src.SBOM = doc.SBOM
+			if opts.Compress {
+				src.Compression = manifest.CompressionGzip
+				src.MediaType = mediaTypeJSON
+			}
 			sources = append(sources, *src)
+			if opts.accepted != nil {
+				opts.accepted(relPath, *src)
+			}
+			for _, vulnID := range doc.Vulnerabilities {
+				groups[vulnID] = append(groups[vulnID], src.Path)
+			}
 		}
+	}
 
-		return nil
-	})
-	if err != nil {
-		return errBuilder.Wrapf(err, "failed to walk the directory")
+	// Remove the files of the previous crawl that were kept but no longer found, e.g. moved out of the root
+	for _, name := range inc.keep() {
+		if carried[name] {
+			continue
+		}
+		if err = fsys.Remove(filepath.Join(vexDir, name)); err != nil && !os.IsNotExist(err) {
+			return result, errBuilder.With("file_path", name).Wrapf(err, "failed to remove the file")
+		}
+	}
+
+	if result.AcceptedFiles == 0 {
+		return result, errBuilder.Wrap(ErrNoVEXFile)
+	}
+
+	// The digests of the carried over files are computed again in case the algorithm has changed
+	for i, src := range sources {
+		if sources[i].Digest, err = digestFile(fsys, filepath.Join(vexDir, src.Path), opts.DigestAlgorithm); err != nil {
+			return result, errBuilder.With("path", src.Path).Wrapf(err, "failed to compute the digest")
+		}
+	}
+
+	if opts.VerifyWrites {
+		if err = verifyWrites(vexDir, t.id, sources, opts); err != nil {
+			return result, errBuilder.Wrapf(err, "failed to verify VEX files")
+		}
+	}
+
+	if version, err := snap.archive(fsys, vexDir, time.Now(), opts); err != nil {
+		return result, errBuilder.Wrapf(err, "failed to archive the previous VEX files")
+	} else if version != "" {
+		logger.Info("Archived the previous VEX files", slog.String("version", version))
 	}
 
-	if !found {
-		return errBuilder.Errorf("no VEX file found")
+	var latest string
+	if opts.Latest {
+		if latest, err = writeLatest(fsys, vexDir, sources); err != nil {
+			// The documents are still published, e.g. when statements lack timestamps to merge them
+			logger.Warn("Failed to write the latest VEX document", slog.Any("error", err))
+			if err = fsys.Remove(filepath.Join(vexDir, LatestFileName)); err != nil && !os.IsNotExist(err) {
+				return result, errBuilder.Wrapf(err, "failed to remove the stale latest VEX document")
+			}
+		}
 	}
 
 	// Check if there are any changes in the VEX directory.
 	// If there are no changes, we don't need to update the manifest.json file.
 	// Since manifest.json has permalink pointing to the default branch,
 	// it's frequently updated even if there are no changes in the VEX directory.
-	if changed, err := hasVEXChanges(vexHubDir, vexDir); err == nil && !changed {
+	if changed, err := hasVEXChanges(vexHubDir, vexDir, opts.manifestFiles(), opts.SemanticChanges); err == nil && !changed {
 		logger.Info("No changes in the VEX directory")
-		return nil
+		// The kept manifest is signed in case the signing has just been enabled
+		return result, errBuilder.Wrap(signManifest(fsys, vexDir, nil, opts))
 	}
 
 	m := manifest.Manifest{
-		ID:      purl.String(),
+		ID:      t.manifestID(opts.Version),
+		Commit:  commit,
 		Sources: sources,
+		Parent:  opts.Parent,
+		Latest:  latest,
+	}
+	if opts.Groups {
+		m.Groups = splitGroups(groups)
 	}
-	if err = manifest.Write(filepath.Join(vexDir, manifest.FileName), m); err != nil {
+	if opts.MergeManifest {
+		if m, err = mergeManifest(fsys, vexDir, m, opts); err != nil {
+			return result, errBuilder.Wrap(err)
+		}
+	}
+	_, span := opts.tracer().Start(ctx, "write manifest", spanAttrs, oteltrace.WithAttributes(attribute.Int("sources", len(sources))))
+	err = writeManifest(fsys, vexDir, m, opts)
+	trace.End(span, err)
+	return result, err
+}
+
+// digestFile returns the digest of the stored file with the algorithm.
+func digestFile(fsys FS, filePath, algorithm string) (string, error) {
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return "", oops.Wrapf(err, "failed to open the file")
+	}
+	defer f.Close()
+	return manifest.NewDigest(algorithm, f)
+}
+
+// writeManifest encodes the manifest and writes it into the directory.
+func writeManifest(fsys FS, vexDir string, m manifest.Manifest, opts Options) error {
+	var mopts []manifest.Option
+	if opts.ManifestEncoding != nil {
+		mopts = append(mopts, manifest.WithEncoding(*opts.ManifestEncoding))
+	}
+	data, err := manifest.Marshal(m, mopts...)
+	if err != nil {
+		return oops.Wrapf(err, "failed to encode sources")
+	}
+	if err = fsys.WriteFile(filepath.Join(vexDir, opts.manifestName()), data, 0644); err != nil {
 		return oops.Wrapf(err, "failed to write sources")
 	}
+	return signManifest(fsys, vexDir, data, opts)
+}
+
+// mergeManifest merges the manifest with the previous one in the directory, if any.
+// A previous manifest that doesn't decode fails the package rather than dropping its curated fields.
+func mergeManifest(fsys FS, vexDir string, m manifest.Manifest, opts Options) (manifest.Manifest, error) {
+	b, err := fsys.ReadFile(filepath.Join(vexDir, opts.manifestName()))
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return m, oops.Wrapf(err, "failed to read the previous manifest")
+	}
+	prev, err := manifest.Decode(bytes.NewReader(b))
+	if err != nil {
+		return m, oops.Wrapf(err, "failed to merge with the previous manifest")
+	}
+	return manifest.Merge(prev, m), nil
+}
 
+// signManifest writes the detached signature of the encoded manifest, read from the directory if data is nil,
+// with Options.ManifestSigningKey.
+func signManifest(fsys FS, vexDir string, data []byte, opts Options) error {
+	if opts.ManifestSigningKey == nil {
+		return nil
+	}
+	manifestPath := filepath.Join(vexDir, opts.manifestName())
+	if data == nil {
+		var err error
+		if data, err = fsys.ReadFile(manifestPath); err != nil {
+			return oops.Wrapf(err, "failed to read the manifest to sign")
+		}
+	}
+	sig := manifest.Sign(opts.ManifestSigningKey, data)
+	if err := fsys.WriteFile(manifestPath+manifest.SignatureSuffix, sig, 0644); err != nil {
+		return oops.Wrapf(err, "failed to write the manifest signature")
+	}
 	return nil
 }
 
-func githubPermalink(repoDir string) *url.URL {
+// PackageDir returns the directory in VEX Hub storing the VEX documents of the package,
+// such as "pkg/<type>/<namespace>/<name>/<subpath>/<version>".
+// Empty elements, e.g. the namespace of "pkg:pypi/requests", are omitted rather than leaving empty path segments.
+// The subpath is canonicalized and its segments become nested directories, e.g. "pkg:golang/example.com/mod#cmd/tool"
+// is stored in "pkg/golang/example.com/mod/cmd/tool".
+func PackageDir(vexHubDir string, purl packageurl.PackageURL, version string) string {
+	dir := filepath.Join(vexHubDir, "pkg", purl.Type, purl.Namespace, purl.Name, filepath.FromSlash(CanonicalSubpath(purl.Subpath)))
+	if purl.Type == packageurl.TypeOCI {
+		name := purl.Qualifiers.Map()["repository_url"]
+		dir = filepath.Join(vexHubDir, "pkg", purl.Type, name)
+	}
+	if version != "" {
+		dir = filepath.Join(dir, version)
+	}
+	return filepath.Clean(filepath.ToSlash(dir))
+}
+
+// archiveSource returns the archive of the commit to download instead of cloning the repository,
+// or an empty string if the host doesn't offer archive downloads.
+// Repositories authenticated with a GitHub App are cloned since the archives don't accept installation tokens.
+// It falls back to cloning if the commit can't be resolved.
+func archiveSource(ctx context.Context, u *xurl.URL, opts Options) (src, commit string) {
+	if opts.Clone || opts.LFS || u.ArchiveString("") == "" || download.Authenticated(u.URL) {
+		return "", ""
+	}
+	commit, err := RemoteHead(ctx, u)
+	if err != nil {
+		slog.Debug("Failed to resolve the commit, falling back to cloning", slog.String("url", u.Redacted()),
+			slog.Any("err", err))
+		return "", ""
+	}
+	return u.ArchiveString(commit), commit
+}
+
+// GitPermalink returns the permalink of the commit checked out in the git repository on disk,
+// discovering the host and the repository from the "origin" remote.
+func GitPermalink(repoDir string) *url.URL {
 	repo, err := git.PlainOpen(repoDir)
 	if err != nil {
 		return nil
@@ -145,58 +1132,358 @@ func githubPermalink(repoDir string) *url.URL {
 	if len(urls) == 0 {
 		return nil
 	}
-	u, err := url.Parse(urls[0])
-	if err != nil || u.Host != "github.com" {
+	head, err := resolveHead(repo)
+	if err != nil {
 		return nil
 	}
-	p, _, ok := strings.Cut(u.Path, ".git")
-	if !ok {
-		return nil
+	return xurl.RemotePermalink(urls[0], head.String())
+}
+
+// verifyWrites re-validates the VEX documents written into VEX Hub.
+func verifyWrites(vexDir, purl string, sources []manifest.Source, opts Options) error {
+	for _, src := range sources {
+		vopts := opts
+		vopts.SBOM = false // The statements extracted from SBOMs are stored as VEX documents
+		if _, ok := builtinValidators[Format(src.Format)]; ok {
+			// The stored name may not follow the conventions, e.g. if renamed as a duplicate
+			vopts.Format, vopts.Patterns = Format(src.Format), []string{"*"}
+		}
+		if err := verifySource(vexDir, purl, src, vopts); err != nil {
+			return err
+		}
 	}
-	head, err := repo.Head()
+	return nil
+}
+
+// VerifySource validates the VEX document of the source stored in the package directory of VEX Hub,
+// e.g. to detect corruption introduced outside the crawler.
+// The format recorded in the manifest is used, since the file may have been found with custom patterns.
+// A document not matching the PURL is accepted since it may have been matched with Options.MatchCPE,
+// Options.ProductPatterns or Options.MatchSubcomponents, which are not recorded.
+func VerifySource(vexDir, purl string, src manifest.Source) error {
+	var opts Options
+	if src.Format != "" {
+		opts.Format, opts.Patterns = Format(src.Format), []string{"*"}
+	}
+	if err := verifySource(vexDir, purl, src, opts); err != nil && !errors.Is(err, ErrPURLMismatch) {
+		return err
+	}
+	return nil
+}
+
+func verifySource(vexDir, purl string, src manifest.Source, opts Options) error {
+	filePath := LongPath(filepath.Join(vexDir, src.Path))
+	if src.Compression != "" {
+		// Validators read the original document
+		tmpPath, cleanup, err := decompressTemp(opts.fs(), vexDir, src)
+		if err != nil {
+			return oops.With("file_path", filePath).Wrapf(err, "corrupt VEX file")
+		}
+		defer cleanup()
+		filePath = tmpPath
+	}
+	matched, err := selectValidators(filePath, opts)
 	if err != nil {
-		return nil
+		return oops.With("file_path", filePath).Wrapf(err, "failed to select the validator")
+	} else if len(matched) == 0 {
+		return oops.With("file_path", filePath).Errorf("no validator found")
 	}
+	vopts := Options{fsys: opts.fsys, MatchCPE: opts.MatchCPE, ProductPatterns: opts.ProductPatterns,
+		MatchSubcomponents: opts.MatchSubcomponents}
+	if _, err = matched[0].Validate(filePath, purl, vopts); err != nil {
+		return oops.With("file_path", filePath).Wrapf(err, "corrupt VEX file")
+	}
+	return nil
+}
 
-	// e.g. https://github.com/aquasecurity/vextest/blob/ed76fc6c0e8e56318ce3148bd7bd938aad41491c/
-	u.Path = path.Join(p, "blob", head.Hash().String())
+// HeadCommit returns the commit hash checked out in the repository, or empty if it's not a git repository.
+func HeadCommit(repoDir string) string {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return ""
+	}
+	head, err := resolveHead(repo)
+	if err != nil {
+		return ""
+	}
+	return head.String()
+}
 
-	u.Scheme = "https"
-	u.User = nil
-	u.RawQuery = ""
-	return u
+// headFallbacks are tried in order when HEAD can't be resolved,
+// e.g. when HEAD points to "master" while the default branch of the remote is "main".
+var headFallbacks = []plumbing.ReferenceName{
+	"refs/remotes/origin/HEAD",
+	plumbing.NewBranchReferenceName("main"),
+	plumbing.NewBranchReferenceName("master"),
+	plumbing.NewRemoteReferenceName("origin", "main"),
+	plumbing.NewRemoteReferenceName("origin", "master"),
 }
 
-func matchPath(path string) bool {
-	path = filepath.Base(path)
-	if path == "openvex.json" || path == "vex.json" ||
-		strings.HasSuffix(path, ".openvex.json") || strings.HasSuffix(path, ".vex.json") {
-		return true
+// resolveHead returns the commit checked out in the repository,
+// falling back to the default branch of the remote if HEAD can't be resolved.
+func resolveHead(repo *git.Repository) (plumbing.Hash, error) {
+	head, err := repo.Head()
+	if err == nil {
+		return head.Hash(), nil
 	}
-	return false
+	for _, name := range headFallbacks {
+		if ref, rerr := repo.Reference(name, true); rerr == nil {
+			return ref.Hash(), nil
+		}
+	}
+	return plumbing.ZeroHash, oops.Wrapf(err, "failed to resolve HEAD")
+}
+
+// openVEX opens the OpenVEX or CSAF document on the filesystem.
+// go-vex reads legacy OpenVEX and CSAF documents from files by itself,
+// so only documents of the current OpenVEX version are supported on other filesystems.
+func openVEX(fsys FS, path string) (*vex.VEX, int, error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, 0, oops.Wrapf(err, "failed to read the file")
+	}
+	var head struct {
+		Context string `json:"@context"`
+	}
+	if err = json.Unmarshal(data, &head); err == nil && head.Context == vex.ContextLocator() {
+		data, invalid, err := dropMalformedTimestamps(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, err := vex.Parse(data)
+		return v, invalid, err
+	} else if isOSFS(fsys) {
+		// Legacy OpenVEX and CSAF documents
+		v, err := vex.Open(path)
+		return v, 0, err
+	} else if err != nil {
+		return nil, 0, oops.Wrapf(err, "failed to decode the file")
+	}
+	return nil, 0, oops.With("context", head.Context).Errorf("unsupported document on the filesystem")
 }
 
-func validateVEX(path, purl string) error {
-	v, err := vex.Open(path)
+// validateVEX parses the VEX document and checks that it contains the PURL,
+// as a product or as a subcomponent of a product if Options.MatchSubcomponents is set.
+// The parsed document is returned along with ErrPURLMismatch, ErrEmbargoed and ErrFilteredOut for diagnostics.
+func validateVEX(path, purl string, opts Options) (*Document, error) {
+	v, invalid, err := openVEX(opts.fs(), path)
 	if err != nil {
-		return oops.Wrapf(err, "failed to open VEX file")
+		return nil, oops.Wrapf(err, "failed to open VEX file")
+	}
+	return checkVEX(v, invalid, purl, opts)
+}
+
+// checkVEX checks the parsed OpenVEX document like validateVEX, where invalid is the number of malformed
+// statement timestamps dropped while parsing.
+func checkVEX(v *vex.VEX, invalid int, purl string, opts Options) (*Document, error) {
+	doc := &Document{
+		Format:            FormatOpenVEX,
+		InvalidTimestamps: invalid,
+	}
+	if v.Timestamp == nil {
+		// The statements whose malformed timestamp was dropped are counted as invalid only
+		doc.MissingTimestamps = missingTimestamps(v) - invalid
+	}
+	doc.Withheld = withholdStatements(v, opts.Embargoed)
+	doc.Filtered = filterStatuses(v, opts.Statuses)
+	if doc.Withheld > 0 || doc.Filtered > 0 || invalid > 0 {
+		// Write the remaining statements, or the document without the malformed timestamps, instead of the original
+		var buf bytes.Buffer
+		if err := v.ToJSON(&buf); err != nil {
+			return nil, oops.Wrapf(err, "failed to encode VEX")
+		}
+		doc.Content = buf.Bytes()
+	}
+	doc.Products = productIDs(v)
+	doc.Vulnerabilities = vulnerabilityIDs(v)
+	doc.Statements = len(v.Statements)
+	doc.MissingJustifications = missingJustifications(v)
+	if v.Author != "" {
+		doc.Authors = []string{v.Author}
+	}
+
+	if len(v.Statements) == 0 && doc.Withheld > 0 {
+		return doc, ErrEmbargoed
+	} else if len(v.Statements) == 0 && doc.Filtered > 0 {
+		return doc, ErrFilteredOut
 	} else if len(v.Statements) == 0 {
-		return errNoStatement
+		return nil, ErrNoStatement
+	}
+	for _, statement := range v.Statements {
+		for _, product := range statement.Products {
+			if matcher, ok := MatchProduct(purl, product.ID, opts); ok {
+				doc.Matcher = matcher
+				return doc, checkStatements(doc, opts)
+			}
+			if !opts.MatchSubcomponents {
+				continue
+			}
+			for _, sub := range product.Subcomponents {
+				if matcher, ok := MatchProduct(purl, sub.ID, opts); ok {
+					doc.Matcher, doc.Subcomponent = matcher, true
+					return doc, checkStatements(doc, opts)
+				}
+			}
+		}
+	}
+	return doc, ErrPURLMismatch
+}
+
+// checkStatements returns the error of the first strict check of the statements failing, if any.
+func checkStatements(doc *Document, opts Options) error {
+	if err := checkTimestamps(doc, opts); err != nil {
+		return err
+	}
+	return checkJustifications(doc, opts)
+}
+
+// checkJustifications returns ErrMissingJustification if any not_affected statement of the document has
+// no justification and Options.StrictJustifications is set.
+func checkJustifications(doc *Document, opts Options) error {
+	if opts.StrictJustifications && doc.MissingJustifications > 0 {
+		return ErrMissingJustification
+	}
+	return nil
+}
+
+// checkTimestamps returns ErrInvalidTimestamp if any statement of the document has a missing or malformed timestamp
+// and Options.StrictTimestamps is set.
+func checkTimestamps(doc *Document, opts Options) error {
+	if opts.StrictTimestamps && doc.MissingTimestamps+doc.InvalidTimestamps > 0 {
+		return ErrInvalidTimestamp
+	}
+	return nil
+}
+
+// validateCSAF validates the CSAF document in the same way as OpenVEX since go-vex converts it.
+func validateCSAF(path, purl string, opts Options) (*Document, error) {
+	doc, err := validateVEX(path, purl, opts)
+	if doc == nil {
+		return nil, err
+	}
+	if doc.Content == nil {
+		// The original CSAF document is copied as is, otherwise it's rewritten as OpenVEX
+		doc.Format = FormatCSAF
+	}
+
+	// go-vex doesn't convert the publisher of CSAF documents
+	publisher, perr := csafPublisher(opts.fs(), path)
+	if perr != nil {
+		return nil, perr
+	} else if publisher != "" {
+		doc.Authors = []string{publisher}
+	}
+	return doc, err
+}
+
+// csafPublisher returns the name of the publisher of the CSAF document.
+func csafPublisher(fsys FS, path string) (string, error) {
+	b, err := fsys.ReadFile(path)
+	if err != nil {
+		return "", oops.Wrapf(err, "failed to read CSAF file")
+	}
+	var csaf struct {
+		Document struct {
+			Publisher struct {
+				Name string `json:"name"`
+			} `json:"publisher"`
+		} `json:"document"`
+	}
+	if err = json.Unmarshal(b, &csaf); err != nil {
+		return "", oops.Wrapf(err, "failed to decode CSAF file")
+	}
+	return csaf.Document.Publisher.Name, nil
+}
+
+// withholdStatements removes the statements about embargoed vulnerabilities
+// and returns the number of removed statements.
+func withholdStatements(v *vex.VEX, embargoed func(string) bool) int {
+	if embargoed == nil {
+		return 0
+	}
+	var statements []vex.Statement
+	for _, statement := range v.Statements {
+		if isEmbargoed(statement.Vulnerability, embargoed) {
+			continue
+		}
+		statements = append(statements, statement)
+	}
+	withheld := len(v.Statements) - len(statements)
+	v.Statements = statements
+	return withheld
+}
+
+func isEmbargoed(vuln vex.Vulnerability, embargoed func(string) bool) bool {
+	if embargoed(string(vuln.Name)) || embargoed(vuln.ID) {
+		return true
 	}
+	for _, alias := range vuln.Aliases {
+		if embargoed(string(alias)) {
+			return true
+		}
+	}
+	return false
+}
+
+// productIDs returns the unique product IDs declared in the VEX document.
+func productIDs(v *vex.VEX) []string {
+	var ids []string
+	seen := make(map[string]struct{})
 	for _, statement := range v.Statements {
 		for _, product := range statement.Products {
-			if vex.PurlMatches(purl, product.ID) {
-				return nil
+			if _, ok := seen[product.ID]; ok {
+				continue
 			}
+			seen[product.ID] = struct{}{}
+			ids = append(ids, product.ID)
+		}
+	}
+	return ids
+}
+
+// splitGroups returns the groups of the vulnerabilities whose statements are split across multiple sources.
+func splitGroups(groups map[string][]string) map[string][]string {
+	split := make(map[string][]string)
+	for vulnID, paths := range groups {
+		if len(paths) > 1 {
+			slices.Sort(paths)
+			split[vulnID] = paths
 		}
 	}
-	return errPURLMismatch
+	if len(split) == 0 {
+		return nil
+	}
+	return split
+}
+
+// vulnerabilityIDs returns the unique vulnerability IDs of the statements.
+func vulnerabilityIDs(v *vex.VEX) []string {
+	var ids []string
+	for _, statement := range v.Statements {
+		id := string(statement.Vulnerability.Name)
+		if id == "" {
+			id = statement.Vulnerability.ID
+		}
+		if id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// foundProducts returns the product IDs for diagnostics, truncated to maxFoundProducts to avoid log spam.
+func foundProducts(ids []string) []string {
+	if len(ids) <= maxFoundProducts {
+		return ids
+	}
+	truncated := slices.Clone(ids[:maxFoundProducts])
+	return append(truncated, fmt.Sprintf("... and %d more", len(ids)-maxFoundProducts))
 }
 
 func fileSource(relPath string, url *xurl.URL, permaLink *url.URL) *manifest.Source {
 	source := manifest.Source{
 		Path: filepath.Base(relPath),
-		URL:  url.String(),
+		URL:  url.Public(), // The credentials in the config must not be published
 	}
 	if permaLink != nil {
 		l := *permaLink
@@ -206,63 +1493,144 @@ func fileSource(relPath string, url *xurl.URL, permaLink *url.URL) *manifest.Sou
 	return &source
 }
 
-// resetDir removes all files other than manifest.json in the directory and creates a new directory.
-func resetDir(dir string) error {
-	entries, err := os.ReadDir(dir)
+// resetDir removes all files other than the kept ones, such as the manifest, in the directory and creates a new directory.
+// Subdirectories are kept as they may belong to other packages (subpaths) or versions.
+func resetDir(fsys FS, dir string, keep ...string) error {
+	entries, err := fsys.ReadDir(dir)
 	if err != nil && !os.IsNotExist(err) {
 		return oops.Wrapf(err, "failed to read the directory")
 	}
 	for _, entry := range entries {
-		if !entry.IsDir() && entry.Name() == manifest.FileName {
+		if entry.IsDir() || slices.Contains(keep, entry.Name()) {
 			continue
 		}
-		if err = os.RemoveAll(entry.Name()); err != nil {
-			return oops.With("file_path", entry.Name()).Wrapf(err, "failed to remove the directory")
+		filePath := filepath.Join(dir, entry.Name())
+		if err = fsys.Remove(filePath); err != nil {
+			return oops.With("file_path", filePath).Wrapf(err, "failed to remove the file")
 		}
 	}
-	if err = os.MkdirAll(dir, 0755); err != nil {
+	if err = fsys.MkdirAll(dir, 0755); err != nil {
 		return oops.With("dir", dir).Wrapf(err, "failed to create a director")
 	}
 	return nil
 }
 
-// hasVEXChanges checks if there are any changes in the .vex/ directory excluding the manifest.json file
-func hasVEXChanges(vexHubDir, vexDir string) (bool, error) {
+// searchRoot returns the directory walked for VEX documents in the repository, which is the subdirectory
+// or Options.Subdir overriding it, or a root directory depending on Options.RootDirMode.
+func searchRoot(fsys FS, repoDir, subdir string, opts Options) (string, error) {
+	// Already rejected when the URL is parsed, but the walk must never leave the repository
+	if err := xurl.CheckSubdirs(subdir); err != nil {
+		return "", oops.With("subdirs", subdir).Wrap(err)
+	}
+	dir := filepath.Join(repoDir, subdir)
+	if opts.Subdir != "" {
+		dir = filepath.Join(repoDir, filepath.Clean("/"+opts.Subdir)) // Don't escape the repository
+	}
+
+	switch opts.RootDirMode {
+	case "", RootDirModeSubdirThenVEX:
+		return markedRoot(fsys, dir, opts.rootDirs()), nil
+	case RootDirModeSubdirOnly:
+		return dir, nil
+	case RootDirModeVEXAnywhere:
+		// The nearest root directory wins, and the parents outside the repository are never searched
+		repoDir = filepath.Clean(repoDir)
+		for d := dir; strings.HasPrefix(d, repoDir); d = filepath.Dir(d) {
+			if marked := markedRoot(fsys, d, opts.rootDirs()); marked != d {
+				return marked, nil
+			} else if d == repoDir {
+				break
+			}
+		}
+		return dir, nil
+	}
+	return "", oops.With("mode", opts.RootDirMode).Errorf("unknown root dir mode")
+}
+
+// markedRoot returns the first of the root directories existing in the directory,
+// or the directory itself if none exists. Entries that are not directories, e.g. a file named ".vex", are ignored.
+func markedRoot(fsys FS, dir string, rootDirs []string) string {
+	for _, rootDir := range rootDirs {
+		marked := filepath.Join(dir, filepath.Clean("/"+filepath.FromSlash(rootDir))) // Don't escape the directory
+		if fi, err := fsys.Stat(marked); err == nil && fi.IsDir() {
+			return marked
+		} else if err == nil {
+			slog.Debug("Ignored the root directory that is not a directory", slog.String("path", marked))
+		}
+	}
+	return dir
+}
+
+// hasVEXChanges checks if there are any changes in the package directory excluding the manifest files,
+// compared with the git index of VEX Hub. Only the files directly in the directory are compared, not nested
+// packages or versions, so the check doesn't scan the status of the whole worktree for each package,
+// and it's unaffected by the other packages being written concurrently.
+// With semantic, modified files are compared by content, and restored if only their formatting changed
+// while nothing else did, see Options.SemanticChanges.
+func hasVEXChanges(vexHubDir, vexDir string, manifestFiles []string, semantic bool) (bool, error) {
 	errBuilder := oops.In("git_error").With("vex_hub_dir", vexHubDir).With("dir", vexDir)
-	// Open the repository
 	repo, err := git.PlainOpen(vexHubDir)
 	if err != nil {
 		return false, errBuilder.Wrapf(err, "open git repository")
 	}
-
-	// Get the worktree
-	wt, err := repo.Worktree()
+	idx, err := repo.Storer.Index()
 	if err != nil {
-		return false, errBuilder.Wrapf(err, "git worktree")
+		return false, errBuilder.Wrapf(err, "git index")
 	}
-
-	// Get the current status
-	status, err := wt.Status()
+	relVexDir, err := filepath.Rel(vexHubDir, vexDir)
 	if err != nil {
-		return false, errBuilder.Wrapf(err, "git status")
+		return false, errBuilder.Wrapf(err, "relative path")
 	}
 
-	// Get the relative path of vexDir from vexHubDir
-	relVexDir, err := filepath.Rel(vexHubDir, vexDir)
+	// Blob hashes of the files tracked in vexDir
+	prefix := filepath.ToSlash(relVexDir) + "/"
+	tracked := make(map[string]plumbing.Hash)
+	for _, e := range idx.Entries {
+		if name, ok := strings.CutPrefix(e.Name, prefix); ok && !strings.Contains(name, "/") && !slices.Contains(manifestFiles, name) {
+			tracked[name] = e.Hash
+		}
+	}
+
+	entries, err := os.ReadDir(LongPath(vexDir))
 	if err != nil {
-		return false, errBuilder.Wrapf(err, "relative path")
+		return false, errBuilder.Wrapf(err, "failed to read the directory")
 	}
+	reformatted := make(map[string][]byte) // Indexed content of the files only changed in formatting
+	for _, entry := range entries {
+		if entry.IsDir() || slices.Contains(manifestFiles, entry.Name()) {
+			continue
+		}
+		hash, ok := tracked[entry.Name()]
+		if !ok {
+			return true, nil // Untracked
+		}
+		delete(tracked, entry.Name())
+		content, err := os.ReadFile(LongPath(filepath.Join(vexDir, entry.Name())))
+		if err != nil {
+			return false, errBuilder.With("file_name", entry.Name()).Wrapf(err, "failed to read the file")
+		} else if plumbing.ComputeHash(plumbing.BlobObject, content) == hash {
+			continue
+		} else if !semantic {
+			return true, nil // Modified
+		}
 
-	// Check for changes in vexDir excluding manifest.json
-	for filePath, fileStatus := range status {
-		// Check if the file is within vexDir
-		if strings.HasPrefix(filePath, relVexDir) {
-			// Exclude manifest.json
-			if filepath.Base(filePath) != manifest.FileName && fileStatus.Worktree != git.Unmodified {
-				return true, nil
-			}
+		indexed, err := readBlob(repo, hash)
+		if err != nil {
+			return false, errBuilder.With("file_name", entry.Name()).Wrap(err)
+		} else if !semanticEqual(entry.Name(), indexed, content) {
+			return true, nil
 		}
+		reformatted[entry.Name()] = indexed
+	}
+	if len(tracked) > 0 {
+		return true, nil // Deleted
 	}
 
+	// Restore the indexed files so that they match the digests of the manifest that is kept
+	for name, content := range reformatted {
+		if err = os.WriteFile(LongPath(filepath.Join(vexDir, name)), content, 0644); err != nil {
+			return false, errBuilder.With("file_name", name).Wrapf(err, "failed to restore the file")
+		}
+	}
 	return false, nil
 }
@@ -1,11 +1,18 @@
 package vex_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,13 +20,16 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 	openvex "github.com/openvex/go-vex/pkg/vex"
 	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
 	"github.com/sosedoff/gitkit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
 	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
 	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+	"github.com/aquasecurity/vexhub-crawler/pkg/vexhub"
 )
 
 var signature = &object.Signature{
@@ -48,9 +58,14 @@ func NewServer(t *testing.T, repo string, setup func(*testing.T, string)) *httpt
 	})
 	require.NoError(t, err)
 
+	return serveRepo(t, repo, wtDir)
+}
+
+// serveRepo serves a bare clone of the working tree as a Git server.
+func serveRepo(t *testing.T, repo, wtDir string) *httptest.Server {
 	bareDir := t.TempDir()
 	gitDir := filepath.Join(bareDir, repo+".git")
-	_, err = git.PlainClone(gitDir, true, &git.CloneOptions{URL: wtDir})
+	_, err := git.PlainClone(gitDir, true, &git.CloneOptions{URL: wtDir})
 	require.NoError(t, err)
 
 	service := gitkit.New(gitkit.Config{
@@ -63,12 +78,26 @@ func NewServer(t *testing.T, repo string, setup func(*testing.T, string)) *httpt
 	return httptest.NewServer(service)
 }
 
+// clearDigests checks the digests of the sources against the files stored in the directory and clears them,
+// so that the manifests can be compared regardless of the stored content.
+func clearDigests(t *testing.T, dir string, sources []manifest.Source, readFile func(string) ([]byte, error)) {
+	t.Helper()
+	for i, src := range sources {
+		b, err := readFile(filepath.Join(dir, src.Path))
+		require.NoError(t, err)
+		require.NoError(t, manifest.VerifyDigest(src.Digest, bytes.NewReader(b)), src.Path)
+		sources[i].Digest = ""
+	}
+}
+
 func TestCrawlPackage(t *testing.T) {
 	tests := []struct {
 		name         string
 		purl         string
+		opts         vex.Options
 		want         openvex.VEX
 		wantManifest manifest.Manifest
+		wantSkipped  []vex.SkippedFile
 		wantErr      string
 		setup        func(*testing.T, string) // Additional setup function for complex cases
 	}{
@@ -101,12 +130,53 @@ func TestCrawlPackage(t *testing.T) {
 				ID: "pkg:golang/github.com/example/package@v1.2.3",
 				Sources: []manifest.Source{
 					{
-						Path: "openvex.json",
+						Path:           "openvex.json",
+						Format:         "openvex",
+						StatementCount: 1,
 						// URL will be set dynamically in the test
 					},
 				},
 			},
 		},
+		{
+			name: "valid VEX file with write verification",
+			purl: "pkg:golang/github.com/example/package@v1.2.3",
+			opts: vex.Options{
+				VerifyWrites: true,
+			},
+			want: openvex.VEX{
+				Metadata: openvex.Metadata{
+					Context: openvex.ContextLocator(),
+					ID:      "https://example.com/vex-1234",
+					Author:  "Example Corp.",
+					Version: 1,
+				},
+				Statements: []openvex.Statement{
+					{
+						Vulnerability: openvex.Vulnerability{ID: "CVE-2023-1234"},
+						Products: []openvex.Product{
+							{
+								Component: openvex.Component{
+									ID: "pkg:golang/github.com/example/package@v1.2.3",
+								},
+							},
+						},
+						Status:        openvex.StatusNotAffected,
+						Justification: openvex.VulnerableCodeNotPresent,
+					},
+				},
+			},
+			wantManifest: manifest.Manifest{
+				ID: "pkg:golang/github.com/example/package@v1.2.3",
+				Sources: []manifest.Source{
+					{
+						Path:           "openvex.json",
+						Format:         "openvex",
+						StatementCount: 1,
+					},
+				},
+			},
+		},
 		{
 			name: "no statements in VEX file",
 			purl: "pkg:golang/github.com/example/package@v1.2.3",
@@ -146,8 +216,45 @@ func TestCrawlPackage(t *testing.T) {
 					},
 				},
 			},
+			wantSkipped: []vex.SkippedFile{
+				{
+					Path:     ".vex/openvex.json",
+					Reason:   "PURL does not match",
+					Products: []string{"pkg:golang/github.com/other/package@v1.2.3"},
+				},
+			},
 			wantErr: "no VEX file found",
 		},
+		{
+			name: "PURL mismatch in strict mode",
+			purl: "pkg:golang/github.com/example/package@v1.2.3",
+			opts: vex.Options{
+				StrictPURL: true,
+			},
+			want: openvex.VEX{
+				Metadata: openvex.Metadata{
+					Context: openvex.ContextLocator(),
+					ID:      "https://example.com/vex-1234",
+					Author:  "Example Corp.",
+					Version: 1,
+				},
+				Statements: []openvex.Statement{
+					{
+						Vulnerability: openvex.Vulnerability{ID: "CVE-2023-1234"},
+						Products: []openvex.Product{
+							{
+								Component: openvex.Component{
+									ID: "pkg:golang/github.com/other/package@v1.2.3",
+								},
+							},
+						},
+						Status:        openvex.StatusNotAffected,
+						Justification: openvex.ComponentNotPresent,
+					},
+				},
+			},
+			wantErr: "strict PURL: PURL does not match",
+		},
 		{
 			name: "OCI package",
 			purl: "pkg:oci/myimage@sha256:123456?repository_url=example.com/repo",
@@ -177,12 +284,50 @@ func TestCrawlPackage(t *testing.T) {
 				ID: "pkg:oci/myimage@sha256%3A123456?repository_url=example.com%2Frepo",
 				Sources: []manifest.Source{
 					{
-						Path: "openvex.json",
+						Path:           "openvex.json",
+						Format:         "openvex",
+						StatementCount: 1,
 						// URL will be set dynamically in the test
 					},
 				},
 			},
 		},
+		{
+			name: "package without namespace",
+			purl: "pkg:pypi/requests@2.31.0",
+			want: openvex.VEX{
+				Metadata: openvex.Metadata{
+					Context: openvex.ContextLocator(),
+					ID:      "https://example.com/vex-1234",
+					Author:  "Example Corp.",
+					Version: 1,
+				},
+				Statements: []openvex.Statement{
+					{
+						Vulnerability: openvex.Vulnerability{ID: "CVE-2023-1234"},
+						Products: []openvex.Product{
+							{
+								Component: openvex.Component{
+									ID: "pkg:pypi/requests@2.31.0",
+								},
+							},
+						},
+						Status:        openvex.StatusNotAffected,
+						Justification: openvex.VulnerableCodeNotPresent,
+					},
+				},
+			},
+			wantManifest: manifest.Manifest{
+				ID: "pkg:pypi/requests@2.31.0",
+				Sources: []manifest.Source{
+					{
+						Path:           "openvex.json",
+						Format:         "openvex",
+						StatementCount: 1,
+					},
+				},
+			},
+		},
 		{
 			name: "non-matching file",
 			purl: "pkg:golang/github.com/example/package@v1.2.3",
@@ -226,12 +371,17 @@ func TestCrawlPackage(t *testing.T) {
 			u, err := url.Parse(server.URL + "/testrepo.git")
 			require.NoError(t, err)
 
-			err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl)
+			result, err := vex.CrawlPackage(context.Background(), vexHubDir, u, purl, tt.opts)
+			assert.Equal(t, tt.wantSkipped, result.Skipped)
 			if tt.wantErr != "" {
 				assert.ErrorContains(t, err, tt.wantErr)
 				return
 			}
 			require.NoError(t, err)
+			assert.Positive(t, result.DownloadedBytes)
+			assert.Positive(t, result.DownloadedFiles)
+			assert.Equal(t, 1, result.AcceptedFiles)
+			assert.Equal(t, result.AcceptedFiles+len(result.Skipped), result.CandidateFiles)
 
 			var vexPath string
 			if purl.Type == packageurl.TypeOCI {
@@ -272,8 +422,1193 @@ func TestCrawlPackage(t *testing.T) {
 			assert.NoError(t, err)
 
 			tt.wantManifest.Sources[0].URL = server.URL + "/testrepo.git"
+			assert.Len(t, gotManifest.Commit, 40)
+			gotManifest.Commit = "" // The commit hash is not deterministic
+			clearDigests(t, filepath.Dir(manifestPath), gotManifest.Sources, os.ReadFile)
 
 			assert.Equal(t, tt.wantManifest, gotManifest)
 		})
 	}
 }
+
+func TestPackageDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		purl    string
+		version string
+		want    string
+	}{
+		{
+			name: "with namespace",
+			purl: "pkg:golang/github.com/example/package@v1.2.3",
+			want: "/hub/pkg/golang/github.com/example/package",
+		},
+		{
+			name: "empty namespace",
+			purl: "pkg:pypi/requests@2.31.0",
+			want: "/hub/pkg/pypi/requests",
+		},
+		{
+			name: "empty namespace with subpath",
+			purl: "pkg:pypi/requests#docs",
+			want: "/hub/pkg/pypi/requests/docs",
+		},
+		{
+			name: "namespace with subpath",
+			purl: "pkg:golang/github.com/example/package#cmd/tool",
+			want: "/hub/pkg/golang/github.com/example/package/cmd/tool",
+		},
+		{
+			name:    "empty namespace with version",
+			purl:    "pkg:cargo/serde",
+			version: "v1.0.0",
+			want:    "/hub/pkg/cargo/serde/v1.0.0",
+		},
+		{
+			name: "OCI",
+			purl: "pkg:oci/myimage@sha256:123456?repository_url=example.com/repo",
+			want: "/hub/pkg/oci/example.com/repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purl, err := packageurl.FromString(tt.purl)
+			require.NoError(t, err)
+
+			got := vex.PackageDir("/hub", purl, tt.version)
+			assert.Equal(t, filepath.FromSlash(tt.want), got)
+			assert.NotContains(t, got, "//")
+		})
+	}
+}
+
+func TestVersionTag(t *testing.T) {
+	wtDir := t.TempDir()
+	r, err := git.PlainInit(wtDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	writeVEX(t, wtDir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	hash, err := wt.Commit("initial commit", &git.CommitOptions{Author: signature})
+	require.NoError(t, err)
+	for _, tag := range []string{"v1.0.0", "2.0.0", "package-v3.0.0"} {
+		_, err = r.CreateTag(tag, hash, nil)
+		require.NoError(t, err)
+	}
+	// Annotated tags are listed along with the peeled ones
+	_, err = r.CreateTag("package/v4.0.0", hash, &git.CreateTagOptions{Tagger: signature, Message: "v4.0.0"})
+	require.NoError(t, err)
+
+	server := serveRepo(t, "testrepo", wtDir)
+	defer server.Close()
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{version: "v1.0.0", want: "v1.0.0"},
+		{version: "1.0.0", want: "v1.0.0"},
+		{version: "v2.0.0", want: "2.0.0"},
+		{version: "3.0.0", want: "package-v3.0.0"},
+		{version: "v4.0.0", want: "package/v4.0.0"},
+		{version: "5.0.0", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := vex.VersionTag(context.Background(), u, "package", tt.version)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCrawlPackage_Tag(t *testing.T) {
+	wtDir := t.TempDir()
+	r, err := git.PlainInit(wtDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	// Commit a VEX document for each version and tag it
+	for _, tag := range []string{"v1.0.0", "v1.1.0", "v2.0.0"} {
+		writeVEX(t, wtDir, "pkg:golang/github.com/example/package", "CVE-"+tag)
+		_, err = wt.Add(".")
+		require.NoError(t, err)
+		hash, err := wt.Commit(tag, &git.CommitOptions{Author: signature})
+		require.NoError(t, err)
+		_, err = r.CreateTag(tag, hash, nil)
+		require.NoError(t, err)
+	}
+	hash, err := r.Head()
+	require.NoError(t, err)
+	_, err = r.CreateTag("not-semver", hash.Hash(), nil)
+	require.NoError(t, err)
+
+	server := serveRepo(t, "testrepo", wtDir)
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+
+	tags, err := vex.LatestTags(context.Background(), u, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v2.0.0", "v1.1.0"}, tags)
+
+	u.SetRef("v1.1.0")
+	vexHubDir := t.TempDir()
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{Version: "v1.1.0"})
+	require.NoError(t, err)
+
+	dir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package", "v1.1.0")
+	doc, err := openvex.Open(filepath.Join(dir, "openvex.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "CVE-v1.1.0", doc.Statements[0].Vulnerability.ID)
+
+	m, err := manifest.Read(filepath.Join(dir, manifest.FileName))
+	require.NoError(t, err)
+	assert.Equal(t, "pkg:golang/github.com/example/package@v1.1.0", m.ID)
+}
+
+func writeVEX(t *testing.T, dir, productID, vulnID string) {
+	doc := openvex.VEX{
+		Metadata: openvex.Metadata{
+			Context: openvex.ContextLocator(),
+			ID:      "https://example.com/vex-1234",
+			Author:  "Example Corp.",
+			Version: 1,
+		},
+		Statements: []openvex.Statement{
+			{
+				Vulnerability: openvex.Vulnerability{ID: vulnID},
+				Products: []openvex.Product{
+					{Component: openvex.Component{ID: productID}},
+				},
+				Status:        openvex.StatusNotAffected,
+				Justification: openvex.VulnerableCodeNotPresent,
+			},
+		},
+	}
+	vexDir := filepath.Join(dir, ".vex")
+	require.NoError(t, os.MkdirAll(vexDir, 0755))
+
+	content, err := json.Marshal(doc)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(vexDir, "openvex.json"), content, 0644))
+}
+
+func TestCrawlPackage_Offline(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		args []string // git command to create the file in the repository
+	}{
+		{
+			name: "git bundle",
+			file: "repo.bundle",
+			args: []string{"bundle", "create", "../repo.bundle", "--all"},
+		},
+		{
+			name: "tarball",
+			file: "repo.tar.gz",
+			args: []string{"archive", "--format=tar.gz", "--output=../repo.tar.gz", "HEAD"},
+		},
+		{
+			name: "zip",
+			file: "repo.zip",
+			args: []string{"archive", "--format=zip", "--prefix=repo/", "--output=../repo.zip", "HEAD"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wtDir := filepath.Join(dir, "repo")
+			r, err := git.PlainInit(wtDir, false)
+			require.NoError(t, err)
+			wt, err := r.Worktree()
+			require.NoError(t, err)
+
+			writeVEX(t, wtDir, "pkg:golang/github.com/example/package", "CVE-2023-1234")
+			_, err = wt.Add(".")
+			require.NoError(t, err)
+			_, err = wt.Commit("initial commit", &git.CommitOptions{Author: signature})
+			require.NoError(t, err)
+
+			cmd := exec.Command("git", tt.args...)
+			cmd.Dir = wtDir
+			require.NoError(t, cmd.Run())
+
+			src := filepath.Join(dir, tt.file)
+			u, err := url.Parse(src)
+			require.NoError(t, err)
+
+			purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+			require.NoError(t, err)
+
+			vexHubDir := t.TempDir()
+			_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{})
+			require.NoError(t, err)
+
+			vexDir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package")
+			assert.FileExists(t, filepath.Join(vexDir, "openvex.json"))
+
+			// No permalink is available without a remote
+			m, err := manifest.Read(filepath.Join(vexDir, manifest.FileName))
+			require.NoError(t, err)
+			assert.Equal(t, src, m.Sources[0].URL)
+		})
+	}
+}
+
+func TestCrawlPackage_ZipSlip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "archive", "evil.zip")
+	require.NoError(t, os.MkdirAll(filepath.Dir(src), 0755))
+	f, err := os.Create(src)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("../../escaped.openvex.json")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("{}"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	u, err := url.Parse(src)
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	_, err = vex.CrawlPackage(context.Background(), t.TempDir(), u, purl, vex.Options{})
+	require.ErrorContains(t, err, "entry contains '..'")
+	assert.NoFileExists(t, filepath.Join(dir, "escaped.openvex.json"))
+}
+
+func TestCrawlPackage_Embargo(t *testing.T) {
+	statement := func(vulnID string) openvex.Statement {
+		return openvex.Statement{
+			Vulnerability: openvex.Vulnerability{Name: openvex.VulnerabilityID(vulnID)},
+			Products: []openvex.Product{
+				{Component: openvex.Component{ID: "pkg:golang/github.com/example/package"}},
+			},
+			Status:        openvex.StatusNotAffected,
+			Justification: openvex.VulnerableCodeNotPresent,
+		}
+	}
+	embargoed := func(vulnID string) bool {
+		return vulnID == "CVE-2024-0001"
+	}
+
+	tests := []struct {
+		name           string
+		statements     []openvex.Statement
+		wantStatements []openvex.Statement
+		wantSkipped    []vex.SkippedFile
+		wantErr        string
+	}{
+		{
+			name:           "embargoed statement withheld",
+			statements:     []openvex.Statement{statement("CVE-2024-0001"), statement("CVE-2024-0002")},
+			wantStatements: []openvex.Statement{statement("CVE-2024-0002")},
+		},
+		{
+			name:       "all statements embargoed",
+			statements: []openvex.Statement{statement("CVE-2024-0001")},
+			wantSkipped: []vex.SkippedFile{
+				{
+					Path:   ".vex/openvex.json",
+					Reason: "all statements are embargoed",
+				},
+			},
+			wantErr: "no VEX file found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+				doc := openvex.New()
+				doc.Statements = tt.statements
+
+				vexDir := filepath.Join(dir, ".vex")
+				require.NoError(t, os.MkdirAll(vexDir, 0755))
+				content, err := json.Marshal(doc)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(vexDir, "openvex.json"), content, 0644))
+			})
+			defer server.Close()
+
+			u, err := url.Parse(server.URL + "/testrepo.git")
+			require.NoError(t, err)
+			purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+			require.NoError(t, err)
+
+			vexHubDir := t.TempDir()
+			result, err := vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{Embargoed: embargoed})
+			assert.Equal(t, tt.wantSkipped, result.Skipped)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := openvex.Open(filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package", "openvex.json"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatements, got.Statements)
+		})
+	}
+}
+
+func TestCrawlPackage_Authors(t *testing.T) {
+	tests := []struct {
+		name        string
+		authors     []string
+		trusted     bool
+		wantSkipped []vex.SkippedFile
+		wantErr     string
+	}{
+		{
+			name: "no restriction",
+		},
+		{
+			name:    "unknown author of trusted source",
+			authors: []string{"Other Corp."},
+			trusted: true,
+		},
+		{
+			name:    "allowed author",
+			authors: []string{"Other Corp.", "Example Corp."},
+		},
+		{
+			name:    "unknown author",
+			authors: []string{"Other Corp."},
+			wantSkipped: []vex.SkippedFile{
+				{
+					Path:   ".vex/openvex.json",
+					Reason: "author is not allowed",
+				},
+			},
+			wantErr: "no VEX file found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+				writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+			})
+			defer server.Close()
+
+			u, err := url.Parse(server.URL + "/testrepo.git")
+			require.NoError(t, err)
+			purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+			require.NoError(t, err)
+
+			vexHubDir := t.TempDir()
+			result, err := vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{Authors: tt.authors, Trusted: tt.trusted})
+			assert.Equal(t, tt.wantSkipped, result.Skipped)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package", "openvex.json"))
+		})
+	}
+}
+
+func TestCrawlPackage_FoundProducts(t *testing.T) {
+	tests := []struct {
+		name     string
+		products int
+		want     []string
+	}{
+		{
+			name:     "few products",
+			products: 2,
+			want: []string{
+				"pkg:golang/github.com/other/package0",
+				"pkg:golang/github.com/other/package1",
+			},
+		},
+		{
+			name:     "too many products",
+			products: 12,
+			want: []string{
+				"pkg:golang/github.com/other/package0",
+				"pkg:golang/github.com/other/package1",
+				"pkg:golang/github.com/other/package2",
+				"pkg:golang/github.com/other/package3",
+				"pkg:golang/github.com/other/package4",
+				"pkg:golang/github.com/other/package5",
+				"pkg:golang/github.com/other/package6",
+				"pkg:golang/github.com/other/package7",
+				"pkg:golang/github.com/other/package8",
+				"pkg:golang/github.com/other/package9",
+				"... and 2 more",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+				doc := openvex.New()
+				for i := range tt.products {
+					doc.Statements = append(doc.Statements, openvex.Statement{
+						Vulnerability: openvex.Vulnerability{ID: "CVE-2024-0001"},
+						Products: []openvex.Product{
+							{Component: openvex.Component{ID: fmt.Sprintf("pkg:golang/github.com/other/package%d", i)}},
+						},
+						Status:        openvex.StatusNotAffected,
+						Justification: openvex.VulnerableCodeNotPresent,
+					})
+				}
+				content, err := json.Marshal(doc)
+				require.NoError(t, err)
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, ".vex"), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, ".vex", "openvex.json"), content, 0644))
+			})
+			defer server.Close()
+
+			u, err := url.Parse(server.URL + "/testrepo.git")
+			require.NoError(t, err)
+			purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+			require.NoError(t, err)
+
+			_, err = vex.CrawlPackage(context.Background(), t.TempDir(), u, purl, vex.Options{StrictPURL: true})
+			require.ErrorIs(t, err, vex.ErrPURLMismatch)
+
+			oopsErr, ok := oops.AsOops(err)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, oopsErr.Context()["found_products"])
+		})
+	}
+}
+
+func TestCrawlPackage_Overrides(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       vex.Options
+		wantFormat string
+		wantErr    string
+	}{
+		{
+			name: "patterns and subdir",
+			opts: vex.Options{
+				Patterns: []string{"advisory-*.json"},
+				Subdir:   "docs/security",
+			},
+			wantFormat: "openvex",
+		},
+		{
+			name: "forced format",
+			opts: vex.Options{
+				Patterns: []string{"advisory-*.json"},
+				Subdir:   "docs/security",
+				Format:   vex.FormatOpenVEX,
+			},
+			wantFormat: "openvex",
+		},
+		{
+			name: "subdir escaping the repository",
+			opts: vex.Options{
+				Patterns: []string{"advisory-*.json"},
+				Subdir:   "../../docs/security",
+			},
+			wantFormat: "openvex",
+		},
+		{
+			name:    "built-in patterns",
+			wantErr: "no VEX file found",
+		},
+		{
+			name: "wrong format",
+			opts: vex.Options{
+				Patterns: []string{"advisory-*.json"},
+				Subdir:   "docs/security",
+				Format:   vex.FormatCycloneDX,
+			},
+			wantErr: "not a CycloneDX document",
+		},
+		{
+			name: "unknown format",
+			opts: vex.Options{
+				Patterns: []string{"advisory-*.json"},
+				Format:   "spdx",
+			},
+			wantErr: "unknown format",
+		},
+	}
+
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+		docsDir := filepath.Join(dir, "docs", "security")
+		require.NoError(t, os.MkdirAll(docsDir, 0755))
+		require.NoError(t, os.Rename(filepath.Join(dir, ".vex", "openvex.json"), filepath.Join(docsDir, "advisory-2024.json")))
+	})
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(server.URL + "/testrepo.git")
+			require.NoError(t, err)
+			purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+			require.NoError(t, err)
+
+			vexHubDir := t.TempDir()
+			tt.opts.VerifyWrites = true
+			_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, tt.opts)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			m, err := manifest.Read(filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package", manifest.FileName))
+			require.NoError(t, err)
+			require.Len(t, m.Sources, 1)
+			assert.Equal(t, "advisory-2024.json", m.Sources[0].Path)
+			assert.Equal(t, tt.wantFormat, m.Sources[0].Format)
+		})
+	}
+}
+
+func TestCrawlPackage_CycloneDX(t *testing.T) {
+	tests := []struct {
+		name        string
+		bom         string
+		wantSkipped []vex.SkippedFile
+		wantErr     string
+	}{
+		{
+			name: "affected component by bom-ref",
+			bom: `{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.5",
+				"components": [
+					{"bom-ref": "pkg", "purl": "pkg:golang/github.com/example/package@v1.2.3"}
+				],
+				"vulnerabilities": [
+					{"id": "CVE-2023-1234", "analysis": {"state": "not_affected"}, "affects": [{"ref": "pkg"}]}
+				]
+			}`,
+		},
+		{
+			name: "affected component by PURL",
+			bom: `{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.5",
+				"vulnerabilities": [
+					{"id": "CVE-2023-1234", "affects": [{"ref": "pkg:golang/github.com/example/package@v1.2.3"}]}
+				]
+			}`,
+		},
+		{
+			name: "PURL mismatch",
+			bom: `{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.5",
+				"vulnerabilities": [
+					{"id": "CVE-2023-1234", "affects": [{"ref": "pkg:golang/github.com/other/package@v1.2.3"}]}
+				]
+			}`,
+			wantSkipped: []vex.SkippedFile{
+				{
+					Path:     ".vex/package.cdx.json",
+					Reason:   "PURL does not match",
+					Products: []string{"pkg:golang/github.com/other/package@v1.2.3"},
+				},
+			},
+			wantErr: "no VEX file found",
+		},
+		{
+			name: "no vulnerabilities",
+			bom: `{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.5"
+			}`,
+			wantErr: "no statement found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+				vexDir := filepath.Join(dir, ".vex")
+				require.NoError(t, os.MkdirAll(vexDir, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(vexDir, "package.cdx.json"), []byte(tt.bom), 0644))
+			})
+			defer server.Close()
+
+			u, err := url.Parse(server.URL + "/testrepo.git")
+			require.NoError(t, err)
+			purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+			require.NoError(t, err)
+
+			vexHubDir := t.TempDir()
+			result, err := vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{VerifyWrites: true})
+			assert.Equal(t, tt.wantSkipped, result.Skipped)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			dir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package")
+			assert.FileExists(t, filepath.Join(dir, "package.cdx.json"))
+
+			m, err := manifest.Read(filepath.Join(dir, manifest.FileName))
+			require.NoError(t, err)
+			assert.Equal(t, "cyclonedx", m.Sources[0].Format)
+		})
+	}
+}
+
+func TestCrawlPackage_ManifestName(t *testing.T) {
+	const manifestName = "vex-manifest.json"
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	vexHubDir := t.TempDir()
+	r, err := git.PlainInit(vexHubDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	pkgDir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package")
+	opts := vex.Options{ManifestName: manifestName}
+
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, opts)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(pkgDir, manifestName))
+	assert.NoFileExists(t, filepath.Join(pkgDir, manifest.FileName))
+
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	_, err = wt.Commit("crawl", &git.CommitOptions{Author: signature})
+	require.NoError(t, err)
+
+	// The manifest must survive the reset of the directory when there are no changes
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, opts)
+	require.NoError(t, err)
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), status.String())
+
+	m, err := manifest.Read(filepath.Join(pkgDir, manifestName))
+	require.NoError(t, err)
+	assert.Equal(t, "pkg:golang/github.com/example/package", m.ID)
+
+	// The index is generated from the manifests with the same name
+	require.NoError(t, vexhub.GenerateIndex(vexHubDir, vexhub.WithManifestName(manifestName)))
+	index, err := os.ReadFile(filepath.Join(vexHubDir, "index.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "pkg/golang/github.com/example/package/openvex.json")
+}
+
+func TestCollect_ConcurrentChanges(t *testing.T) {
+	vexHubDir := t.TempDir()
+	r, err := git.PlainInit(vexHubDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	u, err := url.Parse("https://github.com/example/package")
+	require.NoError(t, err)
+
+	// The packages are collected concurrently into VEX Hub, while each checks its own changes
+	const packages = 8
+	collectAll := func(commit string, vulnID func(i int) string) {
+		t.Helper()
+		var wg sync.WaitGroup
+		errs := make([]error, packages)
+		for i := range packages {
+			purl := packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: fmt.Sprintf("package%d", i)}
+			repoDir := t.TempDir()
+			writeVEX(t, repoDir, purl.String(), vulnID(i))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = vex.Collect(vex.OSFS, vexHubDir, repoDir, u, purl, commit, vex.Options{})
+			}()
+		}
+		wg.Wait()
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+	}
+
+	collectAll("1111111111111111111111111111111111111111", func(int) string { return "CVE-2024-0001" })
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	_, err = wt.Commit("crawl", &git.CommitOptions{Author: signature})
+	require.NoError(t, err)
+
+	// Only the manifest of the package whose document changed is updated to the new commit
+	collectAll("2222222222222222222222222222222222222222", func(i int) string {
+		if i == 0 {
+			return "CVE-2024-0002"
+		}
+		return "CVE-2024-0001"
+	})
+	for i := range packages {
+		m, err := manifest.Read(filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", fmt.Sprintf("package%d", i), manifest.FileName))
+		require.NoError(t, err)
+		want := "1111111111111111111111111111111111111111"
+		if i == 0 {
+			want = "2222222222222222222222222222222222222222"
+		}
+		assert.Equal(t, want, m.Commit, "package%d", i)
+	}
+}
+
+func TestCollect_SemanticChanges(t *testing.T) {
+	const product = "pkg:golang/github.com/example/package"
+	tests := []struct {
+		name       string
+		semantic   bool
+		changed    bool // Whether the content changes along with the formatting
+		wantCommit string
+	}{
+		{
+			name:       "bytes",
+			wantCommit: "2222222222222222222222222222222222222222",
+		},
+		{
+			name:       "semantic",
+			semantic:   true,
+			wantCommit: "1111111111111111111111111111111111111111",
+		},
+		{
+			name:       "semantic with content changes",
+			semantic:   true,
+			changed:    true,
+			wantCommit: "2222222222222222222222222222222222222222",
+		},
+	}
+
+	u, err := url.Parse("https://github.com/example/package")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString(product)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vexHubDir := t.TempDir()
+			r, err := git.PlainInit(vexHubDir, false)
+			require.NoError(t, err)
+			wt, err := r.Worktree()
+			require.NoError(t, err)
+			pkgDir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package")
+			opts := vex.Options{SemanticChanges: tt.semantic}
+
+			repoDir := t.TempDir()
+			writeVEX(t, repoDir, product, "CVE-2024-0001")
+			original, err := os.ReadFile(filepath.Join(repoDir, ".vex", "openvex.json"))
+			require.NoError(t, err)
+			_, err = vex.Collect(vex.OSFS, vexHubDir, repoDir, u, purl, "1111111111111111111111111111111111111111", opts)
+			require.NoError(t, err)
+			_, err = wt.Add(".")
+			require.NoError(t, err)
+			_, err = wt.Commit("crawl", &git.CommitOptions{Author: signature})
+			require.NoError(t, err)
+
+			// The document reformatted upstream
+			repoDir = t.TempDir()
+			content := original
+			if tt.changed {
+				content = bytes.Replace(original, []byte("CVE-2024-0001"), []byte("CVE-2024-0002"), 1)
+			}
+			var buf bytes.Buffer
+			require.NoError(t, json.Indent(&buf, content, "", "  "))
+			require.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".vex"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(repoDir, ".vex", "openvex.json"), buf.Bytes(), 0644))
+			_, err = vex.Collect(vex.OSFS, vexHubDir, repoDir, u, purl, "2222222222222222222222222222222222222222", opts)
+			require.NoError(t, err)
+
+			m, err := manifest.Read(filepath.Join(pkgDir, manifest.FileName))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCommit, m.Commit)
+			status, err := wt.Status()
+			require.NoError(t, err)
+			unchanged := tt.semantic && !tt.changed
+			assert.Equal(t, unchanged, status.IsClean(), status.String())
+			if unchanged {
+				// The stored file is restored so that it still matches the digest in the manifest
+				stored, err := os.ReadFile(filepath.Join(pkgDir, "openvex.json"))
+				require.NoError(t, err)
+				assert.Equal(t, string(original), string(stored))
+			}
+		})
+	}
+}
+
+func TestCrawlPackage_StatementCount(t *testing.T) {
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	vexHubDir := t.TempDir()
+	r, err := git.PlainInit(vexHubDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	manifestPath := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package", manifest.FileName)
+
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{})
+	require.NoError(t, err)
+	m, err := manifest.Read(manifestPath)
+	require.NoError(t, err)
+	require.Len(t, m.Sources, 1)
+	assert.Equal(t, 1, m.Sources[0].StatementCount)
+
+	// A manifest written before the count was recorded is kept as long as the documents are unchanged
+	m.Sources[0].StatementCount = 0
+	b, err := manifest.Marshal(m)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manifestPath, b, 0644))
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	_, err = wt.Commit("crawl", &git.CommitOptions{Author: signature})
+	require.NoError(t, err)
+
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{})
+	require.NoError(t, err)
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), status.String())
+}
+
+func TestCrawlPackage_File(t *testing.T) {
+	dir := t.TempDir()
+	writeVEX(t, dir, "pkg:npm/foo", "CVE-2024-0001")
+	data, err := os.ReadFile(filepath.Join(dir, ".vex", "openvex.json"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".vex", "advisory.json"), data, 0644))
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		patterns []string
+		wantPath string
+		wantErr  string
+	}{
+		{
+			name:     "single file",
+			path:     "/.vex/openvex.json",
+			wantPath: "openvex.json",
+		},
+		{
+			name:     "name outside the built-in conventions",
+			path:     "/.vex/advisory.json",
+			wantPath: "advisory.json",
+		},
+		{
+			name:     "name outside the patterns",
+			path:     "/.vex/advisory.json",
+			patterns: []string{"*.openvex.json"},
+			wantPath: "advisory.json",
+		},
+		{
+			name:    "not found",
+			path:    "/.vex/missing.json",
+			wantErr: "unexpected status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(server.URL + tt.path)
+			require.NoError(t, err)
+			require.True(t, u.IsFile())
+			purl, err := packageurl.FromString("pkg:npm/foo")
+			require.NoError(t, err)
+
+			vexHubDir := t.TempDir()
+			result, err := vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{Patterns: tt.patterns})
+			// The host is reported even if the download fails, so that the flaky hosts can be spotted
+			assert.Equal(t, u.Host, result.Host)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, result.DownloadedFiles)
+			assert.Equal(t, 1, result.AcceptedFiles)
+
+			pkgDir := filepath.Join(vexHubDir, "pkg", "npm", "foo")
+			assert.FileExists(t, filepath.Join(pkgDir, tt.wantPath))
+			m, err := manifest.Read(filepath.Join(pkgDir, manifest.FileName))
+			require.NoError(t, err)
+			clearDigests(t, pkgDir, m.Sources, os.ReadFile)
+			assert.Equal(t, []manifest.Source{
+				{
+					Path:           tt.wantPath,
+					URL:            server.URL + tt.path,
+					Format:         "openvex",
+					StatementCount: 1,
+				},
+			}, m.Sources)
+		})
+	}
+}
+
+func TestCollect_Incremental(t *testing.T) {
+	const product = "pkg:golang/github.com/example/package"
+	repoDir := t.TempDir()
+	r, err := git.PlainInit(repoDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	commit := func(files map[string]string) string {
+		for name, vulnID := range files {
+			writeVEX(t, repoDir, product, vulnID)
+			require.NoError(t, os.Rename(filepath.Join(repoDir, ".vex", "openvex.json"), filepath.Join(repoDir, ".vex", name)))
+		}
+		_, err := wt.Add(".")
+		require.NoError(t, err)
+		hash, err := wt.Commit("update", &git.CommitOptions{Author: signature})
+		require.NoError(t, err)
+		return hash.String()
+	}
+
+	u, err := url.Parse("https://github.com/example/package")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString(product)
+	require.NoError(t, err)
+	vexHubDir := t.TempDir()
+	vexDir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package")
+	opts := vex.Options{Incremental: true}
+
+	// The whole tree is walked without a previous crawl
+	first := commit(map[string]string{"a.openvex.json": "CVE-2024-0001", "b.openvex.json": "CVE-2024-0002"})
+	result, err := vex.Collect(vex.OSFS, vexHubDir, repoDir, u, purl, first, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.AcceptedFiles)
+	assert.Zero(t, result.UnchangedFiles)
+
+	// Only the changed file is validated. The unchanged one is corrupted in the working tree to prove it's not parsed.
+	// The files are moved into VEX Hub, so the unchanged one is written again with the same content
+	second := commit(map[string]string{"a.openvex.json": "CVE-2024-0001", "b.openvex.json": "CVE-2024-0003"})
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, ".vex", "a.openvex.json"), []byte("{"), 0644))
+	result, err = vex.Collect(vex.OSFS, vexHubDir, repoDir, u, purl, second, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.AcceptedFiles)
+	assert.Equal(t, 1, result.UnchangedFiles)
+
+	doc, err := openvex.Open(filepath.Join(vexDir, "a.openvex.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "CVE-2024-0001", doc.Statements[0].Vulnerability.ID)
+	doc, err = openvex.Open(filepath.Join(vexDir, "b.openvex.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "CVE-2024-0003", doc.Statements[0].Vulnerability.ID)
+
+	m, err := manifest.Read(filepath.Join(vexDir, manifest.FileName))
+	require.NoError(t, err)
+	assert.Equal(t, second, m.Commit)
+	require.Len(t, m.Sources, 2)
+	for _, src := range m.Sources {
+		assert.Contains(t, src.URL, second) // Carried over sources point to the new commit as well
+	}
+
+	// The corrupt file is validated once the previous commit is unknown
+	_, err = vex.Collect(vex.OSFS, vexHubDir, repoDir, u, purl, "0123abcd", opts)
+	require.Error(t, err)
+}
+
+func TestFetch_PartialDownload(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		download func(t *testing.T, dst string)
+		wantErr  bool
+	}{
+		{
+			name: "clone",
+			url:  "https://github.com/example/package",
+			download: func(t *testing.T, dst string) {
+				require.NoError(t, os.MkdirAll(filepath.Join(dst, ".git"), 0755))
+			},
+		},
+		{
+			name: "clone without .git",
+			url:  "https://github.com/example/package",
+			download: func(t *testing.T, dst string) {
+				require.NoError(t, os.MkdirAll(dst, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(dst, "README.md"), nil, 0644))
+			},
+			wantErr: true,
+		},
+		{
+			name: "archive",
+			url:  "https://example.com/package.tar.gz",
+			download: func(t *testing.T, dst string) {
+				require.NoError(t, os.MkdirAll(dst, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(dst, "vex.json"), nil, 0644))
+			},
+		},
+		{
+			name: "empty archive",
+			url:  "https://example.com/package.tar.gz",
+			download: func(t *testing.T, dst string) {
+				require.NoError(t, os.MkdirAll(dst, 0755))
+			},
+			wantErr: true,
+		},
+	}
+
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			opts := vex.Options{
+				Clone: true,
+				Download: func(_ context.Context, _, dst string) (download.Stats, error) {
+					tt.download(t, dst)
+					return download.Stats{}, nil
+				},
+			}
+
+			s, err := vex.Fetch(context.Background(), u, purl, opts)
+			if tt.wantErr {
+				require.ErrorIs(t, err, vex.ErrPartialDownload)
+				assert.NoDirExists(t, s.Dir, "the partial download should be removed")
+				return
+			}
+			require.NoError(t, err)
+			assert.DirExists(t, s.Dir)
+			require.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestLastCommit(t *testing.T) {
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	head, err := vex.RemoteHead(context.Background(), u)
+	require.NoError(t, err)
+
+	hash, when, err := vex.LastCommit(context.Background(), u)
+	require.NoError(t, err)
+	assert.Equal(t, head, hash)
+	assert.WithinDuration(t, signature.When, when, time.Second)
+
+	// Archives have no git history
+	u, err = url.Parse(server.URL + "/testrepo.tar.gz")
+	require.NoError(t, err)
+	_, _, err = vex.LastCommit(context.Background(), u)
+	require.ErrorContains(t, err, "no git history")
+}
+
+func TestCrawlPackage_Moved(t *testing.T) {
+	server := NewServer(t, "newrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+	})
+	defer server.Close()
+
+	// The old location redirects to the new one, like a renamed repository on GitHub
+	gitHandler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/oldrepo.git"); ok {
+			u := *r.URL
+			u.Path = "/newrepo.git" + rest
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+			return
+		}
+		gitHandler.ServeHTTP(w, r)
+	})
+
+	u, err := url.Parse(server.URL + "/oldrepo.git")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	vexHubDir := t.TempDir()
+	result, err := vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/newrepo.git", result.MovedTo)
+
+	m, err := manifest.Read(filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package", manifest.FileName))
+	require.NoError(t, err)
+	require.Len(t, m.Sources, 1)
+	assert.Equal(t, server.URL+"/newrepo.git", m.Sources[0].URL)
+
+	// The repository hasn't moved
+	u, err = url.Parse(server.URL + "/newrepo.git")
+	require.NoError(t, err)
+	result, err = vex.CrawlPackage(context.Background(), t.TempDir(), u, purl, vex.Options{})
+	require.NoError(t, err)
+	assert.Empty(t, result.MovedTo)
+}
+
+func TestValidateDir(t *testing.T) {
+	dir := t.TempDir()
+	writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2023-1234")
+	other := t.TempDir()
+	writeVEX(t, other, "pkg:golang/github.com/example/other", "CVE-2023-5678")
+	require.NoError(t, os.Rename(filepath.Join(other, ".vex", "openvex.json"), filepath.Join(dir, ".vex", "other.openvex.json")))
+
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	t.Run("happy path", func(t *testing.T) {
+		got, err := vex.ValidateDir(context.Background(), dir, purl, vex.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, []vex.AcceptedFile{
+			{
+				Path:       filepath.Join(".vex", "openvex.json"),
+				Stored:     "openvex.json",
+				Format:     "openvex",
+				Statements: 1,
+			},
+		}, got.Accepted)
+		require.Len(t, got.Skipped, 1)
+		assert.Equal(t, filepath.Join(".vex", "other.openvex.json"), got.Skipped[0].Path)
+		assert.Equal(t, []string{"pkg:golang/github.com/example/other"}, got.Skipped[0].Products)
+
+		// The directory is left as is
+		assert.FileExists(t, filepath.Join(dir, ".vex", "openvex.json"))
+		assert.FileExists(t, filepath.Join(dir, ".vex", "other.openvex.json"))
+	})
+
+	t.Run("strict PURL", func(t *testing.T) {
+		got, err := vex.ValidateDir(context.Background(), dir, purl, vex.Options{StrictPURL: true})
+		require.ErrorIs(t, err, vex.ErrPURLMismatch)
+		require.NotNil(t, got)
+		assert.Equal(t, 2, got.CandidateFiles)
+	})
+
+	t.Run("no VEX file", func(t *testing.T) {
+		_, err := vex.ValidateDir(context.Background(), t.TempDir(), purl, vex.Options{})
+		require.ErrorIs(t, err, vex.ErrNoVEXFile)
+	})
+}
@@ -0,0 +1,215 @@
+package vex
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/samber/oops"
+)
+
+// cdxBOM is the subset of a CycloneDX BOM needed to validate VEX.
+// cf. https://cyclonedx.org/capabilities/vex/
+type cdxBOM struct {
+	BOMFormat string `json:"bomFormat"`
+	Metadata  struct {
+		Authors []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		Component *cdxComponent `json:"component"`
+	} `json:"metadata"`
+	Components      []cdxComponent     `json:"components"`
+	Vulnerabilities []cdxVulnerability `json:"vulnerabilities"`
+
+	content []byte // The rewritten document if the vulnerabilities were extracted from an SBOM
+}
+
+type cdxComponent struct {
+	BOMRef     string         `json:"bom-ref"`
+	PURL       string         `json:"purl"`
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxVulnerability struct {
+	ID         string `json:"id"`
+	References []struct {
+		ID string `json:"id"`
+	} `json:"references"`
+	Affects []struct {
+		Ref string `json:"ref"`
+	} `json:"affects"`
+	Analysis struct {
+		State string `json:"state"`
+	} `json:"analysis"`
+}
+
+func parseCycloneDX(fsys FS, path string) (*cdxBOM, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to open CycloneDX file")
+	}
+	defer f.Close()
+
+	var bom cdxBOM
+	if err = json.NewDecoder(f).Decode(&bom); err != nil {
+		return nil, oops.Wrapf(err, "failed to decode CycloneDX file")
+	} else if bom.BOMFormat != "CycloneDX" {
+		return nil, oops.Errorf("not a CycloneDX document")
+	}
+	return &bom, nil
+}
+
+// validateCycloneDX checks that the vulnerabilities in the CycloneDX document affect the PURL.
+func validateCycloneDX(path, purl string, opts Options) (*Document, error) {
+	bom, err := parseCycloneDX(opts.fs(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		Format: FormatCycloneDX,
+	}
+	for _, author := range bom.Metadata.Authors {
+		doc.Authors = append(doc.Authors, author.Name)
+	}
+	if len(bom.Components) > 0 && len(bom.Vulnerabilities) == 0 {
+		// A plain SBOM is not a VEX document, whether or not Options.SBOM is set
+		return nil, ErrNoEmbeddedVEX
+	}
+	if opts.SBOM && len(bom.Components) > 0 {
+		// The vulnerabilities affecting the other components are dropped along with the inventory
+		products, err := extractCycloneDX(opts.fs(), path, bom, purl, opts)
+		if err != nil {
+			return nil, oops.Wrapf(err, "failed to extract vulnerabilities")
+		}
+		doc.SBOM = sbomCycloneDX
+		if bom.Vulnerabilities == nil {
+			doc.Products = products
+			return doc, ErrPURLMismatch
+		}
+		doc.Content = bom.content
+	}
+	if opts.Embargoed != nil || len(opts.Statuses) > 0 {
+		var content []byte
+		if doc.Withheld, doc.Filtered, content, err = dropVulnerabilities(opts.fs(), path, bom, opts); err != nil {
+			return nil, oops.Wrapf(err, "failed to drop vulnerabilities")
+		}
+		if content != nil {
+			doc.Content = content
+		}
+	}
+
+	if len(bom.Vulnerabilities) == 0 && doc.Withheld > 0 {
+		return doc, ErrEmbargoed
+	} else if len(bom.Vulnerabilities) == 0 && doc.Filtered > 0 {
+		return doc, ErrFilteredOut
+	} else if len(bom.Vulnerabilities) == 0 {
+		return nil, ErrNoStatement
+	}
+
+	doc.Statements = len(bom.Vulnerabilities)
+	refs := bom.purlsByRef()
+	seen := make(map[string]struct{})
+	var matched bool
+	for _, vuln := range bom.Vulnerabilities {
+		if vuln.ID != "" && !slices.Contains(doc.Vulnerabilities, vuln.ID) {
+			doc.Vulnerabilities = append(doc.Vulnerabilities, vuln.ID)
+		}
+		for _, affect := range vuln.Affects {
+			// The reference is usually a bom-ref of a component, but may be a PURL itself
+			p, ok := refs[affect.Ref]
+			if !ok {
+				p = affect.Ref
+			}
+			if matcher, ok := MatchProduct(purl, p, opts); ok && (!matched || matcher == MatcherPURL) {
+				matched, doc.Matcher = true, matcher
+			}
+			if _, ok = seen[p]; !ok {
+				seen[p] = struct{}{}
+				doc.Products = append(doc.Products, p)
+			}
+		}
+	}
+	if !matched {
+		return doc, ErrPURLMismatch
+	}
+	return doc, nil
+}
+
+// purlsByRef returns the PURLs of the components keyed by bom-ref.
+func (b *cdxBOM) purlsByRef() map[string]string {
+	refs := make(map[string]string)
+	var walk func(components []cdxComponent)
+	walk = func(components []cdxComponent) {
+		for _, c := range components {
+			if c.BOMRef != "" && c.PURL != "" {
+				refs[c.BOMRef] = c.PURL
+			}
+			walk(c.Components)
+		}
+	}
+	if b.Metadata.Component != nil {
+		walk([]cdxComponent{*b.Metadata.Component})
+	}
+	walk(b.Components)
+	return refs
+}
+
+// dropVulnerabilities removes the embargoed vulnerabilities and the ones filtered out by status from the BOM.
+// It returns the numbers of withheld and filtered vulnerabilities, and the rewritten content if any are removed.
+// The content is rewritten from the raw document so that fields unknown to the crawler are preserved.
+func dropVulnerabilities(fsys FS, path string, bom *cdxBOM, opts Options) (int, int, []byte, error) {
+	var kept []cdxVulnerability
+	var keptIdx []int
+	var withheld, filtered int
+	for i, vuln := range bom.Vulnerabilities {
+		if opts.Embargoed != nil && isEmbargoedCycloneDX(vuln, opts.Embargoed) {
+			withheld++
+			continue
+		} else if cdxFiltered(vuln, opts.Statuses) {
+			filtered++
+			continue
+		}
+		kept = append(kept, vuln)
+		keptIdx = append(keptIdx, i)
+	}
+	bom.Vulnerabilities = kept
+	if withheld+filtered == 0 {
+		return 0, 0, nil, nil
+	}
+
+	var err error
+	data := bom.content
+	if data == nil {
+		if data, err = fsys.ReadFile(path); err != nil {
+			return 0, 0, nil, oops.Wrapf(err, "failed to read CycloneDX file")
+		}
+	}
+	var raw map[string]any
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return 0, 0, nil, oops.Wrapf(err, "failed to decode CycloneDX file")
+	}
+	rawVulns, _ := raw["vulnerabilities"].([]any)
+	var rawKept []any
+	for _, i := range keptIdx {
+		rawKept = append(rawKept, rawVulns[i])
+	}
+	raw["vulnerabilities"] = rawKept
+
+	content, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return 0, 0, nil, oops.Wrapf(err, "failed to encode CycloneDX file")
+	}
+	return withheld, filtered, append(content, '\n'), nil
+}
+
+func isEmbargoedCycloneDX(vuln cdxVulnerability, embargoed func(string) bool) bool {
+	if embargoed(vuln.ID) {
+		return true
+	}
+	for _, ref := range vuln.References {
+		if embargoed(ref.ID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package vex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+
+	"github.com/samber/oops"
+)
+
+// DuplicateNames is the strategy for the VEX documents whose file names collide, see Options.DuplicateNames.
+type DuplicateNames string
+
+const (
+	// DuplicateNamesError fails the package, so that the publisher renames the documents.
+	DuplicateNamesError DuplicateNames = "error"
+
+	// DuplicateNamesHashSuffix appends a short hash of the content to the name before the extensions,
+	// e.g. "vex-1a2b3c4d.openvex.json" for "vex.openvex.json".
+	DuplicateNamesHashSuffix DuplicateNames = "hash-suffix"
+
+	// DuplicateNamesPath names the file after its path from the searched directory, with the directories joined
+	// by underscores, e.g. "a_vex.json" for "a/vex.json". Subdirectories of the package directory aren't used since
+	// they hold the versions and the subpaths of the package.
+	DuplicateNamesPath DuplicateNames = "path"
+)
+
+// dedupName returns the name the file colliding with another one is stored under, according to the strategy.
+// The content is the document to be stored, or nil if the file is stored as is.
+func dedupName(fsys FS, root, filePath string, content []byte, strategy DuplicateNames) (string, error) {
+	name := filepath.Base(filePath)
+	switch strategy {
+	case DuplicateNamesHashSuffix:
+		if content == nil {
+			var err error
+			if content, err = fsys.ReadFile(filePath); err != nil {
+				return "", oops.Wrapf(err, "failed to read the file")
+			}
+		}
+		sum := sha256.Sum256(content)
+		suffix := "-" + hex.EncodeToString(sum[:4])
+		// The extensions are kept so that the format is still detected from the name, e.g. ".cdx.json"
+		if i := strings.Index(name, "."); i >= 0 {
+			return name[:i] + suffix + name[i:], nil
+		}
+		return name + suffix, nil
+	case DuplicateNamesPath:
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return "", oops.Wrapf(err, "failed to get the relative path")
+		}
+		return strings.ReplaceAll(filepath.ToSlash(rel), "/", "_"), nil
+	}
+	return name, nil
+}
+
+// storedName returns the name or the path of the file as stored in VEX Hub, with ".gz" if compressed.
+func storedName(name string, opts Options) string {
+	if opts.Compress {
+		return name + ".gz"
+	}
+	return name
+}
@@ -0,0 +1,108 @@
+package vex
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/samber/oops"
+)
+
+// Format is the format of a VEX document.
+type Format string
+
+const (
+	FormatUnknown   Format = ""
+	FormatOpenVEX   Format = "openvex"
+	FormatCSAF      Format = "csaf"
+	FormatCycloneDX Format = "cyclonedx"
+)
+
+// headSize is the number of leading bytes inspected to detect the format.
+const headSize = 4096
+
+// DetectFormat returns the format of the VEX document from its file name and leading bytes.
+// The file name decides whether the file is a VEX document at all and gives the default format,
+// while the content, if given, takes precedence so that e.g. "vex.json" may contain a CSAF document.
+// FormatUnknown is returned for files that are not VEX documents.
+func DetectFormat(path string, head []byte) Format {
+	format := formatFromName(filepath.Base(path))
+	if format == FormatUnknown {
+		return FormatUnknown
+	}
+
+	if f := formatFromContent(head); f != FormatUnknown {
+		return f
+	}
+	return format
+}
+
+func formatFromContent(head []byte) Format {
+	switch {
+	case bytes.Contains(head, []byte(`"bomFormat"`)):
+		return FormatCycloneDX
+	case bytes.Contains(head, []byte(`"csaf_version"`)):
+		return FormatCSAF
+	case bytes.Contains(head, []byte(`openvex.dev/ns`)):
+		return FormatOpenVEX
+	}
+	return FormatUnknown
+}
+
+// formatFromName detects the format from the conventional file names, ignoring case, e.g. "VEX.json".
+func formatFromName(name string) Format {
+	name = strings.ToLower(name)
+	switch {
+	case name == "bom.json" || strings.HasSuffix(name, ".cdx.json"):
+		return FormatCycloneDX
+	case strings.HasSuffix(name, ".csaf.json"):
+		return FormatCSAF
+	case name == "openvex.json" || name == "vex.json" ||
+		strings.HasSuffix(name, ".openvex.json") || strings.HasSuffix(name, ".vex.json"):
+		return FormatOpenVEX
+	}
+	return FormatUnknown
+}
+
+// detectContentFormat detects the format of the file from its content regardless of the name.
+// OpenVEX is assumed if the content is not recognized.
+func detectContentFormat(fsys FS, path string) (Format, error) {
+	head, err := readHead(fsys, path)
+	if err != nil {
+		return FormatUnknown, err
+	}
+	if format := formatFromContent(head); format != FormatUnknown {
+		return format, nil
+	}
+	return FormatOpenVEX, nil
+}
+
+// detectFileFormat detects the format of the file, reading its head only if the name looks like a VEX document.
+func detectFileFormat(fsys FS, path string) (Format, error) {
+	if DetectFormat(path, nil) == FormatUnknown {
+		return FormatUnknown, nil
+	}
+
+	head, err := readHead(fsys, path)
+	if err != nil {
+		return FormatUnknown, err
+	}
+	return DetectFormat(path, head), nil
+}
+
+// readHead reads the leading bytes of the file to detect the format.
+func readHead(fsys FS, path string) ([]byte, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to open the file")
+	}
+	defer f.Close()
+
+	head := make([]byte, headSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, oops.Wrapf(err, "failed to read the file")
+	}
+	return head[:n], nil
+}
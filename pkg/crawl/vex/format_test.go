@@ -0,0 +1,76 @@
+package vex_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		head string
+		want vex.Format
+	}{
+		{
+			name: "OpenVEX by name",
+			path: ".vex/package.openvex.json",
+			want: vex.FormatOpenVEX,
+		},
+		{
+			name: "OpenVEX by content",
+			path: ".vex/vex.json",
+			head: `{"@context": "https://openvex.dev/ns/v0.2.0"`,
+			want: vex.FormatOpenVEX,
+		},
+		{
+			name: "CSAF by name",
+			path: ".vex/package.csaf.json",
+			want: vex.FormatCSAF,
+		},
+		{
+			name: "CSAF by content",
+			path: ".vex/vex.json",
+			head: `{"document": {"category": "csaf_vex", "csaf_version": "2.0"`,
+			want: vex.FormatCSAF,
+		},
+		{
+			name: "OpenVEX by name ignoring case",
+			path: ".vex/Package.OpenVEX.json",
+			want: vex.FormatOpenVEX,
+		},
+		{
+			name: "CycloneDX by name",
+			path: ".vex/bom.json",
+			want: vex.FormatCycloneDX,
+		},
+		{
+			name: "CycloneDX by content",
+			path: ".vex/package.vex.json",
+			head: `{"bomFormat": "CycloneDX", "specVersion": "1.5"`,
+			want: vex.FormatCycloneDX,
+		},
+		{
+			name: "unknown content keeps the format by name",
+			path: ".vex/package.cdx.json",
+			head: `{"foo": "bar"}`,
+			want: vex.FormatCycloneDX,
+		},
+		{
+			name: "not a VEX document",
+			path: "package.json",
+			head: `{"bomFormat": "CycloneDX"}`,
+			want: vex.FormatUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := vex.DetectFormat(tt.path, []byte(tt.head))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
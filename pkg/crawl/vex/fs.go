@@ -0,0 +1,107 @@
+package vex
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS is the filesystem Collect walks, validates and writes the VEX documents on.
+// Paths are native paths as with the os package.
+// The OS filesystem is used in production, while tests can back it with memory to stay hermetic.
+type FS interface {
+	Open(name string) (fs.File, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	MkdirAll(path string, perm fs.FileMode) error
+	MkdirTemp(dir, pattern string) (string, error)
+}
+
+// OSFS is the FS of the operating system.
+var OSFS FS = osFS{}
+
+type osFS struct{}
+
+// The paths are passed through LongPath since VEX Hub directories derived from deep PURLs may exceed
+// the legacy path limit on Windows.
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(LongPath(name)) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(LongPath(name)) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(LongPath(name)) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(LongPath(name)) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(LongPath(name)) }
+func (osFS) Rename(oldpath, newpath string) error {
+	return os.Rename(LongPath(oldpath), LongPath(newpath))
+}
+func (osFS) Remove(name string) error                     { return os.Remove(LongPath(name)) }
+func (osFS) RemoveAll(path string) error                  { return os.RemoveAll(LongPath(path)) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(LongPath(path), perm) }
+func (osFS) MkdirTemp(dir, pattern string) (string, error) {
+	name, err := os.MkdirTemp(LongPath(dir), pattern)
+	if err != nil || dir == "" {
+		return name, err
+	}
+	// Keep the form of the given directory so that paths relative to it still work
+	return filepath.Join(dir, filepath.Base(name)), nil
+}
+func (osFS) WriteFile(name string, b []byte, perm fs.FileMode) error {
+	return os.WriteFile(LongPath(name), b, perm)
+}
+
+// isOSFS reports whether the filesystem is the one of the operating system,
+// which is required by the parsers reading files by themselves.
+func isOSFS(fsys FS) bool {
+	_, ok := fsys.(osFS)
+	return ok
+}
+
+// walkDir walks the file tree on the filesystem in lexical order like filepath.WalkDir.
+// Symbolic links are not followed, including the root.
+func walkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walk(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walk(fsys FS, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Second call to report the error
+		if err = fn(path, d, err); err != nil {
+			if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err = walk(fsys, filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package vex_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+// newClonedRepo creates a repository looking like a clone of the GitHub repository
+// whose default branch is defaultBranch, with HEAD pointing to the head branch.
+func newClonedRepo(t *testing.T, defaultBranch, head string, originHEAD bool) (string, string) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("test"), 0644))
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	hash, err := wt.Commit("initial commit", &git.CommitOptions{Author: signature})
+	require.NoError(t, err)
+
+	_, err = r.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{"https://github.com/example/repo.git"},
+	})
+	require.NoError(t, err)
+
+	// Move the commit from the branch created by the initial commit to the default branch
+	require.NoError(t, r.Storer.RemoveReference(plumbing.Master))
+	remoteRef := plumbing.NewRemoteReferenceName("origin", defaultBranch)
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(remoteRef, hash)))
+	if originHEAD {
+		require.NoError(t, r.Storer.SetReference(plumbing.NewSymbolicReference("refs/remotes/origin/HEAD", remoteRef)))
+	}
+	require.NoError(t, r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD,
+		plumbing.NewBranchReferenceName(head))))
+	return dir, hash.String()
+}
+
+func TestHeadCommit(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultBranch string
+		head          string
+		originHEAD    bool
+	}{
+		{
+			name:          "HEAD pointing to the default branch",
+			defaultBranch: "main",
+			head:          "main",
+			originHEAD:    true,
+		},
+		{
+			name:          "default branch main",
+			defaultBranch: "main",
+			head:          "master",
+			originHEAD:    true,
+		},
+		{
+			name:          "default branch master",
+			defaultBranch: "master",
+			head:          "main",
+			originHEAD:    true,
+		},
+		{
+			name:          "default branch main without origin/HEAD",
+			defaultBranch: "main",
+			head:          "master",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, want := newClonedRepo(t, tt.defaultBranch, tt.head, tt.originHEAD)
+			if tt.head == tt.defaultBranch {
+				// The branch is checked out as expected
+				r, err := git.PlainOpen(dir)
+				require.NoError(t, err)
+				require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(
+					plumbing.NewBranchReferenceName(tt.head), plumbing.NewHash(want))))
+			}
+
+			assert.Equal(t, want, vex.HeadCommit(dir))
+
+			permalink := vex.GitPermalink(dir)
+			require.NotNil(t, permalink)
+			assert.Equal(t, "https://github.com/example/repo/blob/"+want, permalink.String())
+		})
+	}
+}
+
+func TestHeadCommit_NotRepository(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, vex.HeadCommit(dir))
+	assert.Nil(t, vex.GitPermalink(dir))
+}
@@ -0,0 +1,140 @@
+package vex
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// HistoryDir is the directory in the package directory keeping the previous versions of the VEX documents.
+// It's hidden so that the manifests in it aren't listed as packages of VEX Hub.
+const HistoryDir = ".history"
+
+// historyTimeFormat is the format of the time the versions are archived at, prefixing their directory names
+// so that they sort chronologically. The fraction has a fixed width for the same reason.
+const historyTimeFormat = "20060102T150405.000000000Z"
+
+// History configures the retention of the previous versions of the VEX documents.
+type History struct {
+	Keep   int           // Versions kept per package. Zero means no limit
+	MaxAge time.Duration // Age after which versions are pruned. Zero means no limit
+}
+
+// snapshot is the stored files of the previous crawl, including the manifest, by file name.
+type snapshot struct {
+	commit string
+	files  map[string][]byte
+}
+
+// takeSnapshot reads the files of the previous crawl before they're replaced,
+// or returns nil if the history is disabled or the package was never crawled.
+func takeSnapshot(fsys FS, vexDir string, opts Options) (*snapshot, error) {
+	if opts.History == nil {
+		return nil, nil
+	}
+	b, err := fsys.ReadFile(filepath.Join(vexDir, opts.manifestName()))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, oops.Wrapf(err, "failed to read the manifest")
+	}
+	m, err := manifest.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil // Can't tell the files of the previous crawl
+	}
+
+	s := &snapshot{
+		commit: m.Commit,
+		files:  map[string][]byte{opts.manifestName(): b},
+	}
+	for _, src := range m.Sources {
+		if src.Path == "" || filepath.Base(src.Path) != src.Path {
+			continue
+		}
+		content, err := fsys.ReadFile(filepath.Join(vexDir, src.Path))
+		if err != nil {
+			continue // Already missing
+		}
+		s.files[src.Path] = content
+	}
+	return s, nil
+}
+
+// archive copies the previous files into a new version under HistoryDir if any of the VEX documents has changed
+// or is gone, and prunes the versions according to the retention. It returns the name of the new version, if any.
+func (s *snapshot) archive(fsys FS, vexDir string, now time.Time, opts Options) (string, error) {
+	if s == nil {
+		return "", nil
+	}
+	var changed bool
+	for name, content := range s.files {
+		if name == opts.manifestName() {
+			continue
+		}
+		current, err := fsys.ReadFile(filepath.Join(vexDir, name))
+		if err != nil || !bytes.Equal(current, content) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return "", pruneHistory(fsys, vexDir, now, *opts.History)
+	}
+
+	version := now.UTC().Format(historyTimeFormat)
+	if s.commit != "" {
+		version += "-" + s.commit[:min(len(s.commit), 12)]
+	}
+	dir := filepath.Join(vexDir, HistoryDir, version)
+	if err := fsys.MkdirAll(dir, 0755); err != nil {
+		return "", oops.With("dir", dir).Wrapf(err, "failed to create the directory")
+	}
+	for name, content := range s.files {
+		if err := fsys.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			return "", oops.With("dir", dir).With("file_name", name).Wrapf(err, "failed to write")
+		}
+	}
+	return version, pruneHistory(fsys, vexDir, now, *opts.History)
+}
+
+// pruneHistory removes the versions beyond the retention, the oldest first.
+func pruneHistory(fsys FS, vexDir string, now time.Time, h History) error {
+	historyDir := filepath.Join(vexDir, HistoryDir)
+	entries, err := fsys.ReadDir(historyDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return oops.With("dir", historyDir).Wrapf(err, "failed to read the directory")
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	slices.Sort(versions)
+	slices.Reverse(versions) // Newest first
+
+	for i, version := range versions {
+		archivedAt, err := time.Parse(historyTimeFormat, strings.SplitN(version, "-", 2)[0])
+		if err != nil {
+			continue // Not created by the crawler
+		}
+		expired := h.MaxAge > 0 && now.Sub(archivedAt) > h.MaxAge
+		if (h.Keep <= 0 || i < h.Keep) && !expired {
+			continue
+		}
+		if err = fsys.RemoveAll(filepath.Join(historyDir, version)); err != nil {
+			return oops.With("dir", historyDir).With("version", version).Wrapf(err, "failed to prune")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package vex
+
+import (
+	"bytes"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// incremental is the state of a collection examining only the files changed since the previous crawl.
+type incremental struct {
+	changed map[string]bool            // Paths relative to the repository changed between the commits, in slash form
+	sources map[string]manifest.Source // Previous sources not affected by the changes, by the case-folded original file name
+}
+
+// newIncremental returns the state of an incremental collection, or nil if the whole tree must be walked:
+// the previous manifest or its commit is unknown, or the previous commit is not in the history of the repository,
+// e.g. in a shallow clone. Embargoes and groups also need a full walk, since embargoes lift over time
+// and groups need the vulnerabilities of all the documents.
+func newIncremental(fsys FS, repoDir, vexDir, commit string, opts Options) (*incremental, error) {
+	if !opts.Incremental || opts.Embargoed != nil || opts.Groups || commit == "" {
+		return nil, nil
+	}
+	b, err := fsys.ReadFile(filepath.Join(vexDir, opts.manifestName()))
+	if err != nil {
+		return nil, nil // Crawled for the first time
+	}
+	prev, err := manifest.Decode(bytes.NewReader(b))
+	if err != nil || prev.Commit == "" {
+		return nil, nil
+	}
+
+	changed, err := changedPaths(fsys, repoDir, prev.Commit, commit)
+	if err != nil {
+		return nil, err
+	} else if changed == nil {
+		return nil, nil
+	}
+
+	inc := &incremental{
+		changed: changed,
+		sources: make(map[string]manifest.Source),
+	}
+	dirty := make(map[string]bool) // Case-folded names of the changed files
+	for p := range changed {
+		dirty[strings.ToLower(path.Base(p))] = true
+	}
+	for _, src := range prev.Sources {
+		// The stored files are flattened, so a source is affected by any changed file of the same name
+		name := strings.ToLower(originalName(src))
+		if !dirty[name] {
+			inc.sources[name] = src
+		}
+	}
+	return inc, nil
+}
+
+// changedPaths returns the paths added, modified or removed between the commits of the git repository,
+// or nil if any of the commits is not in the repository. No path is changed if the commits are the same,
+// which is also known for sources without git history, such as archives.
+func changedPaths(fsys FS, repoDir, from, to string) (map[string]bool, error) {
+	if from == to {
+		return make(map[string]bool), nil
+	} else if !isOSFS(fsys) {
+		return nil, nil
+	}
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return nil, nil
+	}
+	fromTree, err := commitTree(repo, from)
+	if err != nil {
+		return nil, nil
+	}
+	toTree, err := commitTree(repo, to)
+	if err != nil {
+		return nil, nil
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, oops.With("from", from).With("to", to).Wrapf(err, "failed to diff the commits")
+	}
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" {
+				changed[name] = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+func commitTree(repo *git.Repository, hash string) (*object.Tree, error) {
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+// unchanged returns the previous source of the file if it's unchanged since the previous crawl
+// and stored in the same way, so that it's carried over without validation.
+// An unchanged file without a previous source, e.g. a document about another package, is ignored.
+func (inc *incremental) unchanged(relPath string, opts Options) (src manifest.Source, carried, ignored bool) {
+	if inc == nil || inc.changed[filepath.ToSlash(relPath)] {
+		return manifest.Source{}, false, false
+	}
+	src, ok := inc.sources[strings.ToLower(filepath.Base(relPath))]
+	if !ok {
+		return manifest.Source{}, false, true
+	}
+	// The file is validated again if the compression setting has changed
+	compressed := src.Compression == manifest.CompressionGzip
+	return src, compressed == opts.Compress, false
+}
+
+// keep returns the stored files of the previous sources that may be carried over.
+func (inc *incremental) keep() []string {
+	if inc == nil {
+		return nil
+	}
+	var names []string
+	for _, src := range inc.sources {
+		names = append(names, src.Path)
+	}
+	return names
+}
+
+// originalName returns the name of the file the source was stored from.
+func originalName(src manifest.Source) string {
+	if src.Compression == manifest.CompressionGzip {
+		return strings.TrimSuffix(src.Path, ".gz")
+	}
+	return src.Path
+}
@@ -0,0 +1,17 @@
+package vex
+
+import (
+	"github.com/openvex/go-vex/pkg/vex"
+)
+
+// missingJustifications returns the number of not_affected statements with neither a justification
+// nor an impact statement, which OpenVEX requires to tell why the product is not affected.
+func missingJustifications(v *vex.VEX) int {
+	var missing int
+	for _, statement := range v.Statements {
+		if statement.Status == vex.StatusNotAffected && statement.Justification == "" && statement.ImpactStatement == "" {
+			missing++
+		}
+	}
+	return missing
+}
@@ -0,0 +1,94 @@
+package vex
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// LatestFileName is the file in the package directory merging the OpenVEX documents of the package,
+// see Options.Latest. Upstream files with the same name are skipped like those colliding with the manifest.
+const LatestFileName = "latest.openvex.json"
+
+// writeLatest merges the stored OpenVEX documents of the sources into LatestFileName and returns its name,
+// or removes the file and returns an empty name if there is no OpenVEX document to merge.
+// The merged document is derived from the documents only, so that it doesn't change unless they do.
+func writeLatest(fsys FS, vexDir string, sources []manifest.Source) (string, error) {
+	latestPath := filepath.Join(vexDir, LatestFileName)
+	var docs []*vex.VEX
+	for _, src := range sources {
+		if src.Format != string(FormatOpenVEX) {
+			continue // Only OpenVEX documents can be merged
+		}
+		v, err := readStored(fsys, vexDir, src)
+		if err != nil {
+			return "", oops.With("path", src.Path).Wrap(err)
+		}
+		docs = append(docs, v)
+	}
+	if len(docs) == 0 {
+		if err := fsys.Remove(latestPath); err != nil && !os.IsNotExist(err) {
+			return "", oops.Wrapf(err, "failed to remove the latest document")
+		}
+		return "", nil
+	}
+
+	var authors []string
+	var latest time.Time
+	for _, v := range docs {
+		authors = append(authors, v.Author)
+		for _, t := range []*time.Time{v.Timestamp, v.LastUpdated} {
+			if t != nil && t.After(latest) {
+				latest = *t
+			}
+		}
+	}
+	slices.Sort(authors)
+	merged, err := vex.MergeDocumentsWithOptions(&vex.MergeOptions{
+		Author: strings.Join(slices.Compact(authors), ", "),
+	}, docs)
+	if err != nil {
+		return "", oops.Wrapf(err, "failed to merge the documents")
+	}
+	merged.Timestamp = &latest // Instead of the time of the merge
+
+	var buf bytes.Buffer
+	if err = merged.ToJSON(&buf); err != nil {
+		return "", oops.Wrapf(err, "failed to encode the latest document")
+	}
+	if err = fsys.WriteFile(latestPath, buf.Bytes(), 0644); err != nil {
+		return "", oops.Wrapf(err, "failed to write the latest document")
+	}
+	return LatestFileName, nil
+}
+
+// readStored parses the OpenVEX document of the source stored in the directory.
+func readStored(fsys FS, dir string, src manifest.Source) (*vex.VEX, error) {
+	f, err := fsys.Open(filepath.Join(dir, src.Path))
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to open the file")
+	}
+	r, err := src.Decompress(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to read the file")
+	}
+	v, err := vex.Parse(data)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to parse the file")
+	}
+	return v, nil
+}
@@ -0,0 +1,51 @@
+package vex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+// lfsPointerPrefix is the first line of the pointer files checked out in place of the files stored in Git LFS.
+// cf. https://github.com/git-lfs/git-lfs/blob/main/docs/spec.md
+const lfsPointerPrefix = "version https://git-lfs.github.com/spec/v1\n"
+
+// ErrLFSPointer is returned for VEX documents stored in Git LFS whose content is not fetched.
+var ErrLFSPointer = fmt.Errorf("file is a Git LFS pointer, enable LFS to fetch the content")
+
+// isLFSPointer reports whether the file is a Git LFS pointer rather than the content.
+func isLFSPointer(fsys FS, path string) (bool, error) {
+	head, err := readHead(fsys, path)
+	if err != nil {
+		return false, err
+	}
+	return bytes.HasPrefix(head, []byte(lfsPointerPrefix)), nil
+}
+
+// pullLFS replaces the Git LFS pointer files in the cloned repository with their content.
+// It requires the git-lfs extension, and returns download.ErrLFSUnavailable without it.
+// Sources other than clones, such as archives, are left as is.
+func pullLFS(ctx context.Context, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "lfs", "pull")
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil && !download.DetectGit(ctx).LFS {
+		// Without the extension, the pointer files would be left in place of the documents
+		return download.ErrLFSUnavailable
+	} else if err != nil {
+		return oops.With("stderr", stderr.String()).Wrapf(err, "git lfs pull error")
+	}
+	return nil
+}
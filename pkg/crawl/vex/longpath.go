@@ -0,0 +1,13 @@
+package vex
+
+// legacyMaxPath is the length from which Windows rejects paths without the extended-length prefix.
+// It's MAX_PATH (260) minus the room for a 8.3 file name that CreateDirectory requires.
+const legacyMaxPath = 248
+
+// LongPath returns the path in a form the OS accepts regardless of its length.
+// On Windows, paths reaching the legacy MAX_PATH limit are made absolute and given the extended-length prefix,
+// e.g. "\\?\C:\vexhub\pkg\...", since deep PURLs easily exceed it in VEX Hub.
+// It returns the path unchanged on the other platforms.
+func LongPath(path string) string {
+	return longPath(path)
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package vex
+
+func longPath(path string) string {
+	return path
+}
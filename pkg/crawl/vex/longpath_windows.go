@@ -0,0 +1,22 @@
+package vex
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// cf. https://learn.microsoft.com/en-us/windows/win32/fileio/maximum-file-path-limitation
+func longPath(path string) string {
+	if strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\\.\`) {
+		return path
+	}
+	// The prefix disables the normalization of the path, so it must be absolute and clean
+	abs, err := filepath.Abs(path)
+	if err != nil || len(abs) < legacyMaxPath {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[len(`\\`):]
+	}
+	return `\\?\` + abs
+}
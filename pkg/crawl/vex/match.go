@@ -0,0 +1,153 @@
+package vex
+
+import (
+	"cmp"
+	"path"
+	"strings"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/package-url/packageurl-go"
+)
+
+// Matchers reported by MatchProduct.
+const (
+	MatcherPURL  = "purl"
+	MatcherCPE   = "cpe"
+	MatcherRegex = "regex"
+)
+
+// DigestPolicy is how the digests of the PURLs are matched, either the "digest" qualifier or the version of an OCI PURL,
+// e.g. "pkg:oci/trivy?digest=sha256:..." or "pkg:oci/trivy@sha256:...". Publishers often issue VEX documents for
+// the pinned digests of their images, while the packages are registered without a digest, or the other way around.
+type DigestPolicy string
+
+const (
+	// DigestPolicyExact matches the digest qualifier like the others, see Options.SignificantQualifiers,
+	// so a digest of the package must be in the product. It's the default.
+	DigestPolicyExact DigestPolicy = "exact"
+
+	// DigestPolicyIgnore ignores the digests of both the package and the product.
+	DigestPolicyIgnore DigestPolicy = "ignore"
+
+	// DigestPolicyMatch compares the digests only if both the package and the product have one.
+	DigestPolicyMatch DigestPolicy = "match"
+)
+
+// MatchProduct reports whether the product ID in a VEX document refers to the target, and which matcher succeeded.
+// For a PURL, vex.PurlMatches with Options.SignificantQualifiers and Options.DigestPolicy is tried first, then the CPE comparison if Options.MatchCPE is set,
+// and then Options.ProductPatterns, so that publishers using product IDs other than PURLs can be crawled.
+// For a CPE target, the product ID must be a CPE of the same component, or match Options.ProductPatterns.
+func MatchProduct(target, productID string, opts Options) (string, bool) {
+	if _, ok := cpeFields(target); ok {
+		if cpe, err := ParseCPE(target); err == nil && cpe.Matches(productID) {
+			return MatcherCPE, true
+		}
+	} else if purlMatches(target, productID, opts) {
+		return MatcherPURL, true
+	} else if opts.MatchCPE && cpeMatches(target, productID) {
+		return MatcherCPE, true
+	}
+	for _, re := range opts.ProductPatterns {
+		if re.MatchString(productID) {
+			return MatcherRegex, true
+		}
+	}
+	return "", false
+}
+
+// purlMatches reports whether the product PURL refers to the target PURL, with the digests matched according to
+// Options.DigestPolicy.
+func purlMatches(target, productID string, opts Options) bool {
+	if cmp.Or(opts.DigestPolicy, DigestPolicyExact) == DigestPolicyExact {
+		return qualifiersMatch(target, productID, opts.SignificantQualifiers)
+	}
+	t, err := packageurl.FromString(target)
+	if err != nil {
+		return false
+	}
+	p, err := packageurl.FromString(productID)
+	if err != nil {
+		return false
+	}
+	td, pd := stripDigest(&t), stripDigest(&p)
+	if opts.DigestPolicy == DigestPolicyMatch && td != "" && pd != "" && td != pd {
+		return false
+	}
+	return qualifiersMatch(t.String(), p.String(), opts.SignificantQualifiers)
+}
+
+// stripDigest removes the digest from the PURL and returns it, lowercased since the hex encoding is case-insensitive.
+// The version of an OCI PURL is the digest of the image if it has an algorithm, e.g. "sha256:...", rather than a tag.
+func stripDigest(p *packageurl.PackageURL) string {
+	var digest string
+	var qualifiers packageurl.Qualifiers
+	for _, q := range p.Qualifiers {
+		if strings.EqualFold(q.Key, "digest") {
+			digest = q.Value
+			continue
+		}
+		qualifiers = append(qualifiers, q)
+	}
+	p.Qualifiers = qualifiers
+	if alg, hex, ok := strings.Cut(p.Version, ":"); ok && p.Type == packageurl.TypeOCI && alg != "" && hex != "" {
+		digest = cmp.Or(digest, p.Version)
+		p.Version = ""
+	}
+	return strings.ToLower(digest)
+}
+
+// qualifiersMatch reports whether the product PURL refers to the target PURL. The significant qualifiers are
+// compared on top of vex.PurlMatches without the qualifiers, see Options.SignificantQualifiers.
+func qualifiersMatch(target, productID string, significant []string) bool {
+	if significant == nil {
+		return vex.PurlMatches(target, productID)
+	}
+	t, err := packageurl.FromString(target)
+	if err != nil {
+		return false
+	}
+	p, err := packageurl.FromString(productID)
+	if err != nil {
+		return false
+	}
+	tq, pq := t.Qualifiers.Map(), p.Qualifiers.Map()
+	t.Qualifiers, p.Qualifiers = nil, nil
+	if !vex.PurlMatches(t.String(), p.String()) {
+		return false
+	}
+	for _, key := range significant {
+		key = strings.ToLower(key) // Qualifier keys are case-insensitive
+		if tq[key] != pq[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// cpeMatches reports whether the CPE, in either the 2.3 formatted string or the 2.2 URI binding,
+// names the package of the PURL. The product must equal the name, and the vendor must equal
+// the last segment of the namespace if any, e.g. "cpe:2.3:a:aquasecurity:trivy:*:..." for
+// "pkg:golang/github.com/aquasecurity/trivy". The versions are not compared.
+func cpeMatches(purl, cpe string) bool {
+	fields, ok := cpeFields(cpe)
+	if !ok || len(fields) < 3 || fields[0] != "a" {
+		return false // Only applications are packages
+	}
+	vendor, product := normalizeCPE(fields[1]), normalizeCPE(fields[2])
+
+	p, err := packageurl.FromString(purl)
+	if err != nil {
+		return false
+	} else if product != normalizeCPE(p.Name) {
+		return false
+	}
+	return p.Namespace == "" || vendor == "*" || vendor == normalizeCPE(path.Base(p.Namespace))
+}
+
+// normalizeCPE normalizes the component of a CPE or a PURL for comparison,
+// since CPEs are lowercase and replace hyphens with underscores by convention.
+func normalizeCPE(s string) string {
+	s = strings.ReplaceAll(s, `\`, "") // Unescape, e.g. "node\.js"
+	s = strings.TrimPrefix(s, "@")     // npm scopes, e.g. "@angular"
+	return strings.ReplaceAll(strings.ToLower(s), "-", "_")
+}
@@ -0,0 +1,215 @@
+package vex_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+func TestMatchProduct(t *testing.T) {
+	tests := []struct {
+		name        string
+		purl        string
+		productID   string
+		opts        vex.Options
+		wantMatcher string
+		wantOK      bool
+	}{
+		{
+			name:        "purl",
+			purl:        "pkg:golang/github.com/aquasecurity/trivy",
+			productID:   "pkg:golang/github.com/aquasecurity/trivy@v0.50.0",
+			opts:        vex.Options{MatchCPE: true},
+			wantMatcher: vex.MatcherPURL,
+			wantOK:      true,
+		},
+		{
+			name:      "cpe disabled",
+			purl:      "pkg:golang/github.com/aquasecurity/trivy",
+			productID: "cpe:2.3:a:aquasecurity:trivy:0.50.0:*:*:*:*:*:*:*",
+		},
+		{
+			name:        "cpe 2.3",
+			purl:        "pkg:golang/github.com/aquasecurity/trivy",
+			productID:   "cpe:2.3:a:aquasecurity:trivy:0.50.0:*:*:*:*:*:*:*",
+			opts:        vex.Options{MatchCPE: true},
+			wantMatcher: vex.MatcherCPE,
+			wantOK:      true,
+		},
+		{
+			name:        "cpe 2.2",
+			purl:        "pkg:npm/%40angular/animations",
+			productID:   "cpe:/a:angular:animations:17.0.0",
+			opts:        vex.Options{MatchCPE: true},
+			wantMatcher: vex.MatcherCPE,
+			wantOK:      true,
+		},
+		{
+			name:        "cpe without namespace",
+			purl:        "pkg:pypi/django-rest",
+			productID:   "cpe:2.3:a:encode:django_rest:*:*:*:*:*:*:*:*",
+			opts:        vex.Options{MatchCPE: true},
+			wantMatcher: vex.MatcherCPE,
+			wantOK:      true,
+		},
+		{
+			name:      "cpe of another vendor",
+			purl:      "pkg:golang/github.com/aquasecurity/trivy",
+			productID: "cpe:2.3:a:example:trivy:*:*:*:*:*:*:*:*",
+			opts:      vex.Options{MatchCPE: true},
+		},
+		{
+			name:      "cpe of an operating system",
+			purl:      "pkg:golang/github.com/aquasecurity/trivy",
+			productID: "cpe:2.3:o:aquasecurity:trivy:*:*:*:*:*:*:*:*",
+			opts:      vex.Options{MatchCPE: true},
+		},
+		{
+			name:        "cpe target",
+			purl:        "cpe:2.3:a:example:product:*:*:*:*:*:*:*:*",
+			productID:   "cpe:/a:example:product:1.0.0",
+			wantMatcher: vex.MatcherCPE,
+			wantOK:      true,
+		},
+		{
+			name:      "cpe target of another product",
+			purl:      "cpe:2.3:a:example:product:*:*:*:*:*:*:*:*",
+			productID: "cpe:2.3:a:example:other:1.0.0:*:*:*:*:*:*:*",
+		},
+		{
+			name:      "cpe target against purl",
+			purl:      "cpe:2.3:a:example:product:*:*:*:*:*:*:*:*",
+			productID: "pkg:npm/product",
+		},
+		{
+			name:      "regex",
+			purl:      "pkg:npm/foo",
+			productID: "https://example.com/products/foo",
+			opts: vex.Options{
+				ProductPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://example\.com/products/foo$`)},
+			},
+			wantMatcher: vex.MatcherRegex,
+			wantOK:      true,
+		},
+		{
+			name:      "no regex match",
+			purl:      "pkg:npm/foo",
+			productID: "https://example.com/products/bar",
+			opts: vex.Options{
+				ProductPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://example\.com/products/foo$`)},
+			},
+		},
+		{
+			name:      "qualifier missing in the product",
+			purl:      "pkg:deb/debian/curl?arch=amd64&distro=debian-12",
+			productID: "pkg:deb/debian/curl@7.88.1?arch=amd64",
+		},
+		{
+			name:        "insignificant qualifier",
+			purl:        "pkg:deb/debian/curl?arch=amd64&distro=debian-12",
+			productID:   "pkg:deb/debian/curl@7.88.1?arch=amd64",
+			opts:        vex.Options{SignificantQualifiers: []string{"arch"}},
+			wantMatcher: vex.MatcherPURL,
+			wantOK:      true,
+		},
+		{
+			name:      "significant qualifier mismatch",
+			purl:      "pkg:deb/debian/curl?arch=amd64",
+			productID: "pkg:deb/debian/curl@7.88.1?arch=arm64",
+			opts:      vex.Options{SignificantQualifiers: []string{"arch"}},
+		},
+		{
+			name:      "significant qualifier only in the product",
+			purl:      "pkg:deb/debian/curl",
+			productID: "pkg:deb/debian/curl@7.88.1?arch=arm64",
+			opts:      vex.Options{SignificantQualifiers: []string{"ARCH"}},
+		},
+		{
+			name:        "all qualifiers ignored",
+			purl:        "pkg:deb/debian/curl?arch=amd64",
+			productID:   "pkg:deb/debian/curl@7.88.1?arch=arm64",
+			opts:        vex.Options{SignificantQualifiers: []string{}},
+			wantMatcher: vex.MatcherPURL,
+			wantOK:      true,
+		},
+		{
+			name:      "name mismatch with significant qualifiers",
+			purl:      "pkg:deb/debian/curl?arch=amd64",
+			productID: "pkg:deb/debian/wget?arch=amd64",
+			opts:      vex.Options{SignificantQualifiers: []string{"arch"}},
+		},
+		{
+			name:        "oci digest only in the product",
+			purl:        "pkg:oci/trivy?repository_url=ghcr.io/aquasecurity/trivy",
+			productID:   "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			wantMatcher: vex.MatcherPURL,
+			wantOK:      true,
+		},
+		{
+			name:      "oci digest only in the package",
+			purl:      "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			productID: "pkg:oci/trivy?repository_url=ghcr.io/aquasecurity/trivy",
+		},
+		{
+			name:        "oci digest ignored",
+			purl:        "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			productID:   "pkg:oci/trivy?digest=sha256:def456&repository_url=ghcr.io/aquasecurity/trivy",
+			opts:        vex.Options{DigestPolicy: vex.DigestPolicyIgnore},
+			wantMatcher: vex.MatcherPURL,
+			wantOK:      true,
+		},
+		{
+			name:        "oci digest only in the package matched",
+			purl:        "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			productID:   "pkg:oci/trivy?repository_url=ghcr.io/aquasecurity/trivy",
+			opts:        vex.Options{DigestPolicy: vex.DigestPolicyMatch},
+			wantMatcher: vex.MatcherPURL,
+			wantOK:      true,
+		},
+		{
+			name:        "oci same digests matched",
+			purl:        "pkg:oci/trivy?digest=sha256:ABC123&repository_url=ghcr.io/aquasecurity/trivy",
+			productID:   "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			opts:        vex.Options{DigestPolicy: vex.DigestPolicyMatch},
+			wantMatcher: vex.MatcherPURL,
+			wantOK:      true,
+		},
+		{
+			name:      "oci different digests matched",
+			purl:      "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			productID: "pkg:oci/trivy?digest=sha256:def456&repository_url=ghcr.io/aquasecurity/trivy",
+			opts:      vex.Options{DigestPolicy: vex.DigestPolicyMatch},
+		},
+		{
+			name:        "oci digest version matched",
+			purl:        "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			productID:   "pkg:oci/trivy@sha256%3Aabc123?repository_url=ghcr.io/aquasecurity/trivy",
+			opts:        vex.Options{DigestPolicy: vex.DigestPolicyMatch},
+			wantMatcher: vex.MatcherPURL,
+			wantOK:      true,
+		},
+		{
+			name:      "oci different digest version matched",
+			purl:      "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			productID: "pkg:oci/trivy@sha256%3Adef456?repository_url=ghcr.io/aquasecurity/trivy",
+			opts:      vex.Options{DigestPolicy: vex.DigestPolicyMatch},
+		},
+		{
+			name:      "oci digest ignored with another repository",
+			purl:      "pkg:oci/trivy?digest=sha256:abc123&repository_url=ghcr.io/aquasecurity/trivy",
+			productID: "pkg:oci/trivy?repository_url=docker.io/aquasec/trivy",
+			opts:      vex.Options{DigestPolicy: vex.DigestPolicyIgnore},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, ok := vex.MatchProduct(tt.purl, tt.productID, tt.opts)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMatcher, matcher)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package vex
+
+import (
+	"cmp"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// fileOutcome is the outcome of validating a file found in the source repository.
+type fileOutcome struct {
+	index    int // Position in the walk order
+	filePath string
+	matched  []Validator // Validators handling the file in order of precedence, empty if it's not a VEX document
+	doc      *Document
+	err      error
+	carried  bool // Unchanged since the previous crawl, see Options.Incremental
+}
+
+// validateFiles walks the directory and validates the files with up to the given number of workers,
+// while the walk keeps enumerating the files. The outcomes are returned in the walk order
+// so that the result is deterministic regardless of the number of workers.
+// Symlinks are skipped since they may point outside the repository.
+func validateFiles(fsys FS, root string, workers int, logger *slog.Logger,
+	validate func(filePath string) fileOutcome) ([]fileOutcome, error) {
+	paths := make(chan fileOutcome)
+	var (
+		mu       sync.Mutex
+		outcomes []fileOutcome
+		wg       sync.WaitGroup
+	)
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range paths {
+				outcome := validate(job.filePath)
+				outcome.index, outcome.filePath = job.index, job.filePath
+				mu.Lock()
+				outcomes = append(outcomes, outcome)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var index int
+	err := walkDir(fsys, root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if d.IsDir() {
+			return nil
+		} else if d.Type()&fs.ModeSymlink != 0 {
+			logger.Warn("Ignored symlink", slog.String("file_path", filePath))
+			return nil
+		}
+		paths <- fileOutcome{index: index, filePath: filePath}
+		index++
+		return nil
+	})
+	close(paths)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(outcomes, func(a, b fileOutcome) int {
+		return cmp.Compare(a.index, b.index)
+	})
+	return outcomes, nil
+}
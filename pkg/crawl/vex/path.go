@@ -0,0 +1,36 @@
+package vex
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/samber/oops"
+)
+
+// ErrPathTraversal is returned for paths escaping the directory they must stay in.
+var ErrPathTraversal = fmt.Errorf("path escapes the directory")
+
+// SafeJoin joins the relative path to the directory, rejecting paths that escape it with ErrPathTraversal.
+// Paths derived from source repositories must go through it since the repositories are untrusted.
+func SafeJoin(dir, rel string) (string, error) {
+	if !filepath.IsLocal(rel) {
+		return "", oops.With("dir", dir).With("path", rel).Wrap(ErrPathTraversal)
+	}
+	return filepath.Join(dir, rel), nil
+}
+
+// CanonicalSubpath returns the subpath of a PURL in the canonical form defined by the PURL specification,
+// where empty, "." and ".." segments are discarded, e.g. "./cmd//tool/" becomes "cmd/tool".
+// Discarding ".." keeps the subpath within the package directory when it's mapped to nested directories,
+// and equivalent subpaths map to the same directory.
+func CanonicalSubpath(subpath string) string {
+	var segments []string
+	for _, s := range strings.Split(subpath, "/") {
+		if s == "" || s == "." || s == ".." {
+			continue
+		}
+		segments = append(segments, s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,218 @@
+package vex_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+func TestSafeJoin(t *testing.T) {
+	tests := []struct {
+		name    string
+		rel     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "file name",
+			rel:  "openvex.json",
+			want: "/vexhub/pkg/npm/foo/openvex.json",
+		},
+		{
+			name: "nested path",
+			rel:  "sub/openvex.json",
+			want: "/vexhub/pkg/npm/foo/sub/openvex.json",
+		},
+		{
+			name: "dot segments staying in the directory",
+			rel:  "sub/../openvex.json",
+			want: "/vexhub/pkg/npm/foo/openvex.json",
+		},
+		{
+			name:    "parent directory",
+			rel:     "../bar/openvex.json",
+			wantErr: true,
+		},
+		{
+			name:    "escaping after nested path",
+			rel:     "sub/../../../../etc/passwd",
+			wantErr: true,
+		},
+		{
+			name:    "parent itself",
+			rel:     "..",
+			wantErr: true,
+		},
+		{
+			name:    "absolute path",
+			rel:     "/etc/passwd",
+			wantErr: true,
+		},
+		{
+			name:    "empty path",
+			rel:     "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vex.SafeJoin("/vexhub/pkg/npm/foo", tt.rel)
+			if tt.wantErr {
+				require.ErrorIs(t, err, vex.ErrPathTraversal)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCrawlPackage_Symlink(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "outside.openvex.json")
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+		writeVEX(t, filepath.Dir(outside), "pkg:golang/github.com/example/package", "CVE-2024-0002")
+		require.NoError(t, os.Rename(filepath.Join(filepath.Dir(outside), ".vex", "openvex.json"), outside))
+		// Relative to the root directory wherever the repository is cloned
+		target := strings.Repeat("../", 32) + strings.TrimPrefix(outside, "/")
+		require.NoError(t, os.Symlink(target, filepath.Join(dir, ".vex", "evil.openvex.json")))
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	vexHubDir := t.TempDir()
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{})
+	require.NoError(t, err)
+
+	pkgDir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package")
+	assert.FileExists(t, filepath.Join(pkgDir, "openvex.json"))
+	assert.NoFileExists(t, filepath.Join(pkgDir, "evil.openvex.json"), "symlinks must not be followed")
+	assert.FileExists(t, outside)
+}
+
+func TestCanonicalSubpath(t *testing.T) {
+	tests := []struct {
+		subpath string
+		want    string
+	}{
+		{subpath: "", want: ""},
+		{subpath: "docs", want: "docs"},
+		{subpath: "cmd/tool", want: "cmd/tool"},
+		{subpath: "/cmd//tool/", want: "cmd/tool"},
+		{subpath: "./cmd/./tool", want: "cmd/tool"},
+		{subpath: "../../etc/passwd", want: "etc/passwd"},
+		{subpath: "cmd/../../tool", want: "cmd/tool"},
+		{subpath: "..", want: ""},
+		{subpath: "a b/c%d", want: "a b/c%d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subpath, func(t *testing.T) {
+			assert.Equal(t, tt.want, vex.CanonicalSubpath(tt.subpath))
+		})
+	}
+}
+
+func TestPackageDir_Subpath(t *testing.T) {
+	base := packageurl.PackageURL{Type: packageurl.TypeGolang, Namespace: "github.com/example", Name: "package"}
+	subpaths := []string{"", "cmd", "cmd/tool", "cmd/tool/v2", "cmd-tool", "cmd tool", "internal/cmd/tool", "../../escape"}
+
+	seen := make(map[string]string)
+	for _, subpath := range subpaths {
+		purl := base
+		purl.Subpath = subpath
+		dir := vex.PackageDir("/hub", purl, "")
+
+		// No traversal
+		rel, err := filepath.Rel("/hub/pkg/golang/github.com/example/package", dir)
+		require.NoError(t, err)
+		assert.True(t, rel == "." || filepath.IsLocal(rel), "%q escapes the package directory: %s", subpath, dir)
+
+		// No collision
+		if other, ok := seen[dir]; ok {
+			t.Errorf("%q and %q collide in %s", subpath, other, dir)
+		}
+		seen[dir] = subpath
+
+		// The canonical PURL string maps back to the same directory
+		purl.Subpath = vex.CanonicalSubpath(subpath)
+		parsed, err := packageurl.FromString(purl.String())
+		require.NoError(t, err)
+		assert.Equal(t, dir, vex.PackageDir("/hub", parsed, ""), subpath)
+	}
+}
+
+func TestCrawlPackage_Subpath(t *testing.T) {
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package#cmd/tool", "CVE-2024-0001")
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	purl := packageurl.PackageURL{
+		Type:      packageurl.TypeGolang,
+		Namespace: "github.com/example",
+		Name:      "package",
+		Subpath:   "./cmd//tool/",
+	}
+
+	vexHubDir := t.TempDir()
+	_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{})
+	require.NoError(t, err)
+
+	dir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package", "cmd", "tool")
+	assert.FileExists(t, filepath.Join(dir, "openvex.json"))
+
+	m, err := manifest.Read(filepath.Join(dir, manifest.FileName))
+	require.NoError(t, err)
+	assert.Equal(t, "pkg:golang/github.com/example/package#cmd/tool", m.ID)
+
+	// The manifest ID round-trips to the same directory
+	id, err := packageurl.FromString(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, dir, vex.PackageDir(vexHubDir, id, ""))
+}
+
+func TestLongPath(t *testing.T) {
+	// A package directory beyond the legacy MAX_PATH limit of Windows
+	dir := filepath.Join(t.TempDir(), "pkg", "golang", strings.Repeat("namespace/", 20), strings.Repeat("n", 100))
+	require.Greater(t, len(dir), 260)
+
+	if runtime.GOOS == "windows" {
+		assert.True(t, strings.HasPrefix(vex.LongPath(dir), `\\?\`))
+	} else {
+		assert.Equal(t, dir, vex.LongPath(dir))
+	}
+	short := filepath.Join("vexhub", "pkg", "npm", "foo")
+	assert.Equal(t, short, vex.LongPath(short))
+
+	fsys := vex.OSFS
+	require.NoError(t, fsys.MkdirAll(dir, 0755))
+	require.NoError(t, fsys.WriteFile(filepath.Join(dir, "openvex.json.tmp"), []byte("{}"), 0644))
+	require.NoError(t, fsys.Rename(filepath.Join(dir, "openvex.json.tmp"), filepath.Join(dir, "openvex.json")))
+	b, err := fsys.ReadFile(filepath.Join(dir, "openvex.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(b))
+
+	tmpDir, err := fsys.MkdirTemp(dir, "tmp-")
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(tmpDir))
+	require.NoError(t, fsys.RemoveAll(dir))
+}
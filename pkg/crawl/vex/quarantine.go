@@ -0,0 +1,88 @@
+package vex
+
+import (
+	"bytes"
+	"fmt"
+	neturl "net/url"
+	"path/filepath"
+
+	"github.com/samber/oops"
+)
+
+// QuarantineDir is the directory of VEX Hub keeping the files rejected by the validation, see Options.Quarantine.
+// It's outside the "pkg" and "cpe" trees so that consumers never see the files.
+const QuarantineDir = "quarantine"
+
+// Suffixes of the files in the quarantine, so that they're never taken for VEX documents or manifests.
+const (
+	quarantineFileSuffix   = ".rejected"
+	quarantineReasonSuffix = ".reason"
+)
+
+// Quarantine bounds the files rejected by the validation that are kept for inspection.
+type Quarantine struct {
+	MaxFiles    int   // Files kept per package, the others are dropped as before
+	MaxFileSize int64 // Size in bytes above which only the reason is kept
+}
+
+// quarantine writes the rejected files of a package into its directory in QuarantineDir.
+// A nil quarantine drops the files.
+type quarantine struct {
+	fsys  FS
+	dir   string
+	limit Quarantine
+	url   string
+	count int
+}
+
+// newQuarantine resets the quarantine of the package directory, so that it only keeps the files rejected
+// by the latest crawl, or returns nil if the quarantine is disabled.
+func newQuarantine(fsys FS, vexHubDir, vexDir, url string, opts Options) (*quarantine, error) {
+	if opts.Quarantine == nil {
+		return nil, nil
+	}
+	rel, err := filepath.Rel(vexHubDir, vexDir)
+	if err != nil {
+		return nil, oops.With("dir", vexDir).Wrapf(err, "file rel error")
+	}
+	dir := filepath.Join(vexHubDir, QuarantineDir, rel)
+	if err = resetDir(fsys, dir); err != nil {
+		return nil, oops.With("dir", dir).Wrapf(err, "failed to reset the quarantine")
+	}
+	return &quarantine{fsys: fsys, dir: dir, limit: *opts.Quarantine, url: url}, nil
+}
+
+// add writes the rejected file with its reason, and reports whether it was kept within the limits.
+// The path relative to the repository is escaped into the file name, so that files with the same name
+// in different directories don't collide.
+func (q *quarantine) add(filePath, relPath string, reason error) (bool, error) {
+	if q == nil || q.count >= q.limit.MaxFiles {
+		return false, nil
+	}
+	name := neturl.PathEscape(filepath.ToSlash(relPath))
+	errBuilder := oops.With("dir", q.dir).With("path", relPath)
+
+	info, err := q.fsys.Stat(filePath)
+	if err != nil {
+		return false, errBuilder.Wrapf(err, "failed to stat the rejected file")
+	}
+	content := fmt.Sprintf("path: %s\nurl: %s\nreason: %s\n", filepath.ToSlash(relPath), q.url, reason)
+	if info.Size() > q.limit.MaxFileSize {
+		content += fmt.Sprintf("content: omitted, %d bytes exceed the limit of %d bytes\n", info.Size(), q.limit.MaxFileSize)
+	} else if err = q.copy(filePath, filepath.Join(q.dir, name+quarantineFileSuffix)); err != nil {
+		return false, errBuilder.Wrap(err)
+	}
+	if err = q.fsys.WriteFile(filepath.Join(q.dir, name+quarantineReasonSuffix), []byte(content), 0644); err != nil {
+		return false, errBuilder.Wrapf(err, "failed to write the reason")
+	}
+	q.count++
+	return true, nil
+}
+
+func (q *quarantine) copy(src, dst string) error {
+	var buf bytes.Buffer
+	if err := copyFile(q.fsys, &buf, src); err != nil {
+		return oops.Wrapf(err, "failed to read the rejected file")
+	}
+	return oops.Wrapf(q.fsys.WriteFile(dst, buf.Bytes(), 0644), "failed to write the rejected file")
+}
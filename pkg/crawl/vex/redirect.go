@@ -0,0 +1,71 @@
+package vex
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// infoRefsPath is the discovery endpoint of the git smart HTTP protocol.
+const infoRefsPath = "/info/refs"
+
+// resolveMove returns the URL the git repository has moved to, e.g. after a rename or a transfer on GitHub,
+// or nil if it hasn't moved. The hosts redirect the discovery request of the old location like its web pages,
+// so the redirects are followed there before downloading. The git CLI would follow them as well,
+// but the permalinks and the manifest would still be built from the old location.
+// Only git repositories over HTTP(S) are looked up, and a failed lookup is left to the download to report.
+func resolveMove(ctx context.Context, u *xurl.URL) (*xurl.URL, error) {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.IsArchive() || u.IsBundle() || u.IsFile() {
+		return nil, nil
+	}
+	errBuilder := oops.With("url", u.Redacted())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.RepoString()+infoRefsPath+"?service=git-upload-pack", nil)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create the request")
+	}
+	resp, err := download.Client().Do(req)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "request error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errBuilder.With("status", resp.Status).Errorf("unexpected status")
+	}
+
+	final := resp.Request.URL
+	finalPath := strings.TrimSuffix(final.Path, infoRefsPath)
+	if !strings.HasSuffix(finalPath, ".git") {
+		finalPath += ".git"
+	}
+	old, err := xurl.Parse(u.RepoString())
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	if final.Host == old.Host && finalPath == old.Path {
+		return nil, nil
+	}
+
+	moved := *u.URL
+	moved.Scheme, moved.Host = final.Scheme, final.Host
+	moved.Path = finalPath
+	if !strings.HasSuffix(u.Path, ".git") {
+		moved.Path = strings.TrimSuffix(finalPath, ".git")
+	}
+	if final.Host != u.Host {
+		// The credentials are for the old host
+		moved.User = nil
+	}
+	to, err := xurl.Parse(moved.String())
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	to.SetRef(u.Ref())
+	to.SetSubdirs(u.Subdirs())
+	return to, nil
+}
@@ -0,0 +1,79 @@
+package vex
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/samber/oops"
+
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// listRefs lists the references of the remote repository.
+func listRefs(ctx context.Context, u *xurl.URL) ([]*plumbing.Reference, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{u.RepoString()},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to list remote references")
+	}
+	return refs, nil
+}
+
+// RemoteHead returns the commit hash the remote repository would be crawled at,
+// i.e. the commit of the ref in the URL, or of the default branch if no ref is set.
+func RemoteHead(ctx context.Context, u *xurl.URL) (string, error) {
+	_, hash, err := remoteRef(ctx, u)
+	return hash, err
+}
+
+// remoteRef returns the reference the remote repository would be crawled at and the hash of its commit.
+// The reference is empty if the URL pins a commit.
+func remoteRef(ctx context.Context, u *xurl.URL) (plumbing.ReferenceName, string, error) {
+	errBuilder := oops.Code("remote_head_error").In("crawl").With("url", xurl.Redact(u.RepoString()))
+	if ref := u.Ref(); plumbing.IsHash(ref) {
+		return "", ref, nil
+	}
+
+	refs, err := listRefs(ctx, u)
+	if err != nil {
+		return "", "", errBuilder.Wrap(err)
+	}
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference)
+	for _, ref := range refs {
+		byName[ref.Name()] = ref
+	}
+
+	var candidates []plumbing.ReferenceName
+	if ref := u.Ref(); ref != "" {
+		// Annotated tags are peeled to the commit
+		candidates = []plumbing.ReferenceName{
+			plumbing.NewTagReferenceName(ref + "^{}"),
+			plumbing.NewTagReferenceName(ref),
+			plumbing.NewBranchReferenceName(ref),
+		}
+	} else {
+		candidates = []plumbing.ReferenceName{plumbing.HEAD}
+	}
+
+	for _, name := range candidates {
+		ref, ok := byName[name]
+		if !ok {
+			continue
+		}
+		if ref.Type() == plumbing.SymbolicReference {
+			if ref, ok = byName[ref.Target()]; !ok {
+				continue
+			}
+		}
+		// The peeled tag can't be fetched by name
+		return plumbing.ReferenceName(strings.TrimSuffix(ref.Name().String(), "^{}")), ref.Hash().String(), nil
+	}
+	return "", "", errBuilder.Errorf("reference not found")
+}
@@ -0,0 +1,301 @@
+package vex
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/samber/oops"
+)
+
+// Formats of the SBOMs whose embedded VEX statements are extracted, see Options.SBOM.
+const (
+	sbomSPDX      = "spdx"
+	sbomCycloneDX = "cyclonedx"
+)
+
+// spdxValidator extracts the VEX statements of SPDX documents, which are only candidates with Options.SBOM.
+var spdxValidator = formatValidator{format: sbomSPDX, validate: validateSPDX}
+
+// matchSPDX reports whether the file is an SPDX document whose VEX statements are extracted, i.e. Options.SBOM
+// is set and the name is "*.spdx.json". The file must also match Options.Patterns if any.
+func matchSPDX(path string, opts Options) (bool, error) {
+	if !opts.SBOM || !strings.HasSuffix(strings.ToLower(filepath.Base(path)), ".spdx.json") {
+		return false, nil
+	} else if len(opts.Patterns) == 0 {
+		return true, nil
+	}
+	return matchPatterns(path, opts.Patterns)
+}
+
+// spdxDocument is the subset of an SPDX 3.0 document serialized in JSON-LD needed to extract the VEX statements.
+// SPDX 2 documents have no VEX statements.
+// cf. https://spdx.github.io/spdx-spec/v3.0.1/model/Security/Security/
+type spdxDocument struct {
+	Graph []spdxElement `json:"@graph"`
+}
+
+// spdxElement holds the properties of the elements of the graph used by any of the types.
+type spdxElement struct {
+	Type   string `json:"type"`
+	SPDXID string `json:"spdxId"`
+	Name   string `json:"name"`
+
+	// CreationInfo
+	Created   string   `json:"created"`
+	CreatedBy []string `json:"createdBy"`
+
+	// software_Package and security_Vulnerability
+	PackageURL         string `json:"software_packageUrl"`
+	ExternalIdentifier []struct {
+		Type       string `json:"externalIdentifierType"`
+		Identifier string `json:"identifier"`
+	} `json:"externalIdentifier"`
+
+	// security_Vex*VulnAssessmentRelationship
+	From            string   `json:"from"`
+	To              []string `json:"to"`
+	Justification   string   `json:"security_justificationType"`
+	ImpactStatement string   `json:"security_impactStatement"`
+	ActionStatement string   `json:"security_actionStatement"`
+	StatusNotes     string   `json:"security_statusNotes"`
+	PublishedTime   string   `json:"security_publishedTime"`
+}
+
+// spdxStatuses are the OpenVEX statuses of the VEX relationship types.
+var spdxStatuses = map[string]vex.Status{
+	"security_VexAffectedVulnAssessmentRelationship":           vex.StatusAffected,
+	"security_VexNotAffectedVulnAssessmentRelationship":        vex.StatusNotAffected,
+	"security_VexFixedVulnAssessmentRelationship":              vex.StatusFixed,
+	"security_VexUnderInvestigationVulnAssessmentRelationship": vex.StatusUnderInvestigation,
+}
+
+// spdxJustifications are the OpenVEX justifications of the SPDX ones, which are the same in camel case.
+var spdxJustifications = map[string]vex.Justification{
+	"componentNotPresent":                         vex.ComponentNotPresent,
+	"vulnerableCodeNotPresent":                    vex.VulnerableCodeNotPresent,
+	"vulnerableCodeNotInExecutePath":              vex.VulnerableCodeNotInExecutePath,
+	"vulnerableCodeCannotBeControlledByAdversary": vex.VulnerableCodeCannotBeControlledByAdversary,
+	"inlineMitigationsAlreadyExist":               vex.InlineMitigationsAlreadyExist,
+}
+
+// validateSPDX converts the VEX relationships of the SPDX document about the PURL into an OpenVEX document,
+// which is validated like the published ones.
+func validateSPDX(path, purl string, opts Options) (*Document, error) {
+	data, err := opts.fs().ReadFile(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to read SPDX file")
+	}
+	var spdx spdxDocument
+	if err = json.Unmarshal(data, &spdx); err != nil {
+		return nil, oops.Wrapf(err, "failed to decode SPDX file")
+	}
+
+	v, authors, invalid := spdx.openVEX()
+	if len(v.Statements) == 0 {
+		return nil, ErrNoEmbeddedVEX
+	}
+	if products := productIDs(v); !extractStatements(v, purl, opts) {
+		return &Document{Format: FormatOpenVEX, Products: products, Authors: authors, SBOM: sbomSPDX}, ErrPURLMismatch
+	}
+
+	doc, err := checkVEX(v, invalid, purl, opts)
+	if doc == nil {
+		return nil, err
+	}
+	doc.Authors, doc.SBOM = authors, sbomSPDX
+	if doc.Content == nil {
+		var buf bytes.Buffer
+		if err := v.ToJSON(&buf); err != nil {
+			return nil, oops.Wrapf(err, "failed to encode VEX")
+		}
+		doc.Content = buf.Bytes()
+	}
+	return doc, err
+}
+
+// openVEX converts the VEX relationships into OpenVEX statements, whose products are the PURLs of the elements,
+// or their CPEs. It returns the names of the agents creating the document, and the number of malformed
+// publication times, which are dropped like the malformed timestamps of OpenVEX documents.
+func (d *spdxDocument) openVEX() (*vex.VEX, []string, int) {
+	elements := make(map[string]spdxElement)
+	for _, e := range d.Graph {
+		if e.SPDXID != "" {
+			elements[e.SPDXID] = e
+		}
+	}
+
+	v := &vex.VEX{Metadata: vex.Metadata{Context: vex.ContextLocator(), Version: 1}}
+	var authors []string
+	var invalid int
+	for _, e := range d.Graph {
+		switch e.Type {
+		case "SpdxDocument":
+			v.ID = cmp.Or(v.ID, e.SPDXID)
+		case "CreationInfo":
+			for _, agent := range e.CreatedBy {
+				if name := elements[agent].Name; name != "" && !slices.Contains(authors, name) {
+					authors = append(authors, name)
+				}
+			}
+			if t, err := time.Parse(time.RFC3339, e.Created); err == nil && v.Timestamp == nil {
+				v.Timestamp = &t
+			}
+		}
+
+		status, ok := spdxStatuses[e.Type]
+		if !ok {
+			continue
+		}
+		statement := vex.Statement{
+			Vulnerability:   elements[e.From].vulnerability(e.From),
+			Status:          status,
+			Justification:   spdxJustifications[e.Justification],
+			ImpactStatement: e.ImpactStatement,
+			ActionStatement: e.ActionStatement,
+			StatusNotes:     e.StatusNotes,
+		}
+		if t, err := time.Parse(time.RFC3339, e.PublishedTime); err == nil {
+			statement.Timestamp = &t
+		} else if e.PublishedTime != "" {
+			invalid++
+		}
+		for _, to := range e.To {
+			statement.Products = append(statement.Products, vex.Product{
+				Component: vex.Component{ID: elements[to].productID(to)},
+			})
+		}
+		v.Statements = append(v.Statements, statement)
+	}
+	v.Author = strings.Join(authors, ", ")
+	return v, authors, invalid
+}
+
+// vulnerability returns the vulnerability of the element, named after its CVE if any.
+// The element may be missing from the document, in which case the reference names the vulnerability.
+func (e spdxElement) vulnerability(ref string) vex.Vulnerability {
+	var ids []string
+	for _, id := range e.ExternalIdentifier {
+		if id.Type == "cve" {
+			ids = append([]string{id.Identifier}, ids...)
+		} else if id.Identifier != "" {
+			ids = append(ids, id.Identifier)
+		}
+	}
+	vuln := vex.Vulnerability{Name: vex.VulnerabilityID(cmp.Or(e.Name, ref))}
+	if len(ids) > 0 {
+		vuln.Name = vex.VulnerabilityID(ids[0])
+	}
+	for _, id := range ids {
+		if vex.VulnerabilityID(id) != vuln.Name && !slices.Contains(vuln.Aliases, vex.VulnerabilityID(id)) {
+			vuln.Aliases = append(vuln.Aliases, vex.VulnerabilityID(id))
+		}
+	}
+	return vuln
+}
+
+// productID returns the PURL of the element, or its CPE, falling back to the reference itself.
+func (e spdxElement) productID(ref string) string {
+	if e.PackageURL != "" {
+		return e.PackageURL
+	}
+	for _, id := range e.ExternalIdentifier {
+		if id.Type == "packageUrl" || id.Type == "cpe23" {
+			return id.Identifier
+		}
+	}
+	return ref
+}
+
+// extractStatements keeps the statements about the PURL, along with their matching products only, since the other
+// components of an SBOM are not the package. It reports whether any statement is kept.
+func extractStatements(v *vex.VEX, purl string, opts Options) bool {
+	var kept []vex.Statement
+	for _, statement := range v.Statements {
+		var products []vex.Product
+		for _, product := range statement.Products {
+			if _, ok := MatchProduct(purl, product.ID, opts); ok {
+				products = append(products, product)
+			}
+		}
+		if len(products) > 0 {
+			statement.Products = products
+			kept = append(kept, statement)
+		}
+	}
+	v.Statements = kept
+	return len(kept) > 0
+}
+
+// extractCycloneDX reduces the CycloneDX SBOM to the vulnerabilities affecting the PURL, rewriting the raw document
+// so that fields unknown to the crawler are preserved. The inventory is dropped, so the affected components are
+// referred to by their PURLs. It returns the products of the SBOM for diagnostics, and leaves no vulnerability
+// if none affects the PURL.
+func extractCycloneDX(fsys FS, path string, bom *cdxBOM, purl string, opts Options) ([]string, error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to read CycloneDX file")
+	}
+	var raw map[string]any
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, oops.Wrapf(err, "failed to decode CycloneDX file")
+	}
+	rawVulns, _ := raw["vulnerabilities"].([]any)
+
+	refs := bom.purlsByRef()
+	var products []string
+	var kept []cdxVulnerability
+	var rawKept []any
+	for i, vuln := range bom.Vulnerabilities {
+		// The raw document has the same vulnerabilities and affects as it was decoded into the BOM
+		rawVuln, _ := rawVulns[i].(map[string]any)
+		rawAffects, _ := rawVuln["affects"].([]any)
+		var affects []any
+		n := 0
+		for j, affect := range vuln.Affects {
+			p, ok := refs[affect.Ref]
+			if !ok {
+				p = affect.Ref
+			}
+			if !slices.Contains(products, p) {
+				products = append(products, p)
+			}
+			rawAffect, _ := rawAffects[j].(map[string]any)
+			if _, ok = MatchProduct(purl, p, opts); !ok || rawAffect == nil {
+				continue
+			}
+			rawAffect["ref"] = p
+			affects = append(affects, rawAffect)
+			affect.Ref = p
+			vuln.Affects[n] = affect
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		vuln.Affects = vuln.Affects[:n]
+		rawVuln["affects"] = affects
+		kept = append(kept, vuln)
+		rawKept = append(rawKept, rawVuln)
+	}
+	bom.Components, bom.Vulnerabilities = nil, kept
+	if len(kept) == 0 {
+		return products, nil
+	}
+
+	for _, key := range []string{"components", "services", "dependencies", "compositions"} {
+		delete(raw, key)
+	}
+	raw["vulnerabilities"] = rawKept
+	content, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to encode CycloneDX file")
+	}
+	bom.content = append(content, '\n')
+	return products, nil
+}
@@ -0,0 +1,64 @@
+package vex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/samber/oops"
+)
+
+// semanticEqual reports whether the stored files have the same JSON content regardless of the formatting,
+// e.g. whitespace, key order or escaping. Gzip-compressed files are compared decompressed.
+// Files that don't parse as JSON, e.g. XML documents, are never equal since they've already been compared by bytes.
+func semanticEqual(name string, a, b []byte) bool {
+	va, err := decodeJSON(name, a)
+	if err != nil {
+		return false
+	}
+	vb, err := decodeJSON(name, b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func decodeJSON(name string, data []byte) (any, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if strings.HasSuffix(name, ".gz") {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	d := json.NewDecoder(r)
+	d.UseNumber() // Keep the numbers as written, e.g. large integers
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	} else if d.More() {
+		return nil, oops.Errorf("trailing data")
+	}
+	return v, nil
+}
+
+// readBlob reads the content of the blob from the git repository.
+func readBlob(repo *git.Repository, hash plumbing.Hash) ([]byte, error) {
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return nil, oops.With("hash", hash.String()).Wrapf(err, "git blob")
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, oops.With("hash", hash.String()).Wrapf(err, "git blob")
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
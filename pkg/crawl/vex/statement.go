@@ -0,0 +1,64 @@
+package vex
+
+import (
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/samber/oops"
+)
+
+// Statement is a VEX statement normalized across the formats, e.g. to compare documents.
+type Statement struct {
+	Vulnerability string `json:"vulnerability"`
+	Product       string `json:"product"`
+	Status        string `json:"status"` // The status in the format of the document, e.g. "not_affected"
+}
+
+// Statements returns the statements of the VEX document, one per product.
+func Statements(path string) ([]Statement, error) {
+	format, err := detectContentFormat(OSFS, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	switch format {
+	case FormatCycloneDX:
+		bom, err := parseCycloneDX(OSFS, path)
+		if err != nil {
+			return nil, err
+		}
+		refs := bom.purlsByRef()
+		for _, vuln := range bom.Vulnerabilities {
+			for _, affect := range vuln.Affects {
+				p, ok := refs[affect.Ref]
+				if !ok {
+					p = affect.Ref
+				}
+				statements = append(statements, Statement{
+					Vulnerability: vuln.ID,
+					Product:       p,
+					Status:        vuln.Analysis.State,
+				})
+			}
+		}
+	default:
+		// go-vex converts CSAF documents to OpenVEX
+		v, err := vex.Open(path)
+		if err != nil {
+			return nil, oops.Wrapf(err, "failed to open VEX file")
+		}
+		for _, stmt := range v.Statements {
+			vulnID := string(stmt.Vulnerability.Name)
+			if vulnID == "" {
+				vulnID = stmt.Vulnerability.ID
+			}
+			for _, product := range stmt.Products {
+				statements = append(statements, Statement{
+					Vulnerability: vulnID,
+					Product:       product.ID,
+					Status:        string(stmt.Status),
+				})
+			}
+		}
+	}
+	return statements, nil
+}
@@ -0,0 +1,48 @@
+package vex
+
+import (
+	"slices"
+
+	"github.com/openvex/go-vex/pkg/vex"
+)
+
+// filterStatuses removes the statements whose status is not allowed from the OpenVEX document,
+// and returns the number of removed statements. All the statements are kept if no status is allowed explicitly.
+func filterStatuses(v *vex.VEX, allowed []vex.Status) int {
+	if len(allowed) == 0 {
+		return 0
+	}
+	var statements []vex.Statement
+	for _, statement := range v.Statements {
+		if slices.Contains(allowed, statement.Status) {
+			statements = append(statements, statement)
+		}
+	}
+	filtered := len(v.Statements) - len(statements)
+	v.Statements = statements
+	return filtered
+}
+
+// cdxStatus returns the OpenVEX status of the analysis state of a CycloneDX vulnerability,
+// or an empty string if the vulnerability isn't analyzed.
+// cf. https://cyclonedx.org/docs/1.6/json/#vulnerabilities_items_analysis_state
+func cdxStatus(state string) vex.Status {
+	switch state {
+	case "not_affected", "false_positive":
+		return vex.StatusNotAffected
+	case "exploitable":
+		return vex.StatusAffected
+	case "resolved", "resolved_with_pedigree":
+		return vex.StatusFixed
+	case "in_triage":
+		return vex.StatusUnderInvestigation
+	}
+	return ""
+}
+
+// cdxFiltered reports whether the CycloneDX vulnerability is dropped by the allowed statuses.
+// Vulnerabilities without an analysis state can't be classified and are kept.
+func cdxFiltered(vuln cdxVulnerability, allowed []vex.Status) bool {
+	status := cdxStatus(vuln.Analysis.State)
+	return len(allowed) > 0 && status != "" && !slices.Contains(allowed, status)
+}
@@ -0,0 +1,86 @@
+package vex
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/samber/oops"
+
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// LatestTags returns up to n semver tags of the remote repository, newest first.
+// Tags that are not valid semantic versions are ignored.
+func LatestTags(ctx context.Context, u *xurl.URL, n int) ([]string, error) {
+	errBuilder := oops.Code("list_tags_error").In("crawl").With("url", xurl.Redact(u.RepoString()))
+	refs, err := listRefs(ctx, u)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+
+	type tag struct {
+		name    string
+		version *version.Version
+	}
+	var tags []tag
+	for _, ref := range refs {
+		if !ref.Name().IsTag() {
+			continue
+		}
+		name := ref.Name().Short()
+		v, err := version.NewSemver(strings.TrimPrefix(name, "v"))
+		if err != nil {
+			continue
+		}
+		tags = append(tags, tag{
+			name:    name,
+			version: v,
+		})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].version.GreaterThan(tags[j].version)
+	})
+
+	var names []string
+	for _, t := range tags {
+		if len(names) >= n {
+			break
+		}
+		names = append(names, t.name)
+	}
+	return names, nil
+}
+
+// VersionTag returns the tag of the remote repository releasing the version of the package, or an empty string if none.
+// The version is tried as is and with or without the "v" prefix, then prefixed with the name of the package
+// as monorepos do, e.g. "1.2.3" matches "1.2.3", "v1.2.3", "foo-v1.2.3", "foo/v1.2.3" or "foo@1.2.3" for "foo".
+func VersionTag(ctx context.Context, u *xurl.URL, name, version string) (string, error) {
+	errBuilder := oops.Code("list_tags_error").In("crawl").With("url", xurl.Redact(u.RepoString())).With("version", version)
+	refs, err := listRefs(ctx, u)
+	if err != nil {
+		return "", errBuilder.Wrap(err)
+	}
+	tags := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.Name().IsTag() {
+			tags[strings.TrimSuffix(ref.Name().Short(), "^{}")] = true
+		}
+	}
+
+	bare := strings.TrimPrefix(version, "v")
+	candidates := []string{version, "v" + bare, bare}
+	for _, sep := range []string{"-", "/", "@"} {
+		for _, v := range []string{version, "v" + bare, bare} {
+			candidates = append(candidates, name+sep+v)
+		}
+	}
+	for _, c := range candidates {
+		if tags[c] {
+			return c, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,96 @@
+package vex
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/samber/oops"
+)
+
+// Fields of OpenVEX documents and statements decoded as time.Time by go-vex,
+// which fails the whole document if any of them is malformed.
+var (
+	documentTimeFields  = []string{"timestamp", "last_updated"}
+	statementTimeFields = []string{"timestamp", "last_updated", "action_statement_timestamp"}
+)
+
+// dropMalformedTimestamps removes the timestamps that are not RFC 3339 from the OpenVEX document,
+// so that the document still parses, and returns the number of statements whose timestamp was malformed.
+// The document is returned as is if all the timestamps are well-formed.
+func dropMalformedTimestamps(data []byte) ([]byte, int, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, oops.Wrapf(err, "failed to decode the file")
+	}
+	var statements []map[string]json.RawMessage
+	if raw, ok := doc["statements"]; ok {
+		if err := json.Unmarshal(raw, &statements); err != nil {
+			return nil, 0, oops.Wrapf(err, "failed to decode the statements")
+		}
+	}
+
+	changed := dropFields(doc, documentTimeFields)
+	var invalid int
+	for _, statement := range statements {
+		if !validTime(statement, "timestamp") {
+			invalid++
+		}
+		if dropFields(statement, statementTimeFields) {
+			changed = true
+		}
+	}
+	if !changed {
+		return data, 0, nil
+	}
+
+	if statements != nil {
+		raw, err := json.Marshal(statements)
+		if err != nil {
+			return nil, 0, oops.Wrapf(err, "failed to encode the statements")
+		}
+		doc["statements"] = raw
+	}
+	fixed, err := json.Marshal(doc)
+	if err != nil {
+		return nil, 0, oops.Wrapf(err, "failed to encode the file")
+	}
+	return fixed, invalid, nil
+}
+
+// dropFields removes the malformed timestamps among the fields and reports whether any was removed.
+func dropFields(obj map[string]json.RawMessage, fields []string) bool {
+	var dropped bool
+	for _, field := range fields {
+		if !validTime(obj, field) {
+			delete(obj, field)
+			dropped = true
+		}
+	}
+	return dropped
+}
+
+// validTime reports whether the field is missing, null or decodes as time.Time as go-vex does.
+func validTime(obj map[string]json.RawMessage, field string) bool {
+	raw, ok := obj[field]
+	if !ok {
+		return true
+	}
+	var t *time.Time
+	return json.Unmarshal(raw, &t) == nil
+}
+
+// missingTimestamps returns the number of statements with no timestamp, which is well-formed but missing,
+// excluding the statements inheriting the timestamp of the document.
+func missingTimestamps(v *vex.VEX) int {
+	if v.Timestamp != nil {
+		return 0
+	}
+	var missing int
+	for _, statement := range v.Statements {
+		if statement.Timestamp == nil {
+			missing++
+		}
+	}
+	return missing
+}
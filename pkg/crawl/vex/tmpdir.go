@@ -0,0 +1,111 @@
+package vex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+)
+
+// TempDirPath returns the deterministic temporary directory of the package at the ref,
+// so that repeated runs of the same package use a predictable path.
+func TempDirPath(purl packageurl.PackageURL, ref string) string {
+	return tempDirPath(purlTarget(purl).id, ref)
+}
+
+// tempDirPath returns the deterministic temporary directory of the target ID, either a PURL or a CPE, at the ref.
+func tempDirPath(id, ref string) string {
+	sum := sha256.Sum256([]byte(id + "@" + ref))
+	return filepath.Join(os.TempDir(), "vexhub-crawler-"+hex.EncodeToString(sum[:8]))
+}
+
+// makeTempDir creates the temporary directory to download the source into, and returns the lock to release
+// once it's removed, if any.
+// If deterministic, it claims the directory from TempDirPath under its lock file, and falls back to a random one
+// when the directory is in use, e.g. by a concurrent run of the same package. The directory left behind by a run
+// that crashed is removed, since its lock is released along with the process.
+func makeTempDir(id, ref string, deterministic bool) (string, *tempDirLock, error) {
+	if deterministic {
+		dir := tempDirPath(id, ref)
+		lock, err := lockTempDir(dir)
+		if err != nil {
+			return "", nil, oops.With("dir", dir).Wrapf(err, "failed to lock the temporary directory")
+		} else if lock != nil {
+			if err = claimTempDir(dir); err != nil {
+				lock.release()
+				return "", nil, oops.With("dir", dir).Wrap(err)
+			}
+			return dir, lock, nil
+		}
+		slog.Warn("Temporary directory is in use, falling back to a random one", slog.String("dir", dir))
+	}
+
+	dir, err := os.MkdirTemp("", "vexhub-crawler-*")
+	if err != nil {
+		return "", nil, oops.Wrapf(err, "failed to create a temporary directory")
+	}
+	return dir, nil, nil
+}
+
+// claimTempDir creates the locked directory, removing the one left behind by a crashed run.
+func claimTempDir(dir string) error {
+	err := os.Mkdir(dir, 0700)
+	if errors.Is(err, os.ErrExist) {
+		slog.Info("Removing the stale temporary directory", slog.String("dir", dir))
+		if err = os.RemoveAll(dir); err != nil {
+			return oops.Wrapf(err, "failed to remove the stale temporary directory")
+		}
+		err = os.Mkdir(dir, 0700)
+	}
+	if err != nil {
+		return oops.Wrapf(err, "failed to create the temporary directory")
+	}
+	return nil
+}
+
+// tempDirLock is the lock file of a deterministic temporary directory, "<dir>.lock", held by the run using it.
+type tempDirLock struct {
+	f *os.File
+}
+
+// lockTempDir locks the directory without waiting. It returns nil if the directory is locked by another run.
+func lockTempDir(dir string) (*tempDirLock, error) {
+	path := dir + ".lock"
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		if ok, err := tryLockFile(f); err != nil || !ok {
+			f.Close()
+			return nil, err
+		}
+
+		// The run releasing the lock removes the file, so another one may have replaced it meanwhile
+		locked, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if current, err := os.Stat(path); err == nil && os.SameFile(locked, current) {
+			return &tempDirLock{f: f}, nil
+		}
+		f.Close()
+	}
+}
+
+// release removes the lock file and unlocks it. The file is removed first so that no other run locks it
+// in between, and it's left behind where the open files can't be removed, e.g. on Windows.
+func (l *tempDirLock) release() {
+	if l == nil {
+		return
+	}
+	_ = os.Remove(l.f.Name())
+	_ = unlockFile(l.f)
+	_ = l.f.Close()
+}
@@ -0,0 +1,71 @@
+package vex_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+func TestTempDirPath(t *testing.T) {
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+	other, err := packageurl.FromString("pkg:golang/github.com/example/other")
+	require.NoError(t, err)
+
+	assert.Equal(t, vex.TempDirPath(purl, "v1.0.0"), vex.TempDirPath(purl, "v1.0.0"))
+	assert.NotEqual(t, vex.TempDirPath(purl, "v1.0.0"), vex.TempDirPath(purl, "v1.1.0"))
+	assert.NotEqual(t, vex.TempDirPath(purl, ""), vex.TempDirPath(other, ""))
+}
+
+func TestCrawlPackage_DeterministicTempDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		writeVEX(t, dir, "pkg:golang/github.com/example/package", "CVE-2024-0001")
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+	opts := vex.Options{DeterministicTempDir: true}
+
+	_, err = vex.CrawlPackage(context.Background(), t.TempDir(), u, purl, opts)
+	require.NoError(t, err)
+	assert.NoDirExists(t, vex.TempDirPath(purl, ""), "the directory should be removed after the crawl")
+	assert.NoFileExists(t, vex.TempDirPath(purl, "")+".lock", "the lock should be removed after the crawl")
+
+	// The directory in use by another run is left intact
+	tmpDir := vex.TempDirPath(purl, "")
+	src, err := vex.Fetch(context.Background(), u, purl, opts)
+	require.NoError(t, err)
+	marker := filepath.Join(tmpDir, "in-use")
+	require.NoError(t, os.WriteFile(marker, nil, 0600))
+
+	_, err = vex.CrawlPackage(context.Background(), t.TempDir(), u, purl, opts)
+	require.NoError(t, err)
+	assert.FileExists(t, marker)
+	require.NoError(t, src.Close())
+	assert.NoDirExists(t, tmpDir)
+
+	// The directory left behind by a crashed run is removed and used again
+	require.NoError(t, os.Mkdir(tmpDir, 0700))
+	stale := filepath.Join(tmpDir, "stale")
+	require.NoError(t, os.WriteFile(stale, nil, 0600))
+
+	src, err = vex.Fetch(context.Background(), u, purl, opts)
+	require.NoError(t, err)
+	assert.Equal(t, tmpDir, filepath.Dir(src.Dir))
+	assert.NoFileExists(t, stale)
+	require.NoError(t, src.Close())
+	assert.NoDirExists(t, tmpDir)
+}
@@ -0,0 +1,131 @@
+package vex
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// DirResult is the outcome of validating a local directory as if it were the source of a package, see ValidateDir.
+type DirResult struct {
+	Result
+
+	// Accepted lists the files that would be copied into VEX Hub, in the walk order.
+	Accepted []AcceptedFile `json:"accepted,omitempty"`
+}
+
+// AcceptedFile is a VEX document that would be copied into VEX Hub.
+type AcceptedFile struct {
+	Path       string `json:"path"`   // Relative path from the directory
+	Stored     string `json:"stored"` // File name stored in VEX Hub
+	Format     string `json:"format,omitempty"`
+	Statements int    `json:"statements,omitempty"`
+}
+
+// ValidateDir runs the discovery and the validation of Collect over the local directory, e.g. a checkout of the
+// publisher's repository, and reports which files would be collected for the PURL and which would be skipped.
+// The directory is copied into a temporary one with an empty VEX Hub, so that neither the directory nor any hub
+// is touched. The options of a previous crawl, such as Incremental and MergeManifest, are ignored.
+// The result is returned even if the package would fail, along with the error.
+func ValidateDir(ctx context.Context, dir string, purl packageurl.PackageURL, opts Options) (*DirResult, error) {
+	return validateDir(ctx, dir, purlTarget(purl), opts)
+}
+
+// ValidateDirCPE validates the local directory as if it were the source of the package tracked by the CPE,
+// like ValidateDir.
+func ValidateDirCPE(ctx context.Context, dir string, cpe CPE, opts Options) (*DirResult, error) {
+	return validateDir(ctx, dir, cpeTarget(cpe), opts)
+}
+
+func validateDir(ctx context.Context, dir string, t target, opts Options) (*DirResult, error) {
+	errBuilder := oops.Code("validate_dir_error").In("crawl").With(t.kind, t.id).With("dir", dir)
+	abs, err := filepath.Abs(dir)
+	if err == nil {
+		// The walk doesn't follow the directory itself if it's a symlink
+		abs, err = filepath.EvalSymlinks(abs)
+	}
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to resolve the directory")
+	}
+	// The directory is advertised as a file URL, whose subdirectories are the ones of the options
+	src, err := xurl.Parse((&url.URL{Scheme: "file", Path: "/" + strings.TrimPrefix(filepath.ToSlash(abs), "/")}).String())
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "vexhub-crawler-validate-*")
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create a temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// The files are copied since the accepted ones are moved into VEX Hub
+	repoDir := filepath.Join(tmpDir, "src")
+	if err = copyDir(abs, repoDir); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to copy the directory")
+	}
+
+	result := &DirResult{}
+	opts.Incremental, opts.MergeManifest, opts.History, opts.Quarantine = false, false, nil, nil
+	opts.accepted = func(relPath string, src manifest.Source) {
+		result.Accepted = append(result.Accepted, AcceptedFile{
+			Path:       relPath,
+			Stored:     src.Path,
+			Format:     src.Format,
+			Statements: src.StatementCount,
+		})
+	}
+	res, err := collect(ctx, OSFS, filepath.Join(tmpDir, "hub"), repoDir, src, t, "", opts)
+	if res != nil {
+		result.Result = *res
+	}
+	return result, err
+}
+
+// copyDir copies the regular files of the directory into a new one. Symlinks are skipped as the crawl ignores them,
+// and so is the .git directory, which is never collected.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		to := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir() && d.Name() == ".git" && rel != ".":
+			return filepath.SkipDir
+		case d.IsDir():
+			return os.MkdirAll(to, 0700)
+		case !d.Type().IsRegular():
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(to)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
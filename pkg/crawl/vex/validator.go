@@ -0,0 +1,232 @@
+package vex
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/samber/oops"
+)
+
+// Document is a validated VEX document.
+type Document struct {
+	Format     Format
+	Products   []string // Product IDs declared in the document
+	Authors    []string // Authors declared in the document
+	Withheld   int      // The number of statements withheld due to embargoes
+	Filtered   int      // The number of statements dropped by Options.Statuses
+	Statements int      // The number of statements left, or vulnerabilities for CycloneDX
+
+	// Vulnerabilities are the IDs of the vulnerabilities the statements are about, excluding withheld ones.
+	Vulnerabilities []string
+	Content         []byte // Rewritten content, or nil if the original file is copied as is
+
+	// Matcher is the matcher that matched the PURL against a product ID, e.g. MatcherPURL.
+	Matcher string
+
+	// Subcomponent reports whether the PURL matched a subcomponent rather than a product.
+	Subcomponent bool
+
+	// MissingTimestamps and InvalidTimestamps are the numbers of statements without a timestamp
+	// and with a malformed timestamp respectively. They are only checked for OpenVEX.
+	MissingTimestamps int
+	InvalidTimestamps int
+
+	// MissingJustifications is the number of not_affected statements with neither a justification
+	// nor an impact statement. It's only checked for OpenVEX.
+	MissingJustifications int
+
+	// SBOM is the format of the SBOM the statements were extracted from, e.g. "spdx", or empty for a VEX document.
+	SBOM string
+}
+
+// Validator validates VEX documents of a format.
+type Validator interface {
+	// Match reports whether the file is a VEX document handled by the validator.
+	Match(path string) bool
+
+	// Validate parses the VEX document and checks that it contains the PURL.
+	// It returns ErrPURLMismatch, ErrNoStatement, ErrEmbargoed, ErrFilteredOut or ErrNoEmbeddedVEX to classify
+	// the documents that can't be copied.
+	// The PURL is a CPE for the targets crawled with CrawlCPE, which MatchProduct handles as well.
+	Validate(path, purl string, opts Options) (*Document, error)
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   []Validator
+
+	// builtinValidators are the built-in validators by format, used when the format is forced.
+	builtinValidators = map[Format]Validator{
+		FormatOpenVEX:   formatValidator{format: FormatOpenVEX, validate: validateVEX},
+		FormatCSAF:      formatValidator{format: FormatCSAF, validate: validateCSAF},
+		FormatCycloneDX: formatValidator{format: FormatCycloneDX, validate: validateCycloneDX},
+	}
+)
+
+func init() {
+	for _, format := range []Format{FormatOpenVEX, FormatCSAF, FormatCycloneDX} {
+		RegisterValidator(builtinValidators[format])
+	}
+}
+
+// RegisterValidator registers the validator for CrawlPackage.
+// Validators registered later take precedence so that the built-in validators can be overridden.
+// When multiple validators match a file, the one with the highest precedence is always selected.
+func RegisterValidator(v Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators = append([]Validator{v}, validators...)
+}
+
+// formatMatcher is implemented by the built-in validators to match the format detected once per file,
+// on any filesystem. Other validators read files from the OS filesystem in Match.
+type formatMatcher interface {
+	matchFormat(format Format) bool
+}
+
+// matchValidators returns the validators handling the file in order of precedence.
+// The format is detected once for all the built-in validators, and the error is returned if the file can't be read.
+func matchValidators(fsys FS, path string) ([]Validator, error) {
+	format, err := detectFileFormat(fsys, path)
+	if err != nil {
+		return nil, oops.With("path", path).Wrapf(err, "failed to detect the format")
+	}
+
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	var matched []Validator
+	for _, v := range validators {
+		if m, ok := v.(formatMatcher); ok && m.matchFormat(format) {
+			matched = append(matched, v)
+		} else if !ok && v.Match(path) {
+			matched = append(matched, v)
+		}
+	}
+	return matched, nil
+}
+
+// selectValidators returns the validators handling the file in order of precedence,
+// honoring the file name patterns and the format configured for the package.
+func selectValidators(path string, opts Options) ([]Validator, error) {
+	if ok, err := matchSPDX(path, opts); err != nil {
+		return nil, err
+	} else if ok {
+		// SPDX documents are not VEX documents, so the format and the other validators don't apply
+		return []Validator{spdxValidator}, nil
+	}
+
+	var forced Validator
+	if opts.Format != FormatUnknown {
+		var ok bool
+		if forced, ok = builtinValidators[opts.Format]; !ok {
+			return nil, oops.With("format", opts.Format).Errorf("unknown format")
+		}
+	}
+
+	if opts.single {
+		// The document of a single-file source is validated whatever its name, e.g. "advisory.json"
+		return contentValidators(path, forced, opts)
+	} else if len(opts.Patterns) == 0 {
+		matched, err := matchValidators(opts.fs(), path)
+		if err != nil || len(matched) == 0 || forced == nil {
+			return matched, err
+		}
+		return []Validator{forced}, nil
+	}
+
+	ok, err := matchPatterns(path, opts.Patterns)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	return contentValidators(path, forced, opts)
+}
+
+// contentValidators returns the validators of a file selected regardless of the built-in names,
+// i.e. the forced one, the ones matching the name, or the one of the format detected from the content.
+func contentValidators(path string, forced Validator, opts Options) ([]Validator, error) {
+	if forced != nil {
+		return []Validator{forced}, nil
+	}
+
+	// The file name may not follow the built-in conventions
+	if matched, err := matchValidators(opts.fs(), path); err != nil || len(matched) > 0 {
+		return matched, err
+	}
+	format, err := detectContentFormat(opts.fs(), path)
+	if err != nil {
+		return nil, err
+	}
+	return []Validator{builtinValidators[format]}, nil
+}
+
+// matchPatterns reports whether the file name matches any of the glob patterns, ignoring case.
+func matchPatterns(path string, patterns []string) (bool, error) {
+	// Match case-insensitively so that the same files are selected on every filesystem
+	name := strings.ToLower(filepath.Base(path))
+	for _, pattern := range patterns {
+		ok, err := filepath.Match(strings.ToLower(pattern), name)
+		if err != nil {
+			return false, oops.With("pattern", pattern).Wrapf(err, "invalid pattern")
+		} else if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// validatorNames returns the names of the validators for logging.
+func validatorNames(vs []Validator) []string {
+	var names []string
+	for _, v := range vs {
+		names = append(names, validatorName(v))
+	}
+	return names
+}
+
+func validatorName(v Validator) string {
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// checkAuthors returns ErrUntrustedAuthor unless any author of the document is allowed.
+func checkAuthors(doc *Document, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, author := range doc.Authors {
+		if slices.Contains(allowed, author) {
+			return nil
+		}
+	}
+	return ErrUntrustedAuthor
+}
+
+// formatValidator is a built-in validator dispatched by DetectFormat.
+type formatValidator struct {
+	format   Format
+	validate func(path, purl string, opts Options) (*Document, error)
+}
+
+func (v formatValidator) String() string {
+	return string(v.format)
+}
+
+func (v formatValidator) Match(path string) bool {
+	format, err := detectFileFormat(OSFS, path)
+	return err == nil && v.matchFormat(format)
+}
+
+func (v formatValidator) matchFormat(format Format) bool {
+	return format == v.format
+}
+
+func (v formatValidator) Validate(path, purl string, opts Options) (*Document, error) {
+	return v.validate(path, purl, opts)
+}
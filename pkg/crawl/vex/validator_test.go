@@ -0,0 +1,121 @@
+package vex_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// lineValidator accepts "*.vex.txt" files listing a product ID per line.
+type lineValidator struct{}
+
+func (lineValidator) Match(path string) bool {
+	return strings.HasSuffix(path, ".vex.txt")
+}
+
+func (lineValidator) Validate(path, purl string, _ vex.Options) (*vex.Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := &vex.Document{
+		Format:   "text",
+		Products: strings.Fields(string(b)),
+	}
+	for _, product := range doc.Products {
+		if product == purl {
+			return doc, nil
+		}
+	}
+	return doc, vex.ErrPURLMismatch
+}
+
+func TestRegisterValidator(t *testing.T) {
+	vex.RegisterValidator(lineValidator{})
+
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		vexDir := filepath.Join(dir, ".vex")
+		require.NoError(t, os.MkdirAll(vexDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(vexDir, "package.vex.txt"),
+			[]byte("pkg:golang/github.com/example/package\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(vexDir, "other.vex.txt"),
+			[]byte("pkg:golang/github.com/example/other\n"), 0644))
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	vexHubDir := t.TempDir()
+	result, err := vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{VerifyWrites: true})
+	require.NoError(t, err)
+	assert.Equal(t, []vex.SkippedFile{
+		{
+			Path:     ".vex/other.vex.txt",
+			Reason:   "PURL does not match",
+			Products: []string{"pkg:golang/github.com/example/other"},
+		},
+	}, result.Skipped)
+
+	dir := filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package")
+	m, err := manifest.Read(filepath.Join(dir, manifest.FileName))
+	require.NoError(t, err)
+	require.Len(t, m.Sources, 1)
+	assert.Equal(t, "package.vex.txt", m.Sources[0].Path)
+	assert.Equal(t, "text", m.Sources[0].Format)
+}
+
+// prefixedLineValidator also accepts "*.vex.txt" files, overlapping with lineValidator.
+type prefixedLineValidator struct {
+	lineValidator
+}
+
+func (v prefixedLineValidator) Validate(path, purl string, opts vex.Options) (*vex.Document, error) {
+	doc, err := v.lineValidator.Validate(path, purl, opts)
+	if doc != nil {
+		doc.Format = "prefixed-text"
+	}
+	return doc, err
+}
+
+func TestRegisterValidator_Overlap(t *testing.T) {
+	vex.RegisterValidator(lineValidator{})
+	vex.RegisterValidator(prefixedLineValidator{})
+
+	server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		vexDir := filepath.Join(dir, ".vex")
+		require.NoError(t, os.MkdirAll(vexDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(vexDir, "package.vex.txt"),
+			[]byte("pkg:golang/github.com/example/package\n"), 0644))
+	})
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/testrepo.git")
+	require.NoError(t, err)
+	purl, err := packageurl.FromString("pkg:golang/github.com/example/package")
+	require.NoError(t, err)
+
+	// The validator registered last is always selected
+	for range 2 {
+		vexHubDir := t.TempDir()
+		_, err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.Options{})
+		require.NoError(t, err)
+
+		m, err := manifest.Read(filepath.Join(vexHubDir, "pkg", "golang", "github.com", "example", "package", manifest.FileName))
+		require.NoError(t, err)
+		require.Len(t, m.Sources, 1)
+		assert.Equal(t, "prefixed-text", m.Sources[0].Format)
+	}
+}
@@ -0,0 +1,95 @@
+package download
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/samber/oops"
+)
+
+// BasicAuth is the credentials of HTTP basic authentication sent to the listed hosts,
+// e.g. private file servers serving VEX documents or tarballs.
+// They're only sent over HTTPS, and the requests to the hosts over plain HTTP are refused.
+type BasicAuth struct {
+	Hosts    []string `yaml:"hosts"` // e.g. "files.example.com" or "files.example.com:8443"
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+}
+
+var (
+	basicAuthMu sync.RWMutex
+	basicAuths  map[string]BasicAuth // By host
+)
+
+// ConfigureBasicAuth authenticates the subsequent requests to the hosts with the credentials.
+// Like ConfigureTLS, it must be called before downloads start. An empty list disables the authentication.
+func ConfigureBasicAuth(auths []BasicAuth) error {
+	errBuilder := oops.Code("basic_auth_config_error").In("download")
+
+	var hosts map[string]BasicAuth
+	for i, a := range auths {
+		// Don't include the credentials in errors and logs
+		errBuilder := errBuilder.With("index", i).With("hosts", a.Hosts)
+		if len(a.Hosts) == 0 {
+			return errBuilder.Errorf("hosts are required for basic auth")
+		} else if a.Username == "" && a.Password == "" {
+			return errBuilder.Errorf("username or password is required for basic auth")
+		}
+		if hosts == nil {
+			hosts = make(map[string]BasicAuth)
+		}
+		for _, host := range a.Hosts {
+			if _, ok := hosts[host]; ok {
+				return errBuilder.With("host", host).Errorf("multiple basic auth credentials for the host")
+			}
+			hosts[host] = a
+		}
+	}
+
+	basicAuthMu.Lock()
+	basicAuths = hosts
+	basicAuthMu.Unlock()
+
+	installGitProtocol()
+	return nil
+}
+
+func currentBasicAuths() map[string]BasicAuth {
+	basicAuthMu.RLock()
+	defer basicAuthMu.RUnlock()
+	return basicAuths
+}
+
+// lookupBasicAuth returns the credentials of the host of the URL regardless of the scheme.
+// A host without a port matches the default ports only, as for the TLS settings.
+func lookupBasicAuth(auths map[string]BasicAuth, u *url.URL) (BasicAuth, bool) {
+	if a, ok := auths[u.Host]; ok {
+		return a, true
+	}
+	if port := u.Port(); port == "" || port == "443" || port == "80" {
+		a, ok := auths[u.Hostname()]
+		return a, ok
+	}
+	return BasicAuth{}, false
+}
+
+// basicAuthTransport adds the basic auth credentials to the requests to their hosts.
+type basicAuthTransport struct {
+	base  http.RoundTripper
+	auths map[string]BasicAuth
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	a, ok := lookupBasicAuth(t.auths, req.URL)
+	if !ok || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	} else if req.URL.Scheme != "https" {
+		// Refused rather than sent without the credentials, so that the misconfiguration is noticed
+		return nil, oops.Code("basic_auth_error").In("download").With("host", req.URL.Host).
+			Errorf("refusing to send basic auth credentials over plain HTTP")
+	}
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(a.Username, a.Password)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,119 @@
+package download_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+func TestConfigureBasicAuth(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"@context": "https://openvex.dev/ns/v0.2.0"}`))
+	})
+	tlsServer := httptest.NewTLSServer(handler)
+	t.Cleanup(tlsServer.Close)
+	plainServer := httptest.NewServer(handler)
+	t.Cleanup(plainServer.Close)
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	writePEM(t, caPath, "CERTIFICATE", tlsServer.Certificate().Raw)
+	tlsURL, err := url.Parse(tlsServer.URL)
+	require.NoError(t, err)
+	plainURL, err := url.Parse(plainServer.URL)
+	require.NoError(t, err)
+	require.NoError(t, download.ConfigureTLS(download.TLSConfig{
+		CACerts: []download.CACert{{Hosts: []string{tlsURL.Host}, Path: caPath}},
+	}))
+	t.Cleanup(func() { require.NoError(t, download.ConfigureTLS(download.TLSConfig{})) })
+
+	tests := []struct {
+		name    string
+		src     string
+		hosts   []string
+		wantErr string
+	}{
+		{
+			name:  "https",
+			src:   tlsServer.URL + "/openvex.json",
+			hosts: []string{tlsURL.Host},
+		},
+		{
+			name:    "another host",
+			src:     tlsServer.URL + "/openvex.json",
+			hosts:   []string{"files.example.com"},
+			wantErr: "unexpected status",
+		},
+		{
+			name:    "plain http",
+			src:     plainServer.URL + "/openvex.json",
+			hosts:   []string{plainURL.Host},
+			wantErr: "refusing to send basic auth credentials over plain HTTP",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, download.ConfigureBasicAuth([]download.BasicAuth{
+				{Hosts: tt.hosts, Username: "user", Password: "s3cret"},
+			}))
+			t.Cleanup(func() { require.NoError(t, download.ConfigureBasicAuth(nil)) })
+
+			dst := filepath.Join(t.TempDir(), "openvex.json")
+			_, err := download.File(context.Background(), tt.src, dst)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				assert.NotContains(t, err.Error(), "s3cret")
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, dst)
+		})
+	}
+}
+
+func TestConfigureBasicAuth_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		auths   []download.BasicAuth
+		wantErr string
+	}{
+		{
+			name:    "no hosts",
+			auths:   []download.BasicAuth{{Username: "user", Password: "s3cret"}},
+			wantErr: "hosts are required",
+		},
+		{
+			name:    "no credentials",
+			auths:   []download.BasicAuth{{Hosts: []string{"files.example.com"}}},
+			wantErr: "username or password is required",
+		},
+		{
+			name: "duplicate host",
+			auths: []download.BasicAuth{
+				{Hosts: []string{"files.example.com"}, Username: "user", Password: "s3cret"},
+				{Hosts: []string{"files.example.com"}, Username: "other", Password: "s3cret"},
+			},
+			wantErr: "multiple basic auth credentials for the host",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := download.ConfigureBasicAuth(tt.auths)
+			require.ErrorContains(t, err, tt.wantErr)
+			assert.NotContains(t, err.Error(), "s3cret")
+		})
+	}
+}
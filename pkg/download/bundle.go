@@ -0,0 +1,44 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os/exec"
+
+	"github.com/hashicorp/go-getter"
+	"github.com/samber/oops"
+)
+
+// bundleGetter is a go-getter implementation that clones a local git bundle file.
+// It makes network-free crawls possible for offline mirrors.
+type bundleGetter struct {
+	client *getter.Client
+}
+
+func (g *bundleGetter) ClientMode(_ *url.URL) (getter.ClientMode, error) {
+	return getter.ClientModeDir, nil
+}
+
+func (g *bundleGetter) Get(dst string, u *url.URL) error {
+	ctx := context.Background()
+	if g.client != nil && g.client.Ctx != nil {
+		ctx = g.client.Ctx
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "clone", "--quiet", "--", u.Path, dst)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return oops.With("stderr", stderr.String()).Wrapf(err, "git clone error")
+	}
+	return nil
+}
+
+func (g *bundleGetter) GetFile(_ string, _ *url.URL) error {
+	return oops.Errorf("a single file cannot be retrieved from a git bundle")
+}
+
+func (g *bundleGetter) SetClient(c *getter.Client) {
+	g.client = c
+}
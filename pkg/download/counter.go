@@ -0,0 +1,128 @@
+package download
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pollInterval is how often the destination of the git CLI is measured while it downloads.
+var pollInterval = time.Second
+
+// Counter is the running count of the bytes downloaded with the contexts carrying it, see WithCounter.
+// The bytes are counted as they arrive, so that a budget can stop the downloads in flight.
+type Counter struct {
+	parent   *Counter
+	total    atomic.Int64
+	limit    int64
+	exceeded func()
+	once     sync.Once
+}
+
+// NewCounter returns a counter calling exceeded once the total reaches the limit. Zero means no limit.
+func NewCounter(limit int64, exceeded func()) *Counter {
+	return &Counter{limit: limit, exceeded: exceeded}
+}
+
+// Add adds the bytes to the counter and its parents.
+func (c *Counter) Add(n int64) {
+	for ; c != nil; c = c.parent {
+		total := c.total.Add(n)
+		if c.limit > 0 && total >= c.limit && c.exceeded != nil {
+			c.once.Do(c.exceeded)
+		}
+	}
+}
+
+// Load returns the bytes counted so far.
+func (c *Counter) Load() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.total.Load()
+}
+
+// settle replaces the running count of a single download with the size of its files once it's done,
+// since the archives are counted compressed while they arrive.
+func (c *Counter) settle(stats Stats) {
+	c.Add(stats.Bytes - c.Load())
+}
+
+type counterKey struct{}
+
+// WithCounter returns the context counting the bytes downloaded with it into the counter,
+// including the bytes of the downloads failing halfway.
+func WithCounter(ctx context.Context, c *Counter) context.Context {
+	return context.WithValue(ctx, counterKey{}, c)
+}
+
+// counterFrom returns the counter of the context, or nil.
+func counterFrom(ctx context.Context) *Counter {
+	c, _ := ctx.Value(counterKey{}).(*Counter)
+	return c
+}
+
+// withDownload returns the context counting a single download, whose count is added to the counter of ctx.
+func withDownload(ctx context.Context) (context.Context, *Counter) {
+	c := &Counter{parent: counterFrom(ctx)}
+	return WithCounter(ctx, c), c
+}
+
+// watch measures the destination while the git CLI downloads into it, since its transfers don't go through
+// the HTTP transport. It returns the function stopping the measures.
+func watch(ctx context.Context, dst string, c *Counter) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if stats, err := DiskUsage(dst); err == nil && stats.Bytes > c.Load() {
+					c.settle(stats)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// countingTransport counts the bytes of the response bodies into the counter of the request context.
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if c := counterFrom(req.Context()); err == nil && c != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, ctx: req.Context(), counter: c}
+	}
+	return resp, err
+}
+
+// countingBody is a response body counting the bytes read. It stops as soon as the context is canceled,
+// e.g. by the budget, while the transport may still return the bytes it has buffered.
+type countingBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	counter *Counter
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.counter.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,75 @@
+package download_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+func TestCounter(t *testing.T) {
+	var exceeded int
+	c := download.NewCounter(10, func() { exceeded++ })
+	c.Add(6)
+	assert.Zero(t, exceeded)
+	c.Add(6)
+	c.Add(1)
+	assert.Equal(t, int64(13), c.Load())
+	assert.Equal(t, 1, exceeded, "exceeded should be called once")
+}
+
+func TestFile_Counter(t *testing.T) {
+	body := strings.Repeat("x", 1<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name         string
+		limit        int64
+		wantErr      bool
+		wantExceeded bool
+	}{
+		{
+			name: "within the budget",
+		},
+		{
+			name:         "budget exceeded in flight",
+			limit:        1,
+			wantErr:      true,
+			wantExceeded: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var exceeded bool
+			c := download.NewCounter(tt.limit, func() {
+				exceeded = true
+				cancel()
+			})
+			ctx = download.WithCounter(ctx, c)
+
+			// The body is read in chunks, so the download is canceled before it completes
+			stats, err := download.File(ctx, server.URL+"/openvex.json", filepath.Join(t.TempDir(), "openvex.json"))
+			assert.Equal(t, tt.wantExceeded, exceeded)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Positive(t, c.Load())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(body)), stats.Bytes)
+			assert.Equal(t, int64(len(body)), c.Load())
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package download
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// CredentialHelper obtains the credentials of the listed hosts from an external command at download time,
+// e.g. to integrate with the existing secret management instead of embedding tokens in the config.
+// The command speaks the protocol of git credential helpers: it reads the attributes of the request,
+// such as "protocol=https" and "host=git.example.com", from stdin and writes "username=" and "password=" to stdout.
+// cf. https://git-scm.com/docs/git-credential#IOFMT
+type CredentialHelper struct {
+	Command []string `yaml:"command"` // e.g. ["git", "credential", "fill"] or ["/usr/local/bin/vault-credential", "get"]
+	Hosts   []string `yaml:"hosts"`
+}
+
+// credentialHelperTimeout bounds a single invocation of the helper, so that a hung helper doesn't stall the crawl.
+const credentialHelperTimeout = 30 * time.Second
+
+var (
+	helperMu sync.RWMutex
+	helper   *credentialHelper
+)
+
+// credentialHelper invokes the command and caches the credentials by host for the run.
+// The credentials are never logged or included in errors.
+type credentialHelper struct {
+	command []string
+	hosts   []string
+
+	mu    sync.Mutex // Guards the field below
+	cache map[string]BasicAuth
+}
+
+// ConfigureCredentialHelper obtains the credentials of the subsequent requests to the hosts from the helper.
+// Like ConfigureTLS, it must be called before downloads start. An empty command disables the helper.
+func ConfigureCredentialHelper(cfg CredentialHelper) error {
+	errBuilder := oops.Code("credential_helper_config_error").In("download").With("hosts", cfg.Hosts)
+
+	var h *credentialHelper
+	if len(cfg.Command) > 0 {
+		if cfg.Command[0] == "" {
+			return errBuilder.Errorf("command of the credential helper is empty")
+		} else if len(cfg.Hosts) == 0 {
+			return errBuilder.Errorf("hosts are required for the credential helper")
+		}
+		h = &credentialHelper{
+			command: cfg.Command,
+			hosts:   cfg.Hosts,
+			cache:   make(map[string]BasicAuth),
+		}
+	} else if len(cfg.Hosts) > 0 {
+		return errBuilder.Errorf("command is required for the credential helper")
+	}
+
+	helperMu.Lock()
+	helper = h
+	helperMu.Unlock()
+
+	// The credentials are passed to the git CLI as they're obtained
+	if err := setGitConfig("credential_helper", nil); err != nil {
+		return errBuilder.Wrapf(err, "failed to configure git")
+	}
+	installGitProtocol()
+	return nil
+}
+
+func currentHelper() *credentialHelper {
+	helperMu.RLock()
+	defer helperMu.RUnlock()
+	return helper
+}
+
+// matches reports whether the credentials of the helper are used for the URL, regardless of the scheme.
+// A host without a port matches the default ports only, as for the basic auth.
+func (h *credentialHelper) matches(u *url.URL) bool {
+	if slices.Contains(h.hosts, u.Host) {
+		return true
+	}
+	port := u.Port()
+	return (port == "" || port == "443" || port == "80") && slices.Contains(h.hosts, u.Hostname())
+}
+
+// Credentials returns the cached credentials of the host, invoking the helper on the first request to the host.
+// Failures aren't cached, so that a transient failure of the helper only fails the package being crawled.
+func (h *credentialHelper) Credentials(ctx context.Context, host string) (BasicAuth, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if a, ok := h.cache[host]; ok {
+		return a, nil
+	}
+
+	errBuilder := oops.Code("credential_helper_error").In("download").
+		With("command", h.command[0]).With("host", host)
+	ctx, cancel := context.WithTimeout(ctx, credentialHelperTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.command[0], h.command[1:]...)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=https\nhost=%s\n\n", host))
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// Never prompt on the terminal, e.g. when "git credential fill" has no helper for the host
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if err := cmd.Run(); err != nil {
+		// stdout may contain partial credentials, so only stderr is reported
+		return BasicAuth{}, errBuilder.With("stderr", strings.TrimSpace(stderr.String())).
+			Wrapf(err, "credential helper failed")
+	}
+
+	var a BasicAuth
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			a.Username = value
+		case "password":
+			a.Password = value
+		}
+	}
+	if a.Username == "" && a.Password == "" {
+		return BasicAuth{}, errBuilder.Errorf("credential helper returned no credentials")
+	}
+	h.cache[host] = a
+	slog.Info("Obtained the credentials from the credential helper", slog.String("host", host))
+	return a, nil
+}
+
+// gitCredentials passes the credentials of the helper to the git CLI if the URL is covered by the helper.
+// The GitHub App takes precedence, as for the HTTP requests.
+func gitCredentials(ctx context.Context, u *url.URL) error {
+	h := currentHelper()
+	if h == nil || !h.matches(u) {
+		return nil
+	} else if a := currentAuth(); a != nil && a.matches(u) {
+		return nil
+	} else if u.Scheme != "https" {
+		return oops.Code("credential_helper_error").In("download").With("host", u.Host).
+			Errorf("refusing to send the credentials of the credential helper over plain HTTP")
+	}
+	if _, err := h.Credentials(ctx, u.Host); err != nil {
+		return err
+	}
+
+	// All the hosts obtained so far, since the entries of the feature are replaced at once
+	h.mu.Lock()
+	var entries [][2]string
+	for host, a := range h.cache {
+		header := "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+		entries = append(entries, [2]string{fmt.Sprintf("http.https://%s/.extraHeader", host), "Authorization: " + header})
+	}
+	h.mu.Unlock()
+	slices.SortFunc(entries, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+	return setGitConfig("credential_helper", entries)
+}
+
+// credentialHelperTransport adds the credentials of the helper to the requests to its hosts.
+type credentialHelperTransport struct {
+	base   http.RoundTripper
+	helper *credentialHelper
+}
+
+func (t *credentialHelperTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.helper.matches(req.URL) || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	} else if req.URL.Scheme != "https" {
+		return nil, oops.Code("credential_helper_error").In("download").With("host", req.URL.Host).
+			Errorf("refusing to send the credentials of the credential helper over plain HTTP")
+	}
+	a, err := t.helper.Credentials(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(a.Username, a.Password)
+	return t.base.RoundTrip(req)
+}
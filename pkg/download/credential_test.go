@@ -0,0 +1,144 @@
+package download_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+// writeHelper writes a stub credential helper printing the credentials of the host given on stdin,
+// and recording its invocations in the log file.
+func writeHelper(t *testing.T, dir, script string) (string, string) {
+	t.Helper()
+	path := filepath.Join(dir, "helper.sh")
+	logPath := filepath.Join(dir, "invocations.log")
+	content := "#!/bin/sh\nset -e\ninput=$(cat)\necho \"$input\" | grep '^host=' >> " + logPath + "\n" + script
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o755))
+	return path, logPath
+}
+
+func TestConfigureCredentialHelper(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "helper" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"@context": "https://openvex.dev/ns/v0.2.0"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	writePEM(t, caPath, "CERTIFICATE", server.Certificate().Raw)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, download.ConfigureTLS(download.TLSConfig{
+		CACerts: []download.CACert{{Hosts: []string{u.Host}, Path: caPath}},
+	}))
+	t.Cleanup(func() { require.NoError(t, download.ConfigureTLS(download.TLSConfig{})) })
+
+	tests := []struct {
+		name            string
+		script          string
+		hosts           []string
+		wantErr         string
+		wantInvocations int
+	}{
+		{
+			name:            "credentials",
+			script:          "echo username=helper\necho password=s3cret\n",
+			hosts:           []string{u.Host},
+			wantInvocations: 1, // Cached for the second download
+		},
+		{
+			name:    "another host",
+			script:  "echo username=helper\necho password=s3cret\n",
+			hosts:   []string{"git.example.com"},
+			wantErr: "unexpected status",
+		},
+		{
+			name:            "helper failure",
+			script:          "echo 'vault is sealed' >&2\nexit 1\n",
+			hosts:           []string{u.Host},
+			wantErr:         "credential helper failed",
+			wantInvocations: 1,
+		},
+		{
+			name:            "no credentials",
+			script:          "echo quit=1\n",
+			hosts:           []string{u.Host},
+			wantErr:         "credential helper returned no credentials",
+			wantInvocations: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper, logPath := writeHelper(t, t.TempDir(), tt.script)
+			require.NoError(t, download.ConfigureCredentialHelper(download.CredentialHelper{
+				Command: []string{helper, "get"},
+				Hosts:   tt.hosts,
+			}))
+			t.Cleanup(func() { require.NoError(t, download.ConfigureCredentialHelper(download.CredentialHelper{})) })
+
+			for range 2 {
+				dst := filepath.Join(t.TempDir(), "openvex.json")
+				_, err = download.File(context.Background(), server.URL+"/openvex.json", dst)
+				if tt.wantErr != "" {
+					require.ErrorContains(t, err, tt.wantErr)
+					assert.NotContains(t, err.Error(), "s3cret")
+					break
+				}
+				require.NoError(t, err)
+				assert.FileExists(t, dst)
+			}
+
+			b, err := os.ReadFile(logPath)
+			if tt.wantInvocations == 0 {
+				assert.True(t, os.IsNotExist(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, strings.Repeat("host="+u.Host+"\n", tt.wantInvocations), string(b))
+		})
+	}
+}
+
+func TestConfigureCredentialHelper_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		helper  download.CredentialHelper
+		wantErr string
+	}{
+		{
+			name:    "no hosts",
+			helper:  download.CredentialHelper{Command: []string{"git", "credential", "fill"}},
+			wantErr: "hosts are required for the credential helper",
+		},
+		{
+			name:    "no command",
+			helper:  download.CredentialHelper{Hosts: []string{"git.example.com"}},
+			wantErr: "command is required for the credential helper",
+		},
+		{
+			name:    "empty command",
+			helper:  download.CredentialHelper{Command: []string{""}, Hosts: []string{"git.example.com"}},
+			wantErr: "command of the credential helper is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, download.ConfigureCredentialHelper(tt.helper), tt.wantErr)
+		})
+	}
+}
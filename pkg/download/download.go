@@ -2,22 +2,55 @@ package download
 
 import (
 	"context"
+	"io/fs"
 	"log/slog"
-	"maps"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/hashicorp/go-getter"
 	"github.com/samber/oops"
+
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
+// Stats describes the files written to the destination by a download.
+type Stats struct {
+	Bytes int64 // Total size of the regular files
+	Files int   // Number of the regular files
+}
+
 // Download downloads the configured source to the destination.
-func Download(ctx context.Context, src, dst string) error {
-	slog.Info("Downloading...", slog.String("src", src))
-	errBuilder := oops.Code("download_error").In("download").With("src", src).With("dst", dst)
+// It returns the stats of the files written to the destination, which are also added to the counter
+// of the context, see WithCounter.
+func Download(ctx context.Context, src, dst string) (Stats, error) {
+	slog.Info("Downloading...", slog.String("src", xurl.Redact(src)))
+	errBuilder := oops.Code("download_error").In("download").With("src", xurl.Redact(src)).With("dst", dst)
 
 	pwd, err := os.Getwd()
 	if err != nil {
-		return errBuilder.Wrapf(err, "failed to get the current working directory")
+		return Stats{}, errBuilder.Wrapf(err, "failed to get the current working directory")
+	}
+
+	// The git CLI connects without the HTTP transport
+	if forced, u, ok := strings.Cut(src, "::"); ok && forced == "git" {
+		if parsed, err := url.Parse(u); err == nil {
+			warnInsecure(parsed)
+			if err = gitAuth(ctx, parsed); err != nil {
+				return Stats{}, errBuilder.Wrapf(err, "failed to authenticate to GitHub")
+			}
+			if err = gitCredentials(ctx, parsed); err != nil {
+				return Stats{}, errBuilder.Wrapf(err, "failed to obtain the credentials")
+			}
+		}
+	}
+
+	ctx, counter := withDownload(ctx)
+	stop := func() {}
+	if RequiresGit(src) {
+		stop = watch(ctx, dst, counter)
 	}
 
 	// Build the client
@@ -26,13 +59,82 @@ func Download(ctx context.Context, src, dst string) error {
 		Src:     src,
 		Dst:     dst,
 		Pwd:     pwd,
-		Getters: maps.Clone(getter.Getters),
+		Getters: getters(),
 		Mode:    getter.ClientModeAny,
 	}
 
-	if err = client.Get(); err != nil {
-		return errBuilder.Wrapf(err, "download error")
+	err = client.Get()
+	stop()
+	if err != nil {
+		if RequiresGit(src) && gitMissing() {
+			// The error of go-getter doesn't tell what to do
+			return Stats{}, errBuilder.Wrap(ErrGitUnavailable)
+		}
+		return Stats{}, errBuilder.Wrapf(err, "download error")
+	}
+
+	stats, err := DiskUsage(dst)
+	if err != nil {
+		return Stats{}, errBuilder.Wrapf(err, "failed to calculate the size")
+	}
+	counter.settle(stats)
+	return stats, nil
+}
+
+// getters returns the go-getter getters by the forced getter or the scheme, like getter.Getters.
+// They're created for each download rather than shared, since go-getter sets the client of the download on them.
+func getters() map[string]getter.Getter {
+	httpGetter := &getter.HttpGetter{
+		Netrc:  true,
+		Client: httpClient(),
 	}
+	return map[string]getter.Getter{
+		"bundle": &bundleGetter{},
+		"file":   new(getter.FileGetter),
+		"gcs":    new(getter.GCSGetter),
+		"git":    new(getter.GitGetter),
+		"hg":     new(getter.HgGetter),
+		"http":   httpGetter,
+		"https":  httpGetter,
+		"s3":     new(getter.S3Getter),
+	}
+}
 
+// Supported checks that the source resolves to a getter without any network access,
+// so that malformed sources are reported before the downloads start.
+func Supported(src string) error {
+	errBuilder := oops.Code("download_error").In("download").With("src", xurl.Redact(src))
+	scheme, u, err := detectScheme(src)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to detect the source")
+	}
+	if _, ok := getters()[scheme]; !ok {
+		return errBuilder.With("scheme", scheme).Errorf("unsupported scheme")
+	} else if scheme == "git" && !slices.Contains(gitSchemes, u.Scheme) {
+		return errBuilder.With("scheme", u.Scheme).Errorf("unsupported git transport")
+	}
 	return nil
 }
+
+// gitSchemes are the transports of the git CLI.
+var gitSchemes = []string{"http", "https", "ssh", "git", "file"}
+
+// DiskUsage returns the total size and number of the regular files under the path.
+func DiskUsage(root string) (Stats, error) {
+	var stats Stats
+	err := filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		stats.Bytes += info.Size()
+		stats.Files++
+		return nil
+	})
+	return stats, err
+}
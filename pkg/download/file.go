@@ -0,0 +1,50 @@
+package download
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/samber/oops"
+
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// File downloads the single file at the HTTP(S) URL into the destination file with a single request.
+// Unlike Download, the source is not detected by go-getter, and the stats are those of the response body.
+func File(ctx context.Context, src, dst string) (Stats, error) {
+	slog.Info("Downloading...", slog.String("src", xurl.Redact(src)))
+	errBuilder := oops.Code("download_error").In("download").With("src", xurl.Redact(src)).With("dst", dst)
+
+	ctx, counter := withDownload(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return Stats{}, errBuilder.Wrapf(err, "failed to create the request")
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return Stats{}, errBuilder.Wrapf(err, "download error")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Stats{}, errBuilder.With("status", resp.Status).Errorf("unexpected status")
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return Stats{}, errBuilder.Wrapf(err, "failed to create the file")
+	}
+	defer f.Close()
+	n, err := io.Copy(f, resp.Body)
+	if err != nil {
+		return Stats{}, errBuilder.Wrapf(err, "download error")
+	}
+	if err = f.Close(); err != nil {
+		return Stats{}, errBuilder.Wrapf(err, "failed to close the file")
+	}
+	stats := Stats{Bytes: n, Files: 1}
+	counter.settle(stats)
+	return stats, nil
+}
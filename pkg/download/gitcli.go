@@ -0,0 +1,91 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/go-getter"
+)
+
+// Errors returned for the features requiring the git CLI when it's missing, with the action to take.
+var (
+	// ErrGitUnavailable is returned for the sources cloned with the git CLI, i.e. git repositories
+	// and bundles, if git is not on the PATH.
+	ErrGitUnavailable = fmt.Errorf("the git CLI is not installed, install git or use sources offering archive downloads")
+
+	// ErrLFSUnavailable is returned for the packages with Git LFS enabled if the git-lfs extension is not installed.
+	ErrLFSUnavailable = fmt.Errorf("the git-lfs extension is not installed, install git-lfs or disable lfs")
+)
+
+// GitCapabilities are the features of the git CLI installed on the host.
+// The repositories are read and the remote commits are resolved in pure Go, while the clones,
+// the git bundles and the Git LFS files are fetched with the git CLI.
+type GitCapabilities struct {
+	Version string // Version of git, e.g. "2.43.0", or empty if git is not on the PATH
+	LFS     bool   // Whether the git-lfs extension is installed
+}
+
+// Git reports whether the git CLI is installed.
+func (c GitCapabilities) Git() bool {
+	return c.Version != ""
+}
+
+// DetectGit detects the git CLI and its extensions on the PATH. It's meant to be called once at startup,
+// so that a missing feature is reported before the downloads start.
+func DetectGit(ctx context.Context) GitCapabilities {
+	var caps GitCapabilities
+	out, err := exec.CommandContext(ctx, "git", "version").Output()
+	if err != nil {
+		return caps
+	}
+	// e.g. "git version 2.43.0" or "git version 2.39.3 (Apple Git-146)"
+	fields := strings.Fields(strings.TrimPrefix(string(out), "git version"))
+	caps.Version = "unknown"
+	if len(fields) > 0 {
+		caps.Version = fields[0]
+	}
+	caps.LFS = exec.CommandContext(ctx, "git", "lfs", "version").Run() == nil
+	return caps
+}
+
+// RequiresGit reports whether the source is downloaded with the git CLI, e.g. "git::https://github.com/owner/repo".
+// Archives and single files are downloaded over HTTP without it.
+func RequiresGit(src string) bool {
+	scheme, _, err := detectScheme(src)
+	return err == nil && (scheme == "git" || scheme == "bundle")
+}
+
+// detectScheme returns the getter of the source, i.e. the forced getter or the scheme, along with the URL.
+func detectScheme(src string) (string, *url.URL, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return "", nil, err
+	}
+	detected, err := getter.Detect(src, pwd, getter.Detectors)
+	if err != nil {
+		return "", nil, err
+	}
+
+	scheme, rest, forced := strings.Cut(detected, "::")
+	if !forced {
+		rest = detected
+	}
+	u, err := url.Parse(rest)
+	if err != nil {
+		return "", nil, err
+	}
+	if !forced {
+		scheme = u.Scheme
+	}
+	return scheme, u, nil
+}
+
+// gitMissing reports whether the git CLI is not on the PATH, to explain the failures of the clones.
+func gitMissing() bool {
+	_, err := exec.LookPath("git")
+	return err != nil
+}
@@ -0,0 +1,67 @@
+package download_test
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+func TestRequiresGit(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want bool
+	}{
+		{
+			name: "git",
+			src:  "git::https://github.com/aquasecurity/trivy",
+			want: true,
+		},
+		{
+			name: "detected git",
+			src:  "github.com/aquasecurity/trivy",
+			want: true,
+		},
+		{
+			name: "bundle",
+			src:  "bundle::/mirror/repo.bundle",
+			want: true,
+		},
+		{
+			name: "archive",
+			src:  "https://github.com/aquasecurity/trivy/archive/0123abcd.tar.gz//*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, download.RequiresGit(tt.src))
+		})
+	}
+}
+
+func TestDetectGit(t *testing.T) {
+	t.Run("installed", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+		caps := download.DetectGit(context.Background())
+		assert.True(t, caps.Git())
+		assert.NotEmpty(t, caps.Version)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		caps := download.DetectGit(context.Background())
+		assert.False(t, caps.Git())
+		assert.False(t, caps.LFS)
+
+		_, err := download.Download(context.Background(), "git::https://github.com/aquasecurity/trivy", t.TempDir())
+		require.ErrorIs(t, err, download.ErrGitUnavailable)
+	})
+}
@@ -0,0 +1,275 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// GitHubApp authenticates to GitHub as an installation of a GitHub App, e.g. to crawl the private repositories of an organization.
+// Unlike a personal access token, installation tokens are scoped to the installation and expire within an hour,
+// so they're generated on demand and refreshed automatically during long crawls.
+type GitHubApp struct {
+	AppID          int64    `yaml:"app_id"`
+	InstallationID int64    `yaml:"installation_id"`
+	PrivateKey     string   `yaml:"private_key"` // Path to the PEM-encoded private key of the app
+	Hosts          []string `yaml:"hosts"`       // Hosts the token is sent to. Empty means DefaultGitHubHosts
+	APIURL         string   `yaml:"api_url"`     // Empty means DefaultGitHubAPIURL
+}
+
+// DefaultGitHubHosts are the hosts the installation token is sent to unless GitHubApp.Hosts is set.
+var DefaultGitHubHosts = []string{"github.com"}
+
+// DefaultGitHubAPIURL is the API generating the installation tokens.
+// GitHub Enterprise Server serves it at "https://<host>/api/v3".
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// tokenRefreshMargin is how long before the expiry a token is refreshed, so that a clone doesn't outlive its token.
+const tokenRefreshMargin = 5 * time.Minute
+
+var (
+	authMu  sync.RWMutex
+	appAuth *installationAuth
+)
+
+// installationAuth generates and caches the installation tokens of a GitHub App.
+// Neither the private key nor the tokens are ever logged or included in errors.
+type installationAuth struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	hosts          []string
+	apiURL         string
+
+	mu     sync.Mutex // Guards the fields below
+	token  string
+	expiry time.Time
+}
+
+// ConfigureGitHubApp authenticates the subsequent downloads from the GitHub hosts as the installation of the app.
+// Like ConfigureTLS, it must be called before downloads start. A zero app ID disables the authentication.
+func ConfigureGitHubApp(cfg GitHubApp) error {
+	errBuilder := oops.Code("github_app_config_error").In("download").With("app_id", cfg.AppID)
+
+	var a *installationAuth
+	if cfg.AppID != 0 {
+		if cfg.InstallationID == 0 {
+			return errBuilder.Errorf("installation_id is required for GitHub App")
+		} else if cfg.PrivateKey == "" {
+			return errBuilder.Errorf("private_key is required for GitHub App")
+		}
+		key, err := loadRSAKey(cfg.PrivateKey)
+		if err != nil {
+			// Don't include the key in errors and logs
+			return errBuilder.With("private_key", cfg.PrivateKey).Wrapf(err, "failed to load the private key")
+		}
+		a = &installationAuth{
+			appID:          cfg.AppID,
+			installationID: cfg.InstallationID,
+			key:            key,
+			hosts:          cfg.Hosts,
+			apiURL:         strings.TrimSuffix(cfg.APIURL, "/"),
+		}
+		if len(a.hosts) == 0 {
+			a.hosts = DefaultGitHubHosts
+		}
+		if a.apiURL == "" {
+			a.apiURL = DefaultGitHubAPIURL
+		}
+	}
+
+	authMu.Lock()
+	appAuth = a
+	authMu.Unlock()
+
+	// The token is passed to the git CLI as it's generated
+	if err := setGitConfig("auth", nil); err != nil {
+		return errBuilder.Wrapf(err, "failed to configure git")
+	}
+	installGitProtocol()
+	return nil
+}
+
+// Authenticated reports whether the requests to the URL are authenticated with GitHubApp,
+// e.g. to avoid the archive downloads that don't accept installation tokens.
+func Authenticated(u *url.URL) bool {
+	a := currentAuth()
+	return a != nil && a.matches(u)
+}
+
+func currentAuth() *installationAuth {
+	authMu.RLock()
+	defer authMu.RUnlock()
+	return appAuth
+}
+
+// loadRSAKey loads the private key in either PKCS #1, as downloaded from GitHub, or PKCS #8.
+func loadRSAKey(path string) (*rsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to read the file")
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, oops.Errorf("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, oops.Errorf("unsupported private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, oops.Errorf("private key must be RSA")
+	}
+	return key, nil
+}
+
+// matches reports whether the token is sent to the URL. Tokens are never sent over plain HTTP.
+// A host without a port matches the default port only, as for the TLS settings.
+func (a *installationAuth) matches(u *url.URL) bool {
+	if u.Scheme != "https" {
+		return false
+	} else if slices.Contains(a.hosts, u.Host) {
+		return true
+	}
+	port := u.Port()
+	return (port == "" || port == "443") && slices.Contains(a.hosts, u.Hostname())
+}
+
+// Token returns the cached installation token, generating a new one if it's missing or near expiry.
+func (a *installationAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Until(a.expiry) > tokenRefreshMargin {
+		return a.token, nil
+	}
+
+	errBuilder := oops.Code("github_app_token_error").In("download").
+		With("app_id", a.appID).With("installation_id", a.installationID)
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return "", errBuilder.Wrapf(err, "failed to sign the JWT")
+	}
+	endpoint := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.apiURL, a.installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, http.NoBody)
+	if err != nil {
+		return "", errBuilder.Wrapf(err, "failed to create the request")
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	// The API is called with the TLS settings, but without the token itself
+	resp, err := (&http.Client{Transport: &userAgentTransport{base: tlsTransport()}}).Do(req)
+	if err != nil {
+		return "", errBuilder.Wrapf(err, "failed to request the installation token")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", errBuilder.Wrapf(err, "failed to read the response")
+	} else if resp.StatusCode != http.StatusCreated {
+		return "", errBuilder.With("status", resp.Status).With("body", string(bytes.TrimSpace(body))).
+			Errorf("unexpected status")
+	}
+
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err = json.Unmarshal(body, &token); err != nil {
+		return "", errBuilder.Wrapf(err, "failed to decode the response")
+	} else if token.Token == "" {
+		return "", errBuilder.Errorf("no token in the response")
+	}
+	a.token, a.expiry = token.Token, token.ExpiresAt
+	slog.Info("Generated a GitHub App installation token", slog.Int64("app_id", a.appID),
+		slog.Int64("installation_id", a.installationID), slog.Time("expires_at", a.expiry))
+	return a.token, nil
+}
+
+// jwt returns the JSON Web Token authenticating as the app, valid for the maximum of 10 minutes.
+// The issue time is set in the past to allow for clock drift.
+// cf. https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+func (a *installationAuth) jwt(now time.Time) (string, error) {
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// basicAuth returns the Authorization header value of the token, as accepted by GitHub for git over HTTPS.
+func basicAuth(token string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte("x-access-token:"+token))
+}
+
+// gitAuth passes the installation token to the git CLI if the URL is authenticated,
+// refreshing the token if it's near expiry, since the git CLI can't refresh it by itself.
+func gitAuth(ctx context.Context, u *url.URL) error {
+	a := currentAuth()
+	if a == nil || !a.matches(u) {
+		return nil
+	}
+	token, err := a.Token(ctx)
+	if err != nil {
+		return err
+	}
+	var entries [][2]string
+	for _, host := range a.hosts {
+		// cf. https://git-scm.com/docs/git-config#Documentation/git-config.txt-httpextraHeader
+		entries = append(entries, [2]string{fmt.Sprintf("http.https://%s/.extraHeader", host), "Authorization: " + basicAuth(token)})
+	}
+	return setGitConfig("auth", entries)
+}
+
+// authTransport adds the installation token to the requests to the GitHub hosts, e.g. made by go-git.
+type authTransport struct {
+	base http.RoundTripper
+	auth *installationAuth
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.auth.matches(req.URL) || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.auth.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", basicAuth(token))
+	return t.base.RoundTrip(req)
+}
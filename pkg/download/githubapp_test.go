@@ -0,0 +1,188 @@
+package download_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+// newGitHubAPI starts a fake GitHub API issuing installation tokens valid for the lifetime,
+// after verifying the JWT signed by the app. The tokens are numbered in the order they're issued.
+func newGitHubAPI(t *testing.T, key *rsa.PrivateKey, lifetime time.Duration) (*httptest.Server, *atomic.Int32) {
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/5678/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		if !ok || len(parts) != 3 {
+			http.Error(w, "invalid JWT", http.StatusUnauthorized)
+			return
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err != nil || rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig) != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil || !strings.Contains(string(claims), `"iss":1234`) {
+			http.Error(w, "invalid issuer", http.StatusUnauthorized)
+			return
+		}
+
+		n := issued.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("token-%d", n),
+			"expires_at": time.Now().Add(lifetime),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+// requireToken rejects the requests without the installation token numbered n.
+func requireToken(next http.Handler, n *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "x-access-token" || pass != fmt.Sprintf("token-%d", n.Load()) {
+			w.Header().Set("WWW-Authenticate", `Basic realm=""`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeRSAKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "app.pem")
+	writePEM(t, path, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	return key, path
+}
+
+func TestConfigureGitHubApp(t *testing.T) {
+	unsetGitCAInfo(t)
+	t.Setenv("GIT_TERMINAL_PROMPT", "0")
+
+	tests := []struct {
+		name       string
+		lifetime   time.Duration
+		src        func(serverURL string) string
+		wantIssued int32
+	}{
+		{
+			name:       "git",
+			lifetime:   time.Hour,
+			src:        func(serverURL string) string { return "git::" + serverURL + "/repo.git" },
+			wantIssued: 1,
+		},
+		{
+			name:       "tarball",
+			lifetime:   time.Hour,
+			src:        func(serverURL string) string { return serverURL + "/repo.tar.gz" },
+			wantIssued: 1,
+		},
+		{
+			name:       "refresh near expiry",
+			lifetime:   time.Minute,
+			src:        func(serverURL string) string { return "git::" + serverURL + "/repo.git" },
+			wantIssued: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, keyPath := writeRSAKey(t)
+			api, issued := newGitHubAPI(t, key, tt.lifetime)
+
+			// Tokens are only sent over HTTPS
+			server := httptest.NewTLSServer(requireToken(newRepoHandler(t), issued))
+			t.Cleanup(server.Close)
+			caPath := filepath.Join(t.TempDir(), "ca.crt")
+			writePEM(t, caPath, "CERTIFICATE", server.Certificate().Raw)
+			u, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			require.NoError(t, download.ConfigureTLS(download.TLSConfig{
+				CACerts: []download.CACert{{Hosts: []string{u.Host}, Path: caPath}},
+			}))
+			t.Cleanup(func() { require.NoError(t, download.ConfigureTLS(download.TLSConfig{})) })
+			require.NoError(t, download.ConfigureGitHubApp(download.GitHubApp{
+				AppID:          1234,
+				InstallationID: 5678,
+				PrivateKey:     keyPath,
+				Hosts:          []string{u.Host},
+				APIURL:         api.URL,
+			}))
+			t.Cleanup(func() { require.NoError(t, download.ConfigureGitHubApp(download.GitHubApp{})) })
+			assert.True(t, download.Authenticated(u))
+
+			// A token expiring within the margin is refreshed before the next download
+			for range 2 {
+				dst := filepath.Join(t.TempDir(), "repo")
+				_, err = download.Download(context.Background(), tt.src(server.URL), dst)
+				require.NoError(t, err)
+				assert.FileExists(t, filepath.Join(dst, "vex.json"))
+			}
+			assert.Equal(t, tt.wantIssued, issued.Load())
+		})
+	}
+}
+
+func TestConfigureGitHubApp_Invalid(t *testing.T) {
+	_, keyPath := writeRSAKey(t)
+	ecKey := newCert(t, t.TempDir(), "ec", nil).keyPath
+
+	tests := []struct {
+		name    string
+		cfg     download.GitHubApp
+		wantErr string
+	}{
+		{
+			name:    "no installation",
+			cfg:     download.GitHubApp{AppID: 1234, PrivateKey: keyPath},
+			wantErr: "installation_id is required",
+		},
+		{
+			name:    "no private key",
+			cfg:     download.GitHubApp{AppID: 1234, InstallationID: 5678},
+			wantErr: "private_key is required",
+		},
+		{
+			name:    "not RSA",
+			cfg:     download.GitHubApp{AppID: 1234, InstallationID: 5678, PrivateKey: ecKey},
+			wantErr: "failed to load the private key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := download.ConfigureGitHubApp(tt.cfg)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package download
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/samber/oops"
+)
+
+// TLSConfig configures TLS of the connections made to download sources.
+type TLSConfig struct {
+	// ClientCerts are presented to the hosts requiring mutual TLS.
+	ClientCerts []ClientCert `yaml:"client_certs"`
+
+	// CACerts are trusted to verify the hosts using a private CA, e.g. internal git servers.
+	CACerts []CACert `yaml:"ca_certs"`
+
+	// InsecureHosts are the hosts for which TLS verification is skipped, e.g. throwaway hosts for testing.
+	// A warning is logged whenever an insecure connection is made.
+	InsecureHosts []string `yaml:"insecure_hosts"`
+}
+
+// ClientCert is a client certificate presented only to the listed hosts
+// so that it's never sent to public hosts.
+type ClientCert struct {
+	Hosts  []string `yaml:"hosts"`   // e.g. "git.example.com" or "git.example.com:8443"
+	Cert   string   `yaml:"cert"`    // Path to the PEM-encoded certificate
+	Key    string   `yaml:"key"`     // Path to the PEM-encoded private key
+	CACert string   `yaml:"ca_cert"` // Path to the PEM-encoded CA bundle verifying the hosts. Optional.
+}
+
+// CACert is a CA bundle verifying the listed hosts instead of the system CAs.
+// It's scoped to the hosts so that a private CA can't be used to impersonate public hosts.
+type CACert struct {
+	Hosts []string `yaml:"hosts"`
+	Path  string   `yaml:"path"` // Path to the PEM-encoded CA bundle
+}
+
+var (
+	tlsMu     sync.RWMutex
+	transport *hostTransport
+
+	// gitConfigBase is the number of git config entries already passed through the environment
+	gitConfigBase     int
+	gitConfigBaseOnce sync.Once
+
+	// gitConfigs are the git config entries passed to the git CLI, keyed by the feature setting them, e.g. "tls"
+	gitConfigMu sync.Mutex
+	gitConfigs  = make(map[string][][2]string)
+)
+
+// hostTLS is the TLS configuration of a host.
+type hostTLS struct {
+	config *tls.Config
+	caCert string // Path to the CA bundle, if any
+}
+
+// ConfigureTLS applies the TLS configuration to the subsequent downloads over HTTP and git.
+// Since the git CLI is configured through the environment, it must be called before downloads start.
+func ConfigureTLS(cfg TLSConfig) error {
+	errBuilder := oops.Code("tls_config_error").In("download")
+
+	hosts := make(map[string]*hostTLS)
+	hostConfig := func(host string) *hostTLS {
+		if _, ok := hosts[host]; !ok {
+			hosts[host] = &hostTLS{config: &tls.Config{MinVersion: tls.VersionTLS12}}
+		}
+		return hosts[host]
+	}
+	setCACert := func(host, path string) error {
+		h := hostConfig(host)
+		if h.caCert != "" && h.caCert != path {
+			return errBuilder.With("host", host).Errorf("multiple CA bundles for the host")
+		}
+		pool, err := loadCertPool(path)
+		if err != nil {
+			return errBuilder.With("ca_cert", path).Wrapf(err, "failed to load the CA bundle")
+		}
+		h.caCert = path
+		h.config.RootCAs = pool
+		return nil
+	}
+
+	for _, c := range cfg.ClientCerts {
+		if len(c.Hosts) == 0 {
+			return errBuilder.With("cert", c.Cert).Errorf("hosts are required for client certificate")
+		}
+
+		// Don't include the key in errors and logs
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return errBuilder.With("cert", c.Cert).Wrapf(err, "failed to load the client certificate")
+		}
+		for _, host := range c.Hosts {
+			hostConfig(host).config.Certificates = []tls.Certificate{cert}
+			if c.CACert == "" {
+				continue
+			} else if err = setCACert(host, c.CACert); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, c := range cfg.CACerts {
+		if len(c.Hosts) == 0 {
+			return errBuilder.With("ca_cert", c.Path).Errorf("hosts are required for CA bundle")
+		}
+		for _, host := range c.Hosts {
+			if err := setCACert(host, c.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, host := range cfg.InsecureHosts {
+		hostConfig(host).config.InsecureSkipVerify = true // Explicitly allowed for the host
+	}
+
+	transports := make(map[string]*http.Transport)
+	var gitConfig [][2]string
+	var hasCACert bool
+	var hostNames []string
+	for host := range hosts {
+		hostNames = append(hostNames, host)
+	}
+	slices.Sort(hostNames) // Keep the git config deterministic
+
+	for _, host := range hostNames {
+		h := hosts[host]
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = h.config
+		transports[host] = t
+
+		// cf. https://git-scm.com/docs/git-config#Documentation/git-config.txt-httplturlgt
+		prefix := fmt.Sprintf("http.https://%s/.", host)
+		for _, c := range cfg.ClientCerts {
+			if slices.Contains(c.Hosts, host) {
+				gitConfig = append(gitConfig, [2]string{prefix + "sslCert", c.Cert}, [2]string{prefix + "sslKey", c.Key})
+			}
+		}
+		if h.caCert != "" {
+			hasCACert = true
+			gitConfig = append(gitConfig, [2]string{prefix + "sslCAInfo", h.caCert})
+		}
+		if h.config.InsecureSkipVerify {
+			gitConfig = append(gitConfig, [2]string{prefix + "sslVerify", "false"})
+		}
+	}
+
+	if caInfo := os.Getenv("GIT_SSL_CAINFO"); caInfo != "" && hasCACert {
+		slog.Warn("GIT_SSL_CAINFO takes precedence over the configured CA bundles for git", slog.String("ca_info", caInfo))
+	}
+	if err := setGitConfig("tls", gitConfig); err != nil {
+		return errBuilder.Wrapf(err, "failed to configure git")
+	}
+
+	var rt *hostTransport
+	if len(transports) > 0 {
+		rt = &hostTransport{
+			hosts:    transports,
+			fallback: http.DefaultTransport,
+		}
+	}
+
+	tlsMu.Lock()
+	transport = rt
+	tlsMu.Unlock()
+
+	installGitProtocol()
+	return nil
+}
+
+// installGitProtocol installs the HTTP client to go-git, since tags are listed with go-git rather than the git CLI.
+func installGitProtocol() {
+	client.InstallProtocol("https", githttp.NewClient(httpClient()))
+}
+
+// warnInsecure logs a warning if TLS verification is skipped for the host of the URL.
+func warnInsecure(u *url.URL) {
+	tlsMu.RLock()
+	defer tlsMu.RUnlock()
+	if transport != nil {
+		transport.warnInsecure(u)
+	}
+}
+
+// Client returns the HTTP client with the TLS settings, the authentication and the User-Agent of downloads,
+// e.g. for the registry lookups detecting the source repositories.
+func Client() *http.Client {
+	return httpClient()
+}
+
+// httpClient returns the HTTP client for downloads.
+func httpClient() *http.Client {
+	rt := http.RoundTripper(&countingTransport{base: tlsTransport()})
+	if h := currentHelper(); h != nil {
+		rt = &credentialHelperTransport{base: rt, helper: h}
+	}
+	if a := currentAuth(); a != nil {
+		rt = &authTransport{base: rt, auth: a}
+	}
+	if auths := currentBasicAuths(); auths != nil {
+		rt = &basicAuthTransport{base: rt, auths: auths}
+	}
+	return &http.Client{Transport: &userAgentTransport{base: rt}}
+}
+
+// tlsTransport returns the transport with the TLS settings, without authentication.
+func tlsTransport() http.RoundTripper {
+	tlsMu.RLock()
+	defer tlsMu.RUnlock()
+	if transport == nil {
+		return http.DefaultTransport
+	}
+	return transport
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to read the file")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, oops.Errorf("no certificate found")
+	}
+	return pool, nil
+}
+
+// setGitConfig replaces the git config entries of the feature, and passes all the entries to the git CLI.
+func setGitConfig(feature string, entries [][2]string) error {
+	gitConfigMu.Lock()
+	defer gitConfigMu.Unlock()
+	gitConfigs[feature] = entries
+
+	var features []string
+	for f := range gitConfigs {
+		features = append(features, f)
+	}
+	slices.Sort(features) // Keep the git config deterministic
+
+	var all [][2]string
+	for _, f := range features {
+		all = append(all, gitConfigs[f]...)
+	}
+	return setGitConfigEnv(all)
+}
+
+// setGitConfigEnv passes the config entries to the git CLI through the environment,
+// keeping the entries set by the user.
+func setGitConfigEnv(entries [][2]string) error {
+	gitConfigBaseOnce.Do(func() {
+		gitConfigBase, _ = strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	})
+
+	for i, entry := range entries {
+		n := strconv.Itoa(gitConfigBase + i)
+		if err := os.Setenv("GIT_CONFIG_KEY_"+n, entry[0]); err != nil {
+			return err
+		}
+		if err := os.Setenv("GIT_CONFIG_VALUE_"+n, entry[1]); err != nil {
+			return err
+		}
+	}
+
+	count := gitConfigBase + len(entries)
+	if count == 0 {
+		return os.Unsetenv("GIT_CONFIG_COUNT")
+	}
+	return os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(count))
+}
+
+// hostTransport dispatches the requests to the transport of their host.
+type hostTransport struct {
+	hosts    map[string]*http.Transport
+	fallback http.RoundTripper
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt := t.lookup(req.URL); rt != nil {
+		t.warnInsecure(req.URL)
+		return rt.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
+
+func (t *hostTransport) warnInsecure(u *url.URL) {
+	if u.Scheme != "https" {
+		return
+	}
+	if rt := t.lookup(u); rt != nil && rt.TLSClientConfig.InsecureSkipVerify {
+		slog.Warn("INSECURE: TLS verification is disabled for the host", slog.String("host", u.Host))
+	}
+}
+
+// lookup returns the transport of the host. A host without a port matches the default port only, as git does.
+func (t *hostTransport) lookup(u *url.URL) *http.Transport {
+	if rt, ok := t.hosts[u.Host]; ok {
+		return rt
+	}
+	if port := u.Port(); port == "" || port == "443" {
+		return t.hosts[u.Hostname()]
+	}
+	return nil
+}
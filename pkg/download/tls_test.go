@@ -0,0 +1,373 @@
+package download_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sosedoff/gitkit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+// certFiles is a certificate and its key written in PEM files.
+type certFiles struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certPath string
+	keyPath  string
+}
+
+// newCert issues a certificate signed by the parent, or a self-signed CA certificate if the parent is nil.
+func newCert(t *testing.T, dir, name string, parent *certFiles) *certFiles {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	f := &certFiles{
+		cert:     cert,
+		key:      key,
+		certPath: filepath.Join(dir, name+".crt"),
+		keyPath:  filepath.Join(dir, name+".key"),
+	}
+	writePEM(t, f.certPath, "CERTIFICATE", der)
+	writePEM(t, f.keyPath, "EC PRIVATE KEY", keyDER)
+	return f
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600))
+}
+
+// newRepoHandler serves a git repository at "/repo.git" and its tarball at "/repo.tar.gz".
+func newRepoHandler(t *testing.T) http.Handler {
+	wtDir := t.TempDir()
+	r, err := git.PlainInit(wtDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(wtDir, "vex.json"), []byte("{}"), 0644))
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	_, err = wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	bareDir := t.TempDir()
+	_, err = git.PlainClone(filepath.Join(bareDir, "repo.git"), true, &git.CloneOptions{URL: wtDir})
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", gitkit.New(gitkit.Config{Dir: bareDir}))
+	mux.HandleFunc("/repo.tar.gz", func(w http.ResponseWriter, _ *http.Request) {
+		gw := gzip.NewWriter(w)
+		tw := tar.NewWriter(gw)
+		_ = tw.WriteHeader(&tar.Header{Name: "vex.json", Mode: 0644, Size: 2})
+		_, _ = tw.Write([]byte("{}"))
+		_ = tw.Close()
+		_ = gw.Close()
+	})
+	return mux
+}
+
+// newMTLSServer starts a server requiring client certificates signed by the CA.
+// It returns the server and the path to the CA bundle verifying the server.
+func newMTLSServer(t *testing.T, ca *certFiles) (*httptest.Server, string) {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(newRepoHandler(t))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caPath := filepath.Join(t.TempDir(), "server-ca.crt")
+	writePEM(t, caPath, "CERTIFICATE", server.Certificate().Raw)
+	return server, caPath
+}
+
+// unsetGitCAInfo unsets GIT_SSL_CAINFO during the test as it overrides the CA bundles configured for git.
+func unsetGitCAInfo(t *testing.T) {
+	t.Setenv("GIT_SSL_CAINFO", "") // Restores the original value after the test
+	require.NoError(t, os.Unsetenv("GIT_SSL_CAINFO"))
+}
+
+func TestConfigureTLS_ClientCert(t *testing.T) {
+	unsetGitCAInfo(t)
+	certDir := t.TempDir()
+	ca := newCert(t, certDir, "ca", nil)
+	client := newCert(t, certDir, "client", ca)
+
+	server, serverCA := newMTLSServer(t, ca)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		src     string
+		hosts   []string
+		wantErr bool
+	}{
+		{
+			name:  "git",
+			src:   "git::" + server.URL + "/repo.git",
+			hosts: []string{u.Host},
+		},
+		{
+			name:  "tarball",
+			src:   server.URL + "/repo.tar.gz",
+			hosts: []string{u.Host},
+		},
+		{
+			name:    "git with certificate for another host",
+			src:     "git::" + server.URL + "/repo.git",
+			hosts:   []string{"git.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "tarball with certificate for another host",
+			src:     server.URL + "/repo.tar.gz",
+			hosts:   []string{"git.example.com"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := download.ConfigureTLS(download.TLSConfig{
+				ClientCerts: []download.ClientCert{
+					{
+						Hosts:  tt.hosts,
+						Cert:   client.certPath,
+						Key:    client.keyPath,
+						CACert: serverCA,
+					},
+				},
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() { require.NoError(t, download.ConfigureTLS(download.TLSConfig{})) })
+
+			dst := filepath.Join(t.TempDir(), "repo")
+			_, err = download.Download(context.Background(), tt.src, dst)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, filepath.Join(dst, "vex.json"))
+		})
+	}
+}
+
+func TestConfigureTLS_InvalidClientCert(t *testing.T) {
+	certDir := t.TempDir()
+	client := newCert(t, certDir, "client", nil)
+
+	err := download.ConfigureTLS(download.TLSConfig{
+		ClientCerts: []download.ClientCert{
+			{
+				Hosts: []string{"git.example.com"},
+				Cert:  client.certPath,
+				Key:   filepath.Join(certDir, "missing.key"),
+			},
+		},
+	})
+	require.ErrorContains(t, err, "failed to load the client certificate")
+
+	err = download.ConfigureTLS(download.TLSConfig{
+		ClientCerts: []download.ClientCert{
+			{
+				Cert: client.certPath,
+				Key:  client.keyPath,
+			},
+		},
+	})
+	require.ErrorContains(t, err, "hosts are required")
+}
+
+func TestConfigureTLS_CACert(t *testing.T) {
+	unsetGitCAInfo(t)
+
+	// The certificate of the test server is self-signed
+	server := httptest.NewTLSServer(newRepoHandler(t))
+	t.Cleanup(server.Close)
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	writePEM(t, caPath, "CERTIFICATE", server.Certificate().Raw)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		src     string
+		cfg     download.TLSConfig
+		wantErr bool
+	}{
+		{
+			name: "git",
+			src:  "git::" + server.URL + "/repo.git",
+			cfg: download.TLSConfig{
+				CACerts: []download.CACert{{Hosts: []string{u.Host}, Path: caPath}},
+			},
+		},
+		{
+			name: "tarball",
+			src:  server.URL + "/repo.tar.gz",
+			cfg: download.TLSConfig{
+				CACerts: []download.CACert{{Hosts: []string{u.Host}, Path: caPath}},
+			},
+		},
+		{
+			name:    "git without CA bundle",
+			src:     "git::" + server.URL + "/repo.git",
+			wantErr: true,
+		},
+		{
+			name:    "tarball without CA bundle",
+			src:     server.URL + "/repo.tar.gz",
+			wantErr: true,
+		},
+		{
+			name: "tarball with CA bundle for another host",
+			src:  server.URL + "/repo.tar.gz",
+			cfg: download.TLSConfig{
+				CACerts: []download.CACert{{Hosts: []string{"git.example.com"}, Path: caPath}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, download.ConfigureTLS(tt.cfg))
+			t.Cleanup(func() { require.NoError(t, download.ConfigureTLS(download.TLSConfig{})) })
+
+			dst := filepath.Join(t.TempDir(), "repo")
+			_, err := download.Download(context.Background(), tt.src, dst)
+			if tt.wantErr {
+				require.ErrorContains(t, err, "certificate")
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, filepath.Join(dst, "vex.json"))
+		})
+	}
+}
+
+func TestConfigureTLS_InsecureHosts(t *testing.T) {
+	unsetGitCAInfo(t)
+
+	// The handshake errors of the server would otherwise be logged through slog into the logs being checked
+	server := httptest.NewUnstartedServer(newRepoHandler(t))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		src          string
+		hosts        []string
+		wantErr      bool
+		wantInsecure bool
+	}{
+		{
+			name:         "git",
+			src:          "git::" + server.URL + "/repo.git",
+			hosts:        []string{u.Host},
+			wantInsecure: true,
+		},
+		{
+			name:         "tarball",
+			src:          server.URL + "/repo.tar.gz",
+			hosts:        []string{u.Host},
+			wantInsecure: true,
+		},
+		{
+			name:    "git with another insecure host",
+			src:     "git::" + server.URL + "/repo.git",
+			hosts:   []string{"git.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "tarball with another insecure host",
+			src:     server.URL + "/repo.tar.gz",
+			hosts:   []string{"git.example.com"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			defaultLogger := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+			t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+			require.NoError(t, download.ConfigureTLS(download.TLSConfig{InsecureHosts: tt.hosts}))
+			t.Cleanup(func() { require.NoError(t, download.ConfigureTLS(download.TLSConfig{})) })
+
+			dst := filepath.Join(t.TempDir(), "repo")
+			_, err := download.Download(context.Background(), tt.src, dst)
+			if tt.wantErr {
+				require.ErrorContains(t, err, "certificate")
+			} else {
+				require.NoError(t, err)
+				assert.FileExists(t, filepath.Join(dst, "vex.json"))
+			}
+			assert.Equal(t, tt.wantInsecure, strings.Contains(logs.String(), "INSECURE"))
+		})
+	}
+}
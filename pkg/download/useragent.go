@@ -0,0 +1,59 @@
+package download
+
+import (
+	"cmp"
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	userAgentMu sync.RWMutex
+	userAgent   = DefaultUserAgent()
+)
+
+// DefaultUserAgent returns "vexhub-crawler/<version>", where the version is the one of the main module,
+// e.g. "v0.1.0" when installed with "go install", or "(devel)" when built from a checkout.
+func DefaultUserAgent() string {
+	version := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	return "vexhub-crawler/" + version
+}
+
+// ConfigureUserAgent sets the User-Agent of the subsequent requests over HTTP and git,
+// so that hosts rate-limiting anonymous clients let the crawler through and publishers can identify it.
+// Empty means DefaultUserAgent. Since the git CLI is configured through the environment,
+// it must be called before downloads start.
+func ConfigureUserAgent(ua string) error {
+	ua = cmp.Or(ua, DefaultUserAgent())
+	userAgentMu.Lock()
+	userAgent = ua
+	userAgentMu.Unlock()
+
+	// cf. https://git-scm.com/docs/git-config#Documentation/git-config.txt-httpuserAgent
+	if err := setGitConfig("user_agent", [][2]string{{"http.userAgent", ua}}); err != nil {
+		return err
+	}
+	installGitProtocol()
+	return nil
+}
+
+// UserAgent returns the User-Agent of the requests.
+func UserAgent() string {
+	userAgentMu.RLock()
+	defer userAgentMu.RUnlock()
+	return userAgent
+}
+
+// userAgentTransport sets the User-Agent of the requests, replacing the one set by the libraries, e.g. go-git.
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", UserAgent())
+	return t.base.RoundTrip(req)
+}
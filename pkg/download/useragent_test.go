@@ -0,0 +1,92 @@
+package download_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+func TestConfigureUserAgent(t *testing.T) {
+	var mu sync.Mutex
+	var userAgents []string
+	repo := newRepoHandler(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		mu.Unlock()
+		repo.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	require.NoError(t, download.ConfigureUserAgent("test-crawler/1.0"))
+	t.Cleanup(func() { require.NoError(t, download.ConfigureUserAgent("")) })
+	assert.Equal(t, "test-crawler/1.0", download.UserAgent())
+
+	tests := []struct {
+		name     string
+		download func(dst string) error
+	}{
+		{
+			name: "git",
+			download: func(dst string) error {
+				_, err := download.Download(context.Background(), "git::"+server.URL+"/repo.git", dst)
+				return err
+			},
+		},
+		{
+			name: "tarball",
+			download: func(dst string) error {
+				_, err := download.Download(context.Background(), server.URL+"/repo.tar.gz", dst)
+				return err
+			},
+		},
+		{
+			name: "file",
+			download: func(dst string) error {
+				_, err := download.File(context.Background(), server.URL+"/repo.tar.gz", filepath.Join(t.TempDir(), "repo.tar.gz"))
+				return err
+			},
+		},
+		{
+			name: "client",
+			download: func(string) error {
+				resp, err := download.Client().Get(server.URL + "/repo.tar.gz")
+				if err == nil {
+					resp.Body.Close()
+				}
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			userAgents = nil
+			mu.Unlock()
+
+			require.NoError(t, tt.download(filepath.Join(t.TempDir(), "repo")))
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.NotEmpty(t, userAgents)
+			for _, ua := range userAgents {
+				assert.Equal(t, "test-crawler/1.0", ua)
+			}
+		})
+	}
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	assert.True(t, strings.HasPrefix(download.DefaultUserAgent(), "vexhub-crawler/"))
+	require.NoError(t, download.ConfigureUserAgent(""))
+	assert.Equal(t, download.DefaultUserAgent(), download.UserAgent())
+}
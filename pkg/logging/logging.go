@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/samber/oops"
+)
+
+// modulePrefix is stripped from the package paths to name the components, e.g. "crawl/vex".
+const modulePrefix = "github.com/aquasecurity/vexhub-crawler/pkg/"
+
+// Levels are the minimum log levels, globally and per component. A component is named after the package
+// logging the record relative to pkg/, e.g. "crawl/vex" or "download", or "main" for the command itself.
+// A component also covers its subpackages, e.g. "crawl" covers "crawl/vex", unless they have their own level.
+type Levels struct {
+	Default    slog.Level
+	Components map[string]slog.Level
+}
+
+// ParseLevels parses the global level, e.g. "info", and the comma-separated levels of the components,
+// e.g. "crawl/vex=warn,download=debug". The levels are the ones of slog, e.g. "debug", "warn" or "info+2".
+func ParseLevels(level, components string) (Levels, error) {
+	errBuilder := oops.Code("log_level_error").In("logging")
+	var levels Levels
+	if err := levels.Default.UnmarshalText([]byte(level)); err != nil {
+		return Levels{}, errBuilder.With("level", level).Wrapf(err, "invalid log level")
+	}
+	for _, entry := range strings.Split(components, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.Trim(strings.TrimSpace(name), "/")
+		if !ok || name == "" {
+			return Levels{}, errBuilder.With("entry", entry).Errorf("component level must be <component>=<level>")
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return Levels{}, errBuilder.With("entry", entry).Wrapf(err, "invalid log level")
+		}
+		if levels.Components == nil {
+			levels.Components = make(map[string]slog.Level)
+		}
+		levels.Components[name] = l
+	}
+	return levels, nil
+}
+
+// Min returns the lowest of the levels, which the base handler must accept.
+func (l Levels) Min() slog.Level {
+	level := l.Default
+	for _, c := range l.Components {
+		level = min(level, c)
+	}
+	return level
+}
+
+// level returns the level of the component, falling back to its parents and then to the default.
+func (l Levels) level(component string) slog.Level {
+	for c := component; c != "" && c != "."; {
+		if level, ok := l.Components[c]; ok {
+			return level
+		}
+		i := strings.LastIndex(c, "/")
+		if i < 0 {
+			break
+		}
+		c = c[:i]
+	}
+	return l.Default
+}
+
+// NewHandler returns the handler filtering the records of the base handler by the levels of their components.
+// The base handler must accept all the levels down to Levels.Min.
+func NewHandler(base slog.Handler, levels Levels) slog.Handler {
+	return &handler{base: base, levels: levels}
+}
+
+type handler struct {
+	base   slog.Handler
+	levels Levels
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	// The component is only known from the record
+	return level >= h.levels.Min() && h.base.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	level := h.levels.Default
+	if len(h.levels.Components) > 0 {
+		level = h.levels.level(component(r.PC))
+	}
+	if r.Level < level {
+		return nil
+	}
+	return h.base.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{base: h.base.WithAttrs(attrs), levels: h.levels}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{base: h.base.WithGroup(name), levels: h.levels}
+}
+
+// components caches the components by the program counters of the log calls.
+var components sync.Map
+
+// component returns the component of the function of the program counter, e.g. "crawl/vex".
+func component(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	} else if c, ok := components.Load(pc); ok {
+		return c.(string)
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	c := packagePath(frame.Function)
+	c = strings.TrimPrefix(c, modulePrefix)
+	components.Store(pc, c)
+	return c
+}
+
+// packagePath returns the package path of the fully qualified function name,
+// e.g. "github.com/owner/repo/pkg/crawl/vex" of "github.com/owner/repo/pkg/crawl/vex.(*quarantine).add".
+func packagePath(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
@@ -0,0 +1,132 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/logging"
+	"github.com/aquasecurity/vexhub-crawler/pkg/vexhub"
+)
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      string
+		components string
+		want       logging.Levels
+		wantErr    string
+	}{
+		{
+			name:  "default",
+			level: "info",
+			want:  logging.Levels{Default: slog.LevelInfo},
+		},
+		{
+			name:       "components",
+			level:      "WARN",
+			components: "crawl/vex=error, download=debug,vexhub/=info+2",
+			want: logging.Levels{
+				Default: slog.LevelWarn,
+				Components: map[string]slog.Level{
+					"crawl/vex": slog.LevelError,
+					"download":  slog.LevelDebug,
+					"vexhub":    slog.LevelInfo + 2,
+				},
+			},
+		},
+		{
+			name:    "invalid level",
+			level:   "verbose",
+			wantErr: "invalid log level",
+		},
+		{
+			name:       "invalid component level",
+			level:      "info",
+			components: "download=verbose",
+			wantErr:    "invalid log level",
+		},
+		{
+			name:       "no level",
+			level:      "info",
+			components: "download",
+			wantErr:    "component level must be <component>=<level>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := logging.ParseLevels(tt.level, tt.components)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      string
+		components string
+		want       []string // Messages logged
+	}{
+		{
+			name:  "default",
+			level: "info",
+			want:  []string{"test info", "test warn", "Generating the index of the VEX Hub"},
+		},
+		{
+			name:  "quiet",
+			level: "warn",
+			want:  []string{"test warn"},
+		},
+		{
+			name:       "quiet component",
+			level:      "info",
+			components: "vexhub=warn",
+			want:       []string{"test info", "test warn"},
+		},
+		{
+			name:       "verbose component",
+			level:      "error",
+			components: "logging_test=debug",
+			want:       []string{"test debug", "test info", "test warn"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, err := logging.ParseLevels(tt.level, tt.components)
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			base := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: levels.Min()})
+			logger := slog.New(logging.NewHandler(base, levels)).With(slog.String("key", "value"))
+
+			prev := slog.Default()
+			slog.SetDefault(logger)
+			t.Cleanup(func() { slog.SetDefault(prev) })
+
+			logger.Debug("test debug")
+			logger.Info("test info")
+			logger.Warn("test warn")
+			// Logged by the vexhub package
+			require.NoError(t, vexhub.GenerateIndex(t.TempDir()))
+
+			var got []string
+			for dec := json.NewDecoder(&buf); dec.More(); {
+				var r struct{ Msg string }
+				require.NoError(t, dec.Decode(&r))
+				got = append(got, r.Msg)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
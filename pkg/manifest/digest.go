@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/samber/oops"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Digest algorithms of the stored files, recorded as the prefix of Source.Digest.
+const (
+	DigestSHA256     = "sha256"
+	DigestSHA384     = "sha384"
+	DigestSHA512     = "sha512"
+	DigestBLAKE2b256 = "blake2b-256"
+	DigestBLAKE2b512 = "blake2b-512"
+)
+
+// DefaultDigestAlgorithm is the digest algorithm used unless configured otherwise.
+const DefaultDigestAlgorithm = DigestSHA256
+
+var digestHashes = map[string]func() hash.Hash{
+	DigestSHA256: sha256.New,
+	DigestSHA384: sha512.New384,
+	DigestSHA512: sha512.New,
+	DigestBLAKE2b256: func() hash.Hash {
+		h, _ := blake2b.New256(nil) // Never fails without a key
+		return h
+	},
+	DigestBLAKE2b512: func() hash.Hash {
+		h, _ := blake2b.New512(nil) // Never fails without a key
+		return h
+	},
+}
+
+// DigestAlgorithms returns the supported digest algorithms in sorted order.
+func DigestAlgorithms() []string {
+	var algs []string
+	for alg := range digestHashes {
+		algs = append(algs, alg)
+	}
+	slices.Sort(algs)
+	return algs
+}
+
+// NewDigest returns the digest of the content with the algorithm, in the form "<algorithm>:<hex>",
+// e.g. "sha256:e3b0c442...". Empty means DefaultDigestAlgorithm.
+func NewDigest(algorithm string, r io.Reader) (string, error) {
+	if algorithm == "" {
+		algorithm = DefaultDigestAlgorithm
+	}
+	newHash, ok := digestHashes[algorithm]
+	if !ok {
+		return "", oops.Code("digest_error").In("manifest").With("algorithm", algorithm).
+			With("supported", DigestAlgorithms()).Errorf("unsupported digest algorithm")
+	}
+	h := newHash()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", oops.Code("digest_error").In("manifest").Wrapf(err, "failed to read the content")
+	}
+	return algorithm + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyDigest checks the content against the digest, with the algorithm recorded in the digest.
+func VerifyDigest(digest string, r io.Reader) error {
+	errBuilder := oops.Code("digest_error").In("manifest").With("digest", digest)
+	algorithm, _, ok := strings.Cut(digest, ":")
+	if !ok {
+		return errBuilder.Errorf("digest has no algorithm")
+	}
+	got, err := NewDigest(algorithm, r)
+	if err != nil {
+		return errBuilder.Wrap(err)
+	} else if got != digest {
+		return errBuilder.With("actual", got).Errorf("digest mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package manifest
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Extra holds the fields of a manifest or a source unknown to the crawler, e.g. curated out-of-band,
+// so that they survive a round trip through Decode and Marshal. They're encoded after the known fields
+// in the order of their names, and never override the known fields.
+type Extra map[string]json.RawMessage
+
+type (
+	manifestFields Manifest
+	sourceFields   Source
+)
+
+func (m *Manifest) UnmarshalJSON(data []byte) error {
+	extra, err := unmarshalFields(data, (*manifestFields)(m))
+	if err != nil {
+		return err
+	}
+	m.Extra = extra
+	return nil
+}
+
+func (m Manifest) MarshalJSON() ([]byte, error) {
+	return marshalFields(manifestFields(m), m.Extra)
+}
+
+func (s *Source) UnmarshalJSON(data []byte) error {
+	extra, err := unmarshalFields(data, (*sourceFields)(s))
+	if err != nil {
+		return err
+	}
+	s.Extra = extra
+	return nil
+}
+
+func (s Source) MarshalJSON() ([]byte, error) {
+	return marshalFields(sourceFields(s), s.Extra)
+}
+
+// unmarshalFields decodes the known fields into v, and returns the others.
+func unmarshalFields(data []byte, v any) (Extra, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	known := knownFields(reflect.TypeOf(v).Elem())
+	var extra Extra
+	for name, value := range fields {
+		// encoding/json matches the names case-insensitively
+		if slices.ContainsFunc(known, func(k string) bool { return strings.EqualFold(k, name) }) {
+			continue
+		}
+		if extra == nil {
+			extra = make(Extra)
+		}
+		extra[name] = value
+	}
+	return extra, nil
+}
+
+// marshalFields encodes the known fields of v followed by the extra fields.
+func marshalFields(v any, extra Extra) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	known := knownFields(reflect.TypeOf(v))
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		if !slices.ContainsFunc(known, func(k string) bool { return strings.EqualFold(k, name) }) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	buf := bytes.NewBuffer(bytes.TrimSuffix(data, []byte("}")))
+	for _, name := range names {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// knownFields returns the JSON names of the fields of the struct type.
+func knownFields(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		names = append(names, cmp.Or(name, f.Name))
+	}
+	return names
+}
+
+// Merge carries the extra fields over from the previous manifest to the new one, for the manifest itself
+// and for the sources of the same path, so that the metadata curated out-of-band survives the crawls.
+// The known fields are the ones of the new manifest, and the sources missing from it are dropped.
+func Merge(prev, m Manifest) Manifest {
+	if m.Extra == nil && prev.Extra != nil {
+		m.Extra = prev.Extra
+	}
+	prevSources := make(map[string]Source)
+	for _, src := range prev.Sources {
+		prevSources[src.Path] = src
+	}
+	sources := slices.Clone(m.Sources)
+	for i, src := range sources {
+		if p, ok := prevSources[src.Path]; ok && src.Extra == nil {
+			sources[i].Extra = p.Extra
+		}
+	}
+	m.Sources = sources
+	return m
+}
@@ -1,8 +1,13 @@
 package manifest
 
 import (
+	"compress/gzip"
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/samber/oops"
 )
@@ -11,30 +16,170 @@ const FileName = "manifest.json"
 
 type Manifest struct {
 	ID      string // Must be PURL at the moment
+	Commit  string `json:",omitempty"` // Git commit the VEX documents were crawled from
 	Sources []Source
+
+	// Parent is the PURL of the package owning this one, e.g. a module of a multi-module repository,
+	// so that consumers can navigate the VEX documents of related packages.
+	Parent string `json:",omitempty"`
+
+	// Groups lists the paths of the sources sharing a vulnerability ID, keyed by the ID,
+	// when an advisory is split across multiple documents. It's opt-in to avoid bloating manifests.
+	Groups map[string][]string `json:",omitempty"`
+
+	// Latest is the file name of the OpenVEX document merging the OpenVEX sources, if published.
+	Latest string `json:",omitempty"`
+
+	Extra Extra `json:"-"` // Fields unknown to the crawler
 }
 
 type Source struct {
-	Path string
-	URL  string
+	Path   string // File name stored in VEX Hub
+	URL    string
+	Format string `json:",omitempty"` // e.g. "openvex", "cyclonedx"
+
+	// StatementCount is the number of statements in the document, excluding withheld ones,
+	// which helps to spot sources with few statements. Vulnerabilities are counted for CycloneDX.
+	StatementCount int `json:",omitempty"`
+
+	// Compression is the compression of the stored file, e.g. "gzip", and MediaType is the media type of the
+	// original document. They are set only for compressed files so that consumers know to decompress them.
+	Compression string `json:",omitempty"`
+	MediaType   string `json:",omitempty"`
+
+	// Digest is the digest of the stored file as is, prefixed with the algorithm, e.g. "sha256:<hex>",
+	// so that consumers can check the file against the manifest. See NewDigest.
+	Digest string `json:",omitempty"`
+
+	// SBOM is the format of the SBOM the statements were extracted from, e.g. "spdx" or "cyclonedx",
+	// and is empty for the VEX documents published as such.
+	SBOM string `json:",omitempty"`
+
+	Extra Extra `json:"-"` // Fields unknown to the crawler
 }
 
-func Write(filePath string, m Manifest) error {
-	errBuilder := oops.Code("write_manifest_error").In("manifest").With("filePath", filePath)
-	f, err := os.Create(filePath)
+// CompressionGzip is the compression of the files stored as "<name>.gz".
+const CompressionGzip = "gzip"
+
+// Open opens the stored file of the source in the directory, decompressing it transparently.
+func (s Source) Open(dir string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(dir, s.Path))
 	if err != nil {
-		return errBuilder.Wrapf(err, "failed to create sources file")
+		return nil, oops.Code("open_source_error").In("manifest").With("path", s.Path).
+			Wrapf(err, "failed to open the file")
 	}
-	defer f.Close()
+	return s.Decompress(f)
+}
+
+// Decompress returns the reader of the original document from the reader of the stored file.
+// The stored reader is closed along with the returned reader.
+func (s Source) Decompress(r io.ReadCloser) (io.ReadCloser, error) {
+	errBuilder := oops.Code("open_source_error").In("manifest").With("path", s.Path)
+	switch s.Compression {
+	case "":
+		return r, nil
+	case CompressionGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			r.Close()
+			return nil, errBuilder.Wrapf(err, "failed to decompress the file")
+		}
+		return &gzipReadCloser{Reader: gr, file: r}, nil
+	}
+	r.Close()
+	return nil, errBuilder.With("compression", s.Compression).Errorf("unknown compression")
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	file io.Closer
+}
+
+func (r *gzipReadCloser) Close() error {
+	return errors.Join(r.Reader.Close(), r.file.Close())
+}
+
+// Encoding configures the JSON encoding of the manifest.
+// It should be set in the shared config rather than per run,
+// so that operators don't cause churn by encoding manifests differently.
+type Encoding struct {
+	Indent          string `yaml:"indent"` // Empty means compact JSON
+	TrailingNewline bool   `yaml:"trailing_newline"`
+}
+
+// DefaultEncoding is pretty-printed JSON with a trailing newline, which is friendly to git diffs.
+var DefaultEncoding = Encoding{
+	Indent:          "    ",
+	TrailingNewline: true,
+}
+
+type Option func(*options)
+
+type options struct {
+	encoding   Encoding
+	signingKey ed25519.PrivateKey
+}
+
+func newOptions(opts []Option) options {
+	o := options{encoding: DefaultEncoding}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func WithEncoding(e Encoding) Option {
+	return func(o *options) {
+		o.encoding = e
+	}
+}
 
-	e := json.NewEncoder(f)
-	e.SetIndent("", "    ")
-	if err = e.Encode(m); err != nil {
-		return errBuilder.Wrapf(err, "JSON encode error")
+// WithSigningKey makes Write also write the detached signature of the manifest with the key,
+// next to the manifest with SignatureSuffix. A nil key doesn't sign the manifest.
+func WithSigningKey(key ed25519.PrivateKey) Option {
+	return func(o *options) {
+		o.signingKey = key
+	}
+}
+
+func Write(filePath string, m Manifest, opts ...Option) error {
+	errBuilder := oops.Code("write_manifest_error").In("manifest").With("filePath", filePath)
+	data, err := Marshal(m, opts...)
+	if err != nil {
+		return errBuilder.Wrap(err)
+	}
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return errBuilder.Wrapf(err, "failed to write sources file")
+	}
+	if key := newOptions(opts).signingKey; key != nil {
+		if err = os.WriteFile(filePath+SignatureSuffix, Sign(key, data), 0644); err != nil {
+			return errBuilder.Wrapf(err, "failed to write the signature")
+		}
 	}
 	return nil
 }
 
+// Marshal encodes the manifest as Write does, e.g. to write it to another filesystem.
+// The manifest isn't signed, see Sign.
+func Marshal(m Manifest, opts ...Option) ([]byte, error) {
+	enc := newOptions(opts).encoding
+
+	var data []byte
+	var err error
+	if enc.Indent != "" {
+		data, err = json.MarshalIndent(m, "", enc.Indent)
+	} else {
+		data, err = json.Marshal(m)
+	}
+	if err != nil {
+		return nil, oops.Code("write_manifest_error").In("manifest").Wrapf(err, "JSON encode error")
+	}
+	if enc.TrailingNewline {
+		data = append(data, '\n')
+	}
+	return data, nil
+}
+
 func Read(filePath string) (Manifest, error) {
 	errBuilder := oops.Code("read_manifest_error").In("manifest").With("filePath", filePath)
 	f, err := os.Open(filePath)
@@ -43,9 +188,18 @@ func Read(filePath string) (Manifest, error) {
 	}
 	defer f.Close()
 
+	m, err := Decode(f)
+	if err != nil {
+		return Manifest{}, errBuilder.Wrap(err)
+	}
+	return m, nil
+}
+
+// Decode decodes the manifest from the reader, e.g. a file in a git commit.
+func Decode(r io.Reader) (Manifest, error) {
 	var m Manifest
-	if err = json.NewDecoder(f).Decode(&m); err != nil {
-		return Manifest{}, errBuilder.Wrapf(err, "failed to decode the file")
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return Manifest{}, oops.Code("read_manifest_error").In("manifest").Wrapf(err, "failed to decode the file")
 	}
 	return m, nil
 }
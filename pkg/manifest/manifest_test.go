@@ -0,0 +1,289 @@
+package manifest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+func TestWrite(t *testing.T) {
+	m := manifest.Manifest{
+		ID: "pkg:npm/foo",
+		Sources: []manifest.Source{
+			{
+				Path: "foo.openvex.json",
+				URL:  "https://github.com/org/foo",
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts []manifest.Option
+		want string
+	}{
+		{
+			name: "default",
+			want: `{
+    "ID": "pkg:npm/foo",
+    "Sources": [
+        {
+            "Path": "foo.openvex.json",
+            "URL": "https://github.com/org/foo"
+        }
+    ]
+}
+`,
+		},
+		{
+			name: "compact without trailing newline",
+			opts: []manifest.Option{
+				manifest.WithEncoding(manifest.Encoding{}),
+			},
+			want: `{"ID":"pkg:npm/foo","Sources":[{"Path":"foo.openvex.json","URL":"https://github.com/org/foo"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), manifest.FileName)
+			require.NoError(t, manifest.Write(filePath, m, tt.opts...))
+
+			got, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+
+			// Round trip
+			read, err := manifest.Read(filePath)
+			require.NoError(t, err)
+			require.Equal(t, m, read)
+		})
+	}
+}
+
+func TestSource_Open(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plain.json"), []byte(`{"plain":true}`), 0644))
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write([]byte(`{"compressed":true}`))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "compressed.json.gz"), buf.Bytes(), 0644))
+
+	tests := []struct {
+		name    string
+		src     manifest.Source
+		want    string
+		wantErr string
+	}{
+		{
+			name: "plain",
+			src:  manifest.Source{Path: "plain.json"},
+			want: `{"plain":true}`,
+		},
+		{
+			name: "gzip",
+			src:  manifest.Source{Path: "compressed.json.gz", Compression: manifest.CompressionGzip},
+			want: `{"compressed":true}`,
+		},
+		{
+			name:    "not compressed",
+			src:     manifest.Source{Path: "plain.json", Compression: manifest.CompressionGzip},
+			wantErr: "failed to decompress the file",
+		},
+		{
+			name:    "unknown compression",
+			src:     manifest.Source{Path: "plain.json", Compression: "zstd"},
+			wantErr: "unknown compression",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.src.Open(dir)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestNewDigest(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      string
+		wantErr   string
+	}{
+		{
+			algorithm: "",
+			want:      "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+		{
+			algorithm: manifest.DigestSHA256,
+			want:      "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+		{
+			algorithm: manifest.DigestSHA384,
+			want:      "sha384:59e1748777448c69de6b800d7a33bbfb9ff1b463e44354c3553bcdb9c666fa90125a3c79f90397bdf5f6a13de828684f",
+		},
+		{
+			algorithm: manifest.DigestSHA512,
+			want:      "sha512:9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043",
+		},
+		{
+			algorithm: manifest.DigestBLAKE2b256,
+			want:      "blake2b-256:324dcf027dd4a30a932c441f365a25e86b173defa4b8e58948253471b81b72cf",
+		},
+		{
+			algorithm: manifest.DigestBLAKE2b512,
+			want:      "blake2b-512:e4cfa39a3d37be31c59609e807970799caa68a19bfaa15135f165085e01d41a65ba1e1b146aeb6bd0092b49eac214c103ccfa3a365954bbbe52f74a2b3620c94",
+		},
+		{
+			algorithm: "md5",
+			wantErr:   "unsupported digest algorithm",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			got, err := manifest.NewDigest(tt.algorithm, strings.NewReader("hello"))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+
+			// The algorithm is taken from the digest
+			require.NoError(t, manifest.VerifyDigest(got, strings.NewReader("hello")))
+			require.ErrorContains(t, manifest.VerifyDigest(got, strings.NewReader("hello!")), "digest mismatch")
+		})
+	}
+
+	require.ErrorContains(t, manifest.VerifyDigest("2cf24dba", strings.NewReader("hello")), "digest has no algorithm")
+	require.ErrorContains(t, manifest.VerifyDigest("md5:5d41402a", strings.NewReader("hello")), "unsupported digest algorithm")
+}
+
+func TestWrite_Signed(t *testing.T) {
+	dir := t.TempDir()
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	// The keys round trip through PEM files as generated by openssl
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	keyPath, pubPath := filepath.Join(dir, "manifest.key"), filepath.Join(dir, "manifest.pub")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644))
+	loadedKey, err := manifest.LoadSigningKey(keyPath)
+	require.NoError(t, err)
+	loadedPub, err := manifest.LoadVerifyingKey(pubPath)
+	require.NoError(t, err)
+	_, err = manifest.LoadSigningKey(pubPath)
+	require.ErrorContains(t, err, "unsupported private key")
+
+	filePath := filepath.Join(dir, manifest.FileName)
+	m := manifest.Manifest{ID: "pkg:npm/foo", Sources: []manifest.Source{{Path: "foo.openvex.json"}}}
+	require.NoError(t, manifest.Write(filePath, m, manifest.WithSigningKey(loadedKey)))
+
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	sig, err := os.ReadFile(filePath + manifest.SignatureSuffix)
+	require.NoError(t, err)
+	require.NoError(t, manifest.VerifySignature(loadedPub, data, sig))
+
+	// Any change to the manifest invalidates the signature
+	tampered := bytes.Replace(data, []byte("foo.openvex.json"), []byte("bar.openvex.json"), 1)
+	require.ErrorContains(t, manifest.VerifySignature(loadedPub, tampered, sig), "signature mismatch")
+
+	// Without a key, no signature is written
+	unsigned := filepath.Join(t.TempDir(), manifest.FileName)
+	require.NoError(t, manifest.Write(unsigned, m))
+	require.NoFileExists(t, unsigned+manifest.SignatureSuffix)
+}
+
+func TestDecode_Extra(t *testing.T) {
+	const data = `{
+    "ID": "pkg:npm/foo",
+    "Sources": [
+        {
+            "Path": "foo.openvex.json",
+            "URL": "https://github.com/org/foo",
+            "reviewed_by": "alice"
+        }
+    ],
+    "owner": {
+        "team": "a"
+    }
+}
+`
+	m, err := manifest.Decode(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, manifest.Extra{"owner": []byte("{\n        \"team\": \"a\"\n    }")}, m.Extra)
+	require.Equal(t, manifest.Extra{"reviewed_by": []byte(`"alice"`)}, m.Sources[0].Extra)
+
+	// The extra fields round trip after the known fields
+	got, err := manifest.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, data, string(got))
+
+	// Known fields are matched case-insensitively, as encoding/json does
+	m, err = manifest.Decode(strings.NewReader(`{"id": "pkg:npm/foo", "sources": []}`))
+	require.NoError(t, err)
+	require.Equal(t, "pkg:npm/foo", m.ID)
+	require.Nil(t, m.Extra)
+}
+
+func TestMerge(t *testing.T) {
+	prev := manifest.Manifest{
+		ID:     "pkg:npm/foo",
+		Commit: "0123abcd",
+		Sources: []manifest.Source{
+			{Path: "a.openvex.json", URL: "https://example.com/old/a", Extra: manifest.Extra{"reviewed": []byte("true")}},
+			{Path: "gone.openvex.json", Extra: manifest.Extra{"reviewed": []byte("true")}},
+		},
+		Extra: manifest.Extra{"owner": []byte(`"team-a"`)},
+	}
+	m := manifest.Manifest{
+		ID:     "pkg:npm/foo",
+		Commit: "4567cdef",
+		Sources: []manifest.Source{
+			{Path: "a.openvex.json", URL: "https://example.com/new/a"},
+			{Path: "b.openvex.json", URL: "https://example.com/new/b"},
+		},
+	}
+
+	got := manifest.Merge(prev, m)
+	require.Equal(t, manifest.Manifest{
+		ID:     "pkg:npm/foo",
+		Commit: "4567cdef",
+		Sources: []manifest.Source{
+			{Path: "a.openvex.json", URL: "https://example.com/new/a", Extra: manifest.Extra{"reviewed": []byte("true")}},
+			{Path: "b.openvex.json", URL: "https://example.com/new/b"},
+		},
+		Extra: manifest.Extra{"owner": []byte(`"team-a"`)},
+	}, got)
+	require.Nil(t, m.Sources[0].Extra, "the new manifest must not be modified")
+}
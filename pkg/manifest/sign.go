@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+
+	"github.com/samber/oops"
+)
+
+// SignatureSuffix is appended to the file name of the manifest for its detached signature, e.g. "manifest.json.sig".
+const SignatureSuffix = ".sig"
+
+// Sign returns the detached signature of the encoded manifest, which is the base64-encoded Ed25519 signature
+// of the bytes as written. Ed25519 signatures are deterministic, so the same manifest always has the same signature.
+func Sign(key ed25519.PrivateKey, data []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n")
+}
+
+// VerifySignature checks the detached signature of the encoded manifest with the public key.
+func VerifySignature(key ed25519.PublicKey, data, sig []byte) error {
+	errBuilder := oops.Code("signature_error").In("manifest")
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to decode the signature")
+	} else if !ed25519.Verify(key, data, decoded) {
+		return errBuilder.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// LoadSigningKey loads the PEM-encoded PKCS #8 Ed25519 private key,
+// e.g. generated with "openssl genpkey -algorithm ed25519".
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	// Don't include the key in errors and logs
+	errBuilder := oops.Code("signing_key_error").In("manifest").With("path", path)
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errBuilder.Errorf("unsupported private key")
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errBuilder.Errorf("private key must be Ed25519")
+	}
+	return key, nil
+}
+
+// LoadVerifyingKey loads the PEM-encoded PKIX Ed25519 public key,
+// e.g. extracted with "openssl pkey -pubout".
+func LoadVerifyingKey(path string) (ed25519.PublicKey, error) {
+	errBuilder := oops.Code("signing_key_error").In("manifest").With("path", path)
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse the public key")
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, errBuilder.Errorf("public key must be Ed25519")
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to read the file")
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, oops.Errorf("no PEM block found")
+	}
+	return block, nil
+}
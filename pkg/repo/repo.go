@@ -6,6 +6,7 @@ type Index struct {
 }
 
 type Package struct {
-	ID       string `json:"id"`       // Must be PURL at the moment
-	Location string `json:"location"` // File path to the VEX document
+	ID       string `json:"id"`               // Must be PURL at the moment
+	Location string `json:"location"`         // File path to the VEX document
+	Parent   string `json:"parent,omitempty"` // PURL of the package owning this one, if any
 }
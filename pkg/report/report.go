@@ -0,0 +1,74 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped" // Already crawled in the interrupted run being resumed, or without recent activity
+)
+
+// Category classifies why a package failed, e.g. for CI pipelines to branch on the exit code.
+type Category string
+
+const (
+	CategoryOther      Category = "other"
+	CategoryConfig     Category = "config"     // Invalid settings, e.g. a certificate failing to load
+	CategoryNoVEX      Category = "no_vex"     // No VEX document found in the source
+	CategoryValidation Category = "validation" // VEX documents failing the validation
+	CategoryDownload   Category = "download"   // Sources failing to be detected, resolved or downloaded
+)
+
+// Report summarizes a crawl run.
+// Unlike the manifest, it is not stored in VEX Hub, so it can carry audit details without causing churn.
+type Report struct {
+	Packages []Package `json:"packages"`
+
+	// Hubs are the results grouped by hub when multiple hubs are crawled in the run, instead of Packages.
+	Hubs []Hub `json:"hubs,omitempty"`
+
+	// Stopped is the reason the run stopped before crawling all the packages, if any.
+	Stopped string `json:"stopped,omitempty"`
+}
+
+// Hub is the results of the packages of a hub.
+type Hub struct {
+	Name     string    `json:"name"`
+	Dir      string    `json:"dir"`
+	Packages []Package `json:"packages"`
+}
+
+type Package struct {
+	ID     string `json:"id"` // Must be PURL at the moment
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	// Category classifies the error of a failed package.
+	Category Category `json:"category,omitempty"`
+	*vex.Result
+}
+
+func Write(filePath string, r Report) error {
+	errBuilder := oops.Code("write_report_error").In("report").With("filePath", filePath)
+	f, err := os.Create(filePath)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to create report file")
+	}
+	defer f.Close()
+
+	e := json.NewEncoder(f)
+	e.SetIndent("", "    ")
+	if err = e.Encode(r); err != nil {
+		return errBuilder.Wrapf(err, "JSON encode error")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package trace
+
+import (
+	"context"
+	"os"
+
+	"github.com/samber/oops"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TracerName is the instrumentation name of the spans of the crawler.
+const TracerName = "github.com/aquasecurity/vexhub-crawler"
+
+// NewTracerProvider returns a tracer provider exporting the spans over OTLP/HTTP
+// if an endpoint is configured by OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT,
+// or a no-op provider otherwise. The exporter is configured by the other standard OTEL_* variables,
+// and the returned function flushes the pending spans and must be called before exiting.
+func NewTracerProvider(ctx context.Context) (oteltrace.TracerProvider, func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, nil, oops.Wrapf(err, "failed to create the OTLP exporter")
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence over the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "vexhub-crawler")),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, nil, oops.Wrapf(err, "failed to detect the resource")
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	return tp, tp.Shutdown, nil
+}
+
+// OrNoop returns the tracer, or a no-op tracer if nil.
+func OrNoop(tracer oteltrace.Tracer) oteltrace.Tracer {
+	if tracer == nil {
+		return noop.NewTracerProvider().Tracer(TracerName)
+	}
+	return tracer
+}
+
+// End records the error on the span, if any, and ends the span.
+func End(span oteltrace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,51 @@
+// Package trace carries a request ID in the context of each package crawl,
+// so that the interleaved log lines and errors of a concurrent crawl can be correlated.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"github.com/samber/oops"
+)
+
+// LogKey is the attribute of the request ID in logs.
+const LogKey = "request_id"
+
+type contextKey struct{}
+
+// NewID generates a random request ID.
+func NewID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b) // Never fails
+	return hex.EncodeToString(b)
+}
+
+// WithID returns a copy of the context carrying the request ID.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// ID returns the request ID carried by the context, or an empty string if none.
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Logger returns the default logger with the request ID of the context, if any.
+func Logger(ctx context.Context) *slog.Logger {
+	if id := ID(ctx); id != "" {
+		return slog.Default().With(slog.String(LogKey, id))
+	}
+	return slog.Default()
+}
+
+// Errors returns an error builder tracing the errors with the request ID of the context, if any.
+func Errors(ctx context.Context) oops.OopsErrorBuilder {
+	if id := ID(ctx); id != "" {
+		return oops.Trace(id).With(LogKey, id)
+	}
+	return oops.With()
+}
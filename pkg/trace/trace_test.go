@@ -0,0 +1,50 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/samber/oops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/trace"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	id := trace.NewID()
+	require.Len(t, id, 16)
+	assert.NotEqual(t, id, trace.NewID())
+
+	ctx := trace.WithID(context.Background(), id)
+	assert.Equal(t, id, trace.ID(ctx))
+	trace.Logger(ctx).Info("test")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, id, line[trace.LogKey])
+
+	buf.Reset()
+	trace.Logger(context.Background()).Info("test")
+	line = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.NotContains(t, line, trace.LogKey)
+}
+
+func TestErrors(t *testing.T) {
+	ctx := trace.WithID(context.Background(), "0123456789abcdef")
+	err := oops.Wrapf(trace.Errors(ctx).In("crawl").Errorf("failed"), "wrapped")
+
+	oe, ok := oops.AsOops(err)
+	require.True(t, ok)
+	assert.Equal(t, "0123456789abcdef", oe.Trace())
+	assert.Equal(t, "0123456789abcdef", oe.Context()[trace.LogKey])
+}
@@ -0,0 +1,65 @@
+package url
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// repoPath returns the path of the repository on GitHub or GitLab, e.g. "owner/repo",
+// or false if the URL is not a repository on these hosts.
+func (u *URL) repoPath() (string, bool) {
+	if u.Scheme != "https" || u.User != nil || u.IsBundle() || u.IsArchive() {
+		return "", false
+	}
+	return hostedRepoPath(u.Host, u.Path)
+}
+
+// GitHubRepo returns the repository on GitHub, e.g. "owner/repo", or false if the URL is not a repository on GitHub.
+func (u *URL) GitHubRepo() (string, bool) {
+	if u.Host != "github.com" {
+		return "", false
+	}
+	return u.repoPath()
+}
+
+// hostedRepoPath returns the path of the repository from the URL path on GitHub or GitLab.
+func hostedRepoPath(host, urlPath string) (string, bool) {
+	p := strings.TrimSuffix(strings.Trim(urlPath, "/"), ".git")
+	parts := strings.Split(p, "/")
+	if len(parts) < 2 {
+		return "", false
+	}
+	switch host {
+	case "github.com":
+		return path.Join(parts[0], parts[1]), true // Drop "/tree/<ref>" if any
+	case "gitlab.com":
+		return p, true // Groups can be nested
+	}
+	return "", false
+}
+
+// ArchiveString returns the URL string of the tarball of the commit for hashicorp/go-getter,
+// or an empty string if the host doesn't offer archive downloads.
+// Downloading the archive is lighter than cloning, while the top-level directory of the archive is stripped
+// so that the layout is the same as a clone.
+func (u *URL) ArchiveString(commit string) string {
+	p, ok := u.repoPath()
+	if !ok {
+		return ""
+	}
+
+	uu := url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+	}
+	switch u.Host {
+	case "github.com":
+		// e.g. https://github.com/owner/repo/archive/<commit>.tar.gz
+		uu.Path = path.Join("/", p, "archive", commit+".tar.gz")
+	case "gitlab.com":
+		// e.g. https://gitlab.com/group/repo/-/archive/<commit>/repo-<commit>.tar.gz
+		uu.Path = path.Join("/", p, "-", "archive", commit, path.Base(p)+"-"+commit+".tar.gz")
+	}
+	return uu.String() + "//*"
+}
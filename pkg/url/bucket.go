@@ -0,0 +1,52 @@
+package url
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Schemes of the object storage buckets, e.g. "s3://bucket/prefix" and "gs://bucket/prefix".
+const (
+	SchemeS3  = "s3"
+	SchemeGCS = "gs"
+)
+
+// IsBucket returns true if the URL points to a prefix of the objects in an Amazon S3 or Google Cloud Storage bucket.
+// The objects under the prefix are downloaded with go-getter, authenticated with the standard credential chains.
+func (u *URL) IsBucket() bool {
+	return (u.Scheme == SchemeS3 || u.Scheme == SchemeGCS) && u.Host != ""
+}
+
+// bucketGetterString returns the URL of the prefix in the format of the S3 and GCS getters of go-getter.
+// The region of an S3 bucket may be set with the "region" query parameter, e.g. "s3://bucket/prefix?region=eu-west-1".
+func (u *URL) bucketGetterString() string {
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if u.Scheme == SchemeGCS {
+		// e.g. gcs::https://www.googleapis.com/storage/v1/bucket/prefix
+		return "gcs::https://www.googleapis.com/storage/v1/" + path.Join(u.Host, prefix)
+	}
+
+	// e.g. s3::https://s3-eu-west-1.amazonaws.com/bucket/prefix
+	host := "s3.amazonaws.com" // us-east-1
+	if region := u.Query().Get("region"); region != "" {
+		host = "s3-" + region + ".amazonaws.com"
+	}
+	return "s3::https://" + host + "/" + path.Join(u.Host, prefix)
+}
+
+// objectURL returns the HTTPS URL of the prefix, under which the URLs of the objects are joined.
+func (u *URL) objectURL() *url.URL {
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if u.Scheme == SchemeGCS {
+		// e.g. https://storage.googleapis.com/bucket/prefix
+		return &url.URL{Scheme: "https", Host: "storage.googleapis.com", Path: "/" + path.Join(u.Host, prefix)}
+	}
+
+	// e.g. https://bucket.s3.eu-west-1.amazonaws.com/prefix
+	host := u.Host + ".s3.amazonaws.com"
+	if region := u.Query().Get("region"); region != "" {
+		host = u.Host + ".s3." + region + ".amazonaws.com"
+	}
+	return &url.URL{Scheme: "https", Host: host, Path: "/" + prefix}
+}
@@ -0,0 +1,58 @@
+package url
+
+import (
+	"net/url"
+	"path"
+)
+
+// Permalink returns the base URL of the files at the commit of the repository on GitHub or GitLab,
+// or nil for other hosts. The owner may contain nested groups on GitLab, e.g. "group/subgroup".
+// The inputs may come from the git repository on disk or be known in advance, e.g. for archive downloads.
+func Permalink(host, owner, repo, commit string) *url.URL {
+	if owner == "" || repo == "" || commit == "" {
+		return nil
+	}
+
+	u := &url.URL{
+		Scheme: "https",
+		Host:   host,
+	}
+	switch host {
+	case "github.com":
+		// e.g. https://github.com/owner/repo/blob/<commit>/
+		u.Path = path.Join("/", owner, repo, "blob", commit)
+	case "gitlab.com":
+		// e.g. https://gitlab.com/group/repo/-/blob/<commit>/
+		u.Path = path.Join("/", owner, repo, "-", "blob", commit)
+	default:
+		return nil
+	}
+	return u
+}
+
+// RemotePermalink returns the permalink of the commit from the remote URL of a git repository,
+// e.g. "https://github.com/owner/repo.git". Credentials in the URL are dropped.
+func RemotePermalink(remote, commit string) *url.URL {
+	u, err := url.Parse(remote)
+	if err != nil {
+		return nil
+	}
+	p, ok := hostedRepoPath(u.Host, u.Path)
+	if !ok {
+		return nil
+	}
+	return Permalink(u.Host, path.Dir(p), path.Base(p), commit)
+}
+
+// Permalink returns the permalink of the commit of the repository, or nil for hosts other than GitHub and GitLab.
+// Buckets have no commit, and the URLs of their objects are returned instead.
+func (u *URL) Permalink(commit string) *url.URL {
+	if u.IsBucket() {
+		return u.objectURL()
+	}
+	p, ok := u.repoPath()
+	if !ok {
+		return nil
+	}
+	return Permalink(u.Host, path.Dir(p), path.Base(p), commit)
+}
@@ -1,14 +1,23 @@
 package url
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/samber/oops"
 )
 
+// archiveExts are the archive formats unpacked by go-getter.
+var archiveExts = []string{".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz", ".tar", ".zip"}
+
+// ErrSubdirsTraversal is returned for the subdirectories that would escape the downloaded source,
+// e.g. "../etc" or "/etc".
+var ErrSubdirsTraversal = errors.New("subdirectories escape the source")
+
 type URL struct {
 	*url.URL
 	depth   int
@@ -18,7 +27,7 @@ type URL struct {
 
 // Parse parses rawurl into a URL structure.
 func Parse(rawurl string) (*URL, error) {
-	errBuilder := oops.Code("url_parse_error").In("url").With("url", rawurl)
+	errBuilder := oops.Code("url_parse_error").In("url").With("url", Redact(rawurl))
 
 	parsed, err := url.Parse(rawurl)
 	if err != nil {
@@ -43,10 +52,30 @@ func Parse(rawurl string) (*URL, error) {
 		u.Path = before
 		u.subdirs = after
 	}
+	if err = CheckSubdirs(u.subdirs); err != nil {
+		return nil, errBuilder.With("subdirs", u.subdirs).Wrap(err)
+	}
 
 	return u, nil
 }
 
+// CheckSubdirs checks that the subdirectories stay within the downloaded source when joined to its directory.
+// Absolute paths and ".." elements are rejected rather than cleaned, since they're a misconfiguration.
+func CheckSubdirs(subdirs string) error {
+	if subdirs == "" {
+		return nil
+	} else if strings.HasPrefix(subdirs, "/") || strings.HasPrefix(subdirs, `\`) || filepath.IsAbs(subdirs) ||
+		filepath.VolumeName(subdirs) != "" {
+		return ErrSubdirsTraversal
+	}
+	for _, elem := range strings.FieldsFunc(subdirs, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return ErrSubdirsTraversal
+		}
+	}
+	return nil
+}
+
 func parseGitHubURL(u *URL) {
 	// Split the path
 	parts := strings.Split(u.Path, "/")
@@ -81,16 +110,97 @@ func (u *URL) Subdirs() string {
 	return u.subdirs
 }
 
+// SetRef pins the git reference (branch, tag or commit) to download.
+func (u *URL) SetRef(ref string) {
+	u.ref = ref
+}
+
+func (u *URL) Ref() string {
+	return u.ref
+}
+
 func (u *URL) String() string {
 	return u.URL.String()
 }
 
+// Public returns the URL string without the user info, e.g. the credentials interpolated into the config,
+// so that it can be recorded in VEX Hub.
+func (u *URL) Public() string {
+	uu := *u.URL
+	uu.User = nil
+	return uu.String()
+}
+
+// Redacted returns the URL string with the password replaced by "xxxxx" for logs and errors.
+func (u *URL) Redacted() string {
+	return u.URL.Redacted()
+}
+
+// Redact returns the URL string with the password replaced by "xxxxx" for logs and errors, keeping the forced
+// getter of go-getter, e.g. "git::". Strings that are not URLs are returned as is.
+func Redact(rawurl string) string {
+	forced, rest, ok := strings.Cut(rawurl, "::")
+	if !ok {
+		forced, rest = "", rawurl
+	}
+	u, err := url.Parse(rest)
+	if err != nil || u.User == nil {
+		return rawurl
+	}
+	if forced != "" {
+		return forced + "::" + u.Redacted()
+	}
+	return u.Redacted()
+}
+
+// IsBundle returns true if the URL points to a git bundle file.
+func (u *URL) IsBundle() bool {
+	return strings.HasSuffix(u.Path, ".bundle")
+}
+
+// IsArchive returns true if the URL points to an archive such as a tarball or a zip file.
+func (u *URL) IsArchive() bool {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(u.Path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFile returns true if the URL points to a single VEX document served over HTTP(S), e.g. "https://example.com/vex.json".
+// All the supported formats are JSON.
+func (u *URL) IsFile() bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && path.Ext(u.Path) == ".json"
+}
+
+// RepoString returns the URL of the git repository without go-getter specific parts.
+func (u *URL) RepoString() string {
+	uu := *u.URL
+	if !strings.HasSuffix(uu.Path, ".git") {
+		uu.Path += ".git"
+	}
+	uu.RawQuery = ""
+	return uu.String()
+}
+
 // GetterString returns URL string for hashicorp/go-getter.
 // To keep Git information, do not specify subdirectories.
 // cf. https://github.com/hashicorp/go-getter?tab=readme-ov-file#subdirectories
 func (u *URL) GetterString() string {
 	uu := *u.URL
 
+	switch {
+	case u.IsBucket():
+		return u.bucketGetterString()
+	case u.IsBundle():
+		// Cloned by the custom getter, so the git parameters are not needed
+		return "bundle::" + uu.String()
+	case u.IsArchive():
+		// go-getter unpacks archives based on the extension
+		return uu.String()
+	}
+
 	// Force the git protocol
 	if !strings.HasPrefix(uu.Scheme, "git::") {
 		uu.Scheme = "git::" + uu.Scheme
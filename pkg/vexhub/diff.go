@@ -0,0 +1,246 @@
+package vexhub
+
+import (
+	"cmp"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// Diff is the difference of the VEX statements between two states of the VEX Hub.
+// Unlike git diff, it's based on the parsed statements, so reformatting documents makes no difference.
+type Diff struct {
+	Packages []PackageDiff `json:"packages"`
+}
+
+// PackageDiff is the difference of the VEX statements of a package.
+type PackageDiff struct {
+	ID      string            `json:"id"`
+	Added   []vex.Statement   `json:"added,omitempty"`
+	Removed []vex.Statement   `json:"removed,omitempty"`
+	Changed []StatementChange `json:"changed,omitempty"`
+}
+
+// StatementChange is a statement whose status changed.
+type StatementChange struct {
+	Vulnerability string `json:"vulnerability"`
+	Product       string `json:"product"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+}
+
+// statementKey identifies a statement in a package.
+type statementKey struct {
+	vulnerability string
+	product       string
+}
+
+// hubState is the statuses of the statements by package ID.
+type hubState map[string]map[statementKey]string
+
+// DiffStates compares the statements of the VEX Hub between two git revisions.
+// An empty "from" means HEAD, and an empty "to" means the working tree.
+func DiffStates(root, from, to string, opts ...Option) (Diff, error) {
+	o := newOptions(opts)
+	errBuilder := oops.Code("diff_error").In("vexhub").With("root", root).With("from", from).With("to", to)
+
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return Diff{}, errBuilder.Wrapf(err, "failed to open the git repository")
+	}
+
+	before, err := commitState(repo, cmp.Or(from, "HEAD"), o.manifestName)
+	if err != nil {
+		return Diff{}, errBuilder.Wrap(err)
+	}
+
+	var after hubState
+	if to == "" {
+		after, err = worktreeState(root, o.manifestName)
+	} else {
+		after, err = commitState(repo, to, o.manifestName)
+	}
+	if err != nil {
+		return Diff{}, errBuilder.Wrap(err)
+	}
+	return diffStates(before, after), nil
+}
+
+func diffStates(before, after hubState) Diff {
+	ids := make(map[string]struct{})
+	for id := range before {
+		ids[id] = struct{}{}
+	}
+	for id := range after {
+		ids[id] = struct{}{}
+	}
+	var sortedIDs []string
+	for id := range ids {
+		sortedIDs = append(sortedIDs, id)
+	}
+	slices.Sort(sortedIDs)
+
+	diff := Diff{Packages: []PackageDiff{}}
+	for _, id := range sortedIDs {
+		p := PackageDiff{ID: id}
+		for _, key := range sortedKeys(before[id], after[id]) {
+			from, inBefore := before[id][key]
+			to, inAfter := after[id][key]
+			switch {
+			case !inBefore:
+				p.Added = append(p.Added, vex.Statement{Vulnerability: key.vulnerability, Product: key.product, Status: to})
+			case !inAfter:
+				p.Removed = append(p.Removed, vex.Statement{Vulnerability: key.vulnerability, Product: key.product, Status: from})
+			case from != to:
+				p.Changed = append(p.Changed, StatementChange{
+					Vulnerability: key.vulnerability,
+					Product:       key.product,
+					From:          from,
+					To:            to,
+				})
+			}
+		}
+		if len(p.Added)+len(p.Removed)+len(p.Changed) > 0 {
+			diff.Packages = append(diff.Packages, p)
+		}
+	}
+	return diff
+}
+
+func sortedKeys(states ...map[statementKey]string) []statementKey {
+	seen := make(map[statementKey]struct{})
+	var keys []statementKey
+	for _, state := range states {
+		for key := range state {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.SortFunc(keys, func(a, b statementKey) int {
+		return cmp.Or(cmp.Compare(a.vulnerability, b.vulnerability), cmp.Compare(a.product, b.product))
+	})
+	return keys
+}
+
+// worktreeState reads the statements from the working tree.
+func worktreeState(root, manifestName string) (hubState, error) {
+	state := make(hubState)
+	err := walkManifests(root, manifestName, func(rel string, m manifest.Manifest) error {
+		dir := filepath.Join(root, rel)
+		return state.add(m, func(src manifest.Source) (io.ReadCloser, error) {
+			return src.Open(dir)
+		})
+	})
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to read the working tree")
+	}
+	return state, nil
+}
+
+// commitState reads the statements from the git revision.
+func commitState(repo *git.Repository, rev, manifestName string) (hubState, error) {
+	errBuilder := oops.With("revision", rev)
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to resolve the revision")
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get the commit")
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get the tree")
+	}
+
+	state := make(hubState)
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if path.Base(f.Name) != manifestName {
+			return nil
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return oops.With("path", f.Name).Wrapf(err, "failed to read the file")
+		}
+		defer r.Close()
+		m, err := manifest.Decode(r)
+		if err != nil {
+			return oops.With("path", f.Name).Wrap(err)
+		}
+
+		dir := path.Dir(f.Name)
+		return state.add(m, func(src manifest.Source) (io.ReadCloser, error) {
+			f, err := tree.File(path.Join(dir, src.Path))
+			if err != nil {
+				return nil, err
+			}
+			r, err := f.Reader()
+			if err != nil {
+				return nil, err
+			}
+			return src.Decompress(r)
+		})
+	})
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to read the commit")
+	}
+	return state, nil
+}
+
+// add adds the statements of the VEX documents listed in the manifest.
+func (s hubState) add(m manifest.Manifest, open func(manifest.Source) (io.ReadCloser, error)) error {
+	if _, ok := s[m.ID]; !ok {
+		s[m.ID] = make(map[statementKey]string)
+	}
+	for _, src := range m.Sources {
+		statements, err := readStatements(src, open)
+		if err != nil {
+			return oops.With("id", m.ID).With("path", src.Path).Wrapf(err, "failed to read the statements")
+		}
+		for _, stmt := range statements {
+			s[m.ID][statementKey{vulnerability: stmt.Vulnerability, product: stmt.Product}] = stmt.Status
+		}
+	}
+	return nil
+}
+
+// readStatements parses the statements of the source.
+// The document is copied to a temporary file since the VEX parsers read files.
+func readStatements(src manifest.Source, open func(manifest.Source) (io.ReadCloser, error)) ([]vex.Statement, error) {
+	r, err := open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	tmpDir, err := os.MkdirTemp("", "vexhub-diff-")
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to create a temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	f, err := os.Create(filepath.Join(tmpDir, "vex.json"))
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to create a temporary file")
+	}
+	defer f.Close()
+	if _, err = io.Copy(f, r); err != nil {
+		return nil, oops.Wrapf(err, "failed to copy the document")
+	}
+	if err = f.Close(); err != nil {
+		return nil, oops.Wrapf(err, "failed to close the temporary file")
+	}
+	return vex.Statements(f.Name())
+}
@@ -0,0 +1,112 @@
+package vexhub
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// exportTime is the modification time of all entries in the archive so that the archive is reproducible.
+var exportTime = time.Unix(0, 0).UTC()
+
+// Export writes the "pkg" and "cpe" trees of the VEX Hub and its index into w as a gzip-compressed tarball.
+// The index is built from the manifests rather than read from the file so that it always matches the tree.
+// Entries are written in lexical order with fixed timestamps, ownership and permissions,
+// so the archive is byte-identical across runs as long as the content is unchanged.
+func Export(root string, w io.Writer, opts ...Option) error {
+	errBuilder := oops.Code("export_error").In("vexhub").With("root", root)
+
+	index, err := buildIndex(root, newOptions(opts))
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to build the index")
+	}
+	var buf bytes.Buffer
+	if err = writeIndex(&buf, index); err != nil {
+		return errBuilder.Wrap(err)
+	}
+
+	gw := gzip.NewWriter(w) // The header has neither a name nor a timestamp
+	tw := tar.NewWriter(gw)
+	if err = writeTarFile(tw, "index.json", buf.Bytes()); err != nil {
+		return errBuilder.Wrap(err)
+	}
+
+	// filepath.WalkDir walks in lexical order, and "cpe" sorts before "pkg"
+	for _, tree := range []string{"cpe", "pkg"} {
+		if err = exportTree(tw, root, tree); err != nil {
+			return errBuilder.Wrapf(err, "failed to archive the VEX Hub")
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return errBuilder.Wrapf(err, "tar close error")
+	}
+	if err = gw.Close(); err != nil {
+		return errBuilder.Wrapf(err, "gzip close error")
+	}
+	return nil
+}
+
+// exportTree writes the tree of the VEX Hub, such as "pkg", into the archive.
+// The "cpe" tree is optional since it exists only if packages are tracked by CPE.
+func exportTree(tw *tar.Writer, root, tree string) error {
+	dir := filepath.Join(root, tree)
+	if _, err := os.Stat(dir); tree == "cpe" && errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return oops.With("path", path).Wrapf(err, "file rel error")
+		}
+		name := filepath.ToSlash(rel)
+
+		switch {
+		case d.IsDir():
+			return oops.With("path", path).Wrap(tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     name + "/",
+				Mode:     0755,
+				ModTime:  exportTime,
+				Format:   tar.FormatPAX,
+			}))
+		case d.Type().IsRegular():
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return oops.With("path", path).Wrapf(err, "file read error")
+			}
+			return writeTarFile(tw, name, b)
+		default:
+			return nil // Symlinks and the like are never created by the crawler
+		}
+	})
+}
+
+func writeTarFile(tw *tar.Writer, name string, content []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  exportTime,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return oops.With("name", name).Wrapf(err, "tar header write error")
+	}
+	if _, err = tw.Write(content); err != nil {
+		return oops.With("name", name).Wrapf(err, "tar write error")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package vexhub
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"path/filepath"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+	"github.com/aquasecurity/vexhub-crawler/pkg/vexhub/vexhubpb"
+)
+
+// NewGRPCServer returns a gRPC server of the read-only VEXHub service, see vexhub.proto,
+// for programmatic consumers querying VEX Hub like "GET /vex" of NewHandler.
+// The server reads the hub on every request, so that it reflects the crawls without restarting.
+func NewGRPCServer(root string, opts ...Option) *grpc.Server {
+	s := grpc.NewServer()
+	vexhubpb.RegisterVEXHubServer(s, &grpcService{
+		root: root,
+		opts: newOptions(opts),
+	})
+	return s
+}
+
+type grpcService struct {
+	vexhubpb.UnimplementedVEXHubServer
+	root string
+	opts options
+}
+
+func (s *grpcService) GetVEX(_ context.Context, req *vexhubpb.GetVEXRequest) (*vexhubpb.GetVEXResponse, error) {
+	dirs, id, err := queryDirs(s.root, req.GetPurl(), req.GetCpe())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	manifests, err := readDirs(s.root, s.opts.manifestName, dirs)
+	if err != nil {
+		slog.Error("Failed to read the VEX documents", slog.Any("err", err))
+		return nil, status.Error(codes.Internal, "failed to read the VEX documents")
+	} else if len(manifests) == 0 {
+		return nil, status.Error(codes.NotFound, "no VEX documents found")
+	}
+
+	resp := &vexhubpb.GetVEXResponse{Id: id}
+	for _, vm := range manifests {
+		resp.Manifests = append(resp.Manifests, manifestProto(vm))
+	}
+	return resp, nil
+}
+
+func (s *grpcService) ListVEX(req *vexhubpb.ListVEXRequest, stream grpc.ServerStreamingServer[vexhubpb.Manifest]) error {
+	glob := req.GetGlob()
+	if _, err := path.Match(glob, ""); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid glob")
+	}
+
+	err := walkManifests(s.root, s.opts.manifestName, func(rel string, m manifest.Manifest) error {
+		if ok, _ := path.Match(glob, m.ID); !ok {
+			return nil
+		}
+		vm, err := readManifest(s.root, filepath.Join(s.root, rel), m)
+		if err != nil {
+			return err
+		}
+		return stream.Send(manifestProto(vm))
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err // Canceled by the client
+		}
+		slog.Error("Failed to list the VEX documents", slog.Any("err", err))
+		return status.Error(codes.Internal, "failed to list the VEX documents")
+	}
+	return nil
+}
+
+func manifestProto(vm VEXManifest) *vexhubpb.Manifest {
+	m := &vexhubpb.Manifest{
+		Dir:    vm.Dir,
+		Id:     vm.ID,
+		Commit: vm.Commit,
+		Parent: vm.Parent,
+	}
+	for _, doc := range vm.Documents {
+		m.Documents = append(m.Documents, &vexhubpb.Document{
+			Path:    doc.Path,
+			Url:     doc.URL,
+			Format:  doc.Format,
+			Content: doc.Document,
+		})
+	}
+	return m
+}
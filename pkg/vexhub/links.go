@@ -0,0 +1,97 @@
+package vexhub
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// DefaultLinkHosts are the hosts whose source URLs are checked unless LinkCheck.Hosts is set,
+// i.e. the hosts the crawler builds permalinks for.
+var DefaultLinkHosts = []string{"github.com", "gitlab.com"}
+
+// LinkCheck configures Verify to check that the source URLs of the manifests are still reachable, see WithLinkCheck.
+// Permalinks die when their commit is garbage-collected after a force-push.
+type LinkCheck struct {
+	Client   *http.Client  // nil means http.DefaultClient
+	Hosts    []string      // Only the URLs of the hosts are checked. Empty means DefaultLinkHosts
+	Interval time.Duration // Minimum interval between the requests, rate-limiting the checks
+}
+
+// WithLinkCheck makes Verify also check that the source URLs are reachable with HEAD requests.
+// It's slow and depends on the network, so it's opt-in and never done during crawls.
+func WithLinkCheck(check LinkCheck) Option {
+	return func(o *options) {
+		if check.Client == nil {
+			check.Client = http.DefaultClient
+		}
+		if len(check.Hosts) == 0 {
+			check.Hosts = DefaultLinkHosts
+		}
+		o.linkChecker = &linkChecker{LinkCheck: check, results: make(map[string]error)}
+	}
+}
+
+// linkChecker checks the URLs once each, since the same document may be shared by packages.
+type linkChecker struct {
+	LinkCheck
+	results map[string]error // By URL
+	last    time.Time        // Time of the last request
+}
+
+// check returns an error if the URL is unreachable. The URLs of other hosts are skipped.
+func (c *linkChecker) check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || !slices.Contains(c.Hosts, u.Host) || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil
+	}
+	if err, ok := c.results[rawURL]; ok {
+		return err
+	}
+	err = c.request(http.MethodHead, rawURL)
+	var statusErr *linkStatusError
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusMethodNotAllowed {
+		// Some servers don't support HEAD
+		err = c.request(http.MethodGet, rawURL)
+	}
+	c.results[rawURL] = err
+	return err
+}
+
+func (c *linkChecker) request(method, rawURL string) error {
+	if wait := time.Until(c.last.Add(c.Interval)); wait > 0 {
+		time.Sleep(wait)
+	}
+	c.last = time.Now()
+
+	req, err := http.NewRequest(method, rawURL, http.NoBody)
+	if err != nil {
+		return oops.Wrapf(err, "failed to create the request")
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return oops.Wrapf(err, "source URL unreachable")
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return &linkStatusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return nil
+}
+
+// linkStatusError is the error status of a source URL.
+type linkStatusError struct {
+	code   int
+	status string
+}
+
+func (e *linkStatusError) Error() string {
+	return fmt.Sprintf("broken source URL: %s", e.status)
+}
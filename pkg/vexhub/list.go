@@ -0,0 +1,98 @@
+package vexhub
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// Package is a package in VEX Hub, as recorded in its manifest.
+type Package struct {
+	ID      string    `json:"id"`
+	Dir     string    `json:"dir"` // Directory of the manifest relative to the root
+	Sources int       `json:"sources"`
+	Commit  string    `json:"commit,omitempty"`
+	Updated time.Time `json:"updated"` // Modification time of the manifest
+}
+
+// ListPackages lists the packages in VEX Hub based on the manifests, in the order of their directories.
+func ListPackages(root string, opts ...Option) ([]Package, error) {
+	manifestName := newOptions(opts).manifestName
+	pkgs := make([]Package, 0) // Encoded as an empty list rather than null
+	err := walkManifests(root, manifestName, func(rel string, m manifest.Manifest) error {
+		info, err := os.Stat(filepath.Join(root, rel, manifestName))
+		if err != nil {
+			return oops.With("dir", rel).Wrapf(err, "failed to stat the manifest")
+		}
+		pkgs = append(pkgs, Package{
+			ID:      m.ID,
+			Dir:     filepath.ToSlash(rel),
+			Sources: len(m.Sources),
+			Commit:  m.Commit,
+			Updated: info.ModTime().UTC(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, oops.Code("list_packages_error").In("vexhub").Wrap(err)
+	}
+	return pkgs, nil
+}
+
+// Orphans returns the packages that are no longer in the config, e.g. to remove them from VEX Hub.
+// The versions of the packages are ignored since tags are crawled for the packages in the config,
+// and wildcard packages of the config keep all the packages in their namespace.
+func Orphans(pkgs []Package, configured []config.Package) []Package {
+	keys := make(map[string]bool)
+	for _, p := range configured {
+		if p.Wildcard() {
+			keys[namespaceKey(p.PURL)] = true
+		} else {
+			keys[packageKey(p.ID())] = true
+		}
+	}
+
+	var orphans []Package
+	for _, p := range pkgs {
+		if keys[packageKey(p.ID)] {
+			continue
+		} else if purl, err := packageurl.FromString(p.ID); err == nil && keys[namespaceKey(purl)] {
+			continue
+		}
+		orphans = append(orphans, p)
+	}
+	return orphans
+}
+
+// packageKey normalizes the package ID without the version, so that manifest IDs can be compared with
+// the package IDs of the config. IDs that don't parse are compared as is.
+func packageKey(id string) string {
+	if strings.HasPrefix(id, "cpe:") {
+		cpe, err := vex.ParseCPE(id)
+		if err != nil {
+			return id
+		}
+		cpe.Version = ""
+		return cpe.String()
+	}
+	purl, err := packageurl.FromString(id)
+	if err != nil {
+		return id
+	}
+	purl.Version = ""
+	purl.Subpath = vex.CanonicalSubpath(purl.Subpath)
+	return purl.String()
+}
+
+// namespaceKey identifies the namespace of the PURL, as covered by a wildcard package.
+func namespaceKey(purl packageurl.PackageURL) string {
+	return purl.Type + "/" + purl.Namespace + "/" + config.Wildcard
+}
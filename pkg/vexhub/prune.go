@@ -0,0 +1,107 @@
+package vexhub
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+// Prune removes the directories of the orphaned packages, see Orphans, from VEX Hub along with their quarantined
+// files, and returns the packages removed. It's conservative so that a mistake never removes other packages:
+//   - packages whose directory isn't the one of their ID in the layout of VEX Hub are skipped
+//   - the directories of the other packages nested in the directory of an orphan, e.g. the subpaths of a Go module,
+//     are kept by only removing the other entries of the directory
+func Prune(root string, orphans []Package, opts ...Option) ([]Package, error) {
+	errBuilder := oops.Code("prune_error").In("vexhub")
+	pkgs, err := ListPackages(root, opts...)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	orphaned := make(map[string]bool)
+	for _, p := range orphans {
+		orphaned[p.Dir] = true
+	}
+	var kept []string
+	for _, p := range pkgs {
+		if !orphaned[p.Dir] {
+			kept = append(kept, p.Dir)
+		}
+	}
+
+	var removed []Package
+	for _, p := range orphans {
+		logger := slog.With(slog.String("id", p.ID), slog.String("dir", p.Dir))
+		if dir, ok := layoutDir(p.ID); !ok || filepath.ToSlash(dir) != p.Dir {
+			logger.Warn("Skipping the orphaned package not stored in the directory of its ID")
+			continue
+		}
+		for _, dir := range []string{p.Dir, filepath.ToSlash(filepath.Join(vex.QuarantineDir, p.Dir))} {
+			if err = removeDir(root, dir, kept); err != nil {
+				return removed, errBuilder.With("dir", dir).Wrapf(err, "failed to remove the orphaned package")
+			}
+		}
+		logger.Info("Removed the orphaned package")
+		removed = append(removed, p)
+	}
+	return removed, nil
+}
+
+// layoutDir returns the directory of the manifest ID relative to the root of VEX Hub.
+func layoutDir(id string) (string, bool) {
+	if strings.HasPrefix(id, "cpe:") {
+		cpe, err := vex.ParseCPE(id)
+		if err != nil {
+			return "", false
+		}
+		return vex.CPEDir("", cpe, cpe.Version), true
+	}
+	purl, err := packageurl.FromString(id)
+	if err != nil {
+		return "", false
+	}
+	return vex.PackageDir("", purl, purl.Version), true
+}
+
+// removeDir removes the directory relative to the root except the kept directories nested in it,
+// and then its parents left empty.
+func removeDir(root, dir string, kept []string) error {
+	path := filepath.Join(root, filepath.FromSlash(dir))
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return oops.Wrapf(err, "failed to read the directory")
+	}
+	for _, e := range entries {
+		if e.IsDir() && nests(dir+"/"+e.Name(), kept) {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(path, e.Name())); err != nil {
+			return oops.Wrapf(err, "failed to remove")
+		}
+	}
+
+	// os.Remove fails on the first directory that isn't empty, which ends the cleanup
+	for ; path != filepath.Clean(root) && strings.HasPrefix(path, filepath.Clean(root)); path = filepath.Dir(path) {
+		if os.Remove(path) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// nests reports whether the directory is one of the kept directories or contains one.
+func nests(dir string, kept []string) bool {
+	for _, k := range kept {
+		if k == dir || strings.HasPrefix(k, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package vexhub
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// VEXResponse is the response of "GET /vex", listing the documents stored for the queried package.
+type VEXResponse struct {
+	ID        string        `json:"id"` // The queried PURL or CPE
+	Manifests []VEXManifest `json:"manifests"`
+}
+
+// VEXManifest is a manifest matching the query along with its documents.
+type VEXManifest struct {
+	Dir       string        `json:"dir"` // Directory of the manifest relative to the root
+	ID        string        `json:"id"`
+	Commit    string        `json:"commit,omitempty"`
+	Parent    string        `json:"parent,omitempty"`
+	Documents []VEXDocument `json:"documents"`
+}
+
+// VEXDocument is a stored document, decompressed if needed.
+type VEXDocument struct {
+	Path     string          `json:"path"` // Path relative to the root, also served as a file
+	URL      string          `json:"url"`
+	Format   string          `json:"format,omitempty"`
+	Document json.RawMessage `json:"document"`
+}
+
+// NewHandler returns a read-only HTTP handler previewing VEX Hub, e.g. to debug a crawl locally.
+// The files under the root, such as the manifests and VEX documents, are served as they are,
+// and "GET /vex?purl=<purl>" (or "?cpe=<cpe>") returns the documents stored for the package as JSON.
+// A versioned query returns the documents crawled at the version as well as the unversioned ones.
+// The handler reads the hub on every request, so that it reflects the crawls without restarting.
+func NewHandler(root string, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/vex", func(w http.ResponseWriter, r *http.Request) {
+		serveVEX(w, r, root, o.manifestName)
+	})
+	mux.Handle("/", http.FileServer(hubFS{http.Dir(root)}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// hubFS hides the git metadata of VEX Hub.
+type hubFS struct {
+	http.FileSystem
+}
+
+func (f hubFS) Open(name string) (http.File, error) {
+	if slices.Contains(strings.Split(name, "/"), ".git") {
+		return nil, fs.ErrNotExist
+	}
+	return f.FileSystem.Open(name)
+}
+
+func serveVEX(w http.ResponseWriter, r *http.Request, root, manifestName string) {
+	query := r.URL.Query()
+	dirs, id, err := queryDirs(root, query.Get("purl"), query.Get("cpe"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	manifests, err := readDirs(root, manifestName, dirs)
+	if err != nil {
+		slog.Error("Failed to read the VEX documents", slog.Any("err", err))
+		writeError(w, http.StatusInternalServerError, "failed to read the VEX documents")
+		return
+	} else if len(manifests) == 0 {
+		writeError(w, http.StatusNotFound, "no VEX documents found")
+		return
+	}
+	writeResponse(w, http.StatusOK, VEXResponse{ID: id, Manifests: manifests})
+}
+
+// readDirs reads the manifests in the directories along with their documents.
+// The directories without a manifest are skipped.
+func readDirs(root, manifestName string, dirs []string) ([]VEXManifest, error) {
+	manifests := make([]VEXManifest, 0)
+	for _, dir := range dirs {
+		m, err := manifest.Read(filepath.Join(dir, manifestName))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, oops.With("dir", dir).Wrapf(err, "failed to read the manifest")
+		}
+		vm, err := readManifest(root, dir, m)
+		if err != nil {
+			return nil, oops.With("dir", dir).Wrap(err)
+		}
+		manifests = append(manifests, vm)
+	}
+	return manifests, nil
+}
+
+// queryDirs returns the directories of the package in the query, the versioned one first.
+func queryDirs(root, purl, cpe string) ([]string, string, error) {
+	switch {
+	case purl != "" && cpe != "":
+		return nil, "", oops.Errorf("either purl or cpe must be specified, not both")
+	case purl != "":
+		p, err := packageurl.FromString(purl)
+		if err != nil {
+			return nil, "", oops.Wrapf(err, "invalid purl")
+		}
+		dirs := []string{vex.PackageDir(root, p, "")}
+		if p.Version != "" {
+			dirs = slices.Insert(dirs, 0, vex.PackageDir(root, p, p.Version))
+		}
+		return dirs, p.String(), nil
+	case cpe != "":
+		c, err := vex.ParseCPE(cpe)
+		if err != nil {
+			return nil, "", oops.Wrapf(err, "invalid cpe")
+		}
+		dirs := []string{vex.CPEDir(root, c, "")}
+		if c.Version != "" {
+			dirs = slices.Insert(dirs, 0, vex.CPEDir(root, c, c.Version))
+		}
+		return dirs, c.String(), nil
+	}
+	return nil, "", oops.Errorf("purl or cpe is required")
+}
+
+func readManifest(root, dir string, m manifest.Manifest) (VEXManifest, error) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return VEXManifest{}, oops.Wrapf(err, "file rel error")
+	}
+	vm := VEXManifest{
+		Dir:       filepath.ToSlash(rel),
+		ID:        m.ID,
+		Commit:    m.Commit,
+		Parent:    m.Parent,
+		Documents: make([]VEXDocument, 0, len(m.Sources)),
+	}
+	for _, src := range m.Sources {
+		if src.Path == "" || filepath.Base(src.Path) != src.Path {
+			return VEXManifest{}, oops.With("path", src.Path).Errorf("invalid source path")
+		}
+		content, err := readSource(dir, src)
+		if err != nil {
+			return VEXManifest{}, err
+		}
+		vm.Documents = append(vm.Documents, VEXDocument{
+			Path:     vm.Dir + "/" + src.Path,
+			URL:      src.URL,
+			Format:   src.Format,
+			Document: content,
+		})
+	}
+	return vm, nil
+}
+
+func readSource(dir string, src manifest.Source) (json.RawMessage, error) {
+	r, err := src.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, oops.With("path", src.Path).Wrapf(err, "failed to read the file")
+	} else if !json.Valid(b) {
+		return nil, oops.With("path", src.Path).Errorf("invalid JSON")
+	}
+	return b, nil
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeResponse(w, status, map[string]string{"error": msg})
+}
+
+func writeResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("Failed to write the response", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,103 @@
+package vexhub
+
+import (
+	"path/filepath"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// Sources is the provenance of the VEX documents in VEX Hub.
+// Unlike the index, which is about packages, it focuses on where each document was crawled from.
+type Sources struct {
+	Sources []Source `json:"sources"`
+}
+
+type Source struct {
+	ID       string `json:"id"`       // Must be PURL at the moment
+	Location string `json:"location"` // File path to the VEX document
+	URL      string `json:"url"`
+	Commit   string `json:"commit,omitempty"`
+}
+
+// ListSources lists the sources of all VEX documents in VEX Hub based on the manifests.
+func ListSources(root string, opts ...Option) (Sources, error) {
+	var sources Sources
+	err := walkManifests(root, newOptions(opts).manifestName, func(rel string, m manifest.Manifest) error {
+		for _, src := range m.Sources {
+			sources.Sources = append(sources.Sources, Source{
+				ID:       m.ID,
+				Location: filepath.Join(rel, src.Path),
+				URL:      src.URL,
+				Commit:   m.Commit,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return Sources{}, oops.Code("list_sources_error").In("vexhub").Wrap(err)
+	}
+	return sources, nil
+}
+
+// CycloneDX converts the sources into a minimal CycloneDX BOM,
+// where each VEX document is a file component referencing its source.
+func (s Sources) CycloneDX() CycloneDX {
+	bom := CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+	}
+	for _, src := range s.Sources {
+		c := CycloneDXComponent{
+			Type: "file",
+			Name: src.Location,
+			ExternalReferences: []CycloneDXExternalReference{
+				{
+					Type: "vcs",
+					URL:  src.URL,
+				},
+			},
+			Properties: []CycloneDXProperty{
+				{
+					Name:  "vexhub:id",
+					Value: src.ID,
+				},
+			},
+		}
+		if src.Commit != "" {
+			c.Properties = append(c.Properties, CycloneDXProperty{
+				Name:  "vexhub:commit",
+				Value: src.Commit,
+			})
+		}
+		bom.Components = append(bom.Components, c)
+	}
+	return bom
+}
+
+// CycloneDX represents the subset of the CycloneDX BOM used for the sources listing.
+type CycloneDX struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Components  []CycloneDXComponent `json:"components"`
+}
+
+type CycloneDXComponent struct {
+	Type               string                       `json:"type"`
+	Name               string                       `json:"name"`
+	ExternalReferences []CycloneDXExternalReference `json:"externalReferences,omitempty"`
+	Properties         []CycloneDXProperty          `json:"properties,omitempty"`
+}
+
+type CycloneDXExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,139 @@
+package vexhub
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
+)
+
+// Problem is an integrity problem of a package in VEX Hub.
+type Problem struct {
+	Dir   string `json:"dir"`            // Directory of the manifest relative to the root
+	ID    string `json:"id,omitempty"`   // Empty if the manifest doesn't parse
+	Path  string `json:"path,omitempty"` // Path of the source or the latest document, if the problem is about a file
+	URL   string `json:"url,omitempty"`  // URL of the source, if it's broken
+	Error string `json:"error"`
+}
+
+// Verify checks the integrity of VEX Hub to catch drift or corruption introduced outside the crawler:
+// every manifest parses, and every source it references exists, matches its digest if recorded,
+// and still parses as a VEX document matching the package, as well as the latest document if recorded.
+// With WithManifestPublicKey, the signature of every manifest is also checked,
+// and with WithLinkCheck, the URL of every source is checked to be reachable.
+// All the problems are returned rather than the first one.
+func Verify(root string, opts ...Option) ([]Problem, error) {
+	o := newOptions(opts)
+	manifestName := o.manifestName
+	problems := make([]Problem, 0) // Encoded as an empty list rather than null
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return oops.With("path", path).Wrap(err)
+		} else if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		} else if d.IsDir() || d.Name() != manifestName {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return oops.With("path", path).Wrapf(err, "file rel error")
+		}
+		m, err := manifest.Read(path)
+		if err != nil {
+			problems = append(problems, Problem{Dir: rel, Error: err.Error()})
+			return nil
+		}
+		if o.publicKey != nil {
+			if err = verifySignature(path, o.publicKey); err != nil {
+				problems = append(problems, Problem{Dir: rel, ID: m.ID, Path: manifestName + manifest.SignatureSuffix, Error: err.Error()})
+			}
+		}
+		for _, src := range m.Sources {
+			if err = verifySource(dir, m.ID, src); err != nil {
+				problems = append(problems, Problem{Dir: rel, ID: m.ID, Path: src.Path, Error: err.Error()})
+			}
+			if o.linkChecker == nil || src.URL == "" {
+				continue
+			}
+			if err = o.linkChecker.check(src.URL); err != nil {
+				problems = append(problems, Problem{Dir: rel, ID: m.ID, Path: src.Path, URL: src.URL, Error: err.Error()})
+			}
+		}
+		if m.Latest != "" {
+			// The merged document is checked like a source, without a digest
+			latest := manifest.Source{Path: m.Latest, Format: string(vex.FormatOpenVEX)}
+			if err = verifySource(dir, m.ID, latest); err != nil {
+				problems = append(problems, Problem{Dir: rel, ID: m.ID, Path: m.Latest, Error: err.Error()})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, oops.Code("verify_error").In("vexhub").Wrap(err)
+	}
+	return problems, nil
+}
+
+// verifySignature checks the detached signature of the manifest.
+func verifySignature(manifestPath string, key ed25519.PublicKey) error {
+	sig, err := os.ReadFile(manifestPath + manifest.SignatureSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return oops.Errorf("manifest signature not found")
+	} else if err != nil {
+		return oops.Wrapf(err, "failed to read the manifest signature")
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return oops.Wrapf(err, "failed to read the manifest")
+	}
+	return manifest.VerifySignature(key, data, sig)
+}
+
+// verifySource checks the source stored in the directory of the package.
+func verifySource(dir, id string, src manifest.Source) error {
+	if src.Path == "" || filepath.Base(src.Path) != src.Path {
+		return oops.Errorf("invalid source path")
+	} else if _, err := os.Stat(filepath.Join(dir, src.Path)); errors.Is(err, os.ErrNotExist) {
+		return oops.Errorf("source file not found")
+	} else if err != nil {
+		return oops.Wrapf(err, "failed to stat the source file")
+	}
+
+	if src.Digest != "" {
+		// The stored file is checked as is, with the algorithm recorded in the manifest
+		f, err := os.Open(filepath.Join(dir, src.Path))
+		if err != nil {
+			return oops.Wrapf(err, "failed to open the source file")
+		}
+		err = manifest.VerifyDigest(src.Digest, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The documents are matched against the package regardless of the version they were crawled at
+	if strings.HasPrefix(id, "cpe:") {
+		cpe, err := vex.ParseCPE(id)
+		if err != nil {
+			return oops.Wrapf(err, "invalid manifest ID")
+		}
+		cpe.Version = ""
+		return vex.VerifySource(dir, cpe.String(), src)
+	}
+	purl, err := packageurl.FromString(id)
+	if err != nil {
+		return oops.Wrapf(err, "invalid manifest ID")
+	}
+	purl.Version = ""
+	return vex.VerifySource(dir, purl.String(), src)
+}
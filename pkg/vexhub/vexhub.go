@@ -1,64 +1,125 @@
 package vexhub
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 
 	"github.com/samber/oops"
 
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
 	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
 	"github.com/aquasecurity/vexhub-crawler/pkg/repo"
 )
 
+// Option configures how the VEX Hub is read.
+type Option func(*options)
+
+type options struct {
+	manifestName string
+	publicKey    ed25519.PublicKey
+	linkChecker  *linkChecker
+}
+
+// WithManifestName reads the manifests with the file name instead of manifest.FileName.
+// It must match the name the manifests were written with.
+func WithManifestName(name string) Option {
+	return func(o *options) {
+		if name != "" {
+			o.manifestName = name
+		}
+	}
+}
+
+// WithManifestPublicKey makes Verify check the signatures of the manifests with the key,
+// see manifest.WithSigningKey. Every manifest must be signed.
+func WithManifestPublicKey(key ed25519.PublicKey) Option {
+	return func(o *options) {
+		o.publicKey = key
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{manifestName: manifest.FileName}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 // GenerateIndex generates the index of the VEX Hub
-func GenerateIndex(root string) error {
+func GenerateIndex(root string, opts ...Option) error {
 	slog.Info("Generating the index of the VEX Hub")
 	errBuilder := oops.Code("file_walk_error").In("vexhub")
+	index, err := buildIndex(root, newOptions(opts))
+	if err != nil {
+		return errBuilder.Wrap(err)
+	}
+
+	f, err := os.Create(filepath.Join(root, "index.json"))
+	if err != nil {
+		return errBuilder.Wrapf(err, "file write error")
+	}
+	defer f.Close()
+	return errBuilder.Wrap(writeIndex(f, index))
+}
+
+// buildIndex builds the index from the manifests in the VEX Hub.
+func buildIndex(root string, o options) (repo.Index, error) {
 	index := repo.Index{
 		Version: 1,
 	}
-	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+	err := walkManifests(root, o.manifestName, func(rel string, m manifest.Manifest) error {
+		if len(m.Sources) == 0 {
+			return nil
+		}
+
+		// Take the first VEX document only
+		index.Packages = append(index.Packages, repo.Package{
+			ID:       m.ID,
+			Location: filepath.Join(rel, m.Sources[0].Path),
+			Parent:   m.Parent,
+		})
+		return nil
+	})
+	if err != nil {
+		return repo.Index{}, err
+	}
+	return index, nil
+}
+
+func writeIndex(w io.Writer, index repo.Index) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "   ")
+	return oops.Wrapf(e.Encode(index), "json encode error")
+}
+
+// walkManifests calls fn for each manifest named manifestName in the VEX Hub
+// with the directory of the manifest relative to the root.
+// The previous versions archived in vex.HistoryDir are not packages, so they're skipped.
+func walkManifests(root, manifestName string, fn func(rel string, m manifest.Manifest) error) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		errBuilder := oops.With("path", path)
 		if err != nil {
 			return errBuilder.Wrap(err)
-		} else if d.IsDir() || filepath.Base(path) != manifest.FileName {
+		} else if d.IsDir() && (d.Name() == ".git" || d.Name() == vex.HistoryDir) {
+			return filepath.SkipDir
+		} else if d.IsDir() || filepath.Base(path) != manifestName {
 			return nil
 		}
 
-		dir := filepath.Dir(path)
 		m, err := manifest.Read(path)
 		if err != nil {
 			return errBuilder.Wrapf(err, "manifest read error")
-		} else if len(m.Sources) == 0 {
-			return nil
 		}
 
-		rel, err := filepath.Rel(root, dir)
+		rel, err := filepath.Rel(root, filepath.Dir(path))
 		if err != nil {
 			return errBuilder.Wrapf(err, "file rel error")
 		}
-
-		// Take the first VEX document only
-		index.Packages = append(index.Packages, repo.Package{
-			ID:       m.ID,
-			Location: filepath.Join(rel, m.Sources[0].Path),
-		})
-
-		return nil
+		return fn(rel, m)
 	})
-	if err != nil {
-		return errBuilder.Wrap(err)
-	}
-
-	f, err := os.Create(filepath.Join(root, "index.json"))
-	if err != nil {
-		return errBuilder.Wrapf(err, "file write error")
-	}
-	defer f.Close()
-
-	e := json.NewEncoder(f)
-	e.SetIndent("", "   ")
-	return errBuilder.Wrapf(e.Encode(index), "json encode error")
 }
@@ -1,15 +1,41 @@
 package vexhub_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/package-url/packageurl-go"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
 	"github.com/aquasecurity/vexhub-crawler/pkg/manifest"
 	"github.com/aquasecurity/vexhub-crawler/pkg/vexhub"
+	"github.com/aquasecurity/vexhub-crawler/pkg/vexhub/vexhubpb"
 )
 
 func TestGenerateIndex(t *testing.T) {
@@ -48,6 +74,31 @@ func TestGenerateIndex(t *testing.T) {
 				]
 			}`,
 		},
+		{
+			name: "nested manifest",
+			setup: func(root string) error {
+				dir := filepath.Join(root, "pkg", "golang", "github.com", "example", "foo", "bar")
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return err
+				}
+				return manifest.Write(filepath.Join(dir, manifest.FileName), manifest.Manifest{
+					ID:      "pkg:golang/github.com/example/foo/bar",
+					Sources: []manifest.Source{{Path: "bar.openvex.json"}},
+					Parent:  "pkg:golang/github.com/example/foo",
+				})
+			},
+			wantErr: require.NoError,
+			wantIndex: `{
+				"version": 1,
+				"packages": [
+					{
+						"id": "pkg:golang/github.com/example/foo/bar",
+						"location": "pkg/golang/github.com/example/foo/bar/bar.openvex.json",
+						"parent": "pkg:golang/github.com/example/foo"
+					}
+				]
+			}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -67,3 +118,600 @@ func TestGenerateIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestListSources(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "pkg", "npm", "foo")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, manifest.Write(filepath.Join(dir, manifest.FileName), manifest.Manifest{
+		ID:     "pkg:npm/foo",
+		Commit: "ed76fc6c0e8e56318ce3148bd7bd938aad41491c",
+		Sources: []manifest.Source{
+			{
+				Path: "foo.openvex.json",
+				URL:  "https://github.com/org/foo/blob/ed76fc6c0e8e56318ce3148bd7bd938aad41491c/.vex/foo.openvex.json",
+			},
+		},
+	}))
+
+	got, err := vexhub.ListSources(root)
+	require.NoError(t, err)
+
+	want := vexhub.Sources{
+		Sources: []vexhub.Source{
+			{
+				ID:       "pkg:npm/foo",
+				Location: "pkg/npm/foo/foo.openvex.json",
+				URL:      "https://github.com/org/foo/blob/ed76fc6c0e8e56318ce3148bd7bd938aad41491c/.vex/foo.openvex.json",
+				Commit:   "ed76fc6c0e8e56318ce3148bd7bd938aad41491c",
+			},
+		},
+	}
+	require.Equal(t, want, got)
+
+	bom := got.CycloneDX()
+	require.Len(t, bom.Components, 1)
+	require.Equal(t, "pkg/npm/foo/foo.openvex.json", bom.Components[0].Name)
+	require.Equal(t, "vcs", bom.Components[0].ExternalReferences[0].Type)
+}
+
+func TestExport(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"foo", "bar"} {
+		dir := filepath.Join(root, "pkg", "npm", name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".openvex.json"), []byte("{}"), 0600))
+		require.NoError(t, manifest.Write(filepath.Join(dir, manifest.FileName), manifest.Manifest{
+			ID:      "pkg:npm/" + name,
+			Sources: []manifest.Source{{Path: name + ".openvex.json"}},
+		}))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("not exported"), 0644))
+
+	var first bytes.Buffer
+	require.NoError(t, vexhub.Export(root, &first))
+
+	gr, err := gzip.NewReader(bytes.NewReader(first.Bytes()))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	var names []string
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		require.Equal(t, int64(0), hdr.ModTime.Unix())
+		b, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(b)
+	}
+	require.Equal(t, []string{
+		"index.json",
+		"pkg/",
+		"pkg/npm/",
+		"pkg/npm/bar/",
+		"pkg/npm/bar/bar.openvex.json",
+		"pkg/npm/bar/manifest.json",
+		"pkg/npm/foo/",
+		"pkg/npm/foo/foo.openvex.json",
+		"pkg/npm/foo/manifest.json",
+	}, names)
+	require.JSONEq(t, `{
+		"version": 1,
+		"packages": [
+			{ "id": "pkg:npm/bar", "location": "pkg/npm/bar/bar.openvex.json" },
+			{ "id": "pkg:npm/foo", "location": "pkg/npm/foo/foo.openvex.json" }
+		]
+	}`, files["index.json"])
+
+	// Touching the files doesn't change the archive
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "pkg", "npm", "foo", "foo.openvex.json"), later, later))
+	var second bytes.Buffer
+	require.NoError(t, vexhub.Export(root, &second))
+	require.Equal(t, first.Bytes(), second.Bytes())
+}
+
+// writePackage writes an OpenVEX document with the statements of the vulnerabilities and their statuses.
+func writePackage(t *testing.T, root, name string, statuses map[string]string) {
+	dir := filepath.Join(root, "pkg", "npm", name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	doc := map[string]any{
+		"@context":  "https://openvex.dev/ns/v0.2.0",
+		"@id":       "https://example.com/vex-" + name,
+		"author":    "Example Corp.",
+		"timestamp": "2024-01-01T00:00:00Z",
+		"version":   1,
+	}
+	var statements []map[string]any
+	for vuln, status := range statuses {
+		statements = append(statements, map[string]any{
+			"vulnerability": map[string]string{"name": vuln},
+			"products":      []map[string]string{{"@id": "pkg:npm/" + name}},
+			"status":        status,
+			"justification": "vulnerable_code_not_present",
+		})
+	}
+	doc["statements"] = statements
+	b, err := json.Marshal(doc)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "openvex.json"), b, 0644))
+	require.NoError(t, manifest.Write(filepath.Join(dir, manifest.FileName), manifest.Manifest{
+		ID:      "pkg:npm/" + name,
+		Sources: []manifest.Source{{Path: "openvex.json"}},
+	}))
+}
+
+func commitAll(t *testing.T, root string) string {
+	r, err := git.PlainOpen(root)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	hash, err := wt.Commit("update", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return hash.String()
+}
+
+func TestDiffStates(t *testing.T) {
+	root := t.TempDir()
+	_, err := git.PlainInit(root, false)
+	require.NoError(t, err)
+
+	writePackage(t, root, "foo", map[string]string{
+		"CVE-2024-0001": "not_affected",
+		"CVE-2024-0002": "under_investigation",
+	})
+	writePackage(t, root, "bar", map[string]string{
+		"CVE-2024-0003": "not_affected",
+	})
+	first := commitAll(t, root)
+
+	writePackage(t, root, "foo", map[string]string{
+		"CVE-2024-0002": "not_affected",
+		"CVE-2024-0004": "not_affected",
+	})
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "pkg", "npm", "bar")))
+
+	want := vexhub.Diff{
+		Packages: []vexhub.PackageDiff{
+			{
+				ID: "pkg:npm/bar",
+				Removed: []vex.Statement{
+					{Vulnerability: "CVE-2024-0003", Product: "pkg:npm/bar", Status: "not_affected"},
+				},
+			},
+			{
+				ID: "pkg:npm/foo",
+				Added: []vex.Statement{
+					{Vulnerability: "CVE-2024-0004", Product: "pkg:npm/foo", Status: "not_affected"},
+				},
+				Removed: []vex.Statement{
+					{Vulnerability: "CVE-2024-0001", Product: "pkg:npm/foo", Status: "not_affected"},
+				},
+				Changed: []vexhub.StatementChange{
+					{
+						Vulnerability: "CVE-2024-0002",
+						Product:       "pkg:npm/foo",
+						From:          "under_investigation",
+						To:            "not_affected",
+					},
+				},
+			},
+		},
+	}
+
+	// The working tree against HEAD
+	got, err := vexhub.DiffStates(root, "", "")
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// Two commits
+	second := commitAll(t, root)
+	got, err = vexhub.DiffStates(root, first, second)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// No change
+	got, err = vexhub.DiffStates(root, "", "")
+	require.NoError(t, err)
+	require.Empty(t, got.Packages)
+}
+
+func TestVerify(t *testing.T) {
+	root := t.TempDir()
+	writePackage(t, root, "ok", map[string]string{"CVE-2024-0001": "not_affected"})
+
+	// Missing source
+	writePackage(t, root, "missing", map[string]string{"CVE-2024-0001": "not_affected"})
+	require.NoError(t, os.Remove(filepath.Join(root, "pkg", "npm", "missing", "openvex.json")))
+
+	// Corrupt source
+	writePackage(t, root, "corrupt", map[string]string{"CVE-2024-0001": "not_affected"})
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pkg", "npm", "corrupt", "openvex.json"), []byte(`{"statements": [`), 0644))
+
+	// Corrupt manifest
+	writePackage(t, root, "broken", map[string]string{"CVE-2024-0001": "not_affected"})
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pkg", "npm", "broken", manifest.FileName), []byte(`{`), 0644))
+
+	// Sources with digests are checked with the recorded algorithm
+	for name, alg := range map[string]string{"digest": manifest.DigestSHA512, "tampered": manifest.DigestBLAKE2b256} {
+		writePackage(t, root, name, map[string]string{"CVE-2024-0001": "not_affected"})
+		dir := filepath.Join(root, "pkg", "npm", name)
+		content, err := os.ReadFile(filepath.Join(dir, "openvex.json"))
+		require.NoError(t, err)
+		digest, err := manifest.NewDigest(alg, bytes.NewReader(content))
+		require.NoError(t, err)
+		require.NoError(t, manifest.Write(filepath.Join(dir, manifest.FileName), manifest.Manifest{
+			ID:      "pkg:npm/" + name,
+			Sources: []manifest.Source{{Path: "openvex.json", Digest: digest}},
+		}))
+	}
+	// Still parses, but differs from the digest
+	tampered := filepath.Join(root, "pkg", "npm", "tampered", "openvex.json")
+	content, err := os.ReadFile(tampered)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(tampered, append(content, '\n'), 0644))
+
+	problems, err := vexhub.Verify(root)
+	require.NoError(t, err)
+
+	got := make(map[string]string)
+	for _, p := range problems {
+		got[p.Dir] = p.Error
+	}
+	require.Len(t, got, 4)
+	require.Contains(t, got[filepath.Join("pkg", "npm", "tampered")], "digest mismatch")
+	require.Contains(t, got[filepath.Join("pkg", "npm", "missing")], "source file not found")
+	require.Contains(t, got[filepath.Join("pkg", "npm", "corrupt")], "corrupt VEX file")
+	require.Contains(t, got[filepath.Join("pkg", "npm", "broken")], "decode")
+}
+
+func TestVerify_Signatures(t *testing.T) {
+	root := t.TempDir()
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	for _, name := range []string{"signed", "unsigned", "tampered"} {
+		writePackage(t, root, name, map[string]string{"CVE-2024-0001": "not_affected"})
+		if name == "unsigned" {
+			continue
+		}
+		require.NoError(t, manifest.Write(filepath.Join(root, "pkg", "npm", name, manifest.FileName), manifest.Manifest{
+			ID:      "pkg:npm/" + name,
+			Sources: []manifest.Source{{Path: "openvex.json"}},
+		}, manifest.WithSigningKey(key)))
+	}
+	tampered := filepath.Join(root, "pkg", "npm", "tampered", manifest.FileName)
+	require.NoError(t, manifest.Write(tampered, manifest.Manifest{
+		ID:      "pkg:npm/tampered",
+		Sources: []manifest.Source{{Path: "openvex.json", URL: "https://example.com/elsewhere"}},
+	}))
+
+	// Signatures are only checked with a public key
+	problems, err := vexhub.Verify(root)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	problems, err = vexhub.Verify(root, vexhub.WithManifestPublicKey(pub))
+	require.NoError(t, err)
+	got := make(map[string]string)
+	for _, p := range problems {
+		require.Equal(t, manifest.FileName+manifest.SignatureSuffix, p.Path)
+		got[p.Dir] = p.Error
+	}
+	require.Len(t, got, 2)
+	require.Contains(t, got[filepath.Join("pkg", "npm", "unsigned")], "manifest signature not found")
+	require.Contains(t, got[filepath.Join("pkg", "npm", "tampered")], "signature mismatch")
+}
+
+func TestVerify_Links(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int) // By method and path
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/dead":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/get-only" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	root := t.TempDir()
+	links := map[string]string{
+		"ok":       server.URL + "/ok",
+		"dead":     server.URL + "/dead",
+		"shared":   server.URL + "/dead", // Checked once
+		"get-only": server.URL + "/get-only",
+		"other":    "https://other.example.com/dead",
+	}
+	for name, link := range links {
+		writePackage(t, root, name, map[string]string{"CVE-2024-0001": "not_affected"})
+		require.NoError(t, manifest.Write(filepath.Join(root, "pkg", "npm", name, manifest.FileName), manifest.Manifest{
+			ID:      "pkg:npm/" + name,
+			Sources: []manifest.Source{{Path: "openvex.json", URL: link}},
+		}))
+	}
+
+	// Links are only checked on demand
+	problems, err := vexhub.Verify(root)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+	require.Empty(t, requests)
+
+	problems, err = vexhub.Verify(root, vexhub.WithLinkCheck(vexhub.LinkCheck{
+		Hosts:    []string{u.Host},
+		Interval: time.Millisecond,
+	}))
+	require.NoError(t, err)
+	got := make(map[string]string)
+	for _, p := range problems {
+		require.Equal(t, "openvex.json", p.Path)
+		require.Equal(t, server.URL+"/dead", p.URL)
+		got[p.Dir] = p.Error
+	}
+	require.Len(t, got, 2)
+	require.Contains(t, got[filepath.Join("pkg", "npm", "dead")], "404 Not Found")
+	require.Contains(t, got[filepath.Join("pkg", "npm", "shared")], "404 Not Found")
+	require.Equal(t, map[string]int{
+		"HEAD /ok":       1,
+		"HEAD /dead":     1,
+		"HEAD /get-only": 1,
+		"GET /get-only":  1,
+	}, requests)
+}
+
+func TestNewHandler(t *testing.T) {
+	root := t.TempDir()
+	writePackage(t, root, "foo", map[string]string{"CVE-2024-0001": "not_affected"})
+	versioned := filepath.Join(root, "pkg", "npm", "foo", "v1.0.0")
+	require.NoError(t, os.MkdirAll(versioned, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(versioned, "v1.openvex.json"), []byte(`{"statements": []}`), 0644))
+	require.NoError(t, manifest.Write(filepath.Join(versioned, manifest.FileName), manifest.Manifest{
+		ID:      "pkg:npm/foo@v1.0.0",
+		Sources: []manifest.Source{{Path: "v1.openvex.json"}},
+	}))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".git", "config"), []byte("[core]"), 0644))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantDirs   []string
+	}{
+		{
+			name:       "file",
+			target:     "/pkg/npm/foo/" + manifest.FileName,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "purl",
+			target:     "/vex?purl=pkg:npm/foo",
+			wantStatus: http.StatusOK,
+			wantDirs:   []string{"pkg/npm/foo"},
+		},
+		{
+			name:       "purl with version",
+			target:     "/vex?purl=pkg:npm/foo@v1.0.0",
+			wantStatus: http.StatusOK,
+			wantDirs:   []string{"pkg/npm/foo/v1.0.0", "pkg/npm/foo"},
+		},
+		{
+			name:       "unknown package",
+			target:     "/vex?purl=pkg:npm/bar",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid purl",
+			target:     "/vex?purl=foo",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "git metadata",
+			target:     "/.git/config",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "read-only",
+			method:     http.MethodPost,
+			target:     "/vex?purl=pkg:npm/foo",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	handler := vexhub.NewHandler(root)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(cmp.Or(tt.method, http.MethodGet), tt.target, http.NoBody))
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantDirs == nil {
+				return
+			}
+
+			var resp vexhub.VEXResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			var dirs []string
+			for _, m := range resp.Manifests {
+				dirs = append(dirs, m.Dir)
+				require.Len(t, m.Documents, 1)
+				require.True(t, json.Valid(m.Documents[0].Document))
+			}
+			require.Equal(t, tt.wantDirs, dirs)
+		})
+	}
+}
+
+func TestNewGRPCServer(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"foo", "bar"} {
+		writePackage(t, root, name, map[string]string{"CVE-2024-0001": "not_affected"})
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := vexhub.NewGRPCServer(root)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	client := vexhubpb.NewVEXHubClient(conn)
+	ctx := context.Background()
+
+	t.Run("get", func(t *testing.T) {
+		resp, err := client.GetVEX(ctx, &vexhubpb.GetVEXRequest{Id: &vexhubpb.GetVEXRequest_Purl{Purl: "pkg:npm/foo"}})
+		require.NoError(t, err)
+		require.Len(t, resp.Manifests, 1)
+		m := resp.Manifests[0]
+		require.Equal(t, "pkg/npm/foo", m.Dir)
+		require.Len(t, m.Documents, 1)
+		require.Equal(t, "pkg/npm/foo/openvex.json", m.Documents[0].Path)
+		require.True(t, json.Valid(m.Documents[0].Content))
+	})
+
+	t.Run("get errors", func(t *testing.T) {
+		_, err := client.GetVEX(ctx, &vexhubpb.GetVEXRequest{Id: &vexhubpb.GetVEXRequest_Purl{Purl: "pkg:npm/baz"}})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		_, err = client.GetVEX(ctx, &vexhubpb.GetVEXRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		stream, err := client.ListVEX(ctx, &vexhubpb.ListVEXRequest{Glob: "pkg:npm/*"})
+		require.NoError(t, err)
+		var ids []string
+		for {
+			m, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			ids = append(ids, m.Id)
+		}
+		require.ElementsMatch(t, []string{"pkg:npm/foo", "pkg:npm/bar"}, ids)
+	})
+}
+
+func TestListPackages(t *testing.T) {
+	root := t.TempDir()
+	writePackage(t, root, "foo", map[string]string{"CVE-2024-0001": "fixed"})
+	writePackage(t, root, "bar", map[string]string{"CVE-2024-0002": "fixed"})
+	tagDir := filepath.Join(root, "pkg", "npm", "foo", "v1.0.0")
+	require.NoError(t, os.MkdirAll(tagDir, 0755))
+	require.NoError(t, manifest.Write(filepath.Join(tagDir, manifest.FileName), manifest.Manifest{
+		ID: "pkg:npm/foo@v1.0.0",
+	}))
+
+	pkgs, err := vexhub.ListPackages(root)
+	require.NoError(t, err)
+	var ids, dirs []string
+	for _, p := range pkgs {
+		ids, dirs = append(ids, p.ID), append(dirs, p.Dir)
+		require.False(t, p.Updated.IsZero())
+	}
+	require.Equal(t, []string{"pkg:npm/bar", "pkg:npm/foo", "pkg:npm/foo@v1.0.0"}, ids)
+	require.Equal(t, []string{"pkg/npm/bar", "pkg/npm/foo", "pkg/npm/foo/v1.0.0"}, dirs)
+	require.Equal(t, 1, pkgs[0].Sources)
+
+	tests := []struct {
+		name       string
+		configured []string
+		want       []string
+	}{
+		{
+			name:       "orphaned package",
+			configured: []string{"pkg:npm/foo"},
+			want:       []string{"pkg:npm/bar"},
+		},
+		{
+			name:       "orphaned tags",
+			configured: []string{"pkg:npm/bar"},
+			want:       []string{"pkg:npm/foo", "pkg:npm/foo@v1.0.0"},
+		},
+		{
+			name:       "wildcard",
+			configured: []string{"pkg:npm/*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var configured []config.Package
+			for _, id := range tt.configured {
+				purl, err := packageurl.FromString(id)
+				require.NoError(t, err)
+				configured = append(configured, config.Package{PURL: purl})
+			}
+			var got []string
+			for _, p := range vexhub.Orphans(pkgs, configured) {
+				got = append(got, p.ID)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := t.TempDir()
+	writePackage(t, root, "foo", map[string]string{"CVE-2024-0001": "fixed"})
+	writePackage(t, root, "bar", map[string]string{"CVE-2024-0002": "fixed"})
+	writePackage(t, root, "baz", map[string]string{"CVE-2024-0003": "fixed"})
+	for dir, id := range map[string]string{
+		filepath.Join("pkg", "npm", "foo", "sub"): "pkg:npm/foo#sub",   // Nested in an orphan but configured
+		filepath.Join("pkg", "npm", "misplaced"):  "pkg:npm/elsewhere", // Not in the directory of its ID
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+		require.NoError(t, manifest.Write(filepath.Join(root, dir, manifest.FileName), manifest.Manifest{ID: id}))
+	}
+	quarantineDir := filepath.Join(root, "quarantine", "pkg", "npm", "bar")
+	require.NoError(t, os.MkdirAll(quarantineDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(quarantineDir, "openvex.json.rejected"), []byte("{}"), 0644))
+
+	var configured []config.Package
+	for _, id := range []string{"pkg:npm/baz", "pkg:npm/foo#sub"} {
+		purl, err := packageurl.FromString(id)
+		require.NoError(t, err)
+		configured = append(configured, config.Package{PURL: purl})
+	}
+	pkgs, err := vexhub.ListPackages(root)
+	require.NoError(t, err)
+	orphans := vexhub.Orphans(pkgs, configured)
+	require.Len(t, orphans, 3)
+
+	removed, err := vexhub.Prune(root, orphans)
+	require.NoError(t, err)
+	var ids []string
+	for _, p := range removed {
+		ids = append(ids, p.ID)
+	}
+	require.Equal(t, []string{"pkg:npm/bar", "pkg:npm/foo"}, ids)
+
+	pkgs, err = vexhub.ListPackages(root)
+	require.NoError(t, err)
+	ids = nil
+	for _, p := range pkgs {
+		ids = append(ids, p.ID)
+	}
+	require.Equal(t, []string{"pkg:npm/baz", "pkg:npm/foo#sub", "pkg:npm/elsewhere"}, ids)
+	require.NoFileExists(t, filepath.Join(root, "pkg", "npm", "foo", "openvex.json"))
+	require.NoDirExists(t, filepath.Join(root, "pkg", "npm", "bar"))
+	require.NoDirExists(t, filepath.Join(root, "quarantine"))
+}
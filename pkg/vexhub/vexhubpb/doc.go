@@ -0,0 +1,4 @@
+// Package vexhubpb is the gRPC service serving VEX Hub, generated from vexhub.proto.
+package vexhubpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative vexhub.proto
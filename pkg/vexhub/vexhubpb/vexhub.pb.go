@@ -0,0 +1,455 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.1
+// 	protoc        (unknown)
+// source: vexhub.proto
+
+package vexhubpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetVEXRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Id:
+	//	*GetVEXRequest_Purl
+	//	*GetVEXRequest_Cpe
+	Id isGetVEXRequest_Id `protobuf_oneof:"id"`
+}
+
+func (x *GetVEXRequest) Reset() {
+	*x = GetVEXRequest{}
+	mi := &file_vexhub_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVEXRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVEXRequest) ProtoMessage() {}
+
+func (x *GetVEXRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vexhub_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVEXRequest.ProtoReflect.Descriptor instead.
+func (*GetVEXRequest) Descriptor() ([]byte, []int) {
+	return file_vexhub_proto_rawDescGZIP(), []int{0}
+}
+
+func (m *GetVEXRequest) GetId() isGetVEXRequest_Id {
+	if m != nil {
+		return m.Id
+	}
+	return nil
+}
+
+func (x *GetVEXRequest) GetPurl() string {
+	if x, ok := x.GetId().(*GetVEXRequest_Purl); ok {
+		return x.Purl
+	}
+	return ""
+}
+
+func (x *GetVEXRequest) GetCpe() string {
+	if x, ok := x.GetId().(*GetVEXRequest_Cpe); ok {
+		return x.Cpe
+	}
+	return ""
+}
+
+type isGetVEXRequest_Id interface {
+	isGetVEXRequest_Id()
+}
+
+type GetVEXRequest_Purl struct {
+	Purl string `protobuf:"bytes,1,opt,name=purl,proto3,oneof"`
+}
+
+type GetVEXRequest_Cpe struct {
+	Cpe string `protobuf:"bytes,2,opt,name=cpe,proto3,oneof"`
+}
+
+func (*GetVEXRequest_Purl) isGetVEXRequest_Id() {}
+
+func (*GetVEXRequest_Cpe) isGetVEXRequest_Id() {}
+
+type GetVEXResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id        string      `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // The queried PURL or CPE
+	Manifests []*Manifest `protobuf:"bytes,2,rep,name=manifests,proto3" json:"manifests,omitempty"`
+}
+
+func (x *GetVEXResponse) Reset() {
+	*x = GetVEXResponse{}
+	mi := &file_vexhub_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVEXResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVEXResponse) ProtoMessage() {}
+
+func (x *GetVEXResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vexhub_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVEXResponse.ProtoReflect.Descriptor instead.
+func (*GetVEXResponse) Descriptor() ([]byte, []int) {
+	return file_vexhub_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetVEXResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetVEXResponse) GetManifests() []*Manifest {
+	if x != nil {
+		return x.Manifests
+	}
+	return nil
+}
+
+type ListVEXRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Glob string `protobuf:"bytes,1,opt,name=glob,proto3" json:"glob,omitempty"`
+}
+
+func (x *ListVEXRequest) Reset() {
+	*x = ListVEXRequest{}
+	mi := &file_vexhub_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVEXRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVEXRequest) ProtoMessage() {}
+
+func (x *ListVEXRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vexhub_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVEXRequest.ProtoReflect.Descriptor instead.
+func (*ListVEXRequest) Descriptor() ([]byte, []int) {
+	return file_vexhub_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListVEXRequest) GetGlob() string {
+	if x != nil {
+		return x.Glob
+	}
+	return ""
+}
+
+// Manifest is a manifest of VEX Hub along with its documents.
+type Manifest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Dir       string      `protobuf:"bytes,1,opt,name=dir,proto3" json:"dir,omitempty"` // Directory of the manifest relative to the root
+	Id        string      `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Commit    string      `protobuf:"bytes,3,opt,name=commit,proto3" json:"commit,omitempty"` // Git commit the documents were crawled from, if known
+	Parent    string      `protobuf:"bytes,4,opt,name=parent,proto3" json:"parent,omitempty"` // PURL of the package owning this one, if any
+	Documents []*Document `protobuf:"bytes,5,rep,name=documents,proto3" json:"documents,omitempty"`
+}
+
+func (x *Manifest) Reset() {
+	*x = Manifest{}
+	mi := &file_vexhub_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Manifest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Manifest) ProtoMessage() {}
+
+func (x *Manifest) ProtoReflect() protoreflect.Message {
+	mi := &file_vexhub_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Manifest.ProtoReflect.Descriptor instead.
+func (*Manifest) Descriptor() ([]byte, []int) {
+	return file_vexhub_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Manifest) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+func (x *Manifest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Manifest) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *Manifest) GetParent() string {
+	if x != nil {
+		return x.Parent
+	}
+	return ""
+}
+
+func (x *Manifest) GetDocuments() []*Document {
+	if x != nil {
+		return x.Documents
+	}
+	return nil
+}
+
+// Document is a stored VEX document, decompressed if needed, along with its source.
+type Document struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path    string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`       // Path relative to the root
+	Url     string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`         // Source the document was crawled from
+	Format  string `protobuf:"bytes,3,opt,name=format,proto3" json:"format,omitempty"`   // e.g. "openvex", "cyclonedx"
+	Content []byte `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"` // JSON document
+}
+
+func (x *Document) Reset() {
+	*x = Document{}
+	mi := &file_vexhub_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Document) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Document) ProtoMessage() {}
+
+func (x *Document) ProtoReflect() protoreflect.Message {
+	mi := &file_vexhub_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Document.ProtoReflect.Descriptor instead.
+func (*Document) Descriptor() ([]byte, []int) {
+	return file_vexhub_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Document) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Document) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Document) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *Document) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+var File_vexhub_proto protoreflect.FileDescriptor
+
+var file_vexhub_proto_rawDesc = []byte{
+	0x0a, 0x0c, 0x76, 0x65, 0x78, 0x68, 0x75, 0x62, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09,
+	0x76, 0x65, 0x78, 0x68, 0x75, 0x62, 0x2e, 0x76, 0x31, 0x22, 0x3f, 0x0a, 0x0d, 0x47, 0x65, 0x74,
+	0x56, 0x45, 0x58, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x04, 0x70, 0x75,
+	0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x04, 0x70, 0x75, 0x72, 0x6c,
+	0x12, 0x12, 0x0a, 0x03, 0x63, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52,
+	0x03, 0x63, 0x70, 0x65, 0x42, 0x04, 0x0a, 0x02, 0x69, 0x64, 0x22, 0x53, 0x0a, 0x0e, 0x47, 0x65,
+	0x74, 0x56, 0x45, 0x58, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x31, 0x0a, 0x09,
+	0x6d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x13, 0x2e, 0x76, 0x65, 0x78, 0x68, 0x75, 0x62, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x61, 0x6e, 0x69,
+	0x66, 0x65, 0x73, 0x74, 0x52, 0x09, 0x6d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x73, 0x22,
+	0x24, 0x0a, 0x0e, 0x4c, 0x69, 0x73, 0x74, 0x56, 0x45, 0x58, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x67, 0x6c, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x67, 0x6c, 0x6f, 0x62, 0x22, 0x8f, 0x01, 0x0a, 0x08, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65,
+	0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x69, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x64, 0x69, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x16, 0x0a, 0x06,
+	0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x61,
+	0x72, 0x65, 0x6e, 0x74, 0x12, 0x31, 0x0a, 0x09, 0x64, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74,
+	0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x76, 0x65, 0x78, 0x68, 0x75, 0x62,
+	0x2e, 0x76, 0x31, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x09, 0x64, 0x6f,
+	0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x62, 0x0a, 0x08, 0x44, 0x6f, 0x63, 0x75, 0x6d,
+	0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x6f, 0x72,
+	0x6d, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x32, 0x84, 0x01, 0x0a, 0x06,
+	0x56, 0x45, 0x58, 0x48, 0x75, 0x62, 0x12, 0x3d, 0x0a, 0x06, 0x47, 0x65, 0x74, 0x56, 0x45, 0x58,
+	0x12, 0x18, 0x2e, 0x76, 0x65, 0x78, 0x68, 0x75, 0x62, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74,
+	0x56, 0x45, 0x58, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x76, 0x65, 0x78,
+	0x68, 0x75, 0x62, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x56, 0x45, 0x58, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x07, 0x4c, 0x69, 0x73, 0x74, 0x56, 0x45, 0x58,
+	0x12, 0x19, 0x2e, 0x76, 0x65, 0x78, 0x68, 0x75, 0x62, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x56, 0x45, 0x58, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x76, 0x65,
+	0x78, 0x68, 0x75, 0x62, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74,
+	0x30, 0x01, 0x42, 0x3c, 0x5a, 0x3a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x61, 0x71, 0x75, 0x61, 0x73, 0x65, 0x63, 0x75, 0x72, 0x69, 0x74, 0x79, 0x2f, 0x76, 0x65,
+	0x78, 0x68, 0x75, 0x62, 0x2d, 0x63, 0x72, 0x61, 0x77, 0x6c, 0x65, 0x72, 0x2f, 0x70, 0x6b, 0x67,
+	0x2f, 0x76, 0x65, 0x78, 0x68, 0x75, 0x62, 0x2f, 0x76, 0x65, 0x78, 0x68, 0x75, 0x62, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_vexhub_proto_rawDescOnce sync.Once
+	file_vexhub_proto_rawDescData = file_vexhub_proto_rawDesc
+)
+
+func file_vexhub_proto_rawDescGZIP() []byte {
+	file_vexhub_proto_rawDescOnce.Do(func() {
+		file_vexhub_proto_rawDescData = protoimpl.X.CompressGZIP(file_vexhub_proto_rawDescData)
+	})
+	return file_vexhub_proto_rawDescData
+}
+
+var file_vexhub_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_vexhub_proto_goTypes = []any{
+	(*GetVEXRequest)(nil),  // 0: vexhub.v1.GetVEXRequest
+	(*GetVEXResponse)(nil), // 1: vexhub.v1.GetVEXResponse
+	(*ListVEXRequest)(nil), // 2: vexhub.v1.ListVEXRequest
+	(*Manifest)(nil),       // 3: vexhub.v1.Manifest
+	(*Document)(nil),       // 4: vexhub.v1.Document
+}
+var file_vexhub_proto_depIdxs = []int32{
+	3, // 0: vexhub.v1.GetVEXResponse.manifests:type_name -> vexhub.v1.Manifest
+	4, // 1: vexhub.v1.Manifest.documents:type_name -> vexhub.v1.Document
+	0, // 2: vexhub.v1.VEXHub.GetVEX:input_type -> vexhub.v1.GetVEXRequest
+	2, // 3: vexhub.v1.VEXHub.ListVEX:input_type -> vexhub.v1.ListVEXRequest
+	1, // 4: vexhub.v1.VEXHub.GetVEX:output_type -> vexhub.v1.GetVEXResponse
+	3, // 5: vexhub.v1.VEXHub.ListVEX:output_type -> vexhub.v1.Manifest
+	4, // [4:6] is the sub-list for method output_type
+	2, // [2:4] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_vexhub_proto_init() }
+func file_vexhub_proto_init() {
+	if File_vexhub_proto != nil {
+		return
+	}
+	file_vexhub_proto_msgTypes[0].OneofWrappers = []any{
+		(*GetVEXRequest_Purl)(nil),
+		(*GetVEXRequest_Cpe)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_vexhub_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_vexhub_proto_goTypes,
+		DependencyIndexes: file_vexhub_proto_depIdxs,
+		MessageInfos:      file_vexhub_proto_msgTypes,
+	}.Build()
+	File_vexhub_proto = out.File
+	file_vexhub_proto_rawDesc = nil
+	file_vexhub_proto_goTypes = nil
+	file_vexhub_proto_depIdxs = nil
+}
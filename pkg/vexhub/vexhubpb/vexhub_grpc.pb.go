@@ -0,0 +1,175 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: vexhub.proto
+
+package vexhubpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	VEXHub_GetVEX_FullMethodName  = "/vexhub.v1.VEXHub/GetVEX"
+	VEXHub_ListVEX_FullMethodName = "/vexhub.v1.VEXHub/ListVEX"
+)
+
+// VEXHubClient is the client API for VEXHub service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// VEXHub serves the VEX documents stored in VEX Hub, read from the manifests on every request.
+type VEXHubClient interface {
+	// GetVEX returns the documents stored for the package.
+	// A versioned query returns the documents crawled at the version first, then the unversioned ones.
+	GetVEX(ctx context.Context, in *GetVEXRequest, opts ...grpc.CallOption) (*GetVEXResponse, error)
+	// ListVEX streams the manifests whose ID matches the glob, e.g. "pkg:golang/github.com/example/*",
+	// for bulk queries. "*" doesn't match "/" in the glob.
+	ListVEX(ctx context.Context, in *ListVEXRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Manifest], error)
+}
+
+type vEXHubClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVEXHubClient(cc grpc.ClientConnInterface) VEXHubClient {
+	return &vEXHubClient{cc}
+}
+
+func (c *vEXHubClient) GetVEX(ctx context.Context, in *GetVEXRequest, opts ...grpc.CallOption) (*GetVEXResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVEXResponse)
+	err := c.cc.Invoke(ctx, VEXHub_GetVEX_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vEXHubClient) ListVEX(ctx context.Context, in *ListVEXRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Manifest], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &VEXHub_ServiceDesc.Streams[0], VEXHub_ListVEX_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListVEXRequest, Manifest]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VEXHub_ListVEXClient = grpc.ServerStreamingClient[Manifest]
+
+// VEXHubServer is the server API for VEXHub service.
+// All implementations must embed UnimplementedVEXHubServer
+// for forward compatibility.
+//
+// VEXHub serves the VEX documents stored in VEX Hub, read from the manifests on every request.
+type VEXHubServer interface {
+	// GetVEX returns the documents stored for the package.
+	// A versioned query returns the documents crawled at the version first, then the unversioned ones.
+	GetVEX(context.Context, *GetVEXRequest) (*GetVEXResponse, error)
+	// ListVEX streams the manifests whose ID matches the glob, e.g. "pkg:golang/github.com/example/*",
+	// for bulk queries. "*" doesn't match "/" in the glob.
+	ListVEX(*ListVEXRequest, grpc.ServerStreamingServer[Manifest]) error
+	mustEmbedUnimplementedVEXHubServer()
+}
+
+// UnimplementedVEXHubServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVEXHubServer struct{}
+
+func (UnimplementedVEXHubServer) GetVEX(context.Context, *GetVEXRequest) (*GetVEXResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVEX not implemented")
+}
+func (UnimplementedVEXHubServer) ListVEX(*ListVEXRequest, grpc.ServerStreamingServer[Manifest]) error {
+	return status.Errorf(codes.Unimplemented, "method ListVEX not implemented")
+}
+func (UnimplementedVEXHubServer) mustEmbedUnimplementedVEXHubServer() {}
+func (UnimplementedVEXHubServer) testEmbeddedByValue()                {}
+
+// UnsafeVEXHubServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VEXHubServer will
+// result in compilation errors.
+type UnsafeVEXHubServer interface {
+	mustEmbedUnimplementedVEXHubServer()
+}
+
+func RegisterVEXHubServer(s grpc.ServiceRegistrar, srv VEXHubServer) {
+	// If the following call pancis, it indicates UnimplementedVEXHubServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&VEXHub_ServiceDesc, srv)
+}
+
+func _VEXHub_GetVEX_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVEXRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VEXHubServer).GetVEX(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VEXHub_GetVEX_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VEXHubServer).GetVEX(ctx, req.(*GetVEXRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VEXHub_ListVEX_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListVEXRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VEXHubServer).ListVEX(m, &grpc.GenericServerStream[ListVEXRequest, Manifest]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VEXHub_ListVEXServer = grpc.ServerStreamingServer[Manifest]
+
+// VEXHub_ServiceDesc is the grpc.ServiceDesc for VEXHub service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VEXHub_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vexhub.v1.VEXHub",
+	HandlerType: (*VEXHubServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetVEX",
+			Handler:    _VEXHub_GetVEX_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListVEX",
+			Handler:       _VEXHub_ListVEX_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "vexhub.proto",
+}